@@ -12,7 +12,7 @@ import (
 
 // GetAllAbonnements récupère tous les abonnements
 func GetAllAbonnements(c *fiber.Ctx) error {
-	db := database.DB
+	db := database.DB.WithContext(c.UserContext())
 
 	var abonnements []models.Abonnement
 	if err := db.Preload("Entreprise").Order("created_at DESC").Find(&abonnements).Error; err != nil {
@@ -31,7 +31,7 @@ func GetAllAbonnements(c *fiber.Ctx) error {
 
 // GetPaginatedAbonnements récupère les abonnements avec pagination et filtres
 func GetPaginatedAbonnements(c *fiber.Ctx) error {
-	db := database.DB
+	db := database.DB.WithContext(c.UserContext())
 
 	// Parse query parameters for pagination
 	page, err := strconv.Atoi(c.Query("page", "1"))
@@ -94,7 +94,7 @@ func GetPaginatedAbonnements(c *fiber.Ctx) error {
 
 // GetPaginatedAbonnements récupère les abonnements avec pagination et filtres
 func GetPaginatedAbonnementsEntreprise(c *fiber.Ctx) error {
-	db := database.DB
+	db := database.DB.WithContext(c.UserContext())
 
 	// Parse query parameters for pagination
 	page, err := strconv.Atoi(c.Query("page", "1"))
@@ -163,7 +163,7 @@ func GetPaginatedAbonnementsEntreprise(c *fiber.Ctx) error {
 
 // GetAbonnement récupère un abonnement par UUID
 func GetAbonnement(c *fiber.Ctx) error {
-	db := database.DB
+	db := database.DB.WithContext(c.UserContext())
 	uuid := c.Params("uuid")
 
 	var abonnement models.Abonnement
@@ -182,7 +182,7 @@ func GetAbonnement(c *fiber.Ctx) error {
 
 // CreateAbonnement crée un nouvel abonnement
 func CreateAbonnement(c *fiber.Ctx) error {
-	db := database.DB
+	db := database.DB.WithContext(c.UserContext())
 
 	var abonnement models.Abonnement
 	if err := c.BodyParser(&abonnement); err != nil {
@@ -267,7 +267,7 @@ func CreateAbonnement(c *fiber.Ctx) error {
 
 // UpdateAbonnement met à jour un abonnement
 func UpdateAbonnement(c *fiber.Ctx) error {
-	db := database.DB
+	db := database.DB.WithContext(c.UserContext())
 	uuid := c.Params("uuid")
 
 	var abonnement models.Abonnement
@@ -340,7 +340,7 @@ func UpdateAbonnement(c *fiber.Ctx) error {
 
 // DeleteAbonnement supprime un abonnement (soft delete)
 func DeleteAbonnement(c *fiber.Ctx) error {
-	db := database.DB
+	db := database.DB.WithContext(c.UserContext())
 	uuid := c.Params("uuid")
 
 	var abonnement models.Abonnement
@@ -366,7 +366,7 @@ func DeleteAbonnement(c *fiber.Ctx) error {
 
 // UpdateStatutAbonnement met à jour le statut d'un abonnement
 func UpdateStatutAbonnement(c *fiber.Ctx) error {
-	db := database.DB
+	db := database.DB.WithContext(c.UserContext())
 	uuid := c.Params("uuid")
 
 	var request struct {
@@ -418,7 +418,7 @@ func UpdateStatutAbonnement(c *fiber.Ctx) error {
 // GetAbonnementActuel récupère l'abonnement actuel valide d'une entreprise
 // basé sur la date de création, la durée et le statut
 func GetAbonnementActuel(c *fiber.Ctx) error {
-	db := database.DB
+	db := database.DB.WithContext(c.UserContext())
 	entrepriseUUID := c.Query("entreprise_uuid")
 
 	if entrepriseUUID == "" {
@@ -468,7 +468,7 @@ func GetAbonnementActuel(c *fiber.Ctx) error {
 
 // VerifierValiditeAbonnement vérifie si un abonnement est encore valide
 func VerifierValiditeAbonnement(c *fiber.Ctx) error {
-	db := database.DB
+	db := database.DB.WithContext(c.UserContext())
 	uuid := c.Params("uuid")
 
 	var abonnement models.Abonnement
@@ -506,7 +506,7 @@ func VerifierValiditeAbonnement(c *fiber.Ctx) error {
 
 // GetAbonnementsExpirant récupère les abonnements qui vont expirer dans X jours
 func GetAbonnementsExpirant(c *fiber.Ctx) error {
-	db := database.DB
+	db := database.DB.WithContext(c.UserContext())
 
 	// Paramètre pour le nombre de jours (par défaut 30 jours)
 	jours, err := strconv.Atoi(c.Query("jours", "30"))
@@ -552,7 +552,7 @@ func GetAbonnementsExpirant(c *fiber.Ctx) error {
 
 // GetStatistiquesAbonnements récupère les statistiques des abonnements
 func GetStatistiquesAbonnements(c *fiber.Ctx) error {
-	db := database.DB
+	db := database.DB.WithContext(c.UserContext())
 
 	var stats struct {
 		TotalAbonnements   int64   `json:"total_abonnements"`
@@ -0,0 +1,178 @@
+package apikeys
+
+import (
+	"strings"
+	"time"
+
+	"github.com/kgermando/ipos-stock-api/database"
+	"github.com/kgermando/ipos-stock-api/middlewares"
+	"github.com/kgermando/ipos-stock-api/models"
+	"github.com/kgermando/ipos-stock-api/utils"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Get All data by entreprise
+func GetAllApiKeys(c *fiber.Ctx) error {
+	db := database.DB.WithContext(c.UserContext())
+	entrepriseUUID := c.Params("entreprise_uuid")
+
+	var data []models.ApiKey
+	db.Where("entreprise_uuid = ?", entrepriseUUID).
+		Order("created_at DESC").
+		Find(&data)
+
+	return c.JSON(fiber.Map{
+		"status":  "success",
+		"message": "All api keys",
+		"data":    data,
+	})
+}
+
+// Create data. La clé en clair n'est retournée qu'une seule fois.
+func CreateApiKey(c *fiber.Ctx) error {
+	user := middlewares.GetAuthUser(c)
+	db := database.DB.WithContext(c.UserContext())
+
+	var req models.ApiKeyRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Invalid JSON format",
+			"data":    nil,
+		})
+	}
+
+	if err := utils.ValidateStruct(req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Données invalides",
+			"errors":  err,
+		})
+	}
+
+	scopes := make([]string, 0, len(req.Scopes))
+	permissions := make([]string, 0, len(req.Scopes))
+	for _, s := range req.Scopes {
+		s = strings.TrimSpace(s)
+		p := models.ScopePermission(s)
+		if p == "" {
+			return c.Status(400).JSON(fiber.Map{
+				"status":  "error",
+				"message": "Scope inconnu : " + s,
+				"data":    nil,
+			})
+		}
+		scopes = append(scopes, s)
+		permissions = append(permissions, p)
+	}
+
+	// Une clé ne peut recevoir que des scopes dont le créateur dispose lui-même
+	if !user.CanGrantPermissions(strings.Join(permissions, ",")) {
+		return c.Status(403).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Vous ne pouvez pas accorder ces scopes",
+			"data":    nil,
+		})
+	}
+
+	// Un gérant de POS ne crée que des clés limitées à son point de vente
+	if !user.HasEntrepriseScope() {
+		if req.PosUUID != "" && req.PosUUID != user.PosUUID {
+			return c.Status(403).JSON(fiber.Map{
+				"status":  "error",
+				"message": "Vous ne pouvez créer une clé que pour votre point de vente",
+				"data":    nil,
+			})
+		}
+		req.PosUUID = user.PosUUID
+	}
+
+	if req.ExpiresAt != nil && req.ExpiresAt.Before(time.Now()) {
+		return c.Status(400).JSON(fiber.Map{
+			"status":  "error",
+			"message": "La date d'expiration doit être dans le futur",
+			"data":    nil,
+		})
+	}
+
+	if req.PosUUID != "" {
+		var pos models.Pos
+		db.Where("uuid = ?", req.PosUUID).First(&pos)
+		if pos.UUID == "" {
+			return c.Status(404).JSON(fiber.Map{
+				"status":  "error",
+				"message": "No pos found",
+				"data":    nil,
+			})
+		}
+	}
+
+	token, err := utils.GenerateSecureToken(24)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Erreur lors de la génération de la clé",
+			"data":    nil,
+		})
+	}
+	rawKey := models.ApiKeyPrefix + token
+
+	p := &models.ApiKey{
+		UUID:           utils.GenerateUUID(),
+		Name:           req.Name,
+		Prefix:         rawKey[:12],
+		KeyHash:        utils.HashToken(rawKey),
+		Scopes:         strings.Join(scopes, ","),
+		ExpiresAt:      req.ExpiresAt,
+		CreatedBy:      user.UUID,
+		EntrepriseUUID: user.EntrepriseUUID,
+		PosUUID:        req.PosUUID,
+	}
+
+	if err := db.Create(p).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Erreur lors de la création de la clé",
+			"data":    nil,
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"status":  "success",
+		"message": "Conservez cette clé, elle ne sera plus affichée",
+		"data":    p,
+		"key":     rawKey,
+	})
+}
+
+// Revoke data
+func RevokeApiKey(c *fiber.Ctx) error {
+	uuid := c.Params("uuid")
+	db := database.DB.WithContext(c.UserContext())
+
+	var apiKey models.ApiKey
+	db.Where("uuid = ?", uuid).First(&apiKey)
+	if apiKey.UUID == "" {
+		return c.Status(404).JSON(
+			fiber.Map{
+				"status":  "error",
+				"message": "No api key found",
+				"data":    nil,
+			},
+		)
+	}
+
+	if apiKey.RevokedAt == nil {
+		now := time.Now()
+		db.Model(&apiKey).Update("revoked_at", &now)
+	}
+
+	return c.JSON(
+		fiber.Map{
+			"status":  "success",
+			"message": "Api key revoked success",
+			"data":    apiKey,
+		},
+	)
+}
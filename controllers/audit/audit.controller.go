@@ -0,0 +1,110 @@
+package audit
+
+import (
+	"strconv"
+
+	"github.com/kgermando/ipos-stock-api/database"
+	"github.com/kgermando/ipos-stock-api/models"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Paginate audit logs of an entreprise.
+// Filtres : entity, entity_uuid, user_uuid, action, pos_uuid, start_date, end_date
+func GetPaginatedAuditLogs(c *fiber.Ctx) error {
+	db := database.DB.WithContext(c.UserContext())
+	entrepriseUUID := c.Params("entreprise_uuid")
+
+	page, err := strconv.Atoi(c.Query("page", "1"))
+	if err != nil || page <= 0 {
+		page = 1 // Default page number
+	}
+	limit, err := strconv.Atoi(c.Query("limit", "15"))
+	if err != nil || limit <= 0 {
+		limit = 15
+	}
+	offset := (page - 1) * limit
+
+	query := db.Model(&models.AuditLog{}).Where("entreprise_uuid = ?", entrepriseUUID)
+
+	filters := map[string]string{
+		"entity":      c.Query("entity"),
+		"entity_uuid": c.Query("entity_uuid"),
+		"user_uuid":   c.Query("user_uuid"),
+		"action":      c.Query("action"),
+	}
+	for column, value := range filters {
+		if value != "" {
+			query = query.Where(column+" = ?", value)
+		}
+	}
+	if posUUID := c.Query("pos_uuid"); posUUID != "" && posUUID != "-" {
+		query = query.Where("pos_uuid = ?", posUUID)
+	}
+	if startDate := c.Query("start_date"); startDate != "" {
+		query = query.Where("created_at >= ?", startDate)
+	}
+	if endDate := c.Query("end_date"); endDate != "" {
+		query = query.Where("created_at <= ?", endDate)
+	}
+
+	var totalRecords int64
+	query.Count(&totalRecords)
+
+	var dataList []models.AuditLog
+	err = query.Offset(offset).
+		Limit(limit).
+		Order("created_at DESC").
+		Find(&dataList).Error
+
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Failed to fetch audit logs",
+			"error":   err.Error(),
+		})
+	}
+
+	// Calculate total pages
+	totalPages := int((totalRecords + int64(limit) - 1) / int64(limit))
+
+	// Prepare pagination metadata
+	pagination := map[string]interface{}{
+		"total_records": totalRecords,
+		"total_pages":   totalPages,
+		"current_page":  page,
+		"page_size":     limit,
+	}
+
+	return c.JSON(fiber.Map{
+		"status":     "success",
+		"message":    "All audit logs paginated",
+		"data":       dataList,
+		"pagination": pagination,
+	})
+}
+
+// Get one data
+func GetAuditLog(c *fiber.Ctx) error {
+	uuid := c.Params("uuid")
+	db := database.DB.WithContext(c.UserContext())
+
+	var auditLog models.AuditLog
+	db.Where("uuid = ?", uuid).First(&auditLog)
+	if auditLog.UUID == "" {
+		return c.Status(404).JSON(
+			fiber.Map{
+				"status":  "error",
+				"message": "No audit log found",
+				"data":    nil,
+			},
+		)
+	}
+	return c.JSON(
+		fiber.Map{
+			"status":  "success",
+			"message": "audit log found",
+			"data":    auditLog,
+		},
+	)
+}
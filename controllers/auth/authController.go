@@ -1,19 +1,24 @@
 package auth
 
 import (
-	"fmt"
 	"os"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
 	"github.com/kgermando/ipos-stock-api/database"
+	"github.com/kgermando/ipos-stock-api/middlewares"
 	"github.com/kgermando/ipos-stock-api/models"
 	"github.com/kgermando/ipos-stock-api/utils"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 var SECRET_KEY string = os.Getenv("SECRET_KEY")
 
+// Register crée le gérant d'une entreprise qui vient d'être créée. Elle ne
+// sert qu'à l'inscription : une entreprise qui a déjà des utilisateurs
+// n'accueille de nouveaux comptes que par invitation.
 func Register(c *fiber.Ctx) error {
 
 	nu := new(models.User)
@@ -32,15 +37,15 @@ func Register(c *fiber.Ctx) error {
 		})
 	}
 
+	// Le rôle, les permissions, le statut et le POS ne sont jamais pris dans
+	// la requête : le premier compte est le gérant actif de toute l'entreprise
 	u := &models.User{
 		UUID:           uuid.New().String(),
 		Fullname:       nu.Fullname,
 		Email:          nu.Email,
 		Telephone:      nu.Telephone,
-		Role:           nu.Role,
-		Permission:     nu.Permission,
-		Status:         nu.Status,
-		PosUUID:        nu.PosUUID,
+		Role:           models.RoleEntrepriseManager,
+		Status:         true,
 		EntrepriseUUID: nu.EntrepriseUUID,
 		Signature:      nu.Signature,
 	}
@@ -52,7 +57,35 @@ func Register(c *fiber.Ctx) error {
 		return c.JSON(err)
 	}
 
-	database.DB.Create(u)
+	status, message := 400, "entreprise introuvable"
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		// Le verrou sur l'entreprise empêche deux inscriptions simultanées
+		var entreprise models.Entreprise
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("uuid = ?", u.EntrepriseUUID).
+			First(&entreprise).Error; err != nil {
+			return err
+		}
+
+		var count int64
+		tx.Model(&models.User{}).Unscoped().Where("entreprise_uuid = ?", entreprise.UUID).Count(&count)
+		if count > 0 {
+			status, message = 403, "cette entreprise a déjà un compte : demandez une invitation à son gérant"
+			return gorm.ErrInvalidData
+		}
+
+		if err := tx.Create(u).Error; err != nil {
+			status, message = 500, "erreur lors de la création du compte"
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		c.Status(status)
+		return c.JSON(fiber.Map{
+			"message": message,
+		})
+	}
 
 	return c.JSON(fiber.Map{
 		"message": "user account created",
@@ -76,6 +109,14 @@ func Login(c *fiber.Ctx) error {
 		return c.JSON(err)
 	}
 
+	idKey := models.LoginThrottleKey(lu.Identifier)
+	ipKey := loginIPKey(c.IP())
+
+	// Délai progressif puis verrouillage temporaire après plusieurs échecs
+	if wait := max(throttleWait(idKey, loginIdentifierPolicy), throttleWait(ipKey, loginIPPolicy)); wait > 0 {
+		return tooManyAttempts(c, wait)
+	}
+
 	u := &models.User{}
 
 	result := database.DB.Where("email = ? OR telephone = ?", lu.Identifier, lu.Identifier).
@@ -84,6 +125,8 @@ func Login(c *fiber.Ctx) error {
 		First(&u)
 
 	if result.Error != nil {
+		throttleFail(c, idKey, loginIdentifierPolicy, nil)
+		throttleFail(c, ipKey, loginIPPolicy, nil)
 		c.Status(404)
 		return c.JSON(fiber.Map{
 			"message": "invalid email or telephone 😰",
@@ -91,12 +134,16 @@ func Login(c *fiber.Ctx) error {
 	}
 
 	if err := u.ComparePassword(lu.Password); err != nil {
+		throttleFail(c, idKey, loginIdentifierPolicy, u)
+		throttleFail(c, ipKey, loginIPPolicy, u)
 		c.Status(400)
 		return c.JSON(fiber.Map{
 			"message": "mot de passe incorrect! 😰",
 		})
 	}
 
+	throttleReset(idKey)
+
 	if !u.Status {
 		c.Status(400)
 		return c.JSON(fiber.Map{
@@ -112,35 +159,52 @@ func Login(c *fiber.Ctx) error {
 		})
 	}
 
-	token, err := utils.GenerateJwt(u.UUID)
-	if err != nil {
-		return c.SendStatus(fiber.StatusInternalServerError)
+	// Second facteur : le client doit rappeler /auth/login/2fa avec ce token
+	if u.TwoFactorEnabled {
+		challengeToken, err := utils.GenerateChallengeToken(u.UUID)
+		if err != nil {
+			return c.SendStatus(fiber.StatusInternalServerError)
+		}
+		return c.JSON(fiber.Map{
+			"message":         "2fa_required",
+			"challenge_token": challengeToken,
+			"expires_in":      int(utils.ChallengeTokenTTL.Seconds()),
+		})
 	}
 
-	return c.JSON(fiber.Map{
-		"message": "success",
-		"data":    token,
-	})
+	return startSession(c, u, lu.Device)
 
 }
 
-func AuthUser(c *fiber.Ctx) error {
-
-	token := c.Query("token")
+// startSession enregistre le terminal éventuel puis ouvre une session
+func startSession(c *fiber.Ctx, u *models.User, deviceInfo *models.DeviceInfo) error {
+	deviceUUID := ""
+	if deviceInfo != nil && deviceInfo.DeviceID != "" {
+		device, err := registerDevice(database.DB, u, deviceInfo)
+		if err != nil {
+			return c.SendStatus(fiber.StatusInternalServerError)
+		}
+		if device.RevokedAt != nil {
+			c.Status(403)
+			return c.JSON(fiber.Map{
+				"message": "cet appareil a été révoqué, contactez votre gérant 😰",
+			})
+		}
+		deviceUUID = device.UUID
+	}
 
-	fmt.Println("token", token)
+	_, accessToken, refreshToken, err := createSession(database.DB, c, u.UUID, deviceUUID)
+	if err != nil {
+		return c.SendStatus(fiber.StatusInternalServerError)
+	}
 
-	// cookie := c.Cookies("token")
-	UserUUID, _ := utils.VerifyJwt(token)
+	return tokenResponse(c, accessToken, refreshToken)
+}
 
-	fmt.Println("UserUUID", UserUUID)
+func AuthUser(c *fiber.Ctx) error {
 
-	u := models.User{}
+	u := middlewares.GetAuthUser(c)
 
-	database.DB.Where("users.uuid = ?", UserUUID).
-		Preload("Entreprise").
-		Preload("Pos").
-		First(&u)
 	r := &models.UserResponse{
 		UUID:           u.UUID,
 		Fullname:       u.Fullname,
@@ -156,18 +220,32 @@ func AuthUser(c *fiber.Ctx) error {
 		Signature:      u.Signature,
 		CreatedAt:      u.CreatedAt,
 		UpdatedAt:      u.UpdatedAt,
+
+		TwoFactorEnabled:  u.TwoFactorEnabled,
+		TwoFactorRequired: u.TwoFactorRequired(),
+		HasPin:            u.PinHash != "",
 	}
 	return c.JSON(r)
 }
 
+// GetPermissions retourne le rôle et les permissions effectives de l'utilisateur connecté
+func GetPermissions(c *fiber.Ctx) error {
+	u := middlewares.GetAuthUser(c)
+
+	return c.JSON(fiber.Map{
+		"status":  "success",
+		"message": "User permissions",
+		"data": fiber.Map{
+			"role":        u.Role,
+			"permissions": u.Permissions(),
+		},
+	})
+}
+
+// Logout révoque la session du token utilisé pour la requête
 func Logout(c *fiber.Ctx) error {
-	cookie := fiber.Cookie{
-		Name:     "token",
-		Value:    "",
-		Expires:  time.Now().Add(-time.Hour), // 1 day ,
-		HTTPOnly: true,
-	}
-	c.Cookie(&cookie)
+	now := time.Now()
+	database.DB.Model(middlewares.GetSession(c)).Update("revoked_at", &now)
 
 	return c.JSON(fiber.Map{
 		"message": "success",
@@ -194,16 +272,12 @@ func UpdateInfo(c *fiber.Ctx) error {
 		})
 	}
 
-	cookie := c.Cookies("token")
-
-	UserUUID, _ := utils.VerifyJwt(cookie)
-
 	user := new(models.User)
 
 	db := database.DB
 
 	// Utiliser UUID au lieu de convertir en int
-	result := db.Where("uuid = ?", UserUUID).First(&user)
+	result := db.Where("uuid = ?", middlewares.GetAuthUser(c).UUID).First(&user)
 
 	if result.Error != nil {
 		return c.Status(404).JSON(fiber.Map{
@@ -243,14 +317,10 @@ func ChangePassword(c *fiber.Ctx) error {
 		})
 	}
 
-	cookie := c.Cookies("token")
-
-	UserUUID, _ := utils.VerifyJwt(cookie)
-
 	user := new(models.User)
 
 	// Utiliser UUID au lieu de id car c'est la clé primaire du modèle User
-	result := database.DB.Where("uuid = ?", UserUUID).First(&user)
+	result := database.DB.Where("uuid = ?", middlewares.GetAuthUser(c).UUID).First(&user)
 
 	if result.Error != nil {
 		c.Status(404)
@@ -280,6 +350,9 @@ func ChangePassword(c *fiber.Ctx) error {
 	db := database.DB
 	db.Save(&user)
 
+	// Le changement de mot de passe déconnecte tous les appareils
+	revokeUserSessions(db, user.UUID)
+
 	return c.JSON(fiber.Map{
 		"status":  "success",
 		"message": "Mot de passe modifié avec succès",
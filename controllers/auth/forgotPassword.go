@@ -35,6 +35,14 @@ func Forgot(c *fiber.Ctx) error {
 		})
 	}
 
+	// Limitation du nombre de demandes pour éviter d'inonder un utilisateur d'emails
+	emailKey, ipKey := forgotKey(req.Email), forgotIPKey(c.IP())
+	if wait := max(throttleWait(emailKey, forgotPolicy), throttleWait(ipKey, loginIPPolicy)); wait > 0 {
+		return tooManyAttempts(c, wait)
+	}
+	throttleFail(c, emailKey, forgotPolicy, nil)
+	throttleFail(c, ipKey, loginIPPolicy, nil)
+
 	// Recherche de l'employé dans la base de données
 	user := &models.User{}
 	result := database.DB.Where("email = ?", req.Email).First(user)
@@ -239,6 +247,15 @@ func ResetPassword(c *fiber.Ctx) error {
 		Where("email = ? AND uuid != ? AND used = false", passwordReset.Email, passwordReset.UUID).
 		Update("used", true)
 
+	// Fermeture de toutes les sessions ouvertes avec l'ancien mot de passe
+	if err := revokeUserSessions(tx, user.UUID); err != nil {
+		tx.Rollback()
+		return c.Status(500).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Erreur lors de la fermeture des sessions",
+		})
+	}
+
 	// Validation de la transaction
 	if err := tx.Commit().Error; err != nil {
 		return c.Status(500).JSON(fiber.Map{
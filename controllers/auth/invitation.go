@@ -0,0 +1,148 @@
+package auth
+
+import (
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/kgermando/ipos-stock-api/database"
+	"github.com/kgermando/ipos-stock-api/models"
+	"github.com/kgermando/ipos-stock-api/utils"
+	"gorm.io/gorm"
+)
+
+// findInvitation retourne l'invitation en attente correspondant au token
+func findInvitation(tx *gorm.DB, token string) (*models.Invitation, bool) {
+	invitation := &models.Invitation{}
+	if token == "" || tx.Where("token_hash = ?", utils.HashToken(token)).
+		Preload("Entreprise").
+		Preload("Pos").
+		First(invitation).Error != nil {
+		return nil, false
+	}
+	return invitation, invitation.IsPending()
+}
+
+// VerifyInvitation vérifie un token d'invitation et retourne ses informations
+func VerifyInvitation(c *fiber.Ctx) error {
+	invitation, ok := findInvitation(database.DB, c.Params("token"))
+	if !ok {
+		return c.Status(400).JSON(fiber.Map{
+			"status":  "error",
+			"valid":   false,
+			"message": "Invitation invalide ou expirée",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"status":  "success",
+		"valid":   true,
+		"message": "Invitation valide",
+		"data": fiber.Map{
+			"email":      invitation.Email,
+			"fullname":   invitation.Fullname,
+			"role":       invitation.Role,
+			"entreprise": invitation.Entreprise.Name,
+			"pos":        invitation.Pos.Name,
+			"expires_at": invitation.ExpiresAt,
+		},
+	})
+}
+
+// AcceptInvitation crée le compte de l'invité avec le mot de passe qu'il a choisi
+func AcceptInvitation(c *fiber.Ctx) error {
+	var req models.AcceptInvitationRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Données JSON invalides",
+			"errors":  err.Error(),
+		})
+	}
+
+	if err := utils.ValidateStruct(req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Données invalides",
+			"errors":  err,
+		})
+	}
+
+	if req.Password != req.PasswordConfirm {
+		return c.Status(400).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Les mots de passe ne correspondent pas",
+		})
+	}
+
+	if err := utils.ValidatePassword(req.Password); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"status":  "error",
+			"message": err.Error(),
+		})
+	}
+
+	var user *models.User
+	status, message := 400, "Invitation invalide ou expirée"
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		invitation, ok := findInvitation(tx, c.Params("token"))
+		if !ok {
+			return gorm.ErrRecordNotFound
+		}
+
+		var count int64
+		tx.Model(&models.User{}).Where("email = ? OR telephone = ?", invitation.Email, req.Telephone).Count(&count)
+		if count > 0 {
+			status, message = 409, "Un compte existe déjà avec cet email ou ce téléphone"
+			return gorm.ErrDuplicatedKey
+		}
+
+		user = &models.User{
+			UUID:           utils.GenerateUUID(),
+			Fullname:       req.Fullname,
+			Email:          invitation.Email,
+			Telephone:      req.Telephone,
+			Role:           invitation.Role,
+			Permission:     invitation.Permission,
+			Status:         true,
+			EntrepriseUUID: invitation.EntrepriseUUID,
+			PosUUID:        invitation.PosUUID,
+			Sync:           true,
+		}
+		user.SetPassword(req.Password)
+
+		if err := tx.Create(user).Error; err != nil {
+			status, message = 500, "Erreur lors de la création du compte"
+			return err
+		}
+
+		// La condition sur accepted_at empêche d'accepter deux fois la même invitation
+		now := time.Now()
+		result := tx.Model(invitation).
+			Where("accepted_at IS NULL").
+			Updates(map[string]interface{}{
+				"accepted_at": &now,
+				"user_uuid":   user.UUID,
+			})
+		if result.Error != nil || result.RowsAffected != 1 {
+			return gorm.ErrRecordNotFound
+		}
+		return nil
+	})
+
+	if err != nil {
+		return c.Status(status).JSON(fiber.Map{
+			"status":  "error",
+			"message": message,
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"status":  "success",
+		"message": "Compte activé, vous pouvez vous connecter",
+		"data": fiber.Map{
+			"uuid":  user.UUID,
+			"email": user.Email,
+		},
+	})
+}
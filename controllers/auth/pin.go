@@ -0,0 +1,173 @@
+package auth
+
+import (
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/kgermando/ipos-stock-api/database"
+	"github.com/kgermando/ipos-stock-api/middlewares"
+	"github.com/kgermando/ipos-stock-api/models"
+	"github.com/kgermando/ipos-stock-api/utils"
+	"gorm.io/gorm"
+)
+
+// SetPin définit ou remplace le code PIN de l'utilisateur connecté
+func SetPin(c *fiber.Ctx) error {
+	user := middlewares.GetAuthUser(c)
+
+	var req models.PinRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Données JSON invalides",
+			"errors":  err.Error(),
+		})
+	}
+
+	if err := utils.ValidateStruct(req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Données invalides",
+			"errors":  err,
+		})
+	}
+
+	if err := utils.ValidatePin(req.Pin); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"status":  "error",
+			"message": err.Error(),
+		})
+	}
+
+	if !models.HasPinRole(user.Role) {
+		return c.Status(403).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Le code PIN est réservé aux caissiers",
+		})
+	}
+
+	if err := user.ComparePassword(req.Password); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Mot de passe incorrect",
+		})
+	}
+
+	// Le caissier est choisi sur l'écran de verrouillage avant de saisir son
+	// PIN : deux utilisateurs peuvent donc avoir le même code
+	user.SetPin(req.Pin)
+	database.DB.Model(user).Update("pin_hash", user.PinHash)
+
+	return c.JSON(fiber.Map{
+		"status":  "success",
+		"message": "Code PIN enregistré",
+	})
+}
+
+// RemovePin supprime le code PIN de l'utilisateur connecté
+func RemovePin(c *fiber.Ctx) error {
+	user := middlewares.GetAuthUser(c)
+
+	database.DB.Model(user).Update("pin_hash", "")
+
+	return c.JSON(fiber.Map{
+		"status":  "success",
+		"message": "Code PIN supprimé",
+	})
+}
+
+// SwitchCashier change le caissier actif d'un terminal déjà authentifié pour le POS.
+// La session courante est fermée et une nouvelle session est ouverte pour le caissier.
+func SwitchCashier(c *fiber.Ctx) error {
+	device := middlewares.GetDevice(c)
+	if device == nil {
+		return c.Status(403).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Le changement de caissier n'est possible que depuis un terminal enregistré",
+		})
+	}
+
+	var req models.PinSwitchRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Données JSON invalides",
+			"errors":  err.Error(),
+		})
+	}
+
+	if err := utils.ValidateStruct(req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Données invalides",
+			"errors":  err,
+		})
+	}
+
+	// Les échecs sont comptés pour le caissier visé et pour tout le POS :
+	// changer d'identifiant de terminal ne remet pas les compteurs à zéro
+	userKey, posKey := pinUserKey(req.UserUUID), pinPosKey(device.PosUUID)
+	if wait := max(throttleWait(userKey, pinPolicy), throttleWait(posKey, pinPosPolicy)); wait > 0 {
+		return tooManyAttempts(c, wait)
+	}
+
+	cashier := pinUser(database.DB, device.PosUUID, req.UserUUID)
+	if cashier == nil || !cashier.ComparePin(req.Pin) {
+		throttleFail(c, userKey, pinPolicy, cashier)
+		throttleFail(c, posKey, pinPosPolicy, nil)
+		return c.Status(400).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Code PIN invalide 😰",
+		})
+	}
+	throttleReset(userKey)
+
+	// Les comptes protégés par double authentification passent par le login complet
+	if cashier.TwoFactorEnabled {
+		return c.Status(403).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Ce compte doit se connecter avec son mot de passe et sa double authentification",
+		})
+	}
+
+	var accessToken, refreshToken string
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if session := middlewares.GetSession(c); session != nil {
+			if err := tx.Model(session).Update("revoked_at", time.Now()).Error; err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Model(device).Updates(map[string]interface{}{
+			"user_uuid":    cashier.UUID,
+			"last_seen_at": time.Now(),
+		}).Error; err != nil {
+			return err
+		}
+
+		_, access, refresh, err := createSession(tx, c, cashier.UUID, device.UUID)
+		accessToken, refreshToken = access, refresh
+		return err
+	})
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Erreur lors du changement de caissier",
+		})
+	}
+
+	return tokenResponse(c, accessToken, refreshToken)
+}
+
+// pinUser retourne l'utilisateur actif du POS qui peut se connecter par code
+// PIN, ou nil. Un gérant ne prend jamais la main sur un terminal par son PIN.
+func pinUser(tx *gorm.DB, posUUID, userUUID string) *models.User {
+	var user models.User
+	tx.Where("uuid = ? AND pos_uuid = ? AND status = ? AND role IN ? AND pin_hash <> ''",
+		userUUID, posUUID, true, models.PinRoles).
+		Limit(1).Find(&user)
+	if user.UUID == "" {
+		return nil
+	}
+	return &user
+}
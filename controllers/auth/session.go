@@ -0,0 +1,193 @@
+package auth
+
+import (
+	"log"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/kgermando/ipos-stock-api/database"
+	"github.com/kgermando/ipos-stock-api/middlewares"
+	"github.com/kgermando/ipos-stock-api/models"
+	"github.com/kgermando/ipos-stock-api/utils"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// createSession enregistre une nouvelle session et retourne la paire de tokens
+func createSession(tx *gorm.DB, c *fiber.Ctx, userUUID, deviceUUID string) (*models.Session, string, string, error) {
+	refreshToken, err := utils.GenerateSecureToken(32)
+	if err != nil {
+		return nil, "", "", err
+	}
+
+	session := &models.Session{
+		UUID:             utils.GenerateUUID(),
+		UserUUID:         userUUID,
+		RefreshTokenHash: utils.HashToken(refreshToken),
+		ExpiresAt:        time.Now().Add(utils.RefreshTokenTTL),
+		DeviceUUID:       deviceUUID,
+		UserAgent:        c.Get(fiber.HeaderUserAgent),
+		IP:               c.IP(),
+	}
+
+	if err := tx.Create(session).Error; err != nil {
+		return nil, "", "", err
+	}
+
+	accessToken, err := utils.GenerateAccessToken(userUUID, session.UUID)
+	if err != nil {
+		return nil, "", "", err
+	}
+
+	return session, accessToken, refreshToken, nil
+}
+
+// registerDevice enregistre ou met à jour le terminal POS utilisé pour se connecter
+func registerDevice(tx *gorm.DB, user *models.User, info *models.DeviceInfo) (*models.Device, error) {
+	device := &models.Device{}
+	result := tx.Where("device_id = ? AND pos_uuid = ?", info.DeviceID, user.PosUUID).First(device)
+	if result.Error != nil && result.Error != gorm.ErrRecordNotFound {
+		return nil, result.Error
+	}
+
+	if device.RevokedAt != nil {
+		return device, nil
+	}
+
+	if device.UUID == "" {
+		device.UUID = utils.GenerateUUID()
+		device.DeviceID = info.DeviceID
+		device.EntrepriseUUID = user.EntrepriseUUID
+		device.PosUUID = user.PosUUID
+	}
+	device.Name = info.Name
+	device.Platform = info.Platform
+	device.AppVersion = info.AppVersion
+	device.UserUUID = user.UUID
+	device.LastSeenAt = time.Now()
+
+	if err := tx.Save(device).Error; err != nil {
+		return nil, err
+	}
+	return device, nil
+}
+
+// tokenResponse construit la réponse commune au login et au refresh
+func tokenResponse(c *fiber.Ctx, accessToken, refreshToken string) error {
+	return c.JSON(fiber.Map{
+		"message":       "success",
+		"data":          accessToken,
+		"refresh_token": refreshToken,
+		"expires_in":    int(utils.AccessTokenTTL.Seconds()),
+	})
+}
+
+// revokeUserSessions révoque toutes les sessions actives d'un utilisateur
+func revokeUserSessions(tx *gorm.DB, userUUID string) error {
+	return tx.Model(&models.Session{}).
+		Where("user_uuid = ? AND revoked_at IS NULL", userUUID).
+		Update("revoked_at", time.Now()).Error
+}
+
+// Refresh échange un refresh token valide contre une nouvelle paire de tokens.
+// L'ancien refresh token est révoqué ; sa réutilisation révoque toutes les sessions.
+func Refresh(c *fiber.Ctx) error {
+	req := new(models.RefreshRequest)
+
+	if err := c.BodyParser(req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Données JSON invalides",
+			"errors":  err.Error(),
+		})
+	}
+
+	if err := utils.ValidateStruct(*req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Données invalides",
+			"errors":  err,
+		})
+	}
+
+	var accessToken, refreshToken string
+	reused := false
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		session := &models.Session{}
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("refresh_token_hash = ?", utils.HashToken(req.RefreshToken)).
+			First(session).Error; err != nil {
+			return err
+		}
+
+		if session.RevokedAt != nil && session.ReplacedBy != "" {
+			// Token déjà utilisé : probable vol, on coupe toutes les sessions
+			reused = true
+			return revokeUserSessions(tx, session.UserUUID)
+		}
+
+		if !session.IsActive() {
+			return gorm.ErrRecordNotFound
+		}
+
+		user := &models.User{}
+		if err := tx.Where("uuid = ? AND status = ?", session.UserUUID, true).First(user).Error; err != nil {
+			return err
+		}
+
+		if session.DeviceUUID != "" {
+			device := &models.Device{}
+			if err := tx.Where("uuid = ? AND revoked_at IS NULL", session.DeviceUUID).First(device).Error; err != nil {
+				return err
+			}
+		}
+
+		newSession, access, refresh, err := createSession(tx, c, user.UUID, session.DeviceUUID)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		if err := tx.Model(session).Updates(map[string]interface{}{
+			"revoked_at":  &now,
+			"replaced_by": newSession.UUID,
+		}).Error; err != nil {
+			return err
+		}
+
+		accessToken, refreshToken = access, refresh
+		return nil
+	})
+
+	if reused || err == gorm.ErrRecordNotFound {
+		return c.Status(401).JSON(fiber.Map{
+			"status":  "error",
+			"message": "refresh token invalide ou expiré",
+		})
+	}
+	if err != nil {
+		log.Printf("Erreur refresh token: %v", err)
+		return c.Status(500).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Erreur interne du serveur",
+		})
+	}
+
+	return tokenResponse(c, accessToken, refreshToken)
+}
+
+// LogoutAll révoque toutes les sessions de l'utilisateur connecté
+func LogoutAll(c *fiber.Ctx) error {
+	if err := revokeUserSessions(database.DB, middlewares.GetAuthUser(c).UUID); err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Erreur lors de la déconnexion",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"status":  "success",
+		"message": "Toutes les sessions ont été fermées",
+	})
+}
@@ -0,0 +1,131 @@
+package auth
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/kgermando/ipos-stock-api/database"
+	"github.com/kgermando/ipos-stock-api/models"
+	"github.com/kgermando/ipos-stock-api/utils"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// throttlePolicy définit la tolérance aux échecs pour un type de clé
+type throttlePolicy struct {
+	maxFailures int           // échecs avant verrouillage temporaire
+	delayAfter  int           // échecs avant d'imposer un délai progressif
+	lockFor     time.Duration // durée du verrouillage
+	window      time.Duration // sans échec pendant cette durée, le compteur repart à zéro
+}
+
+var (
+	loginIdentifierPolicy = throttlePolicy{maxFailures: 5, delayAfter: 3, lockFor: 15 * time.Minute, window: 15 * time.Minute}
+	loginIPPolicy         = throttlePolicy{maxFailures: 20, delayAfter: 10, lockFor: 15 * time.Minute, window: 15 * time.Minute}
+	forgotPolicy          = throttlePolicy{maxFailures: 3, delayAfter: 1, lockFor: time.Hour, window: time.Hour}
+	pinPolicy             = throttlePolicy{maxFailures: 5, delayAfter: 2, lockFor: 5 * time.Minute, window: 5 * time.Minute}
+	pinPosPolicy          = throttlePolicy{maxFailures: 20, delayAfter: 10, lockFor: 5 * time.Minute, window: 5 * time.Minute}
+)
+
+func loginIPKey(ip string) string {
+	return "login:ip:" + ip
+}
+
+func pinUserKey(userUUID string) string {
+	return "pin:user:" + userUUID
+}
+
+func pinPosKey(posUUID string) string {
+	return "pin:pos:" + posUUID
+}
+
+// twoFactorKey regroupe les échecs de code TOTP ou de secours d'un compte,
+// à la connexion comme à la gestion de la double authentification
+func twoFactorKey(userUUID string) string {
+	return "2fa:" + userUUID
+}
+
+func forgotKey(email string) string {
+	return "forgot:email:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+func forgotIPKey(ip string) string {
+	return "forgot:ip:" + ip
+}
+
+// throttleWait retourne le temps à attendre avant une nouvelle tentative
+func throttleWait(key string, p throttlePolicy) time.Duration {
+	t := models.LoginThrottle{}
+	if err := database.DB.Where("key = ?", key).First(&t).Error; err != nil {
+		return 0
+	}
+
+	now := time.Now()
+	if t.LockedUntil != nil && now.Before(*t.LockedUntil) {
+		return t.LockedUntil.Sub(now)
+	}
+	if now.Sub(t.LastFailureAt) > p.window || t.Failures < p.delayAfter {
+		return 0
+	}
+
+	// Délai progressif : 1s, 2s, 4s... plafonné à une minute
+	delay := time.Duration(math.Min(math.Pow(2, float64(t.Failures-p.delayAfter)), 60)) * time.Second
+	if wait := t.LastFailureAt.Add(delay).Sub(now); wait > 0 {
+		return wait
+	}
+	return 0
+}
+
+// throttleFail enregistre un échec et verrouille la clé au-delà du seuil
+func throttleFail(c *fiber.Ctx, key string, p throttlePolicy, user *models.User) {
+	database.DB.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		t := models.LoginThrottle{Key: key}
+		tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("key = ?", key).First(&t)
+
+		if now.Sub(t.LastFailureAt) > p.window || (t.LockedUntil != nil && now.After(*t.LockedUntil)) {
+			t.Failures = 0
+			t.LockedUntil = nil
+		}
+		t.Failures++
+		t.LastFailureAt = now
+
+		if t.Failures >= p.maxFailures && t.LockedUntil == nil {
+			lockedUntil := now.Add(p.lockFor)
+			t.LockedUntil = &lockedUntil
+
+			event := &models.SecurityEvent{
+				UUID: utils.GenerateUUID(),
+				Type: "lockout",
+				Key:  key,
+				IP:   c.IP(),
+			}
+			if user != nil {
+				event.UserUUID = user.UUID
+				event.EntrepriseUUID = user.EntrepriseUUID
+			}
+			tx.Create(event)
+		}
+
+		return tx.Save(&t).Error
+	})
+}
+
+// throttleReset efface le compteur après une connexion réussie
+func throttleReset(key string) {
+	database.DB.Where("key = ?", key).Delete(&models.LoginThrottle{})
+}
+
+// tooManyAttempts renvoie une réponse 429 avec l'en-tête Retry-After
+func tooManyAttempts(c *fiber.Ctx, wait time.Duration) error {
+	seconds := int(math.Ceil(wait.Seconds()))
+	c.Set(fiber.HeaderRetryAfter, strconv.Itoa(seconds))
+	return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{
+		"status":      "error",
+		"message":     "trop de tentatives, réessayez plus tard 😰",
+		"retry_after": seconds,
+	})
+}
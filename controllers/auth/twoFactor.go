@@ -0,0 +1,317 @@
+package auth
+
+import (
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/kgermando/ipos-stock-api/database"
+	"github.com/kgermando/ipos-stock-api/middlewares"
+	"github.com/kgermando/ipos-stock-api/models"
+	"github.com/kgermando/ipos-stock-api/utils"
+	"gorm.io/gorm"
+)
+
+const (
+	twoFactorIssuer    = "IPOS-STOCK"
+	recoveryCodesCount = 10
+)
+
+// SetupTwoFactor génère un nouveau secret TOTP et l'URI à afficher en QR code.
+// La double authentification n'est active qu'après ConfirmTwoFactor.
+func SetupTwoFactor(c *fiber.Ctx) error {
+	user := middlewares.GetAuthUser(c)
+
+	if user.TwoFactorEnabled {
+		return c.Status(409).JSON(fiber.Map{
+			"status":  "error",
+			"message": "La double authentification est déjà activée",
+		})
+	}
+
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Erreur interne du serveur",
+		})
+	}
+
+	database.DB.Model(user).Update("two_factor_secret", secret)
+
+	return c.JSON(fiber.Map{
+		"status":  "success",
+		"message": "Scannez le QR code puis confirmez avec un code",
+		"data": fiber.Map{
+			"secret":           secret,
+			"provisioning_uri": utils.TOTPProvisioningURI(secret, user.Email, twoFactorIssuer),
+		},
+	})
+}
+
+// ConfirmTwoFactor active la double authentification et retourne les codes de secours
+func ConfirmTwoFactor(c *fiber.Ctx) error {
+	user := middlewares.GetAuthUser(c)
+
+	var req models.TwoFactorCodeRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Données JSON invalides",
+			"errors":  err.Error(),
+		})
+	}
+
+	if user.TwoFactorEnabled || user.TwoFactorSecret == "" {
+		return c.Status(400).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Aucune configuration de double authentification en attente",
+		})
+	}
+
+	key := twoFactorKey(user.UUID)
+	if wait := throttleWait(key, loginIdentifierPolicy); wait > 0 {
+		return tooManyAttempts(c, wait)
+	}
+
+	step, ok := utils.ValidateTOTP(user.TwoFactorSecret, req.Code, time.Now())
+	if !ok {
+		throttleFail(c, key, loginIdentifierPolicy, user)
+		return c.Status(400).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Code invalide",
+		})
+	}
+	throttleReset(key)
+
+	var codes []string
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(user).Updates(map[string]interface{}{
+			"two_factor_enabled":   true,
+			"two_factor_last_step": step,
+		}).Error; err != nil {
+			return err
+		}
+
+		var err error
+		codes, err = replaceRecoveryCodes(tx, user.UUID)
+		return err
+	})
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Erreur lors de l'activation",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"status":  "success",
+		"message": "Double authentification activée. Conservez ces codes de secours en lieu sûr",
+		"data":    codes,
+	})
+}
+
+// DisableTwoFactor désactive la double authentification (mot de passe et code requis)
+func DisableTwoFactor(c *fiber.Ctx) error {
+	user := middlewares.GetAuthUser(c)
+
+	var req models.TwoFactorCodeRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Données JSON invalides",
+			"errors":  err.Error(),
+		})
+	}
+
+	if user.TwoFactorRequired() {
+		return c.Status(403).JSON(fiber.Map{
+			"status":  "error",
+			"message": "La double authentification est obligatoire pour votre rôle",
+		})
+	}
+
+	key := twoFactorKey(user.UUID)
+	if wait := throttleWait(key, loginIdentifierPolicy); wait > 0 {
+		return tooManyAttempts(c, wait)
+	}
+
+	if err := user.ComparePassword(req.Password); err != nil || !verifySecondFactor(database.DB, user, req.Code, req.RecoveryCode) {
+		throttleFail(c, key, loginIdentifierPolicy, user)
+		return c.Status(400).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Mot de passe ou code invalide",
+		})
+	}
+	throttleReset(key)
+
+	database.DB.Transaction(func(tx *gorm.DB) error {
+		tx.Model(user).Updates(map[string]interface{}{
+			"two_factor_enabled":   false,
+			"two_factor_secret":    "",
+			"two_factor_last_step": 0,
+		})
+		return tx.Where("user_uuid = ?", user.UUID).Delete(&models.RecoveryCode{}).Error
+	})
+
+	return c.JSON(fiber.Map{
+		"status":  "success",
+		"message": "Double authentification désactivée",
+	})
+}
+
+// RegenerateRecoveryCodes remplace les codes de secours existants
+func RegenerateRecoveryCodes(c *fiber.Ctx) error {
+	user := middlewares.GetAuthUser(c)
+
+	var req models.TwoFactorCodeRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Données JSON invalides",
+			"errors":  err.Error(),
+		})
+	}
+
+	if !user.TwoFactorEnabled {
+		return c.Status(400).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Code invalide",
+		})
+	}
+
+	key := twoFactorKey(user.UUID)
+	if wait := throttleWait(key, loginIdentifierPolicy); wait > 0 {
+		return tooManyAttempts(c, wait)
+	}
+
+	if !verifySecondFactor(database.DB, user, req.Code, "") {
+		throttleFail(c, key, loginIdentifierPolicy, user)
+		return c.Status(400).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Code invalide",
+		})
+	}
+	throttleReset(key)
+
+	codes, err := replaceRecoveryCodes(database.DB, user.UUID)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Erreur lors de la génération des codes",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"status":  "success",
+		"message": "Nouveaux codes de secours générés",
+		"data":    codes,
+	})
+}
+
+// LoginTwoFactor termine le login en deux étapes avec un code TOTP ou un code de secours
+func LoginTwoFactor(c *fiber.Ctx) error {
+	var req models.TwoFactorLoginRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Données JSON invalides",
+			"errors":  err.Error(),
+		})
+	}
+
+	if err := utils.ValidateStruct(req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Données invalides",
+			"errors":  err,
+		})
+	}
+
+	userUUID, err := utils.VerifyChallengeToken(req.ChallengeToken)
+	if err != nil {
+		return c.Status(401).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Session de connexion expirée, veuillez recommencer",
+		})
+	}
+
+	key := twoFactorKey(userUUID)
+	if wait := throttleWait(key, loginIdentifierPolicy); wait > 0 {
+		return tooManyAttempts(c, wait)
+	}
+
+	u := &models.User{}
+	if err := database.DB.Where("uuid = ?", userUUID).
+		Preload("Entreprise").
+		Preload("Pos").
+		First(u).Error; err != nil || !u.Status || !u.TwoFactorEnabled {
+		return c.Status(401).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Session de connexion expirée, veuillez recommencer",
+		})
+	}
+
+	if !verifySecondFactor(database.DB, u, req.Code, req.RecoveryCode) {
+		throttleFail(c, key, loginIdentifierPolicy, u)
+		return c.Status(400).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Code invalide 😰",
+		})
+	}
+	throttleReset(key)
+
+	return startSession(c, u, req.Device)
+}
+
+// verifySecondFactor valide un code TOTP (sans rejeu possible) ou consomme un code de secours
+func verifySecondFactor(tx *gorm.DB, user *models.User, code, recoveryCode string) bool {
+	if code != "" {
+		step, ok := utils.ValidateTOTP(user.TwoFactorSecret, code, time.Now())
+		if !ok || step <= user.TwoFactorLastStep {
+			return false
+		}
+		result := tx.Model(&models.User{}).
+			Where("uuid = ? AND two_factor_last_step < ?", user.UUID, step).
+			Update("two_factor_last_step", step)
+		return result.Error == nil && result.RowsAffected == 1
+	}
+
+	if recoveryCode != "" {
+		hash := utils.HashToken(normalizeRecoveryCode(recoveryCode))
+		result := tx.Model(&models.RecoveryCode{}).
+			Where("user_uuid = ? AND code_hash = ? AND used_at IS NULL", user.UUID, hash).
+			Update("used_at", time.Now())
+		return result.Error == nil && result.RowsAffected == 1
+	}
+
+	return false
+}
+
+// replaceRecoveryCodes supprime les anciens codes de secours et en génère de nouveaux
+func replaceRecoveryCodes(tx *gorm.DB, userUUID string) ([]string, error) {
+	if err := tx.Where("user_uuid = ?", userUUID).Delete(&models.RecoveryCode{}).Error; err != nil {
+		return nil, err
+	}
+
+	codes := make([]string, 0, recoveryCodesCount)
+	for i := 0; i < recoveryCodesCount; i++ {
+		raw := strings.ToLower(utils.GenerateRandomString(10))
+		code := raw[:5] + "-" + raw[5:]
+		codes = append(codes, code)
+
+		if err := tx.Create(&models.RecoveryCode{
+			UUID:     utils.GenerateUUID(),
+			UserUUID: userUUID,
+			CodeHash: utils.HashToken(normalizeRecoveryCode(code)),
+		}).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	return codes, nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(code), "-", ""))
+}
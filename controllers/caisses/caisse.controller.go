@@ -2,6 +2,7 @@ package caisses
 
 import (
 	"github.com/kgermando/ipos-stock-api/database"
+	"github.com/kgermando/ipos-stock-api/middlewares"
 	"github.com/kgermando/ipos-stock-api/models"
 
 	"github.com/gofiber/fiber/v2"
@@ -9,11 +10,11 @@ import (
 
 // Synchronisation Send data to Local
 func GetDataSynchronisation(c *fiber.Ctx) error {
-	db := database.DB
+	db := database.DB.WithContext(c.UserContext())
 	entrepriseUUID := c.Params("entreprise_uuid")
 	posUUID := c.Params("pos_uuid")
 
-	sync_created := c.Query("sync_created", "2023-01-01")
+	sync_created, syncDone := middlewares.SyncCursor(c, "caisses")
 
 	var data []models.Caisse
 
@@ -32,6 +33,8 @@ func GetDataSynchronisation(c *fiber.Ctx) error {
 			Find(&data)
 	}
 
+	syncDone()
+
 	return c.JSON(fiber.Map{
 		"status":  "success",
 		"message": "All Caisses",
@@ -41,7 +44,7 @@ func GetDataSynchronisation(c *fiber.Ctx) error {
 
 // Get All data
 func GetTotalAllCaisses(c *fiber.Ctx) error {
-	db := database.DB
+	db := database.DB.WithContext(c.UserContext())
 	entrepriseUUID := c.Params("entreprise_uuid")
 
 	var dataList []models.CaisseItem
@@ -88,7 +91,7 @@ func GetTotalAllCaisses(c *fiber.Ctx) error {
 
 // Get All data
 func GetAllCaisses(c *fiber.Ctx) error {
-	db := database.DB
+	db := database.DB.WithContext(c.UserContext())
 	entrepriseUUID := c.Params("entreprise_uuid")
 
 	var data []models.Caisse
@@ -105,7 +108,7 @@ func GetAllCaisses(c *fiber.Ctx) error {
 
 // Get All data
 func GetAllCaisseByPos(c *fiber.Ctx) error {
-	db := database.DB
+	db := database.DB.WithContext(c.UserContext())
 	entrepriseUUID := c.Params("entreprise_uuid")
 	posUUId := c.Params("pos_uuid")
 
@@ -124,7 +127,7 @@ func GetAllCaisseByPos(c *fiber.Ctx) error {
 
 // Get All data by id
 func GetAllCaisseBySearch(c *fiber.Ctx) error {
-	db := database.DB
+	db := database.DB.WithContext(c.UserContext())
 	entrepriseUUID := c.Params("entreprise_uuid")
 	posUUId := c.Params("pos_uuid")
 
@@ -147,7 +150,7 @@ func GetAllCaisseBySearch(c *fiber.Ctx) error {
 // Get one data
 func GetCaisse(c *fiber.Ctx) error {
 	uuid := c.Params("uuid")
-	db := database.DB
+	db := database.DB.WithContext(c.UserContext())
 
 	var caisse models.Caisse
 	db.Where("uuid = ?", uuid).
@@ -193,7 +196,7 @@ func CreateCaisse(c *fiber.Ctx) error {
 	}
 
 	p.Sync = true
-	database.DB.Create(p)
+	database.DB.WithContext(c.UserContext()).Create(p)
 
 	return c.JSON(
 		fiber.Map{
@@ -207,12 +210,13 @@ func CreateCaisse(c *fiber.Ctx) error {
 // Update data
 func UpdateCaisse(c *fiber.Ctx) error {
 	uuid := c.Params("uuid")
-	db := database.DB
+	db := database.DB.WithContext(c.UserContext())
 
 	type UpdateData struct {
 		Name string `json:"name"` // Nom de la caisse
 		// Entree         float64 `json:"entree"`          // Montant d'entrée
 		// Sortie         float64 `json:"sortie"`          // Montant de sortie
+		PaymentMethods string `json:"payment_methods"` // Moyens de paiement encaissés
 		Signature      string `json:"signature"`       // Signature de la transaction
 		PosUUID        string `json:"pos_uuid"`        // ID du point de vente
 		EntrepriseUUID string `json:"entreprise_uuid"` // ID de l'entreprise
@@ -232,15 +236,30 @@ func UpdateCaisse(c *fiber.Ctx) error {
 
 	caisse := new(models.Caisse)
 
-	db.Where("uuid = ?", uuid).First(&caisse)
+	if err := db.Where("uuid = ?", uuid).First(&caisse).Error; err != nil {
+		return c.Status(404).JSON(
+			fiber.Map{
+				"status":  "error",
+				"message": "No caisse found",
+				"data":    nil,
+			},
+		)
+	}
+	server := *caisse
 	caisse.Name = updateData.Name
 	// caisse.Entree = updateData.Entree
 	// caisse.Sortie = updateData.Sortie
+	caisse.PaymentMethods = updateData.PaymentMethods
 	caisse.Signature = updateData.Signature
 	caisse.PosUUID = updateData.PosUUID
 	caisse.EntrepriseUUID = updateData.EntrepriseUUID
 
 	caisse.Sync = true
+
+	if conflict := middlewares.CheckConflict(c, db, &server, caisse); conflict != nil {
+		return middlewares.ConflictResponse(c, conflict)
+	}
+
 	db.Save(&caisse)
 
 	return c.JSON(
@@ -257,7 +276,7 @@ func UpdateCaisse(c *fiber.Ctx) error {
 func DeleteCaisse(c *fiber.Ctx) error {
 	uuid := c.Params("uuid")
 
-	db := database.DB
+	db := database.DB.WithContext(c.UserContext())
 
 	var caisse models.Caisse
 	db.Where("uuid = ?", uuid).First(&caisse)
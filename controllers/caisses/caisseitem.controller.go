@@ -4,6 +4,7 @@ import (
 	"strconv"
 
 	"github.com/kgermando/ipos-stock-api/database"
+	"github.com/kgermando/ipos-stock-api/middlewares"
 	"github.com/kgermando/ipos-stock-api/models"
 
 	"github.com/gofiber/fiber/v2"
@@ -11,11 +12,11 @@ import (
 
 // Synchronisation Send data to Local
 func GetDataSynchronisationCaisseItem(c *fiber.Ctx) error {
-	db := database.DB
+	db := database.DB.WithContext(c.UserContext())
 	entrepriseUUID := c.Params("entreprise_uuid")
 	posUUID := c.Params("pos_uuid")
 
-	sync_created := c.Query("sync_created", "2023-01-01")
+	sync_created, syncDone := middlewares.SyncCursor(c, "caisse_items")
 	var data []models.CaisseItem
 
 	if posUUID == "-" {
@@ -34,6 +35,8 @@ func GetDataSynchronisationCaisseItem(c *fiber.Ctx) error {
 			Preload("Pos").
 			Find(&data)
 	}
+	syncDone()
+
 	return c.JSON(fiber.Map{
 		"status":  "success",
 		"message": "All CaisseItems",
@@ -43,7 +46,7 @@ func GetDataSynchronisationCaisseItem(c *fiber.Ctx) error {
 
 // Paginate
 func GetPaginatedCaisseItems(c *fiber.Ctx) error {
-	db := database.DB
+	db := database.DB.WithContext(c.UserContext())
 	entrepriseUUID := c.Params("entreprise_uuid")
 	caisseUUID := c.Params("caisse_uuid")
 
@@ -111,7 +114,7 @@ func GetPaginatedCaisseItems(c *fiber.Ctx) error {
 
 // Get All data
 func GetAllCaisseItems(c *fiber.Ctx) error {
-	db := database.DB
+	db := database.DB.WithContext(c.UserContext())
 	entrepriseUUID := c.Params("entreprise_uuid")
 	caisseUUID := c.Params("caisse_uuid")
 
@@ -129,7 +132,7 @@ func GetAllCaisseItems(c *fiber.Ctx) error {
 
 // Get All data by uuid
 func GetAllCaisseItemBySearch(c *fiber.Ctx) error {
-	db := database.DB
+	db := database.DB.WithContext(c.UserContext())
 	entrepriseUUID := c.Params("entreprise_uuid")
 	caisseUUID := c.Params("caisse_uuid")
 
@@ -151,7 +154,7 @@ func GetAllCaisseItemBySearch(c *fiber.Ctx) error {
 // Get one data
 func GetCaisseItem(c *fiber.Ctx) error {
 	UUID := c.Params("uuid")
-	db := database.DB
+	db := database.DB.WithContext(c.UserContext())
 
 	var caisseItem models.CaisseItem
 	db.Where("uuid = ?", UUID).Preload("Caisse").First(&caisseItem)
@@ -194,8 +197,9 @@ func CreateCaisseItem(c *fiber.Ctx) error {
 		)
 	}
 
+	p.CashierUUID = middlewares.GetAuthUser(c).UUID
 	p.Sync = true
-	database.DB.Create(p)
+	database.DB.WithContext(c.UserContext()).Create(p)
 
 	return c.JSON(
 		fiber.Map{
@@ -209,7 +213,7 @@ func CreateCaisseItem(c *fiber.Ctx) error {
 // Update data
 func UpdateCaisseItem(c *fiber.Ctx) error {
 	UUID := c.Params("uuid")
-	db := database.DB
+	db := database.DB.WithContext(c.UserContext())
 
 	type UpdateData struct {
 		CaisseUUID      string  `json:"caisse_uuid"`
@@ -235,7 +239,16 @@ func UpdateCaisseItem(c *fiber.Ctx) error {
 
 	caisseItem := new(models.CaisseItem)
 
-	db.Where("uuid = ?", UUID).First(&caisseItem)
+	if err := db.Where("uuid = ?", UUID).First(&caisseItem).Error; err != nil {
+		return c.Status(404).JSON(
+			fiber.Map{
+				"status":  "error",
+				"message": "No caisse item found",
+				"data":    nil,
+			},
+		)
+	}
+	server := *caisseItem
 	caisseItem.CaisseUUID = updateData.CaisseUUID
 	caisseItem.TypeTransaction = updateData.TypeTransaction
 	caisseItem.Montant = updateData.Montant
@@ -245,6 +258,11 @@ func UpdateCaisseItem(c *fiber.Ctx) error {
 	caisseItem.EntrepriseUUID = updateData.EntrepriseUUID
 
 	caisseItem.Sync = true // Set sync to true for synchronization
+
+	if conflict := middlewares.CheckConflict(c, db, &server, caisseItem); conflict != nil {
+		return middlewares.ConflictResponse(c, conflict)
+	}
+
 	db.Save(&caisseItem)
 
 	return c.JSON(
@@ -261,7 +279,7 @@ func UpdateCaisseItem(c *fiber.Ctx) error {
 func DeleteCaisseItem(c *fiber.Ctx) error {
 	UUID := c.Params("uuid")
 
-	db := database.DB
+	db := database.DB.WithContext(c.UserContext())
 
 	var caisseItem models.CaisseItem
 	db.Where("uuid = ?", UUID).First(&caisseItem)
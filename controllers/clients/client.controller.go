@@ -6,6 +6,7 @@ import (
 	"strconv"
 
 	"github.com/kgermando/ipos-stock-api/database"
+	"github.com/kgermando/ipos-stock-api/middlewares"
 	"github.com/kgermando/ipos-stock-api/models"
 
 	"github.com/gofiber/fiber/v2"
@@ -13,11 +14,11 @@ import (
 
 // Synchronisation Send data to Local
 func GetDataSynchronisation(c *fiber.Ctx) error {
-	db := database.DB
+	db := database.DB.WithContext(c.UserContext())
 	entrepriseUUID := c.Params("entreprise_uuid")
 	posUUID := c.Params("pos_uuid")
 
-	sync_created := c.Query("sync_created", "2023-01-01")
+	sync_created, syncDone := middlewares.SyncCursor(c, "clients")
 	var data []models.Client
 
 	if posUUID == "-" {
@@ -34,6 +35,8 @@ func GetDataSynchronisation(c *fiber.Ctx) error {
 			Preload("Pos").
 			Find(&data)
 	}
+	syncDone()
+
 	return c.JSON(fiber.Map{
 		"status":  "success",
 		"message": "All Clients",
@@ -43,7 +46,7 @@ func GetDataSynchronisation(c *fiber.Ctx) error {
 
 // Paginate
 func GetPaginatedClient(c *fiber.Ctx) error {
-	db := database.DB
+	db := database.DB.WithContext(c.UserContext())
 	entrepriseUUID := c.Params("entreprise_uuid")
 	posUUID := c.Params("pos_uuid")
 
@@ -108,7 +111,7 @@ func GetPaginatedClient(c *fiber.Ctx) error {
 // Get All data
 func GetAllClients(c *fiber.Ctx) error {
 	entrepriseUUID := c.Params("entreprise_uuid")
-	db := database.DB
+	db := database.DB.WithContext(c.UserContext())
 
 	var data []models.Client
 	db.Where("entreprise_uuid = ?", entrepriseUUID).Find(&data)
@@ -122,7 +125,7 @@ func GetAllClients(c *fiber.Ctx) error {
 // Get one data
 func GetClient(c *fiber.Ctx) error {
 	uuid := c.Params("uuid")
-	db := database.DB
+	db := database.DB.WithContext(c.UserContext())
 
 	var client models.Client
 	db.Where("uuid = ?", uuid).First(&client)
@@ -165,8 +168,9 @@ func CreateClient(c *fiber.Ctx) error {
 		)
 	}
 
+	p.CreditBalance = 0 // Crédité uniquement par les retours
 	p.Sync = true
-	database.DB.Create(p)
+	database.DB.WithContext(c.UserContext()).Create(p)
 
 	return c.JSON(
 		fiber.Map{
@@ -180,7 +184,7 @@ func CreateClient(c *fiber.Ctx) error {
 // Update data
 func UpdateClient(c *fiber.Ctx) error {
 	uuid := c.Params("uuid")
-	db := database.DB
+	db := database.DB.WithContext(c.UserContext())
 
 	type UpdateData struct {
 		Fullname   string `json:"fullname"`
@@ -209,7 +213,16 @@ func UpdateClient(c *fiber.Ctx) error {
 
 	client := new(models.Client)
 
-	db.Where("uuid = ?", uuid).First(&client)
+	if err := db.Where("uuid = ?", uuid).First(&client).Error; err != nil {
+		return c.Status(404).JSON(
+			fiber.Map{
+				"status":  "error",
+				"message": "No client found",
+				"data":    nil,
+			},
+		)
+	}
+	server := *client
 	client.Fullname = updateData.Fullname
 	client.Telephone = updateData.Telephone
 	client.Telephone2 = updateData.Telephone2
@@ -221,6 +234,10 @@ func UpdateClient(c *fiber.Ctx) error {
 	client.Signature = updateData.Signature
 	client.EntrepriseUUID = updateData.EntrepriseUUID
 
+	if conflict := middlewares.CheckConflict(c, db, &server, client); conflict != nil {
+		return middlewares.ConflictResponse(c, conflict)
+	}
+
 	db.Save(&client)
 
 	return c.JSON(
@@ -237,7 +254,7 @@ func UpdateClient(c *fiber.Ctx) error {
 func DeleteClient(c *fiber.Ctx) error {
 	uuid := c.Params("uuid")
 
-	db := database.DB
+	db := database.DB.WithContext(c.UserContext())
 
 	var client models.Client
 	db.Where("uuid = ?", uuid).First(&client)
@@ -263,7 +280,7 @@ func DeleteClient(c *fiber.Ctx) error {
 }
 
 func UploadCsvDataClient(c *fiber.Ctx) error {
-	db := database.DB
+	db := database.DB.WithContext(c.UserContext())
 
 	type UploadCSV struct {
 		Data           []models.Client `json:"data"`
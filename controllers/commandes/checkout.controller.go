@@ -0,0 +1,242 @@
+package commandes
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/kgermando/ipos-stock-api/database"
+	"github.com/kgermando/ipos-stock-api/lifecycle"
+	"github.com/kgermando/ipos-stock-api/middlewares"
+	"github.com/kgermando/ipos-stock-api/models"
+	"github.com/kgermando/ipos-stock-api/numbering"
+	"github.com/kgermando/ipos-stock-api/payments"
+	"github.com/kgermando/ipos-stock-api/pricing"
+	"github.com/kgermando/ipos-stock-api/utils"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Checkout enregistre une vente complète dans une seule transaction : la
+// commande, numérotée par le serveur, et ses lignes, la sortie de stock des produits (lignes verrouillées)
+// et ses règlements, portés en entrée dans les caisses. Les prix, remises,
+// promotions et TVA sont ceux du serveur, figés sur chaque ligne ; les totaux
+// éventuellement envoyés par le terminal doivent correspondre à son calcul.
+func Checkout(c *fiber.Ctx) error {
+	db := database.DB.WithContext(c.UserContext())
+	user := middlewares.GetAuthUser(c)
+
+	var req models.CheckoutRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Données JSON invalides",
+			"errors":  err.Error(),
+		})
+	}
+
+	if err := utils.ValidateStruct(req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Données invalides",
+			"errors":  err,
+		})
+	}
+
+	productQty, platQty, err := pricing.Items(req.Items)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Chaque article doit référencer un produit ou un plat",
+		})
+	}
+
+	commande := &models.Commande{
+		UUID:          req.UUID,
+		PosUUID:       req.PosUUID,
+		Status:        models.CommandePaid,
+		ClientUUID:    req.ClientUUID,
+		TableBoxUUID:  req.TableBoxUUID,
+		LivraisonUUID: req.LivraisonUUID,
+		Signature:     req.Signature,
+		CashierUUID:   user.UUID,
+		Sync:          true,
+	}
+	if commande.UUID == "" {
+		commande.UUID = utils.GenerateUUID()
+	}
+
+	var caisseItems []models.CaisseItem
+	var mismatch *pricing.MismatchError
+	status, message := 400, "Vente invalide"
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		tx.Unscoped().Model(&models.Commande{}).Where("uuid = ?", commande.UUID).Count(&count)
+		if count > 0 {
+			status, message = 409, "Commande avec cet UUID existe déjà"
+			return gorm.ErrDuplicatedKey
+		}
+
+		var pos models.Pos
+		if err := tx.Where("uuid = ?", req.PosUUID).First(&pos).Error; err != nil {
+			status, message = 404, "Point de vente introuvable"
+			return err
+		}
+		commande.EntrepriseUUID = pos.EntrepriseUUID
+		policy := pricing.LoadPolicy(tx, pos.EntrepriseUUID)
+
+		promos, err := pricing.LoadPromotions(tx, req.PosUUID, req.CouponCodes, time.Now())
+		if err != nil {
+			status, message = 422, err.Error()
+			return err
+		}
+
+		var caisse models.Caisse
+		if err := tx.Where("uuid = ? AND pos_uuid = ?", req.CaisseUUID, req.PosUUID).First(&caisse).Error; err != nil {
+			status, message = 404, "Caisse introuvable pour ce point de vente"
+			return err
+		}
+
+		// Les produits sont verrouillés dans un ordre stable pour éviter les
+		// interblocages entre deux ventes simultanées
+		productUUIDs := pricing.SortedKeys(productQty)
+		var products []models.Product
+		if len(productUUIDs) > 0 {
+			tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+				Where("uuid IN ? AND pos_uuid = ?", productUUIDs, req.PosUUID).
+				Order("uuid").
+				Find(&products)
+		}
+		if len(products) != len(productUUIDs) {
+			status, message = 404, "Produit introuvable pour ce point de vente"
+			return gorm.ErrRecordNotFound
+		}
+
+		platUUIDs := pricing.SortedKeys(platQty)
+		var plats []models.Plat
+		if len(platUUIDs) > 0 {
+			tx.Where("uuid IN ? AND pos_uuid = ?", platUUIDs, req.PosUUID).Order("uuid").Find(&plats)
+		}
+		if len(plats) != len(platUUIDs) {
+			status, message = 404, "Plat introuvable pour ce point de vente"
+			return gorm.ErrRecordNotFound
+		}
+
+		for _, product := range products {
+			qty := productQty[product.UUID]
+			if product.Stock < float64(qty) {
+				status, message = 409, fmt.Sprintf("Stock insuffisant pour %s (disponible : %g)", product.Name, product.Stock)
+				return gorm.ErrInvalidData
+			}
+
+			line := models.CommandeLine{Quantity: qty}
+			pricing.ProductLine(&line, product, policy)
+			promos.Apply(&line, "", policy)
+			addLine(commande, line)
+
+			if err := tx.Model(&product).
+				Updates(map[string]interface{}{"stock": gorm.Expr("stock - ?", qty), "sync": true}).Error; err != nil {
+				status, message = 500, "Erreur lors de la mise à jour du stock"
+				return err
+			}
+		}
+
+		for _, plat := range plats {
+			if !plat.IsAvailable {
+				status, message = 409, fmt.Sprintf("Le plat %s n'est pas disponible", plat.Name)
+				return gorm.ErrInvalidData
+			}
+			line := models.CommandeLine{Quantity: platQty[plat.UUID]}
+			pricing.PlatLine(&line, plat, policy)
+			promos.Apply(&line, plat.Categorie, policy)
+			addLine(commande, line)
+		}
+
+		totals := pricing.Sum(commande.CommandeLines, policy)
+		received := pricing.Totals{TotalHt: req.TotalHt, TotalTva: req.TotalTva, TotalTtc: req.TotalTtc}
+		if err := pricing.Verify(received, totals, policy); err != nil {
+			mismatch = err.(*pricing.MismatchError)
+			status, message = 422, "Les totaux envoyés ne correspondent pas au calcul du serveur"
+			return err
+		}
+		pricing.Apply(commande, totals)
+
+		if commande.Ncommande, err = numbering.Next(tx, models.DocumentCommande, pos, time.Now()); err != nil {
+			status, message = 500, "Erreur lors de l'attribution du numéro de facture"
+			return err
+		}
+		if err := tx.Omit(clause.Associations).Create(commande).Error; err != nil {
+			status, message = 500, "Erreur lors de l'enregistrement de la commande"
+			return err
+		}
+		if err := tx.Omit(clause.Associations).Create(&commande.CommandeLines).Error; err != nil {
+			status, message = 500, "Erreur lors de l'enregistrement des lignes de commande"
+			return err
+		}
+		if err := lifecycle.Record(tx, commande, "", user); err != nil {
+			status, message = 500, "Erreur lors de l'enregistrement du statut"
+			return err
+		}
+		if err := pricing.Redeem(tx, commande.CommandeLines); err != nil {
+			status, message = 409, err.Error()
+			return err
+		}
+
+		// Sans règlement détaillé, la vente est réglée en espèces dans la caisse choisie
+		tenders := req.Payments
+		if len(tenders) == 0 {
+			tenders = []models.PaymentRequest{{Method: models.PaymentCash, Amount: commande.TotalTtc}}
+		}
+		if !policy.Matches(payments.Sum(tenders, policy), commande.TotalTtc) {
+			status, message = 422, "Les règlements ne correspondent pas au total de la commande"
+			return gorm.ErrInvalidData
+		}
+
+		commande.Payments, caisseItems, err = payments.Post(tx, payments.Tenders{
+			Commande:      commande,
+			Requests:      tenders,
+			DefaultCaisse: caisse.UUID,
+			CashierUUID:   user.UUID,
+			Signature:     req.Signature,
+		}, commande.TotalTtc, policy)
+		if err != nil {
+			status, message = 422, err.Error()
+			return err
+		}
+		return nil
+	})
+
+	if mismatch != nil {
+		return c.Status(status).JSON(fiber.Map{
+			"status":  "error",
+			"message": message,
+			"data":    mismatch,
+		})
+	}
+	if err != nil {
+		return c.Status(status).JSON(fiber.Map{
+			"status":  "error",
+			"message": message,
+		})
+	}
+
+	return c.Status(201).JSON(fiber.Map{
+		"status":  "success",
+		"message": "Vente enregistrée avec succès",
+		"data": fiber.Map{
+			"commande":     commande,
+			"caisse_items": caisseItems,
+		},
+	})
+}
+
+func addLine(commande *models.Commande, line models.CommandeLine) {
+	line.UUID = utils.GenerateUUID()
+	line.CommandeUUID = commande.UUID
+	line.EntrepriseUUID = commande.EntrepriseUUID
+	line.PosUUID = commande.PosUUID
+	line.Sync = true
+	commande.CommandeLines = append(commande.CommandeLines, line)
+}
@@ -1,21 +1,28 @@
 package commandes
 
 import (
+	"errors"
 	"strconv"
+	"time"
 
 	"github.com/kgermando/ipos-stock-api/database"
+	"github.com/kgermando/ipos-stock-api/lifecycle"
+	"github.com/kgermando/ipos-stock-api/middlewares"
 	"github.com/kgermando/ipos-stock-api/models"
+	"github.com/kgermando/ipos-stock-api/numbering"
+	"github.com/kgermando/ipos-stock-api/pricing"
 
 	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
 )
 
 // Synchronisation Send data to Local
 func GetDataSynchronisation(c *fiber.Ctx) error {
-	db := database.DB
+	db := database.DB.WithContext(c.UserContext())
 	entrepriseUUID := c.Params("entreprise_uuid")
 	posUUID := c.Params("pos_uuid")
 
-	sync_created := c.Query("sync_created", "2023-01-01")
+	sync_created, syncDone := middlewares.SyncCursor(c, "commandes")
 	var data []models.Commande
 
 	if posUUID == "-" {
@@ -30,6 +37,8 @@ func GetDataSynchronisation(c *fiber.Ctx) error {
 			Preload("Pos").
 			Find(&data)
 	}
+	syncDone()
+
 	return c.JSON(fiber.Map{
 		"status":  "success",
 		"message": "All Commandes",
@@ -39,7 +48,7 @@ func GetDataSynchronisation(c *fiber.Ctx) error {
 
 // Paginate
 func GetPaginatedCommandeEntreprise(c *fiber.Ctx) error {
-	db := database.DB
+	db := database.DB.WithContext(c.UserContext())
 	entrepriseUUID := c.Params("entreprise_uuid")
 
 	page, err := strconv.Atoi(c.Query("page", "1"))
@@ -100,7 +109,7 @@ func GetPaginatedCommandeEntreprise(c *fiber.Ctx) error {
 
 // Paginate
 func GetPaginatedCommandePOS(c *fiber.Ctx) error {
-	db := database.DB
+	db := database.DB.WithContext(c.UserContext())
 	entrepriseUUID := c.Params("entreprise_uuid")
 	posUUID := c.Params("pos_uuid")
 
@@ -164,7 +173,7 @@ func GetPaginatedCommandePOS(c *fiber.Ctx) error {
 
 // Get All data
 func GetAllCommandes(c *fiber.Ctx) error {
-	db := database.DB
+	db := database.DB.WithContext(c.UserContext())
 	entrepriseUUID := c.Params("entreprise_uuid")
 	posUUID := c.Params("pos_uuid")
 
@@ -183,11 +192,13 @@ func GetAllCommandes(c *fiber.Ctx) error {
 // Get one data
 func GetCommande(c *fiber.Ctx) error {
 	uuid := c.Params("uuid")
-	db := database.DB
+	db := database.DB.WithContext(c.UserContext())
 
 	var commande models.Commande
 	db.Where("uuid = ?", uuid).
 		Preload("CommandeLines").
+		Preload("Payments").
+		Preload("StatusHistory", func(db *gorm.DB) *gorm.DB { return db.Order("created_at") }).
 		First(&commande)
 	if commande.Ncommande == "" {
 		return c.Status(404).JSON(
@@ -215,6 +226,18 @@ func CreateCommande(c *fiber.Ctx) error {
 		return err
 	}
 
+	status, err := lifecycle.Initial(p.Status)
+	if err != nil {
+		return c.Status(400).JSON(
+			fiber.Map{
+				"status":  "error",
+				"message": err.Error(),
+				"data":    nil,
+			},
+		)
+	}
+	p.Status = status
+
 	// Vérifier si la commande existe déjà
 	var existingCommande models.Commande
 	database.DB.Where("uuid = ?", p.UUID).First(&existingCommande)
@@ -228,8 +251,81 @@ func CreateCommande(c *fiber.Ctx) error {
 		)
 	}
 
+	// Les totaux sont calculés à partir des lignes envoyées avec la commande ;
+	// sans lignes, ils suivront celles ajoutées ensuite
+	db := database.DB.WithContext(c.UserContext())
+	policy := pricing.LoadPolicy(db, p.EntrepriseUUID)
+	promos, _ := pricing.LoadPromotions(db, p.PosUUID, nil, time.Now())
+	for i := range p.CommandeLines {
+		line := &p.CommandeLines[i]
+		if err := pricing.Snapshot(db, line, policy, promos); err != nil {
+			return c.Status(404).JSON(
+				fiber.Map{
+					"status":  "error",
+					"message": "Produit ou plat introuvable",
+					"data":    nil,
+				},
+			)
+		}
+		line.PosUUID = p.PosUUID
+		line.Sync = true
+	}
+	totals := pricing.Sum(p.CommandeLines, policy)
+	if len(p.CommandeLines) > 0 {
+		if err := pricing.Verify(pricing.CommandeTotals(p), totals, policy); err != nil {
+			return c.Status(422).JSON(
+				fiber.Map{
+					"status":  "error",
+					"message": "Les totaux envoyés ne correspondent pas au calcul du serveur",
+					"data":    err,
+				},
+			)
+		}
+	}
+	pricing.Apply(p, totals)
+
+	user := middlewares.GetAuthUser(c)
+	p.CashierUUID = user.UUID
+	p.Fiscal = models.FiscalCertificate{} // La certification n'est tenue que par le serveur
 	p.Sync = true
-	database.DB.Create(p)
+	code, message := 500, "Erreur lors de l'enregistrement de la commande"
+	err = db.Transaction(func(tx *gorm.DB) error {
+		var pos models.Pos
+		if err := tx.Where("uuid = ?", p.PosUUID).First(&pos).Error; err != nil {
+			code, message = 404, "Point de vente introuvable"
+			return err
+		}
+		number, err := numbering.Next(tx, models.DocumentCommande, pos, time.Now())
+		if err != nil {
+			code, message = 500, "Erreur lors de l'attribution du numéro de facture"
+			return err
+		}
+		p.Ncommande = number
+		if err := pricing.Redeem(tx, p.CommandeLines); err != nil {
+			if errors.Is(err, pricing.ErrCouponExhausted) {
+				code, message = 409, err.Error()
+			}
+			return err
+		}
+		// Les règlements passent par /payments pour être portés en caisse
+		if err := tx.Omit("Payments", "StatusHistory").Create(p).Error; err != nil {
+			return err
+		}
+		if err := lifecycle.Record(tx, p, "", user); err != nil {
+			code, message = 500, "Erreur lors de l'enregistrement du statut"
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return c.Status(code).JSON(
+			fiber.Map{
+				"status":  "error",
+				"message": message,
+				"data":    nil,
+			},
+		)
+	}
 
 	return c.JSON(
 		fiber.Map{
@@ -243,12 +339,11 @@ func CreateCommande(c *fiber.Ctx) error {
 // Update data
 func UpdateCommande(c *fiber.Ctx) error {
 	uuid := c.Params("uuid")
-	db := database.DB
+	db := database.DB.WithContext(c.UserContext())
 
 	type UpdateData struct {
 		PosUUID        string `json:"pos_uuid"`
-		Ncommande      string `json:"ncommande"` // Number Random
-		Status         string `json:"status"`    // Ouverte et Fermée
+		Status         string `json:"status"` // Nouveau statut, vide pour le garder
 		ClientUUID     string `json:"client_uuid"`
 		Signature      string `json:"signature"`
 		EntrepriseUUID string `json:"entreprise_uuid"`
@@ -268,16 +363,49 @@ func UpdateCommande(c *fiber.Ctx) error {
 
 	commande := new(models.Commande)
 
-	db.Where("uuid = ?", uuid).First(&commande)
+	if err := db.Where("uuid = ?", uuid).First(&commande).Error; err != nil {
+		return c.Status(404).JSON(
+			fiber.Map{
+				"status":  "error",
+				"message": "No commande found",
+				"data":    nil,
+			},
+		)
+	}
+	server := *commande
 	commande.PosUUID = updateData.PosUUID
-	commande.Ncommande = updateData.Ncommande
 	commande.Status = updateData.Status
 	commande.ClientUUID = updateData.ClientUUID
 	commande.Signature = updateData.Signature
 	commande.EntrepriseUUID = updateData.EntrepriseUUID
 
 	commande.Sync = true
-	db.Save(&commande)
+
+	if conflict := middlewares.CheckConflict(c, db, &server, commande); conflict != nil {
+		return middlewares.ConflictResponse(c, conflict)
+	}
+
+	// Le statut ne change que par une transition autorisée du cycle de vie
+	requested := commande.Status
+	commande.Status = server.Status
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&commande).Error; err != nil {
+			return err
+		}
+		if requested == "" {
+			return nil
+		}
+		return lifecycle.Transition(tx, commande, requested, middlewares.GetAuthUser(c), "")
+	})
+	if err != nil {
+		return c.Status(statusCode(err)).JSON(
+			fiber.Map{
+				"status":  "error",
+				"message": err.Error(),
+				"data":    nil,
+			},
+		)
+	}
 
 	return c.JSON(
 		fiber.Map{
@@ -293,7 +421,7 @@ func UpdateCommande(c *fiber.Ctx) error {
 func DeleteCommande(c *fiber.Ctx) error {
 	uuid := c.Params("uuid")
 
-	db := database.DB
+	db := database.DB.WithContext(c.UserContext())
 
 	var commande models.Commande
 	db.Where("uuid = ?", uuid).First(&commande)
@@ -307,6 +435,16 @@ func DeleteCommande(c *fiber.Ctx) error {
 		)
 	}
 
+	if !lifecycle.Deletable(commande.Status) {
+		return c.Status(409).JSON(
+			fiber.Map{
+				"status":  "error",
+				"message": lifecycle.ErrNotDeletable.Error(),
+				"data":    nil,
+			},
+		)
+	}
+
 	db.Delete(&commande)
 
 	return c.JSON(
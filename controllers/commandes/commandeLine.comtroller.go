@@ -2,20 +2,23 @@ package commandes
 
 import (
 	"strconv"
+	"time"
 
 	"github.com/kgermando/ipos-stock-api/database"
+	"github.com/kgermando/ipos-stock-api/middlewares"
 	"github.com/kgermando/ipos-stock-api/models"
+	"github.com/kgermando/ipos-stock-api/pricing"
 
 	"github.com/gofiber/fiber/v2"
 )
 
 // Synchronisation Send data to Local
 func GetDataSynchronisationCommandeLine(c *fiber.Ctx) error {
-	db := database.DB
+	db := database.DB.WithContext(c.UserContext())
 	entrepriseUUID := c.Params("entreprise_uuid")
 	posUUID := c.Params("pos_uuid")
 
-	sync_created := c.Query("sync_created", "2023-01-01")
+	sync_created, syncDone := middlewares.SyncCursor(c, "commande_lines")
 	var data []models.CommandeLine
 
 	if posUUID == "-" {
@@ -30,6 +33,8 @@ func GetDataSynchronisationCommandeLine(c *fiber.Ctx) error {
 			Preload("Pos").
 			Find(&data)
 	}
+	syncDone()
+
 	return c.JSON(fiber.Map{
 		"status":  "success",
 		"message": "All CommandeLines",
@@ -39,7 +44,7 @@ func GetDataSynchronisationCommandeLine(c *fiber.Ctx) error {
 
 // Query all data ID
 func GetPaginatedCommandeLineByID(c *fiber.Ctx) error {
-	db := database.DB
+	db := database.DB.WithContext(c.UserContext())
 	commandeUUID := c.Params("commande_uuid")
 
 	page, err := strconv.Atoi(c.Query("page", "1"))
@@ -76,9 +81,13 @@ func GetPaginatedCommandeLineByID(c *fiber.Ctx) error {
 			products.description AS description,
 			products.unite_vente AS unite_vente,
 			commande_lines.quantity AS quantity,
-			products.prix_vente AS prix_vente,
-			products.tva AS tva,
-			SUM(commande_lines.quantity::FLOAT * products.prix_vente::FLOAT)
+			commande_lines.unit_price AS unit_price,
+			commande_lines.remise AS remise,
+			commande_lines.remise_amount AS remise_amount,
+			commande_lines.tva_rate AS tva_rate,
+			commande_lines.tva_amount AS tva_amount,
+			commande_lines.total_ht AS total_ht,
+			commande_lines.total_ttc AS total_ttc
 		`).
 		Offset(offset).
 		Limit(limit).
@@ -113,7 +122,7 @@ func GetPaginatedCommandeLineByID(c *fiber.Ctx) error {
 
 // Get All data by UUID
 func GetAllCommandeLineByUUId(c *fiber.Ctx) error {
-	db := database.DB
+	db := database.DB.WithContext(c.UserContext())
 	commandeUUID := c.Params("commande_uuid")
 
 	var dataList []models.CommandeLine
@@ -131,7 +140,7 @@ func GetAllCommandeLineByUUId(c *fiber.Ctx) error {
 
 // Get All data
 func GetAllCommandeLines(c *fiber.Ctx) error {
-	db := database.DB
+	db := database.DB.WithContext(c.UserContext())
 	var data []models.CommandeLine
 	db.Find(&data)
 	return c.JSON(fiber.Map{
@@ -143,7 +152,7 @@ func GetAllCommandeLines(c *fiber.Ctx) error {
 
 // Get Total data
 func GetTotalCommandeLine(c *fiber.Ctx) error {
-	db := database.DB
+	db := database.DB.WithContext(c.UserContext())
 	productUUID := c.Params("product_uuid")
 
 	var data []models.CommandeLine
@@ -163,7 +172,7 @@ func GetTotalCommandeLine(c *fiber.Ctx) error {
 // Get one data
 func GetCommandeLine(c *fiber.Ctx) error {
 	uuid := c.Params("uuid")
-	db := database.DB
+	db := database.DB.WithContext(c.UserContext())
 	var commandeLine models.CommandeLine
 
 	db.Where("uuid = ?", uuid).First(&commandeLine)
@@ -206,8 +215,25 @@ func CreateCommandeLine(c *fiber.Ctx) error {
 		)
 	}
 
+	// Prix figés à la vente : les prix et promotions actuels du serveur,
+	// jamais ceux envoyés par le terminal
+	db := database.DB.WithContext(c.UserContext())
+	promos, _ := pricing.LoadPromotions(db, p.PosUUID, nil, time.Now())
+	if err := pricing.Snapshot(db, p, pricing.LoadPolicy(db, p.EntrepriseUUID), promos); err != nil {
+		return c.Status(404).JSON(
+			fiber.Map{
+				"status":  "error",
+				"message": "Produit ou plat introuvable",
+				"data":    nil,
+			},
+		)
+	}
+
 	p.Sync = true
-	database.DB.Create(p)
+	db.Create(p)
+
+	// Les totaux de la commande suivent ses lignes
+	pricing.Recompute(db, p.CommandeUUID)
 
 	return c.JSON(
 		fiber.Map{
@@ -220,7 +246,7 @@ func CreateCommandeLine(c *fiber.Ctx) error {
 
 // Update data
 func UpdateCommandeLine(c *fiber.Ctx) error {
-	db := database.DB
+	db := database.DB.WithContext(c.UserContext())
 	uuid := c.Params("uuid")
 
 	type UpdateData struct {
@@ -244,15 +270,52 @@ func UpdateCommandeLine(c *fiber.Ctx) error {
 
 	commandeLine := new(models.CommandeLine)
 
-	db.Where("uuid = ?", uuid).First(&commandeLine)
+	if err := db.Where("uuid = ?", uuid).First(&commandeLine).Error; err != nil {
+		return c.Status(404).JSON(
+			fiber.Map{
+				"status":  "error",
+				"message": "No commande line found",
+				"data":    nil,
+			},
+		)
+	}
+	server := *commandeLine
 	commandeLine.CommandeUUID = updateData.CommandeUUID
-	commandeLine.ProductUUID = updateData.ProductUUID
 	commandeLine.Quantity = updateData.Quantity
 	commandeLine.EntrepriseUUID = updateData.EntrepriseUUID
 
+	// Un changement de produit reprend ses prix et promotions actuels, sinon
+	// les prix figés sont conservés et la promotion recalculée
+	policy := pricing.LoadPolicy(db, commandeLine.EntrepriseUUID)
+	if updateData.ProductUUID != commandeLine.ProductUUID {
+		commandeLine.ProductUUID = updateData.ProductUUID
+		promos, _ := pricing.LoadPromotions(db, commandeLine.PosUUID, nil, time.Now())
+		if err := pricing.Snapshot(db, commandeLine, policy, promos); err != nil {
+			return c.Status(404).JSON(
+				fiber.Map{
+					"status":  "error",
+					"message": "Produit introuvable",
+					"data":    nil,
+				},
+			)
+		}
+	} else {
+		pricing.Refresh(db, commandeLine, policy)
+	}
+
 	commandeLine.Sync = true
+
+	if conflict := middlewares.CheckConflict(c, db, &server, commandeLine); conflict != nil {
+		return middlewares.ConflictResponse(c, conflict)
+	}
+
 	db.Save(&commandeLine)
 
+	if server.CommandeUUID != commandeLine.CommandeUUID {
+		pricing.Recompute(db, server.CommandeUUID)
+	}
+	pricing.Recompute(db, commandeLine.CommandeUUID)
+
 	return c.JSON(
 		fiber.Map{
 			"status":  "success",
@@ -267,7 +330,7 @@ func UpdateCommandeLine(c *fiber.Ctx) error {
 func DeleteCommandeLine(c *fiber.Ctx) error {
 	uuid := c.Params("uuid")
 
-	db := database.DB
+	db := database.DB.WithContext(c.UserContext())
 
 	var commandeLine models.CommandeLine
 	db.Where("uuid = ?", uuid).First(&commandeLine)
@@ -0,0 +1,94 @@
+package commandes
+
+import (
+	"errors"
+
+	"github.com/kgermando/ipos-stock-api/database"
+	"github.com/kgermando/ipos-stock-api/lifecycle"
+	"github.com/kgermando/ipos-stock-api/middlewares"
+	"github.com/kgermando/ipos-stock-api/models"
+	"github.com/kgermando/ipos-stock-api/utils"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// UpdateCommandeStatus fait passer une commande à un nouveau statut, si le
+// cycle de vie l'autorise, et trace le changement. Seul un gérant annule une
+// commande réglée ; ses règlements sont alors contre-passés en caisse.
+func UpdateCommandeStatus(c *fiber.Ctx) error {
+	uuid := c.Params("uuid")
+	db := database.DB.WithContext(c.UserContext())
+
+	var req models.CommandeStatusRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Données JSON invalides",
+			"errors":  err.Error(),
+		})
+	}
+
+	if err := utils.ValidateStruct(req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Données invalides",
+			"errors":  err,
+		})
+	}
+
+	var commande models.Commande
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("uuid = ?", uuid).First(&commande).Error; err != nil {
+			return err
+		}
+		return lifecycle.Transition(tx, &commande, req.Status, middlewares.GetAuthUser(c), req.Motif)
+	})
+	if err != nil {
+		return c.Status(statusCode(err)).JSON(fiber.Map{
+			"status":  "error",
+			"message": err.Error(),
+			"data":    nil,
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"status":  "success",
+		"message": "commande status updated success",
+		"data":    commande,
+	})
+}
+
+// GetCommandeStatusHistory liste les changements de statut d'une commande
+func GetCommandeStatusHistory(c *fiber.Ctx) error {
+	db := database.DB.WithContext(c.UserContext())
+	uuid := c.Params("uuid")
+
+	var data []models.CommandeStatusHistory
+	db.Where("commande_uuid = ?", uuid).Order("created_at").Find(&data)
+
+	return c.JSON(fiber.Map{
+		"status":  "success",
+		"message": "All commande status history",
+		"data":    data,
+	})
+}
+
+// statusCode traduit une erreur du cycle de vie en code HTTP
+func statusCode(err error) int {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return 404
+	case errors.Is(err, lifecycle.ErrUnknownStatus):
+		return 400
+	case errors.Is(err, lifecycle.ErrVoidForbidden):
+		return 403
+	case errors.Is(err, lifecycle.ErrInvalidTransition), errors.Is(err, lifecycle.ErrPayOnly),
+		errors.Is(err, lifecycle.ErrRefundOnly), errors.Is(err, lifecycle.ErrVoidCertified),
+		errors.Is(err, lifecycle.ErrVoidReturned):
+		return 409
+	}
+	return 500
+}
@@ -0,0 +1,74 @@
+package commandes
+
+import (
+	"bytes"
+	"errors"
+
+	"github.com/kgermando/ipos-stock-api/database"
+	"github.com/kgermando/ipos-stock-api/models"
+	"github.com/kgermando/ipos-stock-api/receipts"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// GetCommandeReceipt imprime la commande : facture A4 en PDF (format=pdf, par
+// défaut) ou ticket ESC/POS brut pour imprimante thermique (format=escpos).
+// Le paramètre width (58 ou 80) remplace la largeur de papier du modèle.
+// La certification reste à la file d'attente : une facture réglée qui n'est
+// pas encore certifiée porte la mention d'attente de certification.
+func GetCommandeReceipt(c *fiber.Ctx) error {
+	uuid := c.Params("uuid")
+	db := database.DB.WithContext(c.UserContext())
+
+	format := c.Query("format", models.ReceiptPDF)
+	if format != models.ReceiptPDF && format != models.ReceiptESCPOS {
+		return c.Status(400).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Format d'impression inconnu : pdf ou escpos",
+			"data":    nil,
+		})
+	}
+
+	receipt, err := receipts.Load(db, uuid)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.Status(404).JSON(fiber.Map{
+				"status":  "error",
+				"message": "Commande introuvable",
+				"data":    nil,
+			})
+		}
+		return c.Status(500).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Impossible de préparer l'impression",
+			"error":   err.Error(),
+		})
+	}
+	if width := c.QueryInt("width"); width == 58 || width == 80 {
+		receipt.Template.PaperWidth = width
+	}
+
+	filename := receipt.Commande.Ncommande
+	if filename == "" {
+		filename = receipt.Commande.UUID
+	}
+
+	if format == models.ReceiptESCPOS {
+		c.Set(fiber.HeaderContentType, fiber.MIMEOctetStream)
+		c.Set(fiber.HeaderContentDisposition, `attachment; filename="`+filename+`.bin"`)
+		return c.Send(receipts.ESCPOS(receipt))
+	}
+
+	var buf bytes.Buffer
+	if err := receipts.PDF(receipt, &buf); err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Impossible de générer la facture",
+			"error":   err.Error(),
+		})
+	}
+	c.Set(fiber.HeaderContentType, "application/pdf")
+	c.Set(fiber.HeaderContentDisposition, `inline; filename="`+filename+`.pdf"`)
+	return c.Send(buf.Bytes())
+}
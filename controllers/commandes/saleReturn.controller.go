@@ -0,0 +1,398 @@
+package commandes
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kgermando/ipos-stock-api/database"
+	"github.com/kgermando/ipos-stock-api/lifecycle"
+	"github.com/kgermando/ipos-stock-api/middlewares"
+	"github.com/kgermando/ipos-stock-api/models"
+	"github.com/kgermando/ipos-stock-api/numbering"
+	"github.com/kgermando/ipos-stock-api/pricing"
+	"github.com/kgermando/ipos-stock-api/utils"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Paginate
+func GetPaginatedSaleReturn(c *fiber.Ctx) error {
+	db := database.DB.WithContext(c.UserContext())
+	entrepriseUUID := c.Params("entreprise_uuid")
+
+	page, err := strconv.Atoi(c.Query("page", "1"))
+	if err != nil || page <= 0 {
+		page = 1
+	}
+	limit, err := strconv.Atoi(c.Query("limit", "15"))
+	if err != nil || limit <= 0 {
+		limit = 15
+	}
+	offset := (page - 1) * limit
+
+	search := c.Query("search", "")
+
+	query := db.Model(&models.SaleReturn{}).
+		Where("entreprise_uuid = ?", entrepriseUUID).
+		Where("nreturn ILIKE ? OR motif ILIKE ?", "%"+search+"%", "%"+search+"%")
+	if posUUID := c.Query("pos_uuid"); posUUID != "" {
+		query = query.Where("pos_uuid = ?", posUUID)
+	}
+
+	var totalRecords int64
+	query.Count(&totalRecords)
+
+	var dataList []models.SaleReturn
+	err = query.Offset(offset).
+		Limit(limit).
+		Order("sale_returns.created_at DESC").
+		Preload("Lines").
+		Find(&dataList).Error
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Failed to fetch sale returns",
+			"error":   err.Error(),
+		})
+	}
+
+	totalPages := int((totalRecords + int64(limit) - 1) / int64(limit))
+
+	pagination := map[string]interface{}{
+		"total_records": totalRecords,
+		"total_pages":   totalPages,
+		"current_page":  page,
+		"page_size":     limit,
+	}
+
+	return c.JSON(fiber.Map{
+		"status":     "success",
+		"message":    "All sale returns paginated",
+		"data":       dataList,
+		"pagination": pagination,
+	})
+}
+
+// GetCommandeSaleReturns liste les retours d'une commande
+func GetCommandeSaleReturns(c *fiber.Ctx) error {
+	db := database.DB.WithContext(c.UserContext())
+	commandeUUID := c.Params("commande_uuid")
+
+	var data []models.SaleReturn
+	db.Where("commande_uuid = ?", commandeUUID).
+		Order("created_at").
+		Preload("Lines").
+		Find(&data)
+
+	return c.JSON(fiber.Map{
+		"status":  "success",
+		"message": "All sale returns",
+		"data":    data,
+	})
+}
+
+// Get one data
+func GetSaleReturn(c *fiber.Ctx) error {
+	uuid := c.Params("uuid")
+	db := database.DB.WithContext(c.UserContext())
+
+	var saleReturn models.SaleReturn
+	if err := db.Where("uuid = ?", uuid).Preload("Lines").First(&saleReturn).Error; err != nil {
+		return c.Status(404).JSON(
+			fiber.Map{
+				"status":  "error",
+				"message": "No sale return found",
+				"data":    nil,
+			},
+		)
+	}
+	return c.JSON(
+		fiber.Map{
+			"status":  "success",
+			"message": "sale return found",
+			"data":    saleReturn,
+		},
+	)
+}
+
+// CreateSaleReturn enregistre un retour client sur une commande réglée, dans
+// une seule transaction : les quantités rendues ne peuvent dépasser celles
+// vendues moins celles déjà rendues ; les produits sont remis en stock ou
+// portés en stock endommagé ; le client est remboursé par une sortie de
+// caisse ou crédité d'un avoir. Un retour n'est ni modifiable ni supprimable.
+func CreateSaleReturn(c *fiber.Ctx) error {
+	db := database.DB.WithContext(c.UserContext())
+	user := middlewares.GetAuthUser(c)
+
+	var req models.SaleReturnRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Données JSON invalides",
+			"errors":  err.Error(),
+		})
+	}
+
+	if err := utils.ValidateStruct(req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Données invalides",
+			"errors":  err,
+		})
+	}
+
+	saleReturn := &models.SaleReturn{
+		UUID:         req.UUID,
+		CommandeUUID: req.CommandeUUID,
+		Motif:        strings.TrimSpace(req.Motif),
+		RefundMethod: req.RefundMethod,
+		CashierUUID:  user.UUID,
+		Signature:    req.Signature,
+		Sync:         true,
+	}
+	if saleReturn.UUID == "" {
+		saleReturn.UUID = utils.GenerateUUID()
+	}
+
+	var commande models.Commande
+	var caisseItem *models.CaisseItem
+	status, message := 400, "Retour invalide"
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		tx.Unscoped().Model(&models.SaleReturn{}).Where("uuid = ?", saleReturn.UUID).Count(&count)
+		if count > 0 {
+			status, message = 409, "Retour avec cet UUID existe déjà"
+			return gorm.ErrDuplicatedKey
+		}
+
+		// Verrouille la commande pour que deux retours simultanés ne rendent
+		// pas deux fois les mêmes articles
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("uuid = ?", req.CommandeUUID).First(&commande).Error; err != nil {
+			status, message = 404, "Commande introuvable"
+			return err
+		}
+		if commande.Status != models.CommandePaid {
+			status, message = 409, "Seule une commande réglée peut faire l'objet d'un retour"
+			return gorm.ErrInvalidData
+		}
+
+		var pos models.Pos
+		if err := tx.Where("uuid = ?", commande.PosUUID).First(&pos).Error; err != nil {
+			status, message = 404, "Point de vente introuvable"
+			return err
+		}
+		number, err := numbering.Next(tx, models.DocumentSaleReturn, pos, time.Now())
+		if err != nil {
+			status, message = 500, "Erreur lors de l'attribution du numéro d'avoir"
+			return err
+		}
+		saleReturn.Nreturn = number
+		saleReturn.ClientUUID = commande.ClientUUID
+		saleReturn.EntrepriseUUID = commande.EntrepriseUUID
+		saleReturn.PosUUID = commande.PosUUID
+		policy := pricing.LoadPolicy(tx, commande.EntrepriseUUID)
+
+		var lines []models.CommandeLine
+		tx.Where("commande_uuid = ?", commande.UUID).Find(&lines)
+		sold := make(map[string]models.CommandeLine, len(lines))
+		for _, line := range lines {
+			sold[line.UUID] = line
+		}
+		returned := returnedByLine(tx, commande.UUID)
+
+		for _, r := range req.Lines {
+			line, ok := sold[r.CommandeLineUUID]
+			if !ok {
+				status, message = 404, "Ligne de commande introuvable pour cette commande"
+				return gorm.ErrRecordNotFound
+			}
+			already := returned[line.UUID]
+			if already.Quantity+r.Quantity > line.Quantity {
+				status, message = 409, fmt.Sprintf("Quantité rendue supérieure à la quantité vendue (reste : %d)", line.Quantity-already.Quantity)
+				return gorm.ErrInvalidData
+			}
+
+			rl := prorate(line, already, r.Quantity, policy)
+			rl.UUID = utils.GenerateUUID()
+			rl.SaleReturnUUID = saleReturn.UUID
+			rl.EntrepriseUUID = commande.EntrepriseUUID
+			rl.PosUUID = commande.PosUUID
+			rl.Sync = true
+
+			switch {
+			case line.ItemType != "product":
+				rl.Disposition = models.ReturnNone
+			case r.Damaged:
+				rl.Disposition = models.ReturnDamaged
+				damaged := models.StockEndommage{
+					UUID:           utils.GenerateUUID(),
+					PosUUID:        commande.PosUUID,
+					ProductUUID:    line.ProductUUID,
+					Quantity:       float64(r.Quantity),
+					PrixAchat:      line.UnitCost,
+					Raison:         "Retour client commande N° " + commande.Ncommande,
+					Signature:      req.Signature,
+					EntrepriseUUID: commande.EntrepriseUUID,
+					Sync:           true,
+				}
+				if saleReturn.Motif != "" {
+					damaged.Raison += " : " + saleReturn.Motif
+				}
+				if err := tx.Omit(clause.Associations).Create(&damaged).Error; err != nil {
+					status, message = 500, "Erreur lors de l'enregistrement du stock endommagé"
+					return err
+				}
+				rl.StockEndommageUUID = damaged.UUID
+				if err := tx.Model(&models.Product{}).Where("uuid = ?", line.ProductUUID).
+					Updates(map[string]interface{}{"stock_endommage": gorm.Expr("stock_endommage + ?", r.Quantity), "sync": true}).Error; err != nil {
+					status, message = 500, "Erreur lors de la mise à jour du stock"
+					return err
+				}
+			default:
+				rl.Disposition = models.ReturnRestock
+				if err := tx.Model(&models.Product{}).Where("uuid = ?", line.ProductUUID).
+					Updates(map[string]interface{}{"stock": gorm.Expr("stock + ?", r.Quantity), "sync": true}).Error; err != nil {
+					status, message = 500, "Erreur lors de la mise à jour du stock"
+					return err
+				}
+			}
+
+			already.Quantity += rl.Quantity
+			already.TotalHt += rl.TotalHt
+			already.TvaAmount += rl.TvaAmount
+			returned[line.UUID] = already
+
+			saleReturn.TotalHt += rl.TotalHt
+			saleReturn.TotalTva += rl.TvaAmount
+			saleReturn.Lines = append(saleReturn.Lines, rl)
+		}
+		saleReturn.TotalHt = policy.Round(saleReturn.TotalHt)
+		saleReturn.TotalTva = policy.Round(saleReturn.TotalTva)
+		saleReturn.TotalTtc = policy.Round(saleReturn.TotalHt + saleReturn.TotalTva)
+
+		switch saleReturn.RefundMethod {
+		case models.RefundCaisse:
+			var caisse models.Caisse
+			if err := tx.Where("uuid = ? AND pos_uuid = ?", req.CaisseUUID, commande.PosUUID).First(&caisse).Error; err != nil {
+				status, message = 404, "Caisse introuvable pour ce point de vente"
+				return err
+			}
+			caisseItem = &models.CaisseItem{
+				UUID:            utils.GenerateUUID(),
+				CaisseUUID:      caisse.UUID,
+				TypeTransaction: "Sortie",
+				Montant:         saleReturn.TotalTtc,
+				Libelle:         "Retour commande N° " + commande.Ncommande + " (avoir N° " + saleReturn.Nreturn + ")",
+				Reference:       saleReturn.Nreturn,
+				Signature:       req.Signature,
+				CashierUUID:     user.UUID,
+				EntrepriseUUID:  commande.EntrepriseUUID,
+				PosUUID:         commande.PosUUID,
+				Sync:            true,
+			}
+			if err := tx.Omit(clause.Associations).Create(caisseItem).Error; err != nil {
+				status, message = 500, "Erreur lors de l'enregistrement de la sortie de caisse"
+				return err
+			}
+			saleReturn.CaisseUUID = caisse.UUID
+			saleReturn.CaisseItemUUID = caisseItem.UUID
+
+		case models.RefundStoreCredit:
+			res := tx.Model(&models.Client{}).Where("uuid = ?", commande.ClientUUID).
+				Updates(map[string]interface{}{"credit_balance": gorm.Expr("credit_balance + ?", saleReturn.TotalTtc), "sync": true})
+			if res.Error != nil {
+				status, message = 500, "Erreur lors du crédit de l'avoir"
+				return res.Error
+			}
+			if res.RowsAffected == 0 {
+				status, message = 422, "La commande n'est rattachée à aucun client : l'avoir ne peut pas être crédité"
+				return gorm.ErrRecordNotFound
+			}
+		}
+
+		if err := tx.Omit(clause.Associations).Create(saleReturn).Error; err != nil {
+			status, message = 500, "Erreur lors de l'enregistrement du retour"
+			return err
+		}
+		if err := tx.Omit(clause.Associations).Create(&saleReturn.Lines).Error; err != nil {
+			status, message = 500, "Erreur lors de l'enregistrement des articles rendus"
+			return err
+		}
+
+		// Une commande entièrement rendue est remboursée
+		for _, line := range lines {
+			if returned[line.UUID].Quantity < line.Quantity {
+				return nil
+			}
+		}
+		if err := lifecycle.Refund(tx, &commande, user, "Retour N° "+saleReturn.Nreturn); err != nil {
+			status, message = 500, "Erreur lors de la mise à jour de la commande"
+			return err
+		}
+		return nil
+	})
+
+	if err != nil {
+		return c.Status(status).JSON(fiber.Map{
+			"status":  "error",
+			"message": message,
+		})
+	}
+
+	return c.Status(201).JSON(fiber.Map{
+		"status":  "success",
+		"message": "Retour enregistré avec succès",
+		"data": fiber.Map{
+			"sale_return": saleReturn,
+			"commande":    commande,
+			"caisse_item": caisseItem,
+		},
+	})
+}
+
+// returnedByLine retourne, par ligne de commande, les quantités et montants
+// déjà rendus
+func returnedByLine(tx *gorm.DB, commandeUUID string) map[string]models.SaleReturnLine {
+	var rows []models.SaleReturnLine
+	tx.Model(&models.SaleReturnLine{}).
+		Select("commande_line_uuid, SUM(quantity) AS quantity, SUM(total_ht) AS total_ht, SUM(tva_amount) AS tva_amount").
+		Where("sale_return_uuid IN (?)", tx.Model(&models.SaleReturn{}).Select("uuid").Where("commande_uuid = ?", commandeUUID)).
+		Group("commande_line_uuid").
+		Scan(&rows)
+
+	returned := make(map[string]models.SaleReturnLine, len(rows))
+	for _, row := range rows {
+		returned[row.CommandeLineUUID] = row
+	}
+	return returned
+}
+
+// prorate calcule la part rendue d'une ligne au prorata de la quantité. Le
+// dernier retour de la ligne reprend le solde exact, pour que la somme des
+// retours ne s'écarte jamais des montants vendus à cause des arrondis.
+func prorate(line models.CommandeLine, already models.SaleReturnLine, qty uint64, policy pricing.Policy) models.SaleReturnLine {
+	rl := models.SaleReturnLine{
+		CommandeLineUUID: line.UUID,
+		ProductUUID:      line.ProductUUID,
+		PlatUUID:         line.PlatUUID,
+		ItemType:         line.ItemType,
+		Quantity:         qty,
+		UnitCost:         line.UnitCost,
+	}
+	if already.Quantity+qty == line.Quantity {
+		rl.TotalHt = policy.Round(line.TotalHt - already.TotalHt)
+		rl.TvaAmount = policy.Round(line.TvaAmount - already.TvaAmount)
+	} else {
+		share := float64(qty) / float64(line.Quantity)
+		rl.TotalHt = policy.Round(line.TotalHt * share)
+		rl.TvaAmount = policy.Round(line.TvaAmount * share)
+	}
+	rl.TotalTtc = policy.Round(rl.TotalHt + rl.TvaAmount)
+	return rl
+}
@@ -0,0 +1,224 @@
+package conflicts
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/kgermando/ipos-stock-api/database"
+	"github.com/kgermando/ipos-stock-api/middlewares"
+	"github.com/kgermando/ipos-stock-api/models"
+	"github.com/kgermando/ipos-stock-api/utils"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Paginate conflicts of an entreprise.
+// Filtres : entity, entity_uuid, resolution, pos_uuid, reviewed (true/false), start_date, end_date
+func GetPaginatedConflicts(c *fiber.Ctx) error {
+	db := database.DB.WithContext(c.UserContext())
+	entrepriseUUID := c.Params("entreprise_uuid")
+
+	page, err := strconv.Atoi(c.Query("page", "1"))
+	if err != nil || page <= 0 {
+		page = 1 // Default page number
+	}
+	limit, err := strconv.Atoi(c.Query("limit", "15"))
+	if err != nil || limit <= 0 {
+		limit = 15
+	}
+	offset := (page - 1) * limit
+
+	query := db.Model(&models.ConflictLog{}).Where("entreprise_uuid = ?", entrepriseUUID)
+
+	filters := map[string]string{
+		"entity":      c.Query("entity"),
+		"entity_uuid": c.Query("entity_uuid"),
+		"resolution":  c.Query("resolution"),
+	}
+	for column, value := range filters {
+		if value != "" {
+			query = query.Where(column+" = ?", value)
+		}
+	}
+	if posUUID := c.Query("pos_uuid"); posUUID != "" && posUUID != "-" {
+		query = query.Where("pos_uuid = ?", posUUID)
+	}
+	switch c.Query("reviewed") {
+	case "true":
+		query = query.Where("reviewed_at IS NOT NULL")
+	case "false":
+		query = query.Where("reviewed_at IS NULL")
+	}
+	if startDate := c.Query("start_date"); startDate != "" {
+		query = query.Where("created_at >= ?", startDate)
+	}
+	if endDate := c.Query("end_date"); endDate != "" {
+		query = query.Where("created_at <= ?", endDate)
+	}
+
+	var totalRecords int64
+	query.Count(&totalRecords)
+
+	var dataList []models.ConflictLog
+	err = query.Offset(offset).
+		Limit(limit).
+		Order("created_at DESC").
+		Find(&dataList).Error
+
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Failed to fetch conflicts",
+			"error":   err.Error(),
+		})
+	}
+
+	// Calculate total pages
+	totalPages := int((totalRecords + int64(limit) - 1) / int64(limit))
+
+	// Prepare pagination metadata
+	pagination := map[string]interface{}{
+		"total_records": totalRecords,
+		"total_pages":   totalPages,
+		"current_page":  page,
+		"page_size":     limit,
+	}
+
+	return c.JSON(fiber.Map{
+		"status":     "success",
+		"message":    "All conflicts paginated",
+		"data":       dataList,
+		"pagination": pagination,
+	})
+}
+
+// Get one data
+func GetConflict(c *fiber.Ctx) error {
+	uuid := c.Params("uuid")
+	db := database.DB.WithContext(c.UserContext())
+
+	var conflict models.ConflictLog
+	db.Where("uuid = ?", uuid).First(&conflict)
+	if conflict.UUID == "" {
+		return c.Status(404).JSON(
+			fiber.Map{
+				"status":  "error",
+				"message": "No conflict found",
+				"data":    nil,
+			},
+		)
+	}
+	return c.JSON(
+		fiber.Map{
+			"status":  "success",
+			"message": "conflict found",
+			"data":    conflict,
+		},
+	)
+}
+
+// ReviewConflict marque le conflit comme vu par un gérant
+func ReviewConflict(c *fiber.Ctx) error {
+	uuid := c.Params("uuid")
+	db := database.DB.WithContext(c.UserContext())
+	user := middlewares.GetAuthUser(c)
+
+	var conflict models.ConflictLog
+	db.Where("uuid = ?", uuid).First(&conflict)
+	if conflict.UUID == "" {
+		return c.Status(404).JSON(
+			fiber.Map{
+				"status":  "error",
+				"message": "No conflict found",
+				"data":    nil,
+			},
+		)
+	}
+
+	now := time.Now()
+	db.Model(&conflict).Updates(map[string]interface{}{
+		"reviewed_at": &now,
+		"reviewed_by": user.UUID,
+	})
+
+	return c.JSON(
+		fiber.Map{
+			"status":  "success",
+			"message": "Conflit marqué comme revu",
+			"data":    conflict,
+		},
+	)
+}
+
+// GetConflictRules retourne la règle appliquée à chaque table : celle de
+// l'entreprise si elle existe, sinon la règle par défaut
+func GetConflictRules(c *fiber.Ctx) error {
+	db := database.DB.WithContext(c.UserContext())
+	entrepriseUUID := c.Params("entreprise_uuid")
+
+	var custom []models.ConflictRule
+	db.Where("entreprise_uuid = ?", entrepriseUUID).Order("entity").Find(&custom)
+
+	rules := map[string]models.ConflictRule{}
+	for entity, rule := range models.DefaultConflictRules {
+		rules[entity] = rule
+	}
+	for _, rule := range custom {
+		rules[rule.Entity] = rule
+	}
+
+	return c.JSON(fiber.Map{
+		"status":  "success",
+		"message": "Règles de résolution des conflits (server_wins pour les autres tables)",
+		"data":    rules,
+	})
+}
+
+// SetConflictRule crée ou remplace la règle de l'entreprise pour une table
+func SetConflictRule(c *fiber.Ctx) error {
+	db := database.DB.WithContext(c.UserContext())
+	entrepriseUUID := c.Params("entreprise_uuid")
+
+	var req models.ConflictRuleRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Données JSON invalides",
+			"errors":  err.Error(),
+		})
+	}
+
+	if err := utils.ValidateStruct(req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Données invalides",
+			"errors":  err,
+		})
+	}
+
+	var rule models.ConflictRule
+	db.Where("entreprise_uuid = ? AND entity = ?", entrepriseUUID, req.Entity).First(&rule)
+
+	rule.Policy = req.Policy
+	rule.MergeFields = req.MergeFields
+	if rule.UUID == "" {
+		rule.UUID = utils.GenerateUUID()
+		rule.EntrepriseUUID = entrepriseUUID
+		rule.Entity = req.Entity
+		if err := db.Create(&rule).Error; err != nil {
+			return c.Status(500).JSON(fiber.Map{
+				"status":  "error",
+				"message": "Erreur lors de l'enregistrement de la règle",
+				"error":   err.Error(),
+			})
+		}
+	} else {
+		db.Save(&rule)
+	}
+
+	return c.JSON(fiber.Map{
+		"status":  "success",
+		"message": "Règle de résolution enregistrée",
+		"data":    rule,
+	})
+}
@@ -22,6 +22,11 @@ func buildPosFilter(entrepriseUUID, posUUID string) (string, []interface{}) {
 	return "entreprise_uuid = ? AND pos_uuid = ?", []interface{}{entrepriseUUID, posUUID}
 }
 
+// returnedLines joint aux lignes vendues (cl) les quantités et montants déjà
+// rendus par les clients (rl) : les ventes sont ainsi comptées nettes des retours
+const returnedLines = "LEFT JOIN (SELECT commande_line_uuid, SUM(quantity) AS quantity, SUM(total_ht) AS total_ht " +
+	"FROM sale_return_lines WHERE deleted_at IS NULL GROUP BY commande_line_uuid) rl ON rl.commande_line_uuid = cl.uuid"
+
 // ============================= ENDPOINTS =============================
 
 // GetDashboardStats retourne les statistiques principales du dashboard
@@ -411,6 +416,33 @@ func GetRepartitionTransactionsData(c *fiber.Ctx) error {
 	return c.JSON(repartitionData)
 }
 
+// GetPaymentMethodsData retourne la répartition des ventes par moyen de paiement
+func GetPaymentMethodsData(c *fiber.Ctx) error {
+	entrepriseUUID := c.Query("entreprise_uuid")
+	posUUID := c.Query("pos_uuid")
+	startDateStr := c.Query("start_date")
+	endDateStr := c.Query("end_date")
+
+	if entrepriseUUID == "" {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Le paramètre entreprise_uuid est requis",
+		})
+	}
+
+	var startDate, endDate *time.Time
+	if startDateStr != "" && endDateStr != "" {
+		start, err1 := time.Parse("2006-01-02T15:04:05Z07:00", startDateStr)
+		end, err2 := time.Parse("2006-01-02T15:04:05Z07:00", endDateStr)
+		if err1 == nil && err2 == nil {
+			startDate = &start
+			endDate = &end
+		}
+	}
+
+	paymentData := getPaymentMethodsData(entrepriseUUID, posUUID, startDate, endDate)
+	return c.JSON(paymentData)
+}
+
 // GetTopTransactions retourne les meilleures transactions
 func GetTopTransactions(c *fiber.Ctx) error {
 	entrepriseUUID := c.Query("entreprise_uuid")
@@ -466,14 +498,15 @@ func calculateDashboardStats(entrepriseUUID, posUUID string, startDate, endDate
 	var commandeArgs []interface{}
 	if posUUID == "" {
 		commandeFilter = "c.entreprise_uuid = ? AND c.status = ? AND cl.item_type = ?"
-		commandeArgs = []interface{}{entrepriseUUID, "paid", "product"}
+		commandeArgs = []interface{}{entrepriseUUID, models.CommandePaid, "product"}
 	} else {
 		commandeFilter = "c.entreprise_uuid = ? AND c.pos_uuid = ? AND c.status = ? AND cl.item_type = ?"
-		commandeArgs = []interface{}{entrepriseUUID, posUUID, "paid", "product"}
+		commandeArgs = []interface{}{entrepriseUUID, posUUID, models.CommandePaid, "product"}
 	}
 
 	query := db.Table("commande_lines cl").
 		Joins("JOIN commandes c ON cl.commande_uuid = c.uuid").
+		Joins(returnedLines).
 		Where(commandeFilter, commandeArgs...)
 
 	if startDate != nil && endDate != nil {
@@ -481,20 +514,21 @@ func calculateDashboardStats(entrepriseUUID, posUUID string, startDate, endDate
 	}
 
 	var totalVentes int64
-	query.Select("COALESCE(SUM(cl.quantity), 0)").Scan(&totalVentes)
+	query.Select("COALESCE(SUM(cl.quantity - COALESCE(rl.quantity, 0)), 0)").Scan(&totalVentes)
 
-	// 4. Total montant vendu (uniquement pour les produits)
+	// 4. Total montant vendu (uniquement pour les produits, net des retours)
 	var totalMontantVendu float64
 	subquery := db.Table("commande_lines cl").
 		Joins("JOIN commandes c ON cl.commande_uuid = c.uuid").
 		Joins("JOIN products p ON cl.product_uuid = p.uuid").
+		Joins(returnedLines).
 		Where(commandeFilter, commandeArgs...)
 
 	if startDate != nil && endDate != nil {
 		subquery = subquery.Where("c.created_at BETWEEN ? AND ?", startDate, endDate)
 	}
 
-	subquery.Select("COALESCE(SUM(cl.quantity * p.prix_vente), 0)").Scan(&totalMontantVendu)
+	subquery.Select("COALESCE(SUM(cl.total_ht - COALESCE(rl.total_ht, 0)), 0)").Scan(&totalMontantVendu)
 
 	// Calcul des pourcentages
 	var articlesRuptureStockPercentage int
@@ -569,24 +603,26 @@ func getSalesChartData(entrepriseUUID, posUUID string, startDate, endDate time.T
 	var results []struct {
 		CreatedAt time.Time
 		Quantity  int64
-		PrixVente float64
-		PrixAchat float64
+		TotalHt   float64
+		UnitCost  float64
 	}
 
 	var commandeFilter string
 	var commandeArgs []interface{}
 	if posUUID == "" {
 		commandeFilter = "c.entreprise_uuid = ? AND c.status = ? AND cl.item_type = ?"
-		commandeArgs = []interface{}{entrepriseUUID, "paid", "product"}
+		commandeArgs = []interface{}{entrepriseUUID, models.CommandePaid, "product"}
 	} else {
 		commandeFilter = "c.entreprise_uuid = ? AND c.pos_uuid = ? AND c.status = ? AND cl.item_type = ?"
-		commandeArgs = []interface{}{entrepriseUUID, posUUID, "paid", "product"}
+		commandeArgs = []interface{}{entrepriseUUID, posUUID, models.CommandePaid, "product"}
 	}
 
 	query := db.Table("commande_lines cl").
-		Select("c.created_at, cl.quantity, p.prix_vente, p.prix_achat").
+		Select("c.created_at, cl.quantity - COALESCE(rl.quantity, 0) AS quantity, "+
+			"cl.total_ht - COALESCE(rl.total_ht, 0) AS total_ht, cl.unit_cost").
 		Joins("JOIN commandes c ON cl.commande_uuid = c.uuid").
 		Joins("JOIN products p ON cl.product_uuid = p.uuid").
+		Joins(returnedLines).
 		Where(commandeFilter, commandeArgs...).
 		Where("c.created_at BETWEEN ? AND ?", startDate, endDate)
 
@@ -603,8 +639,8 @@ func getSalesChartData(entrepriseUUID, posUUID string, startDate, endDate time.T
 
 		if data, exists := timeData[timeKey]; exists {
 			data.commandes += result.Quantity
-			chiffresAffaires := result.PrixVente * float64(result.Quantity)
-			cout := result.PrixAchat * float64(result.Quantity)
+			chiffresAffaires := result.TotalHt
+			cout := result.UnitCost * float64(result.Quantity)
 			data.montant += chiffresAffaires
 			data.gain += chiffresAffaires - cout
 			timeData[timeKey] = data
@@ -653,16 +689,17 @@ func getPlatChartData(entrepriseUUID, posUUID string, startDate, endDate time.Ti
 	var commandeArgs []interface{}
 	if posUUID == "" {
 		commandeFilter = "c.entreprise_uuid = ? AND c.status = ? AND cl.item_type = ?"
-		commandeArgs = []interface{}{entrepriseUUID, "paid", "plat"}
+		commandeArgs = []interface{}{entrepriseUUID, models.CommandePaid, "plat"}
 	} else {
 		commandeFilter = "c.entreprise_uuid = ? AND c.pos_uuid = ? AND c.status = ? AND cl.item_type = ?"
-		commandeArgs = []interface{}{entrepriseUUID, posUUID, "paid", "plat"}
+		commandeArgs = []interface{}{entrepriseUUID, posUUID, models.CommandePaid, "plat"}
 	}
 
 	query := db.Table("commande_lines cl").
-		Select("pl.name, SUM(cl.quantity * pl.prix) as montant, SUM(cl.quantity) as quantity").
+		Select("pl.name, SUM(cl.total_ht - COALESCE(rl.total_ht, 0)) as montant, SUM(cl.quantity - COALESCE(rl.quantity, 0)) as quantity").
 		Joins("JOIN commandes c ON cl.commande_uuid = c.uuid").
 		Joins("JOIN plats pl ON cl.plat_uuid = pl.uuid").
+		Joins(returnedLines).
 		Where(commandeFilter, commandeArgs...).
 		Where("c.created_at BETWEEN ? AND ?", startDate, endDate).
 		Group("pl.uuid, pl.name").
@@ -720,16 +757,17 @@ func getProductChartData(entrepriseUUID, posUUID string, startDate, endDate time
 	var commandeArgs []interface{}
 	if posUUID == "" {
 		commandeFilter = "c.entreprise_uuid = ? AND c.status = ? AND cl.item_type = ?"
-		commandeArgs = []interface{}{entrepriseUUID, "paid", "product"}
+		commandeArgs = []interface{}{entrepriseUUID, models.CommandePaid, "product"}
 	} else {
 		commandeFilter = "c.entreprise_uuid = ? AND c.pos_uuid = ? AND c.status = ? AND cl.item_type = ?"
-		commandeArgs = []interface{}{entrepriseUUID, posUUID, "paid", "product"}
+		commandeArgs = []interface{}{entrepriseUUID, posUUID, models.CommandePaid, "product"}
 	}
 
 	query := db.Table("commande_lines cl").
-		Select("p.name, SUM(cl.quantity * p.prix_vente) as montant, SUM(cl.quantity) as quantity").
+		Select("p.name, SUM(cl.total_ht - COALESCE(rl.total_ht, 0)) as montant, SUM(cl.quantity - COALESCE(rl.quantity, 0)) as quantity").
 		Joins("JOIN commandes c ON cl.commande_uuid = c.uuid").
 		Joins("JOIN products p ON cl.product_uuid = p.uuid").
+		Joins(returnedLines).
 		Where(commandeFilter, commandeArgs...).
 		Where("c.created_at BETWEEN ? AND ?", startDate, endDate).
 		Group("p.uuid, p.name").
@@ -898,15 +936,16 @@ func getStockRotationData(entrepriseUUID, posUUID string, startDate, endDate *ti
 	var commandeArgs []interface{}
 	if posUUID == "" {
 		commandeFilter = "c.entreprise_uuid = ? AND c.status = ? AND cl.item_type = ?"
-		commandeArgs = []interface{}{entrepriseUUID, "paid", "product"}
+		commandeArgs = []interface{}{entrepriseUUID, models.CommandePaid, "product"}
 	} else {
 		commandeFilter = "c.entreprise_uuid = ? AND c.pos_uuid = ? AND c.status = ? AND cl.item_type = ?"
-		commandeArgs = []interface{}{entrepriseUUID, posUUID, "paid", "product"}
+		commandeArgs = []interface{}{entrepriseUUID, posUUID, models.CommandePaid, "product"}
 	}
 
 	db.Table("commande_lines cl").
-		Select("cl.product_uuid, SUM(cl.quantity) as total_sales").
+		Select("cl.product_uuid, SUM(cl.quantity - COALESCE(rl.quantity, 0)) as total_sales").
 		Joins("JOIN commandes c ON cl.commande_uuid = c.uuid").
+		Joins(returnedLines).
 		Where(commandeFilter, commandeArgs...).
 		Where("c.created_at BETWEEN ? AND ?", periodStart, periodEnd).
 		Group("cl.product_uuid").
@@ -1028,10 +1067,10 @@ func getPlatStatistics(entrepriseUUID, posUUID string, startDate, endDate *time.
 	var commandeArgs []interface{}
 	if posUUID == "" {
 		commandeFilter = "c.entreprise_uuid = ? AND c.status = ? AND cl.item_type = ?"
-		commandeArgs = []interface{}{entrepriseUUID, "paid", "plat"}
+		commandeArgs = []interface{}{entrepriseUUID, models.CommandePaid, "plat"}
 	} else {
 		commandeFilter = "c.entreprise_uuid = ? AND c.pos_uuid = ? AND c.status = ? AND cl.item_type = ?"
-		commandeArgs = []interface{}{entrepriseUUID, posUUID, "paid", "plat"}
+		commandeArgs = []interface{}{entrepriseUUID, posUUID, models.CommandePaid, "plat"}
 	}
 
 	// Total clients uniques ayant commandé des plats
@@ -1051,26 +1090,28 @@ func getPlatStatistics(entrepriseUUID, posUUID string, startDate, endDate *time.
 	var quantitesVendues int64
 	platQuery := db.Table("commande_lines cl").
 		Joins("JOIN commandes c ON cl.commande_uuid = c.uuid").
+		Joins(returnedLines).
 		Where(commandeFilter, commandeArgs...)
 
 	if startDate != nil && endDate != nil {
 		platQuery = platQuery.Where("c.created_at BETWEEN ? AND ?", startDate, endDate)
 	}
 
-	platQuery.Select("COALESCE(SUM(cl.quantity), 0)").Scan(&quantitesVendues)
+	platQuery.Select("COALESCE(SUM(cl.quantity - COALESCE(rl.quantity, 0)), 0)").Scan(&quantitesVendues)
 
 	// Chiffre d'affaires (plats)
 	var chiffresAffaires float64
 	caQuery := db.Table("commande_lines cl").
 		Joins("JOIN commandes c ON cl.commande_uuid = c.uuid").
 		Joins("JOIN plats pl ON cl.plat_uuid = pl.uuid").
+		Joins(returnedLines).
 		Where(commandeFilter, commandeArgs...)
 
 	if startDate != nil && endDate != nil {
 		caQuery = caQuery.Where("c.created_at BETWEEN ? AND ?", startDate, endDate)
 	}
 
-	caQuery.Select("COALESCE(SUM(cl.quantity * pl.prix), 0)").Scan(&chiffresAffaires)
+	caQuery.Select("COALESCE(SUM(cl.total_ht - COALESCE(rl.total_ht, 0)), 0)").Scan(&chiffresAffaires)
 
 	return models.PlatStatistics{
 		TotalPlats:       totalPlats,
@@ -1514,6 +1555,79 @@ func getRepartitionTransactionsData(entrepriseUUID, posUUID string, startDate, e
 	}
 }
 
+// getPaymentMethodsData répartit les règlements des commandes payées par moyen de paiement
+func getPaymentMethodsData(entrepriseUUID, posUUID string, startDate, endDate *time.Time) models.PaymentMethodsData {
+	db := database.DB
+
+	var results []struct {
+		Method  string
+		Montant float64
+		Count   int64
+	}
+
+	query := db.Table("payments pa").
+		Select("pa.method, SUM(pa.amount) as montant, COUNT(*) as count").
+		Joins("JOIN commandes c ON pa.commande_uuid = c.uuid").
+		Where("pa.entreprise_uuid = ? AND c.status = ? AND pa.deleted_at IS NULL", entrepriseUUID, models.CommandePaid)
+
+	if posUUID != "" {
+		query = query.Where("pa.pos_uuid = ?", posUUID)
+	}
+	if startDate != nil && endDate != nil {
+		query = query.Where("pa.created_at BETWEEN ? AND ?", startDate, endDate)
+	}
+
+	query.Group("pa.method").
+		Order("montant DESC").
+		Scan(&results)
+
+	data := models.PaymentMethodsData{
+		Methods:     []string{},
+		Labels:      []string{},
+		Values:      []float64{},
+		Counts:      []int64{},
+		Percentages: []float64{},
+		Colors:      []string{},
+	}
+
+	var total float64
+	for _, result := range results {
+		total += result.Montant
+	}
+
+	colorMap := map[string]string{
+		models.PaymentCash:        "#28a745",
+		models.PaymentMpesa:       "#dc3545",
+		models.PaymentAirtelMoney: "#fd7e14",
+		models.PaymentOrangeMoney: "#ffc107",
+		models.PaymentCard:        "#007bff",
+	}
+
+	for _, result := range results {
+		label := models.PaymentMethodLabels[result.Method]
+		if label == "" {
+			label = result.Method
+		}
+		color := colorMap[result.Method]
+		if color == "" {
+			color = "#6c757d"
+		}
+
+		data.Methods = append(data.Methods, result.Method)
+		data.Labels = append(data.Labels, label)
+		data.Values = append(data.Values, result.Montant)
+		data.Counts = append(data.Counts, result.Count)
+		if total > 0 {
+			data.Percentages = append(data.Percentages, (result.Montant/total)*100)
+		} else {
+			data.Percentages = append(data.Percentages, 0)
+		}
+		data.Colors = append(data.Colors, color)
+	}
+
+	return data
+}
+
 // getTopTransactions récupère les meilleures transactions (entrées et sorties)
 func getTopTransactions(entrepriseUUID, posUUID string, startDate, endDate *time.Time, _ int) models.TopTransactions {
 	db := database.DB
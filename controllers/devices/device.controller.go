@@ -0,0 +1,120 @@
+package devices
+
+import (
+	"time"
+
+	"github.com/kgermando/ipos-stock-api/database"
+	"github.com/kgermando/ipos-stock-api/models"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Get All data by POS
+func GetAllDevicesByPos(c *fiber.Ctx) error {
+	db := database.DB.WithContext(c.UserContext())
+	entrepriseUUID := c.Params("entreprise_uuid")
+	posUUID := c.Params("pos_uuid")
+
+	var data []models.Device
+	query := db.Where("entreprise_uuid = ?", entrepriseUUID)
+	if posUUID != "-" {
+		query = query.Where("pos_uuid = ?", posUUID)
+	}
+	query.Order("last_seen_at DESC").
+		Preload("Pos").
+		Preload("SyncCursors").
+		Find(&data)
+
+	return c.JSON(fiber.Map{
+		"status":  "success",
+		"message": "All devices",
+		"data":    data,
+	})
+}
+
+// Get one data
+func GetDevice(c *fiber.Ctx) error {
+	uuid := c.Params("uuid")
+	db := database.DB.WithContext(c.UserContext())
+
+	var device models.Device
+	db.Where("uuid = ?", uuid).
+		Preload("SyncCursors").
+		First(&device)
+	if device.UUID == "" {
+		return c.Status(404).JSON(
+			fiber.Map{
+				"status":  "error",
+				"message": "No device found",
+				"data":    nil,
+			},
+		)
+	}
+	return c.JSON(
+		fiber.Map{
+			"status":  "success",
+			"message": "device found",
+			"data":    device,
+		},
+	)
+}
+
+// Revoke device and close all its sessions
+func RevokeDevice(c *fiber.Ctx) error {
+	uuid := c.Params("uuid")
+	db := database.DB.WithContext(c.UserContext())
+
+	var device models.Device
+	db.Where("uuid = ?", uuid).First(&device)
+	if device.UUID == "" {
+		return c.Status(404).JSON(
+			fiber.Map{
+				"status":  "error",
+				"message": "No device found",
+				"data":    nil,
+			},
+		)
+	}
+
+	now := time.Now()
+	db.Model(&device).Update("revoked_at", &now)
+	db.Model(&models.Session{}).
+		Where("device_uuid = ? AND revoked_at IS NULL", device.UUID).
+		Update("revoked_at", &now)
+
+	return c.JSON(
+		fiber.Map{
+			"status":  "success",
+			"message": "device revoked success",
+			"data":    device,
+		},
+	)
+}
+
+// Restore a revoked device
+func RestoreDevice(c *fiber.Ctx) error {
+	uuid := c.Params("uuid")
+	db := database.DB.WithContext(c.UserContext())
+
+	var device models.Device
+	db.Where("uuid = ?", uuid).First(&device)
+	if device.UUID == "" {
+		return c.Status(404).JSON(
+			fiber.Map{
+				"status":  "error",
+				"message": "No device found",
+				"data":    nil,
+			},
+		)
+	}
+
+	db.Model(&device).Update("revoked_at", nil)
+
+	return c.JSON(
+		fiber.Map{
+			"status":  "success",
+			"message": "device restored success",
+			"data":    device,
+		},
+	)
+}
@@ -4,6 +4,7 @@ import (
 	"strconv"
 
 	"github.com/kgermando/ipos-stock-api/database"
+	"github.com/kgermando/ipos-stock-api/middlewares"
 	"github.com/kgermando/ipos-stock-api/models"
 	"github.com/kgermando/ipos-stock-api/utils"
 
@@ -12,7 +13,7 @@ import (
 
 // Paginate
 func GetPaginatedEntreprise(c *fiber.Ctx) error {
-	db := database.DB
+	db := database.DB.WithContext(c.UserContext())
 
 	page, err := strconv.Atoi(c.Query("page", "1"))
 	if err != nil || page <= 0 {
@@ -82,6 +83,7 @@ func GetPaginatedEntreprise(c *fiber.Ctx) error {
 			Currency:       entreprise.Currency,
 			Step:           entreprise.Step,
 			TypeAbonnement: entreprise.TypeAbonnement,
+			TwoFactorRoles: entreprise.TwoFactorRoles,
 
 			TotalUser:       len(entreprise.Users),
 			TotalPos:        len(entreprise.Pos),
@@ -99,7 +101,7 @@ func GetPaginatedEntreprise(c *fiber.Ctx) error {
 
 // Get All data
 func GetAllEntreprises(c *fiber.Ctx) error {
-	db := database.DB
+	db := database.DB.WithContext(c.UserContext())
 	var data []models.Entreprise
 	db.Preload("Users").Preload("Pos").Find(&data)
 	return c.JSON(fiber.Map{
@@ -112,7 +114,7 @@ func GetAllEntreprises(c *fiber.Ctx) error {
 // Get one data
 func GetEntreprise(c *fiber.Ctx) error {
 	uuid := c.Params("uuid")
-	db := database.DB
+	db := database.DB.WithContext(c.UserContext())
 
 	var entreprise models.Entreprise
 
@@ -198,7 +200,19 @@ func CreateEntreprise(c *fiber.Ctx) error {
 // Update data
 func UpdateEntreprise(c *fiber.Ctx) error {
 	uuid := c.Params("uuid")
-	db := database.DB
+	db := database.DB.WithContext(c.UserContext())
+
+	// Un gérant ne modifie que sa propre entreprise
+	user := middlewares.GetAuthUser(c)
+	if user == nil || (!user.IsSuperAdmin() && user.EntrepriseUUID != uuid) {
+		return c.Status(403).JSON(
+			fiber.Map{
+				"status":  "error",
+				"message": "accès refusé à cette entreprise",
+				"data":    nil,
+			},
+		)
+	}
 
 	type UpdateData struct {
 		TypeEntreprise string `json:"type_entreprise"` // PME, GE, Particulier
@@ -211,9 +225,10 @@ func UpdateEntreprise(c *fiber.Ctx) error {
 		Telephone      string `json:"telephone"` // Telephone officiel
 		Manager        string `json:"manager"`
 		Status         bool   `json:"status"`
-		Currency       string `json:"currency"`        // Devise de l'entreprise, default CDF
-		Step           int    `json:"step"`            // Etape de l'entreprise dans le processus d'inscription
-		TypeAbonnement string `json:"type_abonnement"` // Pack starter, business, pro, entreprise
+		Currency       string `json:"currency"`         // Devise de l'entreprise, default CDF
+		Step           int    `json:"step"`             // Etape de l'entreprise dans le processus d'inscription
+		TypeAbonnement string `json:"type_abonnement"`  // Pack starter, business, pro, entreprise
+		TwoFactorRoles string `json:"two_factor_roles"` // Rôles soumis à la double authentification
 	}
 
 	var updateData UpdateData
@@ -230,7 +245,16 @@ func UpdateEntreprise(c *fiber.Ctx) error {
 
 	entreprise := new(models.Entreprise)
 
-	db.Where("uuid = ?", uuid).First(&entreprise)
+	if err := db.Where("uuid = ?", uuid).First(&entreprise).Error; err != nil {
+		return c.Status(404).JSON(
+			fiber.Map{
+				"status":  "error",
+				"message": "No entreprise found",
+				"data":    nil,
+			},
+		)
+	}
+	server := *entreprise
 	entreprise.TypeEntreprise = updateData.TypeEntreprise
 	entreprise.Name = updateData.Name
 	entreprise.Rccm = updateData.Rccm
@@ -240,10 +264,18 @@ func UpdateEntreprise(c *fiber.Ctx) error {
 	entreprise.Email = updateData.Email
 	entreprise.Telephone = updateData.Telephone
 	entreprise.Manager = updateData.Manager
-	entreprise.Status = updateData.Status
 	entreprise.Currency = updateData.Currency
 	entreprise.Step = updateData.Step
-	entreprise.TypeAbonnement = updateData.TypeAbonnement
+	// L'activation et l'abonnement ne sont modifiés que par le support
+	if user.Can("entreprises:manage") {
+		entreprise.Status = updateData.Status
+		entreprise.TypeAbonnement = updateData.TypeAbonnement
+	}
+	entreprise.TwoFactorRoles = updateData.TwoFactorRoles
+
+	if conflict := middlewares.CheckConflict(c, db, &server, entreprise); conflict != nil {
+		return middlewares.ConflictResponse(c, conflict)
+	}
 
 	db.Save(&entreprise)
 
@@ -261,7 +293,7 @@ func UpdateEntreprise(c *fiber.Ctx) error {
 func DeleteEntreprise(c *fiber.Ctx) error {
 	uuid := c.Params("uuid")
 
-	db := database.DB
+	db := database.DB.WithContext(c.UserContext())
 
 	var entreprise models.Entreprise
 	db.Where("uuid = ?", uuid).First(&entreprise)
@@ -0,0 +1,123 @@
+package fiscal
+
+import (
+	"errors"
+
+	"github.com/kgermando/ipos-stock-api/database"
+	"github.com/kgermando/ipos-stock-api/fiscal"
+	"github.com/kgermando/ipos-stock-api/models"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// GetFiscalQueue retourne les commandes de l'entreprise en attente de
+// certification et celles refusées par le dispositif fiscal
+func GetFiscalQueue(c *fiber.Ctx) error {
+	db := database.DB.WithContext(c.UserContext())
+	entrepriseUUID := c.Params("entreprise_uuid")
+
+	var jobs []models.FiscalJob
+	db.Where("entreprise_uuid = ?", entrepriseUUID).
+		Order("created_at").
+		Find(&jobs)
+
+	var rejected []models.Commande
+	db.Select("uuid, ncommande, pos_uuid, total_ttc, created_at, fiscal_status, fiscal_error").
+		Where("entreprise_uuid = ? AND fiscal_status = ?", entrepriseUUID, models.FiscalRejected).
+		Order("created_at").
+		Find(&rejected)
+
+	device := ""
+	if certifier := fiscal.Current(); certifier != nil {
+		device = certifier.Name()
+	}
+
+	return c.JSON(fiber.Map{
+		"status":  "success",
+		"message": "fiscal queue",
+		"data": fiber.Map{
+			"device":   device,
+			"pending":  jobs,
+			"rejected": rejected,
+		},
+	})
+}
+
+// CertifyCommande soumet sans attendre une commande réglée au dispositif
+// fiscal, notamment pour relancer une facture refusée après correction. Si le
+// dispositif est indisponible, la commande reste en file d'attente.
+func CertifyCommande(c *fiber.Ctx) error {
+	uuid := c.Params("uuid")
+	db := database.DB.WithContext(c.UserContext())
+
+	if !fiscal.Enabled() {
+		return c.Status(503).JSON(fiber.Map{
+			"status":  "error",
+			"message": fiscal.ErrDisabled.Error(),
+			"data":    nil,
+		})
+	}
+
+	var commande models.Commande
+	if err := db.Where("uuid = ?", uuid).First(&commande).Error; err != nil {
+		return c.Status(404).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Commande introuvable",
+			"data":    nil,
+		})
+	}
+	if commande.Fiscal.Status == models.FiscalCertified {
+		return c.JSON(fiber.Map{
+			"status":  "success",
+			"message": "Commande déjà certifiée",
+			"data":    commande.Fiscal,
+		})
+	}
+	if commande.Status != models.CommandePaid && commande.Status != models.CommandeRefunded {
+		return c.Status(409).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Seule une commande réglée peut être certifiée",
+			"data":    nil,
+		})
+	}
+
+	if err := db.Transaction(func(tx *gorm.DB) error {
+		return fiscal.Enqueue(tx, &commande)
+	}); err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Impossible de mettre la commande en file d'attente",
+			"error":   err.Error(),
+		})
+	}
+
+	err := fiscal.Certify(db, uuid)
+	db.Where("uuid = ?", uuid).First(&commande)
+	switch {
+	case err == nil:
+		return c.JSON(fiber.Map{
+			"status":  "success",
+			"message": "Commande certifiée",
+			"data":    commande.Fiscal,
+		})
+	case errors.Is(err, fiscal.ErrRejected):
+		return c.Status(422).JSON(fiber.Map{
+			"status":  "error",
+			"message": err.Error(),
+			"data":    commande.Fiscal,
+		})
+	case errors.Is(err, fiscal.ErrNotQueued):
+		return c.Status(409).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Certification déjà en cours",
+			"data":    commande.Fiscal,
+		})
+	default:
+		return c.Status(202).JSON(fiber.Map{
+			"status":  "success",
+			"message": "Dispositif fiscal indisponible, commande en file d'attente",
+			"data":    commande.Fiscal,
+		})
+	}
+}
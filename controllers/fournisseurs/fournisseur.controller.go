@@ -6,6 +6,7 @@ import (
 	"strconv"
 
 	"github.com/kgermando/ipos-stock-api/database"
+	"github.com/kgermando/ipos-stock-api/middlewares"
 	"github.com/kgermando/ipos-stock-api/models"
 
 	"github.com/gofiber/fiber/v2"
@@ -13,11 +14,11 @@ import (
 
 // Synchronisation Send data to Local
 func GetDataSynchronisation(c *fiber.Ctx) error {
-	db := database.DB
+	db := database.DB.WithContext(c.UserContext())
 	entrepriseUUID := c.Params("entreprise_uuid")
 	posUUID := c.Params("pos_uuid")
 
-	sync_created := c.Query("sync_created", "2023-01-01")
+	sync_created, syncDone := middlewares.SyncCursor(c, "fournisseurs")
 	var data []models.Fournisseur
 
 	if posUUID == "-" {
@@ -34,6 +35,8 @@ func GetDataSynchronisation(c *fiber.Ctx) error {
 			Preload("Pos").
 			Find(&data)
 	}
+	syncDone()
+
 	return c.JSON(fiber.Map{
 		"status":  "success",
 		"message": "All Fournisseur",
@@ -43,7 +46,7 @@ func GetDataSynchronisation(c *fiber.Ctx) error {
 
 // Paginate
 func GetPaginatedFournisseur(c *fiber.Ctx) error {
-	db := database.DB
+	db := database.DB.WithContext(c.UserContext())
 	entrepriseUUID := c.Params("entreprise_uuid")
 	posUUID := c.Params("pos_uuid")
 
@@ -109,7 +112,7 @@ func GetPaginatedFournisseur(c *fiber.Ctx) error {
 // Get All data
 func GetAllFournisseurs(c *fiber.Ctx) error {
 	entrepriseUUID := c.Params("entreprise_uuid")
-	db := database.DB
+	db := database.DB.WithContext(c.UserContext())
 
 	var data []models.Fournisseur
 	db.Where("entreprise_uuid = ?", entrepriseUUID).Find(&data)
@@ -123,7 +126,7 @@ func GetAllFournisseurs(c *fiber.Ctx) error {
 // Get one data
 func GetFournisseur(c *fiber.Ctx) error {
 	uuid := c.Params("uuid")
-	db := database.DB
+	db := database.DB.WithContext(c.UserContext())
 
 	var fournisseur models.Fournisseur
 	db.Where("uuid = ?", uuid).
@@ -171,7 +174,7 @@ func CreateFournisseur(c *fiber.Ctx) error {
 
 	p.Sync = true
 
-	database.DB.Create(p)
+	database.DB.WithContext(c.UserContext()).Create(p)
 
 	return c.JSON(
 		fiber.Map{
@@ -185,7 +188,7 @@ func CreateFournisseur(c *fiber.Ctx) error {
 // Update data
 func UpdateFournisseur(c *fiber.Ctx) error {
 	uuid := c.Params("uuid")
-	db := database.DB
+	db := database.DB.WithContext(c.UserContext())
 
 	type UpdateData struct {
 		EntrepriseName string `json:"entreprise_name"`
@@ -216,7 +219,16 @@ func UpdateFournisseur(c *fiber.Ctx) error {
 
 	fournisseur := new(models.Fournisseur)
 
-	db.Where("uuid = ?", uuid).First(&fournisseur)
+	if err := db.Where("uuid = ?", uuid).First(&fournisseur).Error; err != nil {
+		return c.Status(404).JSON(
+			fiber.Map{
+				"status":  "error",
+				"message": "No fournisseur found",
+				"data":    nil,
+			},
+		)
+	}
+	server := *fournisseur
 	fournisseur.EntrepriseName = updateData.EntrepriseName
 	fournisseur.Rccm = updateData.Rccm
 	fournisseur.IdNat = updateData.IdNat
@@ -230,6 +242,11 @@ func UpdateFournisseur(c *fiber.Ctx) error {
 	fournisseur.EntrepriseUUID = updateData.EntrepriseUUID
 
 	fournisseur.Sync = true
+
+	if conflict := middlewares.CheckConflict(c, db, &server, fournisseur); conflict != nil {
+		return middlewares.ConflictResponse(c, conflict)
+	}
+
 	db.Save(&fournisseur)
 
 	return c.JSON(
@@ -246,7 +263,7 @@ func UpdateFournisseur(c *fiber.Ctx) error {
 func DeleteFournisseur(c *fiber.Ctx) error {
 	uuid := c.Params("uuid")
 
-	db := database.DB
+	db := database.DB.WithContext(c.UserContext())
 
 	var fournisseur models.Fournisseur
 	err := db.Where("uuid = ?", uuid).First(&fournisseur)
@@ -282,7 +299,7 @@ func DeleteFournisseur(c *fiber.Ctx) error {
 }
 
 func UploadCsvDataFournisseur(c *fiber.Ctx) error {
-	db := database.DB
+	db := database.DB.WithContext(c.UserContext())
 
 	type UploadCSV struct {
 		Data           []models.Fournisseur `json:"data"`
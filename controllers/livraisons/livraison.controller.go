@@ -6,6 +6,7 @@ import (
 	"strconv"
 
 	"github.com/kgermando/ipos-stock-api/database"
+	"github.com/kgermando/ipos-stock-api/middlewares"
 	"github.com/kgermando/ipos-stock-api/models"
 
 	"github.com/gofiber/fiber/v2"
@@ -13,11 +14,11 @@ import (
 
 // Synchronisation Send data to Local
 func GetDataSynchronisation(c *fiber.Ctx) error {
-	db := database.DB
+	db := database.DB.WithContext(c.UserContext())
 	entrepriseUUID := c.Params("entreprise_uuid")
 	posUUID := c.Params("pos_uuid")
 
-	sync_created := c.Query("sync_created", "2023-01-01")
+	sync_created, syncDone := middlewares.SyncCursor(c, "livraisons")
 	var data []models.Livraison
 
 	if posUUID == "-" {
@@ -42,6 +43,8 @@ func GetDataSynchronisation(c *fiber.Ctx) error {
 			Preload("Commandes").
 			Find(&data)
 	}
+	syncDone()
+
 	return c.JSON(fiber.Map{
 		"status":  "success",
 		"message": "All Livraisons",
@@ -51,7 +54,7 @@ func GetDataSynchronisation(c *fiber.Ctx) error {
 
 // Paginate
 func GetPaginatedLivraison(c *fiber.Ctx) error {
-	db := database.DB
+	db := database.DB.WithContext(c.UserContext())
 	entrepriseUUID := c.Params("entreprise_uuid")
 	posUUID := c.Params("pos_uuid")
 
@@ -121,7 +124,7 @@ func GetPaginatedLivraison(c *fiber.Ctx) error {
 // Get All data
 func GetAllLivraisons(c *fiber.Ctx) error {
 	entrepriseUUID := c.Params("entreprise_uuid")
-	db := database.DB
+	db := database.DB.WithContext(c.UserContext())
 
 	var data []models.Livraison
 	db.Where("entreprise_uuid = ?", entrepriseUUID).
@@ -141,7 +144,7 @@ func GetAllLivraisons(c *fiber.Ctx) error {
 // Get one data
 func GetLivraison(c *fiber.Ctx) error {
 	uuid := c.Params("uuid")
-	db := database.DB
+	db := database.DB.WithContext(c.UserContext())
 
 	var livraison models.Livraison
 	db.Where("uuid = ?", uuid).
@@ -191,7 +194,7 @@ func CreateLivraison(c *fiber.Ctx) error {
 	}
 
 	p.Sync = true
-	database.DB.Create(p)
+	database.DB.WithContext(c.UserContext()).Create(p)
 
 	return c.JSON(
 		fiber.Map{
@@ -205,7 +208,7 @@ func CreateLivraison(c *fiber.Ctx) error {
 // Update data
 func UpdateLivraison(c *fiber.Ctx) error {
 	uuid := c.Params("uuid")
-	db := database.DB
+	db := database.DB.WithContext(c.UserContext())
 
 	type UpdateData struct {
 		ClientUUID     string `json:"client_uuid"`
@@ -230,7 +233,16 @@ func UpdateLivraison(c *fiber.Ctx) error {
 
 	livraison := new(models.Livraison)
 
-	db.Where("uuid = ?", uuid).First(&livraison)
+	if err := db.Where("uuid = ?", uuid).First(&livraison).Error; err != nil {
+		return c.Status(404).JSON(
+			fiber.Map{
+				"status":  "error",
+				"message": "No livraison found",
+				"data":    nil,
+			},
+		)
+	}
+	server := *livraison
 	livraison.ClientUUID = updateData.ClientUUID
 	livraison.LivreurUUID = updateData.LivreurUUID
 	livraison.ZoneUUID = updateData.ZoneUUID
@@ -238,6 +250,10 @@ func UpdateLivraison(c *fiber.Ctx) error {
 	livraison.Signature = updateData.Signature
 	livraison.EntrepriseUUID = updateData.EntrepriseUUID
 
+	if conflict := middlewares.CheckConflict(c, db, &server, livraison); conflict != nil {
+		return middlewares.ConflictResponse(c, conflict)
+	}
+
 	db.Save(&livraison)
 
 	return c.JSON(
@@ -253,7 +269,7 @@ func UpdateLivraison(c *fiber.Ctx) error {
 func DeleteLivraison(c *fiber.Ctx) error {
 	uuid := c.Params("uuid")
 
-	db := database.DB
+	db := database.DB.WithContext(c.UserContext())
 
 	var livraison models.Livraison
 	db.Where("uuid = ?", uuid).First(&livraison)
@@ -279,7 +295,7 @@ func DeleteLivraison(c *fiber.Ctx) error {
 }
 
 func UploadCsvDataLivraison(c *fiber.Ctx) error {
-	db := database.DB
+	db := database.DB.WithContext(c.UserContext())
 
 	type UploadCSV struct {
 		Data           []models.Livraison `json:"data"`
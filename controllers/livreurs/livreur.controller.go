@@ -6,6 +6,7 @@ import (
 	"strconv"
 
 	"github.com/kgermando/ipos-stock-api/database"
+	"github.com/kgermando/ipos-stock-api/middlewares"
 	"github.com/kgermando/ipos-stock-api/models"
 
 	"github.com/gofiber/fiber/v2"
@@ -13,11 +14,11 @@ import (
 
 // Synchronisation Send data to Local
 func GetDataSynchronisation(c *fiber.Ctx) error {
-	db := database.DB
+	db := database.DB.WithContext(c.UserContext())
 	entrepriseUUID := c.Params("entreprise_uuid")
 	posUUID := c.Params("pos_uuid")
 
-	sync_created := c.Query("sync_created", "2023-01-01")
+	sync_created, syncDone := middlewares.SyncCursor(c, "livreurs")
 	var data []models.Livreur
 
 	if posUUID == "-" {
@@ -34,6 +35,8 @@ func GetDataSynchronisation(c *fiber.Ctx) error {
 			Preload("Pos").
 			Find(&data)
 	}
+	syncDone()
+
 	return c.JSON(fiber.Map{
 		"status":  "success",
 		"message": "All Livreurs",
@@ -43,7 +46,7 @@ func GetDataSynchronisation(c *fiber.Ctx) error {
 
 // Paginate
 func GetPaginatedLivreur(c *fiber.Ctx) error {
-	db := database.DB
+	db := database.DB.WithContext(c.UserContext())
 	entrepriseUUID := c.Params("entreprise_uuid")
 	posUUID := c.Params("pos_uuid")
 
@@ -108,7 +111,7 @@ func GetPaginatedLivreur(c *fiber.Ctx) error {
 // Get All data
 func GetAllLivreurs(c *fiber.Ctx) error {
 	entrepriseUUID := c.Params("entreprise_uuid")
-	db := database.DB
+	db := database.DB.WithContext(c.UserContext())
 
 	var data []models.Livreur
 	db.Where("entreprise_uuid = ?", entrepriseUUID).Find(&data)
@@ -122,7 +125,7 @@ func GetAllLivreurs(c *fiber.Ctx) error {
 // Get one data
 func GetLivreur(c *fiber.Ctx) error {
 	uuid := c.Params("uuid")
-	db := database.DB
+	db := database.DB.WithContext(c.UserContext())
 
 	var livreur models.Livreur
 	db.Where("uuid = ?", uuid).First(&livreur)
@@ -166,7 +169,7 @@ func CreateLivreur(c *fiber.Ctx) error {
 	}
 
 	p.Sync = true
-	database.DB.Create(p)
+	database.DB.WithContext(c.UserContext()).Create(p)
 
 	return c.JSON(
 		fiber.Map{
@@ -180,7 +183,7 @@ func CreateLivreur(c *fiber.Ctx) error {
 // Update data
 func UpdateLivreur(c *fiber.Ctx) error {
 	uuid := c.Params("uuid")
-	db := database.DB
+	db := database.DB.WithContext(c.UserContext())
 
 	type UpdateData struct {
 		TypeLivreur    string `json:"type_livreur"`
@@ -207,7 +210,16 @@ func UpdateLivreur(c *fiber.Ctx) error {
 
 	livreur := new(models.Livreur)
 
-	db.Where("uuid = ?", uuid).First(&livreur)
+	if err := db.Where("uuid = ?", uuid).First(&livreur).Error; err != nil {
+		return c.Status(404).JSON(
+			fiber.Map{
+				"status":  "error",
+				"message": "No livreur found",
+				"data":    nil,
+			},
+		)
+	}
+	server := *livreur
 	livreur.TypeLivreur = updateData.TypeLivreur
 	livreur.Name = updateData.Name
 	livreur.Telephone = updateData.Telephone
@@ -217,6 +229,10 @@ func UpdateLivreur(c *fiber.Ctx) error {
 	livreur.Signature = updateData.Signature
 	livreur.EntrepriseUUID = updateData.EntrepriseUUID
 
+	if conflict := middlewares.CheckConflict(c, db, &server, livreur); conflict != nil {
+		return middlewares.ConflictResponse(c, conflict)
+	}
+
 	db.Save(&livreur)
 
 	return c.JSON(
@@ -232,7 +248,7 @@ func UpdateLivreur(c *fiber.Ctx) error {
 func DeleteLivreur(c *fiber.Ctx) error {
 	uuid := c.Params("uuid")
 
-	db := database.DB
+	db := database.DB.WithContext(c.UserContext())
 
 	var livreur models.Livreur
 	db.Where("uuid = ?", uuid).First(&livreur)
@@ -258,7 +274,7 @@ func DeleteLivreur(c *fiber.Ctx) error {
 }
 
 func UploadCsvDataLivreur(c *fiber.Ctx) error {
-	db := database.DB
+	db := database.DB.WithContext(c.UserContext())
 
 	type UploadCSV struct {
 		Data           []models.Livreur `json:"data"`
@@ -304,7 +320,7 @@ func UploadCsvDataLivreur(c *fiber.Ctx) error {
 
 // Get livreurs by type
 func GetLivreursByType(c *fiber.Ctx) error {
-	db := database.DB
+	db := database.DB.WithContext(c.UserContext())
 	entrepriseUUID := c.Params("entreprise_uuid")
 	posUUID := c.Params("pos_uuid")
 	typeLivreur := c.Params("type")
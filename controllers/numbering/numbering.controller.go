@@ -0,0 +1,147 @@
+package numbering
+
+import (
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/kgermando/ipos-stock-api/database"
+	"github.com/kgermando/ipos-stock-api/models"
+	"github.com/kgermando/ipos-stock-api/numbering"
+	"github.com/kgermando/ipos-stock-api/utils"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// GetNumberingPatterns liste les modèles de numérotation configurés pour
+// l'entreprise, ainsi que les modèles par défaut
+func GetNumberingPatterns(c *fiber.Ctx) error {
+	db := database.DB.WithContext(c.UserContext())
+	entrepriseUUID := c.Params("entreprise_uuid")
+
+	var data []models.NumberingPattern
+	db.Where("entreprise_uuid = ?", entrepriseUUID).
+		Order("document_type, pos_scope").
+		Find(&data)
+
+	return c.JSON(fiber.Map{
+		"status":  "success",
+		"message": "All numbering patterns",
+		"data": fiber.Map{
+			"patterns": data,
+			"defaults": models.DefaultNumberPatterns,
+		},
+	})
+}
+
+// SaveNumberingPattern crée ou remplace le modèle d'un type de document pour
+// l'entreprise ou pour un point de vente. Le modèle ne s'applique qu'aux
+// numéros attribués ensuite ; le compteur n'est pas remis à zéro.
+func SaveNumberingPattern(c *fiber.Ctx) error {
+	db := database.DB.WithContext(c.UserContext())
+
+	p := &models.NumberingPattern{}
+	if err := c.BodyParser(p); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Données JSON invalides",
+			"data":    nil,
+		})
+	}
+	p.Pattern = strings.TrimSpace(p.Pattern)
+	if t, ok := database.TenantFromContext(c.UserContext()); ok && p.EntrepriseUUID == "" {
+		p.EntrepriseUUID = t.EntrepriseUUID
+	}
+
+	if !slices.Contains(models.DocumentTypes, p.DocumentType) {
+		return c.Status(400).JSON(fiber.Map{
+			"status":  "error",
+			"message": numbering.ErrUnknownDocument.Error(),
+			"data":    nil,
+		})
+	}
+	if err := numbering.Validate(p.Pattern); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"status":  "error",
+			"message": err.Error(),
+			"data":    nil,
+		})
+	}
+
+	var existing models.NumberingPattern
+	db.Where("entreprise_uuid = ? AND document_type = ? AND COALESCE(pos_scope, '') = ?",
+		p.EntrepriseUUID, p.DocumentType, p.PosScope).
+		Limit(1).Find(&existing)
+
+	if existing.UUID != "" {
+		existing.Pattern = p.Pattern
+		existing.Signature = p.Signature
+		existing.Sync = true
+		db.Save(&existing)
+		p = &existing
+	} else {
+		p.UUID = utils.GenerateUUID()
+		p.Sync = true
+		db.Create(p)
+	}
+
+	return c.JSON(fiber.Map{
+		"status":  "success",
+		"message": "numbering pattern saved success",
+		"data":    p,
+	})
+}
+
+// DeleteNumberingPattern supprime un modèle : le modèle de l'entreprise, ou
+// celui par défaut, s'applique de nouveau
+func DeleteNumberingPattern(c *fiber.Ctx) error {
+	uuid := c.Params("uuid")
+	db := database.DB.WithContext(c.UserContext())
+
+	var pattern models.NumberingPattern
+	if err := db.Where("uuid = ?", uuid).First(&pattern).Error; err != nil {
+		return c.Status(404).JSON(fiber.Map{
+			"status":  "error",
+			"message": "No numbering pattern found",
+			"data":    nil,
+		})
+	}
+
+	db.Delete(&pattern)
+
+	return c.JSON(fiber.Map{
+		"status":  "success",
+		"message": "numbering pattern deleted success",
+		"data":    nil,
+	})
+}
+
+// PreviewNumber retourne le prochain numéro d'un type de document pour le
+// point de vente, sans l'attribuer
+func PreviewNumber(c *fiber.Ctx) error {
+	db := database.DB.WithContext(c.UserContext())
+
+	var pos models.Pos
+	if err := db.Where("uuid = ?", c.Params("pos_uuid")).First(&pos).Error; err != nil {
+		return c.Status(404).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Point de vente introuvable",
+			"data":    nil,
+		})
+	}
+
+	number, err := numbering.Peek(db, c.Params("document_type"), pos, time.Now())
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"status":  "error",
+			"message": err.Error(),
+			"data":    nil,
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"status":  "success",
+		"message": "next number",
+		"data":    number,
+	})
+}
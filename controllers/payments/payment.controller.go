@@ -0,0 +1,125 @@
+package payments
+
+import (
+	"github.com/kgermando/ipos-stock-api/database"
+	"github.com/kgermando/ipos-stock-api/lifecycle"
+	"github.com/kgermando/ipos-stock-api/middlewares"
+	"github.com/kgermando/ipos-stock-api/models"
+	"github.com/kgermando/ipos-stock-api/payments"
+	"github.com/kgermando/ipos-stock-api/pricing"
+	"github.com/kgermando/ipos-stock-api/utils"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// GetCommandePayments liste les règlements d'une commande
+func GetCommandePayments(c *fiber.Ctx) error {
+	db := database.DB.WithContext(c.UserContext())
+	commandeUUID := c.Params("commande_uuid")
+
+	var data []models.Payment
+	db.Where("commande_uuid = ?", commandeUUID).Order("created_at").Find(&data)
+
+	return c.JSON(fiber.Map{
+		"status":  "success",
+		"message": "All payments",
+		"data":    data,
+	})
+}
+
+// PayCommande enregistre un ou plusieurs règlements pour une commande déjà
+// ouverte, dans la limite du reste à payer. La commande passe au statut
+// réglé lorsqu'elle est entièrement réglée.
+func PayCommande(c *fiber.Ctx) error {
+	db := database.DB.WithContext(c.UserContext())
+	user := middlewares.GetAuthUser(c)
+	commandeUUID := c.Params("commande_uuid")
+
+	var req models.PayCommandeRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Données JSON invalides",
+			"errors":  err.Error(),
+		})
+	}
+
+	if err := utils.ValidateStruct(req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Données invalides",
+			"errors":  err,
+		})
+	}
+
+	var commande models.Commande
+	var created []models.Payment
+	var caisseItems []models.CaisseItem
+	var paid, remaining float64
+	status, message := 400, "Règlement invalide"
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		// Verrouille la commande pour ne pas accepter deux règlements du même reste
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("uuid = ?", commandeUUID).First(&commande).Error; err != nil {
+			status, message = 404, "Commande introuvable"
+			return err
+		}
+		if commande.Status == models.CommandePaid {
+			status, message = 409, "Commande déjà réglée"
+			return gorm.ErrInvalidData
+		}
+		if !lifecycle.Payable(commande.Status) {
+			status, message = 409, "Une commande "+commande.Status+" ne peut pas être réglée"
+			return gorm.ErrInvalidData
+		}
+
+		policy := pricing.LoadPolicy(tx, commande.EntrepriseUUID)
+		paid = payments.Paid(tx, commande.UUID)
+		due := policy.Round(commande.TotalTtc - paid)
+
+		var err error
+		created, caisseItems, err = payments.Post(tx, payments.Tenders{
+			Commande:      &commande,
+			Requests:      req.Payments,
+			DefaultCaisse: req.CaisseUUID,
+			CashierUUID:   user.UUID,
+			Signature:     req.Signature,
+		}, due, policy)
+		if err != nil {
+			status, message = 422, err.Error()
+			return err
+		}
+
+		paid = policy.Round(paid + payments.Sum(req.Payments, policy))
+		remaining = policy.Round(commande.TotalTtc - paid)
+		if policy.Matches(paid, commande.TotalTtc) {
+			if err := lifecycle.Pay(tx, &commande, user); err != nil {
+				status, message = 500, "Erreur lors de la mise à jour de la commande"
+				return err
+			}
+		}
+		return nil
+	})
+
+	if err != nil {
+		return c.Status(status).JSON(fiber.Map{
+			"status":  "error",
+			"message": message,
+		})
+	}
+
+	return c.Status(201).JSON(fiber.Map{
+		"status":  "success",
+		"message": "Règlement enregistré avec succès",
+		"data": fiber.Map{
+			"commande":     commande,
+			"payments":     created,
+			"caisse_items": caisseItems,
+			"paid":         paid,
+			"remaining":    remaining,
+		},
+	})
+}
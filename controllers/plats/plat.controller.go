@@ -4,6 +4,7 @@ import (
 	"strconv"
 
 	"github.com/kgermando/ipos-stock-api/database"
+	"github.com/kgermando/ipos-stock-api/middlewares"
 	"github.com/kgermando/ipos-stock-api/models"
 
 	"github.com/gofiber/fiber/v2"
@@ -11,11 +12,11 @@ import (
 
 // Synchronisation Send data to Local
 func GetDataSynchronisation(c *fiber.Ctx) error {
-	db := database.DB
+	db := database.DB.WithContext(c.UserContext())
 	entrepriseUUID := c.Params("entreprise_uuid")
 	posUUID := c.Params("pos_uuid")
 
-	sync_created := c.Query("sync_created", "2023-01-01")
+	sync_created, syncDone := middlewares.SyncCursor(c, "plats")
 	var data []models.Plat
 
 	if posUUID == "-" {
@@ -32,6 +33,8 @@ func GetDataSynchronisation(c *fiber.Ctx) error {
 			Preload("Pos").
 			Find(&data)
 	}
+	syncDone()
+
 	return c.JSON(fiber.Map{
 		"status":  "success",
 		"message": "All plats sync data",
@@ -41,7 +44,7 @@ func GetDataSynchronisation(c *fiber.Ctx) error {
 
 // Paginate by entreprise
 func GetPaginatedPlatEntreprise(c *fiber.Ctx) error {
-	db := database.DB
+	db := database.DB.WithContext(c.UserContext())
 	entrepriseUUID := c.Params("entreprise_uuid")
 
 	page, _ := strconv.Atoi(c.Query("page", "1"))
@@ -100,7 +103,7 @@ func GetPaginatedPlatEntreprise(c *fiber.Ctx) error {
 
 // Paginate by posUUID
 func GetPaginatedPlatByPosUUID(c *fiber.Ctx) error {
-	db := database.DB
+	db := database.DB.WithContext(c.UserContext())
 	entrepriseUUID := c.Params("entreprise_uuid")
 	posUUID := c.Params("pos_uuid")
 
@@ -160,7 +163,7 @@ func GetPaginatedPlatByPosUUID(c *fiber.Ctx) error {
 
 // Get All data
 func GetAllPlats(c *fiber.Ctx) error {
-	db := database.DB
+	db := database.DB.WithContext(c.UserContext())
 	entrepriseUUID := c.Params("entreprise_uuid")
 	posUUID := c.Params("pos_uuid")
 
@@ -178,7 +181,7 @@ func GetAllPlats(c *fiber.Ctx) error {
 
 // Get All data by search
 func GetAllPlatBySearch(c *fiber.Ctx) error {
-	db := database.DB
+	db := database.DB.WithContext(c.UserContext())
 	entrepriseUUID := c.Params("entreprise_uuid")
 	posUUID := c.Params("pos_uuid")
 	search := c.Query("search", "")
@@ -203,7 +206,7 @@ func GetAllPlatBySearch(c *fiber.Ctx) error {
 // Get one data
 func GetPlat(c *fiber.Ctx) error {
 	uuid := c.Params("uuid")
-	db := database.DB
+	db := database.DB.WithContext(c.UserContext())
 
 	var plat models.Plat
 	db.Where("uuid = ?", uuid).Preload("Pos").First(&plat)
@@ -258,7 +261,7 @@ func CreatePlat(c *fiber.Ctx) error {
 
 	p.Sync = true
 
-	database.DB.Create(p)
+	database.DB.WithContext(c.UserContext()).Create(p)
 
 	return c.JSON(
 		fiber.Map{
@@ -272,7 +275,7 @@ func CreatePlat(c *fiber.Ctx) error {
 // Update data
 func UpdatePlat(c *fiber.Ctx) error {
 	uuid := c.Params("uuid")
-	db := database.DB
+	db := database.DB.WithContext(c.UserContext())
 
 	var plat models.Plat
 
@@ -288,6 +291,8 @@ func UpdatePlat(c *fiber.Ctx) error {
 		)
 	}
 
+	server := plat
+
 	// Parse request body
 	if err := c.BodyParser(&plat); err != nil {
 		return err
@@ -295,8 +300,12 @@ func UpdatePlat(c *fiber.Ctx) error {
 
 	plat.Sync = true
 
+	if conflict := middlewares.CheckConflict(c, db, &server, &plat); conflict != nil {
+		return middlewares.ConflictResponse(c, conflict)
+	}
+
 	// Save to database
-	database.DB.Save(&plat)
+	database.DB.WithContext(c.UserContext()).Save(&plat)
 	return c.JSON(
 		fiber.Map{
 			"status":  "success",
@@ -309,7 +318,7 @@ func UpdatePlat(c *fiber.Ctx) error {
 // Delete data
 func DeletePlat(c *fiber.Ctx) error {
 	uuid := c.Params("uuid")
-	db := database.DB
+	db := database.DB.WithContext(c.UserContext())
 
 	var plat models.Plat
 	db.Where("uuid = ?", uuid).First(&plat)
@@ -336,7 +345,7 @@ func DeletePlat(c *fiber.Ctx) error {
 // Update availability
 func UpdatePlatAvailability(c *fiber.Ctx) error {
 	uuid := c.Params("uuid")
-	db := database.DB
+	db := database.DB.WithContext(c.UserContext())
 
 	var plat models.Plat
 	db.Where("uuid = ?", uuid).First(&plat)
@@ -350,6 +359,8 @@ func UpdatePlatAvailability(c *fiber.Ctx) error {
 		)
 	}
 
+	server := plat
+
 	// Parse request body for availability
 	var updateData struct {
 		IsAvailable bool `json:"is_available"`
@@ -362,6 +373,10 @@ func UpdatePlatAvailability(c *fiber.Ctx) error {
 	plat.IsAvailable = updateData.IsAvailable
 	plat.Sync = true
 
+	if conflict := middlewares.CheckConflict(c, db, &server, &plat); conflict != nil {
+		return middlewares.ConflictResponse(c, conflict)
+	}
+
 	db.Save(&plat)
 	return c.JSON(
 		fiber.Map{
@@ -374,7 +389,7 @@ func UpdatePlatAvailability(c *fiber.Ctx) error {
 
 // Get available plats only
 func GetAvailablePlats(c *fiber.Ctx) error {
-	db := database.DB
+	db := database.DB.WithContext(c.UserContext())
 	entrepriseUUID := c.Params("entreprise_uuid")
 	posUUID := c.Params("pos_uuid")
 
@@ -4,6 +4,7 @@ import (
 	"strconv"
 
 	"github.com/kgermando/ipos-stock-api/database"
+	"github.com/kgermando/ipos-stock-api/middlewares"
 	"github.com/kgermando/ipos-stock-api/models"
 	"github.com/kgermando/ipos-stock-api/utils"
 
@@ -13,7 +14,7 @@ import (
 
 // Paginate
 func GetPaginatedPos(c *fiber.Ctx) error {
-	db := database.DB
+	db := database.DB.WithContext(c.UserContext())
 
 	page, err := strconv.Atoi(c.Query("page", "1"))
 	if err != nil || page <= 0 {
@@ -72,7 +73,7 @@ func GetPaginatedPos(c *fiber.Ctx) error {
 
 // Query all data UUID
 func GetPaginatedPosByUUID(c *fiber.Ctx) error {
-	db := database.DB
+	db := database.DB.WithContext(c.UserContext())
 	EntrepriseUUID := c.Params("entreprise_uuid")
 
 	page, err := strconv.Atoi(c.Query("page", "1"))
@@ -134,7 +135,7 @@ func GetPaginatedPosByUUID(c *fiber.Ctx) error {
 
 // Get All data
 func GetAllPoss(c *fiber.Ctx) error {
-	db := database.DB
+	db := database.DB.WithContext(c.UserContext())
 
 	var data []models.Pos
 	db.Find(&data)
@@ -148,7 +149,7 @@ func GetAllPoss(c *fiber.Ctx) error {
 
 // Get All data by UUID
 func GetAllPosByUUId(c *fiber.Ctx) error {
-	db := database.DB
+	db := database.DB.WithContext(c.UserContext())
 	EntrepriseUUID := c.Params("entreprise_uuid")
 
 	var data []models.Pos
@@ -164,7 +165,7 @@ func GetAllPosByUUId(c *fiber.Ctx) error {
 func GetPos(c *fiber.Ctx) error {
 	uuid := c.Params("uuid")
 
-	db := database.DB
+	db := database.DB.WithContext(c.UserContext())
 	var pos models.Pos
 
 	db.Where("uuid = ?", uuid).
@@ -204,7 +205,7 @@ func CreatePos(c *fiber.Ctx) error {
 
 	p.UUID = utils.GenerateUUID()
 	p.Sync = true
-	database.DB.Create(p)
+	database.DB.WithContext(c.UserContext()).Create(p)
 
 	return c.JSON(
 		fiber.Map{
@@ -218,11 +219,12 @@ func CreatePos(c *fiber.Ctx) error {
 // Update data
 func UpdatePos(c *fiber.Ctx) error {
 	uuid := c.Params("uuid")
-	db := database.DB
+	db := database.DB.WithContext(c.UserContext())
 
 	type UpdateData struct {
 		EntrepriseUUID string `json:"entreprise_uuid"`
 		Name           string `json:"name"`
+		Code           string `json:"code"`
 		Adresse        string `json:"adresse"`
 		Email          string `json:"email"`
 		Telephone      string `json:"telephone"`
@@ -245,15 +247,29 @@ func UpdatePos(c *fiber.Ctx) error {
 
 	pos := new(models.Pos)
 
-	db.Where("uuid = ?", uuid).First(&pos)
+	if err := db.Where("uuid = ?", uuid).First(&pos).Error; err != nil {
+		return c.Status(404).JSON(
+			fiber.Map{
+				"status":  "error",
+				"message": "No pos found",
+				"data":    nil,
+			},
+		)
+	}
+	server := *pos
 	pos.EntrepriseUUID = updateData.EntrepriseUUID
 	pos.Name = updateData.Name
+	pos.Code = updateData.Code
 	pos.Email = updateData.Email
 	pos.Telephone = updateData.Telephone
 	pos.Manager = updateData.Manager
 	pos.Status = updateData.Status
 	pos.Signature = updateData.Signature
 
+	if conflict := middlewares.CheckConflict(c, db, &server, pos); conflict != nil {
+		return middlewares.ConflictResponse(c, conflict)
+	}
+
 	db.Save(&pos)
 
 	return c.JSON(
@@ -270,7 +286,7 @@ func UpdatePos(c *fiber.Ctx) error {
 func DeletePos(c *fiber.Ctx) error {
 	uuid := c.Params("uuid")
 
-	db := database.DB
+	db := database.DB.WithContext(c.UserContext())
 
 	var pos models.Pos
 	db.Where("uuid = ?", uuid).First(&pos)
@@ -0,0 +1,59 @@
+package pricing
+
+import (
+	"errors"
+
+	"github.com/kgermando/ipos-stock-api/database"
+	"github.com/kgermando/ipos-stock-api/models"
+	"github.com/kgermando/ipos-stock-api/pricing"
+	"github.com/kgermando/ipos-stock-api/utils"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Quote chiffre un panier avec les prix, remises et TVA du serveur, sans
+// enregistrer de commande ni toucher au stock. Le terminal affiche ces
+// montants et peut les renvoyer tels quels au passage en caisse.
+func Quote(c *fiber.Ctx) error {
+	db := database.DB.WithContext(c.UserContext())
+
+	var req models.QuoteRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Données JSON invalides",
+			"errors":  err.Error(),
+		})
+	}
+
+	if err := utils.ValidateStruct(req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Données invalides",
+			"errors":  err,
+		})
+	}
+
+	quote, err := pricing.NewQuote(db, req.PosUUID, req.Items, req.CouponCodes)
+	if err != nil {
+		status := 400
+		switch {
+		case errors.Is(err, pricing.ErrItemNotFound):
+			status = 404
+		case errors.Is(err, pricing.ErrUnavailable):
+			status = 409
+		case errors.Is(err, pricing.ErrCouponInvalid):
+			status = 422
+		}
+		return c.Status(status).JSON(fiber.Map{
+			"status":  "error",
+			"message": err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"status":  "success",
+		"message": "Devis calculé",
+		"data":    quote,
+	})
+}
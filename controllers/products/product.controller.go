@@ -7,6 +7,7 @@ import (
 	"strings"
 
 	"github.com/kgermando/ipos-stock-api/database"
+	"github.com/kgermando/ipos-stock-api/middlewares"
 	"github.com/kgermando/ipos-stock-api/models"
 	"github.com/kgermando/ipos-stock-api/utils"
 	"github.com/xuri/excelize/v2"
@@ -16,11 +17,11 @@ import (
 
 // Synchronisation Send data to Local
 func GetDataSynchronisation(c *fiber.Ctx) error {
-	db := database.DB
+	db := database.DB.WithContext(c.UserContext())
 	entrepriseUUID := c.Params("entreprise_uuid")
 	posUUID := c.Params("pos_uuid")
 
-	sync_created := c.Query("sync_created", "2023-01-01")
+	sync_created, syncDone := middlewares.SyncCursor(c, "products")
 	var data []models.Product
 
 	if posUUID == "-" {
@@ -37,6 +38,8 @@ func GetDataSynchronisation(c *fiber.Ctx) error {
 			Preload("Pos").
 			Find(&data)
 	}
+	syncDone()
+
 	return c.JSON(fiber.Map{
 		"status":  "success",
 		"message": "All products",
@@ -46,7 +49,7 @@ func GetDataSynchronisation(c *fiber.Ctx) error {
 
 // Paginate
 func GetPaginatedProductEntreprise(c *fiber.Ctx) error {
-	db := database.DB
+	db := database.DB.WithContext(c.UserContext())
 	entrepriseUUID := c.Params("entreprise_uuid")
 
 	page, err := strconv.Atoi(c.Query("page", "1"))
@@ -108,7 +111,7 @@ func GetPaginatedProductEntreprise(c *fiber.Ctx) error {
 
 // Paginate by posUUID
 func GetPaginatedProductByPosUUID(c *fiber.Ctx) error {
-	db := database.DB
+	db := database.DB.WithContext(c.UserContext())
 	entrepriseUUID := c.Params("entreprise_uuid")
 	posUUID := c.Params("pos_uuid")
 
@@ -173,7 +176,7 @@ func GetPaginatedProductByPosUUID(c *fiber.Ctx) error {
 
 // Get All data
 func GetAllProducts(c *fiber.Ctx) error {
-	db := database.DB
+	db := database.DB.WithContext(c.UserContext())
 	entrepriseUUID := c.Params("entreprise_uuid")
 	posUUID := c.Params("pos_uuid")
 
@@ -190,7 +193,7 @@ func GetAllProducts(c *fiber.Ctx) error {
 
 // Get All data by id
 func GetAllProductBySearch(c *fiber.Ctx) error {
-	db := database.DB
+	db := database.DB.WithContext(c.UserContext())
 	entrepriseUUID := c.Params("entreprise_uuid")
 	posUUID := c.Params("pos_uuid")
 
@@ -211,7 +214,7 @@ func GetAllProductBySearch(c *fiber.Ctx) error {
 // Get one data
 func GetProduct(c *fiber.Ctx) error {
 	uuid := c.Params("uuid")
-	db := database.DB
+	db := database.DB.WithContext(c.UserContext())
 
 	var product models.Product
 	db.Where("uuid = ?", uuid).First(&product)
@@ -256,7 +259,7 @@ func CreateProduct(c *fiber.Ctx) error {
 
 	p.Sync = true
 
-	database.DB.Create(p)
+	database.DB.WithContext(c.UserContext()).Create(p)
 
 	return c.JSON(
 		fiber.Map{
@@ -270,7 +273,7 @@ func CreateProduct(c *fiber.Ctx) error {
 // Update data
 func UpdateProduct(c *fiber.Ctx) error {
 	uuid := c.Params("uuid")
-	db := database.DB
+	db := database.DB.WithContext(c.UserContext())
 
 	type UpdateData struct {
 		// Image          string  `json:"image"`
@@ -302,7 +305,16 @@ func UpdateProduct(c *fiber.Ctx) error {
 
 	product := new(models.Product)
 
-	db.Where("uuid = ?", uuid).First(&product)
+	if err := db.Where("uuid = ?", uuid).First(&product).Error; err != nil {
+		return c.Status(404).JSON(
+			fiber.Map{
+				"status":  "error",
+				"message": "No product found",
+				"data":    nil,
+			},
+		)
+	}
+	server := *product
 
 	product.Reference = updateData.Reference
 	product.Name = updateData.Name
@@ -318,6 +330,10 @@ func UpdateProduct(c *fiber.Ctx) error {
 	product.PosUUID = updateData.PosUUID
 	product.EntrepriseUUID = updateData.EntrepriseUUID
 
+	if conflict := middlewares.CheckConflict(c, db, &server, product); conflict != nil {
+		return middlewares.ConflictResponse(c, conflict)
+	}
+
 	db.Save(&product)
 
 	return c.JSON(
@@ -333,7 +349,7 @@ func UpdateProduct(c *fiber.Ctx) error {
 // Update data stock disponible
 func UpdateProductStockDispo(c *fiber.Ctx) error {
 	uuid := c.Params("uuid")
-	db := database.DB
+	db := database.DB.WithContext(c.UserContext())
 
 	type UpdateData struct {
 		Stock float64 `json:"stock"` // stock disponible
@@ -353,10 +369,24 @@ func UpdateProductStockDispo(c *fiber.Ctx) error {
 
 	product := new(models.Product)
 
-	db.Where("uuid = ?", uuid).First(&product)
+	if err := db.Where("uuid = ?", uuid).First(&product).Error; err != nil {
+		return c.Status(404).JSON(
+			fiber.Map{
+				"status":  "error",
+				"message": "No product found",
+				"data":    nil,
+			},
+		)
+	}
+	server := *product
 	product.Stock = updateData.Stock
 
 	product.Sync = true
+
+	if conflict := middlewares.CheckConflict(c, db, &server, product); conflict != nil {
+		return middlewares.ConflictResponse(c, conflict)
+	}
+
 	db.Save(&product)
 
 	return c.JSON(
@@ -371,7 +401,7 @@ func UpdateProductStockDispo(c *fiber.Ctx) error {
 // Update data stock Endommage
 func UpdateProductStockEndommage(c *fiber.Ctx) error {
 	uuid := c.Params("uuid")
-	db := database.DB
+	db := database.DB.WithContext(c.UserContext())
 
 	type UpdateData struct {
 		StockEndommage float64 `json:"stock_endommage"` // stock endommage
@@ -391,9 +421,22 @@ func UpdateProductStockEndommage(c *fiber.Ctx) error {
 
 	product := new(models.Product)
 
-	db.Where("uuid = ?", uuid).First(&product)
+	if err := db.Where("uuid = ?", uuid).First(&product).Error; err != nil {
+		return c.Status(404).JSON(
+			fiber.Map{
+				"status":  "error",
+				"message": "No product found",
+				"data":    nil,
+			},
+		)
+	}
+	server := *product
 	product.StockEndommage = updateData.StockEndommage
 
+	if conflict := middlewares.CheckConflict(c, db, &server, product); conflict != nil {
+		return middlewares.ConflictResponse(c, conflict)
+	}
+
 	db.Save(&product)
 
 	return c.JSON(
@@ -408,7 +451,7 @@ func UpdateProductStockEndommage(c *fiber.Ctx) error {
 // Update data Restitution
 func UpdateProductRestitution(c *fiber.Ctx) error {
 	uuid := c.Params("uuid")
-	db := database.DB
+	db := database.DB.WithContext(c.UserContext())
 
 	type UpdateData struct {
 		Restitution float64 `gorm:"default:0" json:"restitution"` // stock restitution
@@ -428,9 +471,22 @@ func UpdateProductRestitution(c *fiber.Ctx) error {
 
 	product := new(models.Product)
 
-	db.Where("uuid = ?", uuid).First(&product)
+	if err := db.Where("uuid = ?", uuid).First(&product).Error; err != nil {
+		return c.Status(404).JSON(
+			fiber.Map{
+				"status":  "error",
+				"message": "No product found",
+				"data":    nil,
+			},
+		)
+	}
+	server := *product
 	product.Restitution = updateData.Restitution
 
+	if conflict := middlewares.CheckConflict(c, db, &server, product); conflict != nil {
+		return middlewares.ConflictResponse(c, conflict)
+	}
+
 	db.Save(&product)
 
 	return c.JSON(
@@ -446,7 +502,7 @@ func UpdateProductRestitution(c *fiber.Ctx) error {
 func DeleteProduct(c *fiber.Ctx) error {
 	uuid := c.Params("uuid")
 
-	db := database.DB
+	db := database.DB.WithContext(c.UserContext())
 
 	var product models.Product
 	db.Where("uuid = ?", uuid).First(&product)
@@ -547,7 +603,7 @@ func UploadProductsFromExcel(c *fiber.Ctx) error {
 		})
 	}
 
-	db := database.DB
+	db := database.DB.WithContext(c.UserContext())
 	var createdProducts []models.Product
 	var errors []string
 	successCount := 0
@@ -0,0 +1,326 @@
+package promotions
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/kgermando/ipos-stock-api/database"
+	"github.com/kgermando/ipos-stock-api/middlewares"
+	"github.com/kgermando/ipos-stock-api/models"
+	"github.com/kgermando/ipos-stock-api/utils"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// Paginate
+func GetPaginatedPromotion(c *fiber.Ctx) error {
+	db := database.DB.WithContext(c.UserContext())
+	entrepriseUUID := c.Params("entreprise_uuid")
+
+	page, err := strconv.Atoi(c.Query("page", "1"))
+	if err != nil || page <= 0 {
+		page = 1
+	}
+	limit, err := strconv.Atoi(c.Query("limit", "15"))
+	if err != nil || limit <= 0 {
+		limit = 15
+	}
+	offset := (page - 1) * limit
+
+	search := c.Query("search", "")
+
+	query := db.Model(&models.Promotion{}).
+		Where("entreprise_uuid = ?", entrepriseUUID).
+		Where("name ILIKE ? OR coupon_code ILIKE ?", "%"+search+"%", "%"+search+"%")
+	if posUUID := c.Query("pos_uuid"); posUUID != "" {
+		query = query.Where("pos_scope = '' OR pos_scope IS NULL OR pos_scope = ?", posUUID)
+	}
+
+	var totalRecords int64
+	query.Count(&totalRecords)
+
+	var dataList []models.Promotion
+	err = query.Offset(offset).
+		Limit(limit).
+		Order("promotions.updated_at DESC").
+		Find(&dataList).Error
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Failed to fetch promotions",
+			"error":   err.Error(),
+		})
+	}
+
+	totalPages := int((totalRecords + int64(limit) - 1) / int64(limit))
+
+	pagination := map[string]interface{}{
+		"total_records": totalRecords,
+		"total_pages":   totalPages,
+		"current_page":  page,
+		"page_size":     limit,
+	}
+
+	return c.JSON(fiber.Map{
+		"status":     "success",
+		"message":    "All promotions paginated",
+		"data":       dataList,
+		"pagination": pagination,
+	})
+}
+
+// Get one data
+func GetPromotion(c *fiber.Ctx) error {
+	uuid := c.Params("uuid")
+	db := database.DB.WithContext(c.UserContext())
+
+	var promotion models.Promotion
+	if err := db.Where("uuid = ?", uuid).First(&promotion).Error; err != nil {
+		return c.Status(404).JSON(
+			fiber.Map{
+				"status":  "error",
+				"message": "No promotion found",
+				"data":    nil,
+			},
+		)
+	}
+	return c.JSON(
+		fiber.Map{
+			"status":  "success",
+			"message": "promotion found",
+			"data":    promotion,
+		},
+	)
+}
+
+// Create data
+func CreatePromotion(c *fiber.Ctx) error {
+	db := database.DB.WithContext(c.UserContext())
+	p := &models.Promotion{}
+
+	if err := c.BodyParser(&p); err != nil {
+		return err
+	}
+	if p.UUID == "" {
+		p.UUID = utils.GenerateUUID()
+	}
+	// Un utilisateur rattaché à un POS ne crée que des promotions de son POS
+	if user := middlewares.GetAuthUser(c); !user.HasEntrepriseScope() {
+		p.PosScope = user.PosUUID
+	}
+
+	var existing models.Promotion
+	database.DB.Where("uuid = ?", p.UUID).First(&existing)
+	if existing.UUID != "" {
+		return c.Status(409).JSON(
+			fiber.Map{
+				"status":  "error",
+				"message": "Promotion avec cet UUID existe déjà",
+				"data":    nil,
+			},
+		)
+	}
+
+	if status, message := checkPromotion(db, p); status != 0 {
+		return c.Status(status).JSON(
+			fiber.Map{
+				"status":  "error",
+				"message": message,
+				"data":    nil,
+			},
+		)
+	}
+
+	p.UsageCount = 0
+	p.Sync = true
+	db.Create(p)
+
+	return c.JSON(
+		fiber.Map{
+			"status":  "success",
+			"message": "promotion created success",
+			"data":    p,
+		},
+	)
+}
+
+// Update data
+func UpdatePromotion(c *fiber.Ctx) error {
+	uuid := c.Params("uuid")
+	db := database.DB.WithContext(c.UserContext())
+
+	promotion := new(models.Promotion)
+	if err := db.Where("uuid = ?", uuid).First(&promotion).Error; err != nil {
+		return c.Status(404).JSON(
+			fiber.Map{
+				"status":  "error",
+				"message": "No promotion found",
+				"data":    nil,
+			},
+		)
+	}
+	user := middlewares.GetAuthUser(c)
+	if !ownsPromotion(user, promotion) {
+		return c.Status(403).JSON(
+			fiber.Map{
+				"status":  "error",
+				"message": "Cette promotion ne dépend pas de votre point de vente",
+				"data":    nil,
+			},
+		)
+	}
+	server := *promotion
+
+	if err := c.BodyParser(promotion); err != nil {
+		return c.Status(500).JSON(
+			fiber.Map{
+				"status":  "error",
+				"message": "Review your input",
+				"data":    nil,
+			},
+		)
+	}
+
+	// Le compteur d'utilisations n'est tenu que par le serveur
+	promotion.UUID = server.UUID
+	promotion.CreatedAt = server.CreatedAt
+	promotion.UsageCount = server.UsageCount
+	promotion.Sync = true
+	if !user.HasEntrepriseScope() {
+		promotion.PosScope = user.PosUUID
+	}
+
+	if status, message := checkPromotion(db, promotion); status != 0 {
+		return c.Status(status).JSON(
+			fiber.Map{
+				"status":  "error",
+				"message": message,
+				"data":    nil,
+			},
+		)
+	}
+
+	if conflict := middlewares.CheckConflict(c, db, &server, promotion); conflict != nil {
+		return middlewares.ConflictResponse(c, conflict)
+	}
+
+	db.Save(&promotion)
+
+	return c.JSON(
+		fiber.Map{
+			"status":  "success",
+			"message": "promotion updated success",
+			"data":    promotion,
+		},
+	)
+}
+
+// Delete data
+func DeletePromotion(c *fiber.Ctx) error {
+	uuid := c.Params("uuid")
+	db := database.DB.WithContext(c.UserContext())
+
+	var promotion models.Promotion
+	if err := db.Where("uuid = ?", uuid).First(&promotion).Error; err != nil {
+		return c.Status(404).JSON(
+			fiber.Map{
+				"status":  "error",
+				"message": "No promotion found",
+				"data":    nil,
+			},
+		)
+	}
+
+	if !ownsPromotion(middlewares.GetAuthUser(c), &promotion) {
+		return c.Status(403).JSON(
+			fiber.Map{
+				"status":  "error",
+				"message": "Cette promotion ne dépend pas de votre point de vente",
+				"data":    nil,
+			},
+		)
+	}
+
+	db.Delete(&promotion)
+
+	return c.JSON(
+		fiber.Map{
+			"status":  "success",
+			"message": "promotion deleted success",
+			"data":    nil,
+		},
+	)
+}
+
+// checkPromotion vérifie la cohérence de la règle et l'unicité du code
+// coupon parmi les promotions de l'entreprise. Retourne 0 si tout est valide.
+func checkPromotion(db *gorm.DB, p *models.Promotion) (int, string) {
+	if strings.TrimSpace(p.Name) == "" {
+		return 400, "Le nom de la promotion est obligatoire"
+	}
+
+	switch p.Type {
+	case models.PromotionPercentage:
+		if p.Value <= 0 || p.Value > 100 {
+			return 400, "Le pourcentage doit être compris entre 0 et 100"
+		}
+	case models.PromotionFixed:
+		if p.Value <= 0 {
+			return 400, "Le montant de la remise doit être positif"
+		}
+	case models.PromotionBuyXGetY:
+		if p.BuyQuantity == 0 || p.GetQuantity == 0 {
+			return 400, "Les quantités achetée et offerte sont obligatoires"
+		}
+	case models.PromotionBundle:
+		if p.BuyQuantity < 2 || p.Value <= 0 {
+			return 400, "Un lot comporte au moins 2 unités et un prix positif"
+		}
+	default:
+		return 400, "Type de promotion inconnu"
+	}
+
+	if p.ItemType != "" && p.ItemType != "product" && p.ItemType != "plat" {
+		return 400, "Type d'article inconnu"
+	}
+	if p.StartsAt != nil && p.EndsAt != nil && p.EndsAt.Before(*p.StartsAt) {
+		return 400, "La date de fin précède la date de début"
+	}
+	for _, clock := range []string{p.StartTime, p.EndTime} {
+		if clock != "" && !validClock(clock) {
+			return 400, "Les heures doivent être au format HH:MM"
+		}
+	}
+	if (p.StartTime == "") != (p.EndTime == "") {
+		return 400, "La plage horaire doit avoir une heure de début et de fin"
+	}
+
+	p.CouponCode = strings.ToUpper(strings.TrimSpace(p.CouponCode))
+	if p.CouponCode != "" {
+		var count int64
+		db.Model(&models.Promotion{}).
+			Where("UPPER(coupon_code) = ? AND uuid <> ?", p.CouponCode, p.UUID).
+			Count(&count)
+		if count > 0 {
+			return 409, "Ce code coupon est déjà utilisé par une autre promotion"
+		}
+	}
+	return 0, ""
+}
+
+func validClock(s string) bool {
+	if len(s) != 5 || s[2] != ':' {
+		return false
+	}
+	h, errH := strconv.Atoi(s[:2])
+	m, errM := strconv.Atoi(s[3:])
+	return errH == nil && errM == nil && h >= 0 && h < 24 && m >= 0 && m < 60
+}
+
+// ownsPromotion indique si l'utilisateur peut modifier la promotion. PosScope
+// n'étant pas filtré par le périmètre de la requête, un utilisateur rattaché
+// à un POS ne modifie que les promotions limitées à son POS.
+func ownsPromotion(user *models.User, p *models.Promotion) bool {
+	return user.HasEntrepriseScope() || (p.PosScope != "" && p.PosScope == user.PosUUID)
+}
@@ -0,0 +1,74 @@
+package receipts
+
+import (
+	"strings"
+
+	"github.com/kgermando/ipos-stock-api/database"
+	"github.com/kgermando/ipos-stock-api/models"
+	"github.com/kgermando/ipos-stock-api/receipts"
+	"github.com/kgermando/ipos-stock-api/utils"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// GetReceiptTemplate retourne le modèle d'impression du point de vente, ou le
+// modèle par défaut s'il n'en a pas configuré
+func GetReceiptTemplate(c *fiber.Ctx) error {
+	db := database.DB.WithContext(c.UserContext())
+
+	return c.JSON(fiber.Map{
+		"status":  "success",
+		"message": "receipt template found",
+		"data":    receipts.Template(db, c.Params("pos_uuid")),
+	})
+}
+
+// SaveReceiptTemplate crée ou modifie le modèle d'impression du point de
+// vente. Les champs absents du corps gardent leur valeur actuelle.
+func SaveReceiptTemplate(c *fiber.Ctx) error {
+	posUUID := c.Params("pos_uuid")
+	db := database.DB.WithContext(c.UserContext())
+
+	var pos models.Pos
+	if err := db.Where("uuid = ?", posUUID).First(&pos).Error; err != nil {
+		return c.Status(404).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Point de vente introuvable",
+			"data":    nil,
+		})
+	}
+
+	tpl := receipts.Template(db, posUUID)
+	if err := c.BodyParser(&tpl); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Données JSON invalides",
+			"data":    nil,
+		})
+	}
+	if tpl.PaperWidth != 58 && tpl.PaperWidth != 80 {
+		return c.Status(400).JSON(fiber.Map{
+			"status":  "error",
+			"message": "La largeur du papier doit être 58 ou 80 mm",
+			"data":    nil,
+		})
+	}
+	tpl.InvoiceTitle = strings.TrimSpace(tpl.InvoiceTitle)
+	tpl.ReceiptTitle = strings.TrimSpace(tpl.ReceiptTitle)
+	tpl.PosUUID = pos.UUID
+	tpl.EntrepriseUUID = pos.EntrepriseUUID
+	tpl.Sync = true
+
+	if tpl.UUID == "" {
+		tpl.UUID = utils.GenerateUUID()
+		db.Create(&tpl)
+	} else {
+		db.Save(&tpl)
+	}
+
+	return c.JSON(fiber.Map{
+		"status":  "success",
+		"message": "receipt template saved success",
+		"data":    tpl,
+	})
+}
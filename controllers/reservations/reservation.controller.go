@@ -4,6 +4,7 @@ import (
 	"strconv"
 
 	"github.com/kgermando/ipos-stock-api/database"
+	"github.com/kgermando/ipos-stock-api/middlewares"
 	"github.com/kgermando/ipos-stock-api/models"
 
 	"github.com/gofiber/fiber/v2"
@@ -11,11 +12,11 @@ import (
 
 // Synchronisation Send data to Local
 func GetDataSynchronisation(c *fiber.Ctx) error {
-	db := database.DB
+	db := database.DB.WithContext(c.UserContext())
 	entrepriseUUID := c.Params("entreprise_uuid")
 	posUUID := c.Params("pos_uuid")
 
-	sync_created := c.Query("sync_created", "2023-01-01")
+	sync_created, syncDone := middlewares.SyncCursor(c, "reservations")
 	var data []models.Reservation
 
 	if posUUID == "-" {
@@ -32,6 +33,8 @@ func GetDataSynchronisation(c *fiber.Ctx) error {
 			Preload("Pos").
 			Find(&data)
 	}
+	syncDone()
+
 	return c.JSON(fiber.Map{
 		"status":  "success",
 		"message": "All reservations sync data",
@@ -41,7 +44,7 @@ func GetDataSynchronisation(c *fiber.Ctx) error {
 
 // Paginate by entreprise
 func GetPaginatedReservationEntreprise(c *fiber.Ctx) error {
-	db := database.DB
+	db := database.DB.WithContext(c.UserContext())
 	entrepriseUUID := c.Params("entreprise_uuid")
 
 	page, _ := strconv.Atoi(c.Query("page", "1"))
@@ -100,7 +103,7 @@ func GetPaginatedReservationEntreprise(c *fiber.Ctx) error {
 
 // Paginate by posUUID
 func GetPaginatedReservationByPosUUID(c *fiber.Ctx) error {
-	db := database.DB
+	db := database.DB.WithContext(c.UserContext())
 	entrepriseUUID := c.Params("entreprise_uuid")
 	posUUID := c.Params("pos_uuid")
 
@@ -160,7 +163,7 @@ func GetPaginatedReservationByPosUUID(c *fiber.Ctx) error {
 
 // Get All data
 func GetAllReservations(c *fiber.Ctx) error {
-	db := database.DB
+	db := database.DB.WithContext(c.UserContext())
 	entrepriseUUID := c.Params("entreprise_uuid")
 	posUUID := c.Params("pos_uuid")
 
@@ -178,7 +181,7 @@ func GetAllReservations(c *fiber.Ctx) error {
 
 // Get All data by search
 func GetAllReservationBySearch(c *fiber.Ctx) error {
-	db := database.DB
+	db := database.DB.WithContext(c.UserContext())
 	entrepriseUUID := c.Params("entreprise_uuid")
 	posUUID := c.Params("pos_uuid")
 	search := c.Query("search", "")
@@ -203,7 +206,7 @@ func GetAllReservationBySearch(c *fiber.Ctx) error {
 // Get one data
 func GetReservation(c *fiber.Ctx) error {
 	uuid := c.Params("uuid")
-	db := database.DB
+	db := database.DB.WithContext(c.UserContext())
 
 	var reservation models.Reservation
 	db.Where("uuid = ?", uuid).Preload("Pos").First(&reservation)
@@ -258,7 +261,7 @@ func CreateReservation(c *fiber.Ctx) error {
 
 	p.Sync = true
 
-	database.DB.Create(p)
+	database.DB.WithContext(c.UserContext()).Create(p)
 
 	return c.JSON(
 		fiber.Map{
@@ -272,7 +275,7 @@ func CreateReservation(c *fiber.Ctx) error {
 // Update data
 func UpdateReservation(c *fiber.Ctx) error {
 	uuid := c.Params("uuid")
-	db := database.DB
+	db := database.DB.WithContext(c.UserContext())
 
 	var reservation models.Reservation
 
@@ -288,6 +291,8 @@ func UpdateReservation(c *fiber.Ctx) error {
 		)
 	}
 
+	server := reservation
+
 	// Parse request body
 	if err := c.BodyParser(&reservation); err != nil {
 		return err
@@ -295,8 +300,12 @@ func UpdateReservation(c *fiber.Ctx) error {
 
 	reservation.Sync = true
 
+	if conflict := middlewares.CheckConflict(c, db, &server, &reservation); conflict != nil {
+		return middlewares.ConflictResponse(c, conflict)
+	}
+
 	// Save to database
-	database.DB.Save(&reservation)
+	database.DB.WithContext(c.UserContext()).Save(&reservation)
 	return c.JSON(
 		fiber.Map{
 			"status":  "success",
@@ -309,7 +318,7 @@ func UpdateReservation(c *fiber.Ctx) error {
 // Delete data
 func DeleteReservation(c *fiber.Ctx) error {
 	uuid := c.Params("uuid")
-	db := database.DB
+	db := database.DB.WithContext(c.UserContext())
 
 	var reservation models.Reservation
 	db.Where("uuid = ?", uuid).First(&reservation)
@@ -335,7 +344,7 @@ func DeleteReservation(c *fiber.Ctx) error {
 
 // Get reservations by status
 func GetReservationsByStatus(c *fiber.Ctx) error {
-	db := database.DB
+	db := database.DB.WithContext(c.UserContext())
 	entrepriseUUID := c.Params("entreprise_uuid")
 	posUUID := c.Params("pos_uuid")
 	status := c.Params("status")
@@ -355,7 +364,7 @@ func GetReservationsByStatus(c *fiber.Ctx) error {
 
 // Get reservations by date
 func GetReservationsByDate(c *fiber.Ctx) error {
-	db := database.DB
+	db := database.DB.WithContext(c.UserContext())
 	entrepriseUUID := c.Params("entreprise_uuid")
 	posUUID := c.Params("pos_uuid")
 	date := c.Params("date")
@@ -375,7 +384,7 @@ func GetReservationsByDate(c *fiber.Ctx) error {
 
 // Get reservations by table
 func GetReservationsByTable(c *fiber.Ctx) error {
-	db := database.DB
+	db := database.DB.WithContext(c.UserContext())
 	entrepriseUUID := c.Params("entreprise_uuid")
 	posUUID := c.Params("pos_uuid")
 	table := c.Params("table")
@@ -4,6 +4,7 @@ import (
 	"strconv"
 
 	"github.com/kgermando/ipos-stock-api/database"
+	"github.com/kgermando/ipos-stock-api/middlewares"
 	"github.com/kgermando/ipos-stock-api/models"
 
 	"github.com/gofiber/fiber/v2"
@@ -11,11 +12,11 @@ import (
 
 // Synchronisation Send data to Local
 func GetDataSynchronisationRestitution(c *fiber.Ctx) error {
-	db := database.DB
+	db := database.DB.WithContext(c.UserContext())
 	entrepriseUUID := c.Params("entreprise_uuid")
 	posUUID := c.Params("pos_uuid")
 
-	sync_created := c.Query("sync_created", "2023-01-01")
+	sync_created, syncDone := middlewares.SyncCursor(c, "restitutions")
 	var data []models.Restitution
 
 	if posUUID == "-" {
@@ -32,6 +33,8 @@ func GetDataSynchronisationRestitution(c *fiber.Ctx) error {
 			Preload("Pos").
 			Find(&data)
 	}
+	syncDone()
+
 	return c.JSON(fiber.Map{
 		"status":  "success",
 		"message": "All Restitutions",
@@ -41,7 +44,7 @@ func GetDataSynchronisationRestitution(c *fiber.Ctx) error {
 
 // Paginate
 func GetPaginatedRestitution(c *fiber.Ctx) error {
-	db := database.DB
+	db := database.DB.WithContext(c.UserContext())
 	productUUID := c.Params("product_uuid")
 
 	page, err := strconv.Atoi(c.Query("page", "1"))
@@ -105,7 +108,7 @@ func GetPaginatedRestitution(c *fiber.Ctx) error {
 
 // Get Total data
 func GetTotalRestitution(c *fiber.Ctx) error {
-	db := database.DB
+	db := database.DB.WithContext(c.UserContext())
 	productUUID := c.Params("product_uuid")
 
 	var data []models.Restitution
@@ -122,7 +125,7 @@ func GetTotalRestitution(c *fiber.Ctx) error {
 
 // Get All data
 func GetAllRestitutions(c *fiber.Ctx) error {
-	db := database.DB
+	db := database.DB.WithContext(c.UserContext())
 	productUUID := c.Params("product_uuid")
 	var data []models.Restitution
 	db.Where("product_uuid = ?", productUUID).Find(&data)
@@ -136,7 +139,7 @@ func GetAllRestitutions(c *fiber.Ctx) error {
 // Get one data
 func GetRestitution(c *fiber.Ctx) error {
 	uuid := c.Params("uuid")
-	db := database.DB
+	db := database.DB.WithContext(c.UserContext())
 
 	var restitution models.Restitution
 	db.Where("uuid = ?", uuid).First(&restitution)
@@ -181,7 +184,7 @@ func CreateRestitution(c *fiber.Ctx) error {
 	}
 
 	p.Sync = true
-	database.DB.Create(p)
+	database.DB.WithContext(c.UserContext()).Create(p)
 
 	return c.JSON(
 		fiber.Map{
@@ -195,7 +198,7 @@ func CreateRestitution(c *fiber.Ctx) error {
 // Update data
 func UpdateRestitution(c *fiber.Ctx) error {
 	uuid := c.Params("uuid")
-	db := database.DB
+	db := database.DB.WithContext(c.UserContext())
 
 	type UpdateData struct {
 		PosUUID         string  `json:"pos_uuid"`
@@ -223,7 +226,16 @@ func UpdateRestitution(c *fiber.Ctx) error {
 
 	restitution := new(models.Restitution)
 
-	db.Where("uuid = ?", uuid).First(&restitution)
+	if err := db.Where("uuid = ?", uuid).First(&restitution).Error; err != nil {
+		return c.Status(404).JSON(
+			fiber.Map{
+				"status":  "error",
+				"message": "No restitution found",
+				"data":    nil,
+			},
+		)
+	}
+	server := *restitution
 	restitution.PosUUID = updateData.PosUUID
 	restitution.ProductUUID = updateData.ProductUUID
 	restitution.Description = updateData.Description
@@ -235,6 +247,11 @@ func UpdateRestitution(c *fiber.Ctx) error {
 	restitution.EntrepriseUUID = updateData.EntrepriseUUID
 
 	restitution.Sync = true
+
+	if conflict := middlewares.CheckConflict(c, db, &server, restitution); conflict != nil {
+		return middlewares.ConflictResponse(c, conflict)
+	}
+
 	db.Save(&restitution)
 
 	return c.JSON(
@@ -251,7 +268,7 @@ func UpdateRestitution(c *fiber.Ctx) error {
 func DeleteRestitution(c *fiber.Ctx) error {
 	uuid := c.Params("uuid")
 
-	db := database.DB
+	db := database.DB.WithContext(c.UserContext())
 
 	var restitution models.Restitution
 	db.Where("uuid = ?", uuid).First(&restitution)
@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/kgermando/ipos-stock-api/database"
+	"github.com/kgermando/ipos-stock-api/middlewares"
 	"github.com/kgermando/ipos-stock-api/models"
 
 	"github.com/gofiber/fiber/v2"
@@ -12,11 +13,11 @@ import (
 
 // Synchronisation Send data to Local
 func GetDataSynchronisationStock(c *fiber.Ctx) error {
-	db := database.DB
+	db := database.DB.WithContext(c.UserContext())
 	entrepriseUUID := c.Params("entreprise_uuid")
 	posUUID := c.Params("pos_uuid")
 
-	sync_created := c.Query("sync_created", "2023-01-01")
+	sync_created, syncDone := middlewares.SyncCursor(c, "stocks")
 	var data []models.Stock
 
 	if posUUID == "-" {
@@ -33,6 +34,8 @@ func GetDataSynchronisationStock(c *fiber.Ctx) error {
 			Preload("Pos").
 			Find(&data)
 	}
+	syncDone()
+
 	return c.JSON(fiber.Map{
 		"status":  "success",
 		"message": "All Stocks",
@@ -42,7 +45,7 @@ func GetDataSynchronisationStock(c *fiber.Ctx) error {
 
 // Paginate
 func GetPaginatedStock(c *fiber.Ctx) error {
-	db := database.DB
+	db := database.DB.WithContext(c.UserContext())
 	productUUID := c.Params("product_uuid")
 
 	page, err := strconv.Atoi(c.Query("page", "1"))
@@ -107,7 +110,7 @@ func GetPaginatedStock(c *fiber.Ctx) error {
 
 // Get data
 func GetStockMargeBeneficiaire(c *fiber.Ctx) error {
-	db := database.DB
+	db := database.DB.WithContext(c.UserContext())
 	productUUID := c.Params("product_uuid")
 
 	var data models.Stock
@@ -123,7 +126,7 @@ func GetStockMargeBeneficiaire(c *fiber.Ctx) error {
 
 // Get Total data
 func GetTotalStock(c *fiber.Ctx) error {
-	db := database.DB
+	db := database.DB.WithContext(c.UserContext())
 	productUUID := c.Params("product_uuid")
 
 	var data []models.Stock
@@ -140,7 +143,7 @@ func GetTotalStock(c *fiber.Ctx) error {
 
 // Get All data
 func GetAllStocks(c *fiber.Ctx) error {
-	db := database.DB
+	db := database.DB.WithContext(c.UserContext())
 	productUUID := c.Params("product_uuid")
 	var data []models.Stock
 	db.Where("product_uuid = ?", productUUID).Find(&data)
@@ -154,7 +157,7 @@ func GetAllStocks(c *fiber.Ctx) error {
 // Get one data
 func GetStock(c *fiber.Ctx) error {
 	uuid := c.Params("uuid")
-	db := database.DB
+	db := database.DB.WithContext(c.UserContext())
 
 	var stock models.Stock
 	db.Where("uuid = ?", uuid).First(&stock)
@@ -199,7 +202,7 @@ func CreateStock(c *fiber.Ctx) error {
 	}
 
 	p.Sync = true
-	database.DB.Create(p)
+	database.DB.WithContext(c.UserContext()).Create(p)
 
 	return c.JSON(
 		fiber.Map{
@@ -213,7 +216,7 @@ func CreateStock(c *fiber.Ctx) error {
 // Update data
 func UpdateStock(c *fiber.Ctx) error {
 	uuid := c.Params("uuid")
-	db := database.DB
+	db := database.DB.WithContext(c.UserContext())
 
 	type UpdateData struct {
 		PosUUID         string    `json:"pos_uuid"`
@@ -241,7 +244,16 @@ func UpdateStock(c *fiber.Ctx) error {
 
 	stock := new(models.Stock)
 
-	db.Where("uuid = ?", uuid).First(&stock)
+	if err := db.Where("uuid = ?", uuid).First(&stock).Error; err != nil {
+		return c.Status(404).JSON(
+			fiber.Map{
+				"status":  "error",
+				"message": "No stock found",
+				"data":    nil,
+			},
+		)
+	}
+	server := *stock
 	stock.PosUUID = updateData.PosUUID
 	stock.ProductUUID = updateData.ProductUUID
 	stock.Description = updateData.Description
@@ -253,6 +265,11 @@ func UpdateStock(c *fiber.Ctx) error {
 	stock.EntrepriseUUID = updateData.EntrepriseUUID
 
 	stock.Sync = true
+
+	if conflict := middlewares.CheckConflict(c, db, &server, stock); conflict != nil {
+		return middlewares.ConflictResponse(c, conflict)
+	}
+
 	db.Save(&stock)
 
 	return c.JSON(
@@ -269,7 +286,7 @@ func UpdateStock(c *fiber.Ctx) error {
 func DeleteStock(c *fiber.Ctx) error {
 	uuid := c.Params("uuid")
 
-	db := database.DB
+	db := database.DB.WithContext(c.UserContext())
 
 	var stock models.Stock
 	db.Where("uuid = ?", uuid).First(&stock)
@@ -4,6 +4,7 @@ import (
 	"strconv"
 
 	"github.com/kgermando/ipos-stock-api/database"
+	"github.com/kgermando/ipos-stock-api/middlewares"
 	"github.com/kgermando/ipos-stock-api/models"
 
 	"github.com/gofiber/fiber/v2"
@@ -11,11 +12,11 @@ import (
 
 // Synchronisation Send data to Local
 func GetDataSynchronisationStockEndommage(c *fiber.Ctx) error {
-	db := database.DB
+	db := database.DB.WithContext(c.UserContext())
 	entrepriseUUID := c.Params("entreprise_uuid")
 	posUUID := c.Params("pos_uuid")
 
-	sync_created := c.Query("sync_created", "2023-01-01")
+	sync_created, syncDone := middlewares.SyncCursor(c, "stock_endommages")
 	var data []models.StockEndommage
 
 	if posUUID == "-" {
@@ -32,6 +33,8 @@ func GetDataSynchronisationStockEndommage(c *fiber.Ctx) error {
 			Preload("Pos").
 			Find(&data)
 	}
+	syncDone()
+
 	return c.JSON(fiber.Map{
 		"status":  "success",
 		"message": "All StockEndommages",
@@ -41,7 +44,7 @@ func GetDataSynchronisationStockEndommage(c *fiber.Ctx) error {
 
 // Get All data
 func GetAllByUUIDStockEndommages(c *fiber.Ctx) error {
-	db := database.DB
+	db := database.DB.WithContext(c.UserContext())
 	entrepriseUUID := c.Query("entreprise_uuid")
 	posUUID := c.Query("pos_uuid")
 
@@ -62,7 +65,7 @@ func GetAllByUUIDStockEndommages(c *fiber.Ctx) error {
 
 // Paginate
 func GetPaginatedStockEndommage(c *fiber.Ctx) error {
-	db := database.DB
+	db := database.DB.WithContext(c.UserContext())
 	productUUID := c.Params("product_uuid")
 
 	page, err := strconv.Atoi(c.Query("page", "1"))
@@ -126,7 +129,7 @@ func GetPaginatedStockEndommage(c *fiber.Ctx) error {
 
 // Get Total data
 func GetTotalStockEndommage(c *fiber.Ctx) error {
-	db := database.DB
+	db := database.DB.WithContext(c.UserContext())
 	productUUID := c.Params("product_uuid")
 
 	var data []models.StockEndommage
@@ -143,7 +146,7 @@ func GetTotalStockEndommage(c *fiber.Ctx) error {
 
 // Get All data
 func GetAllStockEndommages(c *fiber.Ctx) error {
-	db := database.DB
+	db := database.DB.WithContext(c.UserContext())
 	productUUID := c.Params("product_uuid")
 	var data []models.StockEndommage
 	db.Where("product_uuid = ?", productUUID).Find(&data)
@@ -157,7 +160,7 @@ func GetAllStockEndommages(c *fiber.Ctx) error {
 // Get one data
 func GetStockEndommage(c *fiber.Ctx) error {
 	uuid := c.Params("uuid")
-	db := database.DB
+	db := database.DB.WithContext(c.UserContext())
 
 	var stockEndommage models.StockEndommage
 	db.Where("uuid = ?", uuid).First(&stockEndommage)
@@ -203,7 +206,7 @@ func CreateStockEndommage(c *fiber.Ctx) error {
 
 	p.Sync = true
 
-	database.DB.Create(p)
+	database.DB.WithContext(c.UserContext()).Create(p)
 
 	return c.JSON(
 		fiber.Map{
@@ -217,7 +220,7 @@ func CreateStockEndommage(c *fiber.Ctx) error {
 // Update data
 func UpdateStockEndommage(c *fiber.Ctx) error {
 	uuid := c.Params("uuid")
-	db := database.DB
+	db := database.DB.WithContext(c.UserContext())
 
 	type UpdateData struct {
 		PosUUID        string  `json:"pos_uuid"`
@@ -243,7 +246,16 @@ func UpdateStockEndommage(c *fiber.Ctx) error {
 
 	stockEndommage := new(models.StockEndommage)
 
-	db.Where("uuid = ?", uuid).First(&stockEndommage)
+	if err := db.Where("uuid = ?", uuid).First(&stockEndommage).Error; err != nil {
+		return c.Status(404).JSON(
+			fiber.Map{
+				"status":  "error",
+				"message": "No stock endommage found",
+				"data":    nil,
+			},
+		)
+	}
+	server := *stockEndommage
 	stockEndommage.PosUUID = updateData.PosUUID
 	stockEndommage.ProductUUID = updateData.ProductUUID
 	stockEndommage.Quantity = updateData.Quantity
@@ -253,6 +265,11 @@ func UpdateStockEndommage(c *fiber.Ctx) error {
 	stockEndommage.EntrepriseUUID = updateData.EntrepriseUUID
 
 	stockEndommage.Sync = true
+
+	if conflict := middlewares.CheckConflict(c, db, &server, stockEndommage); conflict != nil {
+		return middlewares.ConflictResponse(c, conflict)
+	}
+
 	db.Save(&stockEndommage)
 
 	return c.JSON(
@@ -269,7 +286,7 @@ func UpdateStockEndommage(c *fiber.Ctx) error {
 func DeleteStockEndommage(c *fiber.Ctx) error {
 	uuid := c.Params("uuid")
 
-	db := database.DB
+	db := database.DB.WithContext(c.UserContext())
 
 	var stockEndommage models.StockEndommage
 	db.Where("uuid = ?", uuid).First(&stockEndommage)
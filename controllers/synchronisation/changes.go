@@ -0,0 +1,159 @@
+package synchronisation
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kgermando/ipos-stock-api/database"
+	"github.com/kgermando/ipos-stock-api/middlewares"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// changedAt est la date du dernier changement d'une ligne : la suppression
+// douce de GORM ne met à jour que deleted_at
+const changedAt = "GREATEST(updated_at, COALESCE(deleted_at, updated_at))"
+
+// SyncTombstone signale au client un enregistrement supprimé
+type SyncTombstone struct {
+	UUID      string    `json:"uuid"`
+	DeletedAt time.Time `json:"deleted_at"`
+}
+
+// SyncChanges regroupe les changements d'une table depuis le curseur
+type SyncChanges struct {
+	Upserted interface{}     `json:"upserted"`
+	Deleted  []SyncTombstone `json:"deleted"`
+	HasMore  bool            `json:"has_more"`
+}
+
+// GetChanges retourne en un seul appel les créations, modifications et
+// suppressions de toutes les tables synchronisées depuis le curseur fourni.
+// Paramètres : cursor (retourné par l'appel précédent, vide pour tout recevoir),
+// entities (liste séparée par des virgules, toutes par défaut), limit (par table).
+func GetChanges(c *fiber.Ctx) error {
+	db := database.DB.WithContext(c.UserContext())
+	user := middlewares.GetAuthUser(c)
+	entrepriseUUID := c.Params("entreprise_uuid")
+	posUUID := c.Params("pos_uuid")
+
+	cursor, err := decodeCursor(c.Query("cursor"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Curseur de synchronisation invalide",
+		})
+	}
+
+	limit, err := strconv.Atoi(c.Query("limit", "500"))
+	if err != nil || limit <= 0 || limit > 2000 {
+		limit = 500
+	}
+
+	entities := syncEntities
+	if list := c.Query("entities"); list != "" {
+		entities = nil
+		for _, name := range strings.Split(list, ",") {
+			e, ok := findSyncEntity(strings.TrimSpace(name))
+			if !ok {
+				return c.Status(400).JSON(fiber.Map{
+					"status":  "error",
+					"message": "Entité inconnue : " + name,
+				})
+			}
+			entities = append(entities, e)
+		}
+	}
+
+	start := time.Now()
+	hasMore := false
+	data := map[string]SyncChanges{}
+
+	for _, e := range entities {
+		if !user.Can(e.resource + ":read") {
+			continue
+		}
+
+		changes, position, err := readChanges(db, e, entrepriseUUID, posUUID, cursor[e.name], limit)
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{
+				"status":  "error",
+				"message": "Failed to fetch " + e.name,
+				"error":   err.Error(),
+			})
+		}
+
+		if !changes.HasMore {
+			position = syncPosition{At: start.Add(-syncOverlap)}
+		}
+		cursor[e.name] = position
+		hasMore = hasMore || changes.HasMore
+		data[e.name] = changes
+	}
+
+	if device := middlewares.GetDevice(c); device != nil {
+		database.DB.Model(device).Update("last_sync_at", &start)
+	}
+
+	return c.JSON(fiber.Map{
+		"status":      "success",
+		"message":     "Changes since cursor",
+		"data":        data,
+		"cursor":      cursor.encode(),
+		"has_more":    hasMore,
+		"server_time": start,
+	})
+}
+
+// readChanges lit au plus limit changements d'une table après la position
+// donnée, triés par date de changement puis uuid
+func readChanges(db *gorm.DB, e syncEntity, entrepriseUUID, posUUID string, from syncPosition, limit int) (SyncChanges, syncPosition, error) {
+	dest := e.newSlice()
+
+	query := db.Unscoped().Where("entreprise_uuid = ?", entrepriseUUID)
+	if posUUID != "-" {
+		filter := e.posFilter
+		if filter == "" {
+			filter = "pos_uuid = ?"
+		}
+		query = query.Where(filter, posUUID)
+	}
+	if !from.At.IsZero() {
+		query = query.Where("("+changedAt+", uuid) > (?, ?)", from.At, from.UUID)
+	}
+
+	if err := query.Order(changedAt + ", uuid").Limit(limit + 1).Find(dest).Error; err != nil {
+		return SyncChanges{}, from, err
+	}
+
+	rows := reflect.ValueOf(dest).Elem()
+	changes := SyncChanges{Deleted: []SyncTombstone{}}
+	if rows.Len() > limit {
+		changes.HasMore = true
+		rows = rows.Slice(0, limit)
+	}
+
+	upserted := reflect.MakeSlice(rows.Type(), 0, rows.Len())
+	position := from
+	for i := 0; i < rows.Len(); i++ {
+		row := rows.Index(i)
+		uuid := row.FieldByName("UUID").String()
+		at := row.FieldByName("UpdatedAt").Interface().(time.Time)
+
+		if deleted := row.FieldByName("DeletedAt").Interface().(gorm.DeletedAt); deleted.Valid {
+			if deleted.Time.After(at) {
+				at = deleted.Time
+			}
+			changes.Deleted = append(changes.Deleted, SyncTombstone{UUID: uuid, DeletedAt: deleted.Time})
+		} else {
+			upserted = reflect.Append(upserted, row)
+		}
+		position = syncPosition{At: at, UUID: uuid}
+	}
+	changes.Upserted = upserted.Interface()
+
+	return changes, position, nil
+}
@@ -0,0 +1,43 @@
+package synchronisation
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"time"
+)
+
+// syncOverlap fait repartir le curseur légèrement en arrière une fois les
+// changements épuisés : une transaction validée après la lecture mais datée
+// d'avant sera renvoyée au prochain appel. Le client doit donc appliquer les
+// changements de façon idempotente (upsert par uuid).
+const syncOverlap = 5 * time.Second
+
+// syncPosition est la position d'un client dans une table : date du dernier
+// changement reçu et uuid pour départager les changements simultanés
+type syncPosition struct {
+	At   time.Time `json:"t"`
+	UUID string    `json:"u,omitempty"`
+}
+
+// syncCursor est opaque pour le client : il le renvoie tel quel au prochain appel
+type syncCursor map[string]syncPosition
+
+func decodeCursor(raw string) (syncCursor, error) {
+	cursor := syncCursor{}
+	if raw == "" {
+		return cursor, nil
+	}
+	data, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return nil, err
+	}
+	return cursor, nil
+}
+
+func (c syncCursor) encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
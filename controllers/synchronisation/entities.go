@@ -0,0 +1,83 @@
+package synchronisation
+
+import (
+	"github.com/kgermando/ipos-stock-api/models"
+)
+
+// syncEntity décrit une table synchronisée avec les terminaux POS
+type syncEntity struct {
+	name      string             // nom de la table, clé dans la réponse et dans le curseur
+	resource  string             // ressource des permissions (resource:read, resource:write...)
+	newSlice  func() interface{} // pointeur vers une slice vide du modèle
+	newModel  func() interface{} // pointeur vers un modèle vide ; nil si la table n'accepte pas de push
+	posFilter string             // condition SQL limitant au POS ; par défaut pos_uuid = ?
+}
+
+// syncEntities est ordonnée pour que le client reçoive les parents avant leurs enfants
+var syncEntities = []syncEntity{
+	{name: "caisses", resource: "caisses",
+		newSlice: func() interface{} { return &[]models.Caisse{} },
+		newModel: func() interface{} { return &models.Caisse{} }},
+	{name: "caisse_items", resource: "caisses",
+		newSlice: func() interface{} { return &[]models.CaisseItem{} },
+		newModel: func() interface{} { return &models.CaisseItem{} }},
+	{name: "fournisseurs", resource: "fournisseurs",
+		newSlice: func() interface{} { return &[]models.Fournisseur{} }},
+	{name: "products", resource: "products",
+		newSlice: func() interface{} { return &[]models.Product{} }},
+	{name: "plats", resource: "plats",
+		newSlice: func() interface{} { return &[]models.Plat{} }},
+	{name: "promotions", resource: "promotions",
+		newSlice:  func() interface{} { return &[]models.Promotion{} },
+		posFilter: "(pos_scope = '' OR pos_scope IS NULL OR pos_scope = ?)"},
+	{name: "receipt_templates", resource: "receipts",
+		newSlice: func() interface{} { return &[]models.ReceiptTemplate{} }},
+	{name: "stocks", resource: "stocks",
+		newSlice: func() interface{} { return &[]models.Stock{} },
+		newModel: func() interface{} { return &models.Stock{} }},
+	{name: "stock_endommages", resource: "stocks",
+		newSlice: func() interface{} { return &[]models.StockEndommage{} },
+		newModel: func() interface{} { return &models.StockEndommage{} }},
+	{name: "restitutions", resource: "stocks",
+		newSlice: func() interface{} { return &[]models.Restitution{} },
+		newModel: func() interface{} { return &models.Restitution{} }},
+	{name: "table_boxes", resource: "tablebox",
+		newSlice: func() interface{} { return &[]models.TableBox{} }},
+	{name: "reservations", resource: "reservations",
+		newSlice: func() interface{} { return &[]models.Reservation{} }},
+	{name: "clients", resource: "clients",
+		newSlice: func() interface{} { return &[]models.Client{} },
+		newModel: func() interface{} { return &models.Client{} }},
+	{name: "zones", resource: "zones",
+		newSlice: func() interface{} { return &[]models.Zone{} }},
+	{name: "livreurs", resource: "livreurs",
+		newSlice: func() interface{} { return &[]models.Livreur{} }},
+	{name: "livraisons", resource: "livraisons",
+		newSlice: func() interface{} { return &[]models.Livraison{} }},
+	{name: "commandes", resource: "commandes",
+		newSlice: func() interface{} { return &[]models.Commande{} },
+		newModel: func() interface{} { return &models.Commande{} }},
+	{name: "commande_lines", resource: "commandes",
+		newSlice:  func() interface{} { return &[]models.CommandeLine{} },
+		newModel:  func() interface{} { return &models.CommandeLine{} },
+		posFilter: "commande_uuid IN (SELECT uuid FROM commandes WHERE pos_uuid = ?)"},
+	{name: "commande_status_histories", resource: "commandes",
+		newSlice: func() interface{} { return &[]models.CommandeStatusHistory{} }},
+	// Les règlements passent par payments.Post (mouvement de caisse, montant
+	// dû) : les terminaux règlent la commande par son encaissement, jamais par push
+	{name: "payments", resource: "commandes",
+		newSlice: func() interface{} { return &[]models.Payment{} }},
+	{name: "sale_returns", resource: "returns",
+		newSlice: func() interface{} { return &[]models.SaleReturn{} }},
+	{name: "sale_return_lines", resource: "returns",
+		newSlice: func() interface{} { return &[]models.SaleReturnLine{} }},
+}
+
+func findSyncEntity(name string) (syncEntity, bool) {
+	for _, e := range syncEntities {
+		if e.name == name {
+			return e, true
+		}
+	}
+	return syncEntity{}, false
+}
@@ -0,0 +1,471 @@
+package synchronisation
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/kgermando/ipos-stock-api/database"
+	"github.com/kgermando/ipos-stock-api/lifecycle"
+	"github.com/kgermando/ipos-stock-api/middlewares"
+	"github.com/kgermando/ipos-stock-api/models"
+	"github.com/kgermando/ipos-stock-api/numbering"
+	"github.com/kgermando/ipos-stock-api/pricing"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// maxPushOperations limite la taille d'un lot envoyé par un terminal
+const maxPushOperations = 5000
+
+// PushOperation est une modification faite hors ligne par le terminal
+type PushOperation struct {
+	ID     string          `json:"id"`     // Identifiant local, renvoyé dans le résultat
+	Entity string          `json:"entity"` // Nom de la table, comme dans /sync/changes
+	Action string          `json:"action"` // upsert ou delete
+	Data   json.RawMessage `json:"data"`   // Enregistrement complet, ou {"uuid": ...} pour delete
+
+	// Version du serveur sur laquelle la modification hors ligne est basée, et
+	// ses valeurs pour permettre la fusion champ par champ (facultatifs)
+	BaseUpdatedAt *time.Time      `json:"base_updated_at,omitempty"`
+	Base          json.RawMessage `json:"base,omitempty"`
+}
+
+type PushRequest struct {
+	Operations []PushOperation `json:"operations"`
+}
+
+// PushResult indique le sort de chaque opération du lot
+type PushResult struct {
+	ID     string      `json:"id"`
+	Entity string      `json:"entity"`
+	UUID   string      `json:"uuid"`
+	Status string      `json:"status"` // created, updated, deleted, conflict ou error
+	Error  string      `json:"error,omitempty"`
+	Server interface{} `json:"server,omitempty"` // État du serveur en cas de conflit
+}
+
+// Push applique dans une seule transaction un lot d'opérations envoyées par un
+// terminal qui se reconnecte. Chaque opération est isolée par un savepoint :
+// une opération invalide est annulée et signalée sans bloquer les autres.
+func Push(c *fiber.Ctx) error {
+	db := database.DB.WithContext(c.UserContext())
+	user := middlewares.GetAuthUser(c)
+
+	var req PushRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Données JSON invalides",
+			"errors":  err.Error(),
+		})
+	}
+
+	if len(req.Operations) > maxPushOperations {
+		return c.Status(413).JSON(fiber.Map{
+			"status":  "error",
+			"message": fmt.Sprintf("Un lot ne peut pas dépasser %d opérations", maxPushOperations),
+		})
+	}
+
+	deviceUUID := ""
+	if device := middlewares.GetDevice(c); device != nil {
+		deviceUUID = device.UUID
+	}
+
+	results := make([]PushResult, 0, len(req.Operations))
+	failed := 0
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		for i, op := range req.Operations {
+			savepoint := fmt.Sprintf("push_op_%d", i)
+			if err := tx.SavePoint(savepoint).Error; err != nil {
+				return err
+			}
+
+			result, err := applyOperation(tx, user, deviceUUID, op)
+			if err != nil {
+				if err := tx.RollbackTo(savepoint).Error; err != nil {
+					return err
+				}
+				result.Status, result.Error = "error", err.Error()
+
+				// Le conflit refusé est journalisé hors du savepoint annulé
+				var conflict *database.Conflict
+				if errors.As(err, &conflict) {
+					result.Status, result.Server = "conflict", conflict.Server
+					if err := database.RecordConflict(tx, conflict); err != nil {
+						return err
+					}
+				}
+				failed++
+			}
+			results = append(results, result)
+		}
+		return nil
+	})
+
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Erreur lors de l'application du lot",
+			"error":   err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"status":  "success",
+		"message": fmt.Sprintf("%d opération(s) appliquée(s), %d en erreur", len(results)-failed, failed),
+		"data":    results,
+	})
+}
+
+func applyOperation(tx *gorm.DB, user *models.User, deviceUUID string, op PushOperation) (PushResult, error) {
+	result := PushResult{ID: op.ID, Entity: op.Entity}
+
+	e, ok := findSyncEntity(op.Entity)
+	if !ok || e.newModel == nil {
+		return result, errors.New("entité non synchronisable")
+	}
+
+	switch op.Action {
+	case "upsert":
+		if !user.Can(e.resource + ":write") {
+			return result, errors.New("permission refusée")
+		}
+		model := e.newModel()
+		if err := json.Unmarshal(op.Data, model); err != nil {
+			return result, errors.New("données invalides : " + err.Error())
+		}
+		result.UUID = stringField(model, "UUID")
+
+		pre := database.Precondition{UpdatedAt: op.BaseUpdatedAt, Source: "sync", DeviceUUID: deviceUUID}
+		if len(op.Base) > 0 {
+			if err := json.Unmarshal(op.Base, &pre.Base); err != nil {
+				return result, errors.New("valeurs de base invalides : " + err.Error())
+			}
+		}
+
+		status, err := upsertModel(tx, user, model, pre)
+		if err != nil {
+			return result, err
+		}
+
+		// Les lignes d'une commande peuvent être envoyées avec elle. Les totaux
+		// sont toujours recalculés à partir des lignes enregistrées ; lorsque
+		// les lignes accompagnent la commande, ils doivent correspondre à ceux
+		// du terminal.
+		switch m := model.(type) {
+		case *models.Commande:
+			for i := range m.CommandeLines {
+				line := &m.CommandeLines[i]
+				line.CommandeUUID = m.UUID
+				if _, err := upsertModel(tx, user, line, database.Precondition{}); err != nil {
+					return result, fmt.Errorf("ligne %s : %w", line.UUID, err)
+				}
+			}
+			totals, err := pricing.Recompute(tx, m.UUID)
+			if err != nil {
+				return result, err
+			}
+			if len(m.CommandeLines) > 0 {
+				if err := pricing.Verify(pricing.CommandeTotals(m), totals, pricing.LoadPolicy(tx, m.EntrepriseUUID)); err != nil {
+					return result, err
+				}
+			}
+		case *models.CommandeLine:
+			// La commande peut arriver plus loin dans le lot
+			if _, err := pricing.Recompute(tx, m.CommandeUUID); err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+				return result, err
+			}
+		}
+		result.Status = status
+
+	case "delete":
+		if !user.Can(e.resource + ":delete") {
+			return result, errors.New("permission refusée")
+		}
+		var ref struct {
+			UUID string `json:"uuid"`
+		}
+		if err := json.Unmarshal(op.Data, &ref); err != nil || ref.UUID == "" {
+			return result, errors.New("uuid manquant")
+		}
+		result.UUID = ref.UUID
+
+		// Une commande envoyée en cuisine, servie ou réglée s'annule
+		if e.name == "commandes" {
+			var commande models.Commande
+			tx.Where("uuid = ?", ref.UUID).Limit(1).Find(&commande)
+			if commande.UUID != "" && !lifecycle.Deletable(commande.Status) {
+				return result, lifecycle.ErrNotDeletable
+			}
+		}
+
+		// Le stock vendu par une ligne supprimée est rendu au produit
+		if e.name == "commande_lines" {
+			var line models.CommandeLine
+			tx.Where("uuid = ?", ref.UUID).Limit(1).Find(&line)
+			if err := pushLineStock(tx, nil, &line); err != nil {
+				return result, err
+			}
+		}
+
+		// Supprimer un enregistrement déjà supprimé n'est pas une erreur
+		if err := tx.Where("uuid = ?", ref.UUID).Delete(e.newModel()).Error; err != nil {
+			return result, err
+		}
+		result.Status = "deleted"
+
+	default:
+		return result, errors.New("action inconnue : " + op.Action)
+	}
+
+	return result, nil
+}
+
+// upsertModel crée l'enregistrement ou met à jour celui du serveur, après
+// vérification de la version de base du client. Les colonnes d'identité (uuid,
+// date de création, POS, caissier) ne sont jamais modifiées par une mise à jour.
+func upsertModel(tx *gorm.DB, user *models.User, model interface{}, pre database.Precondition) (string, error) {
+	uuid := stringField(model, "UUID")
+	if uuid == "" {
+		return "", errors.New("uuid manquant")
+	}
+
+	existing := reflect.New(reflect.TypeOf(model).Elem()).Interface()
+	if err := tx.Unscoped().Where("uuid = ?", uuid).Limit(1).Find(existing).Error; err != nil {
+		return "", err
+	}
+
+	if line, ok := model.(*models.CommandeLine); ok {
+		if err := pushLinePrices(tx, line, existing.(*models.CommandeLine)); err != nil {
+			if errors.Is(err, pricing.ErrPriceMismatch) {
+				return "", err
+			}
+			return "", errors.New("produit ou plat introuvable")
+		}
+	}
+	// Le solde d'avoir d'un client n'est tenu que par le serveur
+	if client, ok := model.(*models.Client); ok {
+		client.CreditBalance = existing.(*models.Client).CreditBalance
+	}
+	// Le statut d'une commande suit le cycle de vie ; chaque changement est tracé
+	commande, _ := model.(*models.Commande)
+	var from string
+	if commande != nil {
+		var err error
+		if from, err = pushStatus(commande, existing.(*models.Commande), user); err != nil {
+			return "", err
+		}
+		if err := pushNumber(tx, commande, existing.(*models.Commande)); err != nil {
+			return "", err
+		}
+		// La certification fiscale n'est tenue que par le serveur
+		commande.Fiscal = existing.(*models.Commande).Fiscal
+	}
+
+	// La date de modification est celle du serveur : c'est elle qui fait
+	// avancer les curseurs de /sync/changes des autres terminaux
+	setField(model, "Sync", true)
+	setField(model, "UpdatedAt", time.Now())
+
+	if stringField(existing, "UUID") == "" {
+		if field := reflect.ValueOf(model).Elem().FieldByName("CashierUUID"); field.IsValid() {
+			field.SetString(pushCashier(tx, user, field.String()))
+		}
+		if err := tx.Omit(clause.Associations).Create(model).Error; err != nil {
+			return "", err
+		}
+		if commande != nil {
+			if err := lifecycle.Record(tx, commande, "", user); err != nil {
+				return "", err
+			}
+		}
+		if line, ok := model.(*models.CommandeLine); ok {
+			if err := pushLineStock(tx, line, nil); err != nil {
+				return "", err
+			}
+		}
+		return "created", nil
+	}
+
+	if deleted := reflect.ValueOf(existing).Elem().FieldByName("DeletedAt"); deleted.IsValid() && deleted.Interface().(gorm.DeletedAt).Valid {
+		return "", errors.New("enregistrement supprimé sur le serveur")
+	}
+
+	if conflict := database.ResolveConflict(tx, existing, model, pre); conflict != nil {
+		if conflict.Rejected() {
+			return "", conflict
+		}
+		if err := database.RecordConflict(tx, conflict); err != nil {
+			return "", err
+		}
+	}
+
+	// Le stock est corrigé avant la mise à jour, qui recopie les nouvelles
+	// valeurs dans existing
+	if line, ok := model.(*models.CommandeLine); ok {
+		if err := pushLineStock(tx, line, existing.(*models.CommandeLine)); err != nil {
+			return "", err
+		}
+	}
+	if err := tx.Model(existing).
+		Select("*").
+		Omit("uuid", "created_at", "deleted_at", "pos_uuid", "cashier_uuid", clause.Associations).
+		Updates(model).Error; err != nil {
+		return "", err
+	}
+	if commande != nil && commande.Status != from {
+		if from == models.CommandePaid {
+			if err := lifecycle.Void(tx, commande, user); err != nil {
+				return "", err
+			}
+		}
+		if err := lifecycle.Record(tx, commande, from, user); err != nil {
+			return "", err
+		}
+	}
+	return "updated", nil
+}
+
+// pushNumber attribue son numéro de facture à une commande créée hors ligne ;
+// le numéro provisoire du terminal est remplacé et ne change plus ensuite
+func pushNumber(tx *gorm.DB, commande, existing *models.Commande) error {
+	if existing.UUID != "" {
+		commande.Ncommande = existing.Ncommande
+		return nil
+	}
+	var pos models.Pos
+	if err := tx.Where("uuid = ?", commande.PosUUID).First(&pos).Error; err != nil {
+		return errors.New("point de vente introuvable")
+	}
+	number, err := numbering.Next(tx, models.DocumentCommande, pos, time.Now())
+	if err != nil {
+		return err
+	}
+	commande.Ncommande = number
+	return nil
+}
+
+// pushStatus contrôle le statut d'une commande envoyée par le terminal : un
+// statut initial à la création, sinon une transition autorisée depuis celui
+// du serveur. Un statut vide garde celui du serveur. Retourne le statut du serveur.
+func pushStatus(commande, existing *models.Commande, user *models.User) (string, error) {
+	if existing.UUID == "" {
+		status, err := lifecycle.Initial(commande.Status)
+		commande.Status = status
+		return "", err
+	}
+
+	from := existing.Status
+	if commande.Status == "" {
+		commande.Status = from
+		return from, nil
+	}
+	to, err := lifecycle.Canonical(commande.Status)
+	if err != nil {
+		return from, err
+	}
+	commande.Status = to
+	if to != from {
+		if err := lifecycle.Check(from, to, user); err != nil {
+			return from, err
+		}
+	}
+	return from, nil
+}
+
+// pushLinePrices fige les prix d'une ligne de commande vendue hors ligne.
+// Une ligne déjà enregistrée garde ses prix figés ; seule sa promotion suit la
+// quantité. Une nouvelle ligne est chiffrée aux prix du serveur au moment de
+// la vente, et refusée si le terminal a appliqué un autre prix.
+func pushLinePrices(tx *gorm.DB, line, existing *models.CommandeLine) error {
+	policy := pricing.LoadPolicy(tx, line.EntrepriseUUID)
+	if existing.UUID != "" && existing.ProductUUID == line.ProductUUID && existing.PlatUUID == line.PlatUUID {
+		line.ItemType = existing.ItemType
+		line.UnitPrice, line.UnitCost = existing.UnitPrice, existing.UnitCost
+		line.Remise, line.TvaRate = existing.Remise, existing.TvaRate
+		line.PromotionUUID = existing.PromotionUUID
+		pricing.Refresh(tx, line, policy)
+		return nil
+	}
+
+	at := line.CreatedAt
+	if at.IsZero() || at.After(time.Now()) {
+		at = time.Now()
+	}
+	return pricing.Offline(tx, line, line.PosUUID, at, policy)
+}
+
+// pushCashier garde le caissier indiqué par le terminal s'il fait partie du
+// périmètre de l'utilisateur, sinon attribue l'opération à l'utilisateur connecté
+func pushCashier(tx *gorm.DB, user *models.User, cashierUUID string) string {
+	if cashierUUID == "" || cashierUUID == user.UUID {
+		return user.UUID
+	}
+	var count int64
+	tx.Model(&models.User{}).Where("uuid = ?", cashierUUID).Count(&count)
+	if count == 0 {
+		return user.UUID
+	}
+	return cashierUUID
+}
+
+func stringField(model interface{}, name string) string {
+	field := reflect.ValueOf(model).Elem().FieldByName(name)
+	if !field.IsValid() || field.Kind() != reflect.String {
+		return ""
+	}
+	return field.String()
+}
+
+func setField(model interface{}, name string, value interface{}) {
+	field := reflect.ValueOf(model).Elem().FieldByName(name)
+	if field.IsValid() && field.CanSet() {
+		field.Set(reflect.ValueOf(value))
+	}
+}
+
+// pushLineStock reporte sur le stock des produits une ligne vendue hors ligne,
+// comme l'encaissement : une nouvelle ligne déduit sa quantité, une ligne
+// modifiée ou supprimée rend celle enregistrée sur le serveur. Les produits
+// sont verrouillés dans un ordre stable, comme pour l'encaissement.
+func pushLineStock(tx *gorm.DB, line, existing *models.CommandeLine) error {
+	delta := map[string]float64{}
+	if existing != nil && existing.UUID != "" && existing.ItemType == "product" {
+		delta[existing.ProductUUID] += float64(existing.Quantity)
+	}
+	if line != nil && line.ItemType == "product" {
+		delta[line.ProductUUID] -= float64(line.Quantity)
+	}
+
+	productUUIDs := make([]string, 0, len(delta))
+	for uuid, d := range delta {
+		if d != 0 {
+			productUUIDs = append(productUUIDs, uuid)
+		}
+	}
+	sort.Strings(productUUIDs)
+
+	for _, uuid := range productUUIDs {
+		var product models.Product
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("uuid = ?", uuid).First(&product).Error; err != nil {
+			return errors.New("produit introuvable")
+		}
+		if product.Stock+delta[uuid] < 0 {
+			return fmt.Errorf("stock insuffisant pour %s (disponible : %g)", product.Name, product.Stock)
+		}
+		if err := tx.Model(&product).
+			Updates(map[string]interface{}{"stock": gorm.Expr("stock + ?", delta[uuid]), "sync": true}).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
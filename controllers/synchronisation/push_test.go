@@ -0,0 +1,95 @@
+package synchronisation
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/kgermando/ipos-stock-api/lifecycle"
+	"github.com/kgermando/ipos-stock-api/models"
+)
+
+func TestApplyOperationRejected(t *testing.T) {
+	cashier := &models.User{Role: models.RoleCashier}
+	stockKeeper := &models.User{Role: models.RoleStockKeeper}
+	manager := &models.User{Role: models.RolePosManager}
+
+	// Opérations refusées avant tout accès à la base
+	tests := []struct {
+		name string
+		user *models.User
+		op   PushOperation
+		err  string
+	}{
+		{"table inconnue", manager, PushOperation{Entity: "users", Action: "upsert"}, "entité non synchronisable"},
+		{"table en lecture seule", manager, PushOperation{Entity: "products", Action: "upsert"}, "entité non synchronisable"},
+		{"règlement poussé", manager, PushOperation{Entity: "payments", Action: "upsert"}, "entité non synchronisable"},
+		{"magasinier : commande", stockKeeper, PushOperation{Entity: "commandes", Action: "upsert", Data: json.RawMessage(`{}`)}, "permission refusée"},
+		{"caissier : suppression de commande", cashier, PushOperation{Entity: "commandes", Action: "delete", Data: json.RawMessage(`{"uuid":"c1"}`)}, "permission refusée"},
+		{"caissier : mouvement de stock", cashier, PushOperation{Entity: "stocks", Action: "upsert", Data: json.RawMessage(`{}`)}, "permission refusée"},
+		{"suppression sans uuid", manager, PushOperation{Entity: "clients", Action: "delete", Data: json.RawMessage(`{}`)}, "uuid manquant"},
+		{"action inconnue", manager, PushOperation{Entity: "clients", Action: "merge"}, "action inconnue : merge"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.op.ID = "op-1"
+			result, err := applyOperation(nil, tt.user, "", tt.op)
+			if err == nil || err.Error() != tt.err {
+				t.Fatalf("erreur = %v, attendu %q", err, tt.err)
+			}
+			if result.ID != "op-1" || result.Entity != tt.op.Entity {
+				t.Errorf("résultat = %+v : l'opération n'est pas identifiée", result)
+			}
+		})
+	}
+
+	if _, err := applyOperation(nil, manager, "", PushOperation{Entity: "clients", Action: "upsert", Data: json.RawMessage(`[`)}); err == nil {
+		t.Error("données invalides acceptées")
+	}
+}
+
+func TestPushStatus(t *testing.T) {
+	cashier := &models.User{Role: models.RoleCashier}
+	manager := &models.User{Role: models.RolePosManager}
+
+	tests := []struct {
+		name     string
+		existing string // Statut du serveur, vide pour une commande créée hors ligne
+		pushed   string
+		user     *models.User
+		want     string
+		err      error
+	}{
+		{"création sans statut", "", "", cashier, models.CommandeOpen, nil},
+		{"création avec un ancien statut", "", "ouverte", cashier, models.CommandeOpen, nil},
+		{"création déjà réglée", "", "closed", cashier, "", lifecycle.ErrPayOnly},
+		{"création annulée", "", models.CommandeCancelled, cashier, "", lifecycle.ErrInvalidTransition},
+		{"statut vide : celui du serveur", models.CommandeServed, "", cashier, models.CommandeServed, nil},
+		{"commande servie", models.CommandeOpen, "Served", cashier, models.CommandeServed, nil},
+		{"réglée hors encaissement", models.CommandeServed, models.CommandePaid, cashier, models.CommandePaid, lifecycle.ErrPayOnly},
+		{"retour en arrière", models.CommandeServed, models.CommandeOpen, cashier, models.CommandeOpen, lifecycle.ErrInvalidTransition},
+		{"annulation d'une commande réglée par un caissier", models.CommandePaid, models.CommandeCancelled, cashier, models.CommandeCancelled, lifecycle.ErrVoidForbidden},
+		{"annulation d'une commande réglée par un gérant", models.CommandePaid, models.CommandeCancelled, manager, models.CommandeCancelled, nil},
+		{"statut inconnu", models.CommandeOpen, "livrée", cashier, "livrée", lifecycle.ErrUnknownStatus},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			existing := &models.Commande{Status: tt.existing}
+			if tt.existing != "" {
+				existing.UUID = "commande"
+			}
+			commande := &models.Commande{UUID: "commande", Status: tt.pushed}
+
+			from, err := pushStatus(commande, existing, tt.user)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("erreur = %v, attendu %v", err, tt.err)
+			}
+			if from != tt.existing {
+				t.Errorf("statut du serveur = %q, attendu %q", from, tt.existing)
+			}
+			if tt.err == nil && commande.Status != tt.want {
+				t.Errorf("statut = %q, attendu %q", commande.Status, tt.want)
+			}
+		})
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"strconv"
 
 	"github.com/kgermando/ipos-stock-api/database"
+	"github.com/kgermando/ipos-stock-api/middlewares"
 	"github.com/kgermando/ipos-stock-api/models"
 
 	"github.com/gofiber/fiber/v2"
@@ -11,11 +12,11 @@ import (
 
 // Synchronisation Send data to Local
 func GetDataSynchronisation(c *fiber.Ctx) error {
-	db := database.DB
+	db := database.DB.WithContext(c.UserContext())
 	entrepriseUUID := c.Params("entreprise_uuid")
 	posUUID := c.Params("pos_uuid")
 
-	sync_created := c.Query("sync_created", "2023-01-01")
+	sync_created, syncDone := middlewares.SyncCursor(c, "table_boxes")
 	var data []models.TableBox
 
 	if posUUID == "-" {
@@ -32,6 +33,8 @@ func GetDataSynchronisation(c *fiber.Ctx) error {
 			Preload("Pos").
 			Find(&data)
 	}
+	syncDone()
+
 	return c.JSON(fiber.Map{
 		"status":  "success",
 		"message": "All table boxes sync data",
@@ -41,7 +44,7 @@ func GetDataSynchronisation(c *fiber.Ctx) error {
 
 // Paginate by entreprise
 func GetPaginatedTableBoxEntreprise(c *fiber.Ctx) error {
-	db := database.DB
+	db := database.DB.WithContext(c.UserContext())
 	entrepriseUUID := c.Params("entreprise_uuid")
 
 	page, _ := strconv.Atoi(c.Query("page", "1"))
@@ -100,7 +103,7 @@ func GetPaginatedTableBoxEntreprise(c *fiber.Ctx) error {
 
 // Paginate by posUUID
 func GetPaginatedTableBoxByPosUUID(c *fiber.Ctx) error {
-	db := database.DB
+	db := database.DB.WithContext(c.UserContext())
 	entrepriseUUID := c.Params("entreprise_uuid")
 	posUUID := c.Params("pos_uuid")
 
@@ -160,7 +163,7 @@ func GetPaginatedTableBoxByPosUUID(c *fiber.Ctx) error {
 
 // Get All data
 func GetAllTableBoxs(c *fiber.Ctx) error {
-	db := database.DB
+	db := database.DB.WithContext(c.UserContext())
 	entrepriseUUID := c.Params("entreprise_uuid")
 	posUUID := c.Params("pos_uuid")
 
@@ -178,7 +181,7 @@ func GetAllTableBoxs(c *fiber.Ctx) error {
 
 // Get All data by search
 func GetAllTableBoxBySearch(c *fiber.Ctx) error {
-	db := database.DB
+	db := database.DB.WithContext(c.UserContext())
 	entrepriseUUID := c.Params("entreprise_uuid")
 	posUUID := c.Params("pos_uuid")
 	search := c.Query("search", "")
@@ -203,7 +206,7 @@ func GetAllTableBoxBySearch(c *fiber.Ctx) error {
 // Get one data
 func GetTableBox(c *fiber.Ctx) error {
 	uuid := c.Params("uuid")
-	db := database.DB
+	db := database.DB.WithContext(c.UserContext())
 
 	var tableBox models.TableBox
 	db.Where("uuid = ?", uuid).Preload("Pos").First(&tableBox)
@@ -258,7 +261,7 @@ func CreateTableBox(c *fiber.Ctx) error {
 
 	p.Sync = true
 
-	database.DB.Create(p)
+	database.DB.WithContext(c.UserContext()).Create(p)
 
 	return c.JSON(
 		fiber.Map{
@@ -272,7 +275,7 @@ func CreateTableBox(c *fiber.Ctx) error {
 // Update data
 func UpdateTableBox(c *fiber.Ctx) error {
 	uuid := c.Params("uuid")
-	db := database.DB
+	db := database.DB.WithContext(c.UserContext())
 
 	var tableBox models.TableBox
 
@@ -288,6 +291,8 @@ func UpdateTableBox(c *fiber.Ctx) error {
 		)
 	}
 
+	server := tableBox
+
 	// Parse request body
 	if err := c.BodyParser(&tableBox); err != nil {
 		return err
@@ -295,8 +300,12 @@ func UpdateTableBox(c *fiber.Ctx) error {
 
 	tableBox.Sync = true
 
+	if conflict := middlewares.CheckConflict(c, db, &server, &tableBox); conflict != nil {
+		return middlewares.ConflictResponse(c, conflict)
+	}
+
 	// Save to database
-	database.DB.Save(&tableBox)
+	database.DB.WithContext(c.UserContext()).Save(&tableBox)
 	return c.JSON(
 		fiber.Map{
 			"status":  "success",
@@ -309,7 +318,7 @@ func UpdateTableBox(c *fiber.Ctx) error {
 // Delete data
 func DeleteTableBox(c *fiber.Ctx) error {
 	uuid := c.Params("uuid")
-	db := database.DB
+	db := database.DB.WithContext(c.UserContext())
 
 	var tableBox models.TableBox
 	db.Where("uuid = ?", uuid).First(&tableBox)
@@ -335,7 +344,7 @@ func DeleteTableBox(c *fiber.Ctx) error {
 
 // Get table boxes by category
 func GetTableBoxsByCategory(c *fiber.Ctx) error {
-	db := database.DB
+	db := database.DB.WithContext(c.UserContext())
 	entrepriseUUID := c.Params("entreprise_uuid")
 	posUUID := c.Params("pos_uuid")
 	category := c.Params("category")
@@ -355,7 +364,7 @@ func GetTableBoxsByCategory(c *fiber.Ctx) error {
 
 // Get table boxes by statut
 func GetTableBoxsByStatut(c *fiber.Ctx) error {
-	db := database.DB
+	db := database.DB.WithContext(c.UserContext())
 	entrepriseUUID := c.Params("entreprise_uuid")
 	posUUID := c.Params("pos_uuid")
 	statut := c.Params("statut")
@@ -0,0 +1,265 @@
+package users
+
+import (
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/kgermando/ipos-stock-api/database"
+	"github.com/kgermando/ipos-stock-api/middlewares"
+	"github.com/kgermando/ipos-stock-api/models"
+	"github.com/kgermando/ipos-stock-api/utils"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// Paginate pending invitations
+func GetPendingInvitations(c *fiber.Ctx) error {
+	db := database.DB.WithContext(c.UserContext())
+	entrepriseUUID := c.Params("entreprise_uuid")
+
+	page, err := strconv.Atoi(c.Query("page", "1"))
+	if err != nil || page <= 0 {
+		page = 1
+	}
+	limit, err := strconv.Atoi(c.Query("limit", "15"))
+	if err != nil || limit <= 0 {
+		limit = 15
+	}
+	offset := (page - 1) * limit
+
+	query := db.Model(&models.Invitation{}).
+		Where("entreprise_uuid = ? AND accepted_at IS NULL AND revoked_at IS NULL", entrepriseUUID)
+
+	var totalRecords int64
+	query.Count(&totalRecords)
+
+	var dataList []models.Invitation
+	err = query.Offset(offset).
+		Limit(limit).
+		Order("created_at DESC").
+		Preload("Pos").
+		Find(&dataList).Error
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Failed to fetch invitations",
+			"error":   err.Error(),
+		})
+	}
+
+	totalPages := int((totalRecords + int64(limit) - 1) / int64(limit))
+
+	pagination := map[string]interface{}{
+		"total_records": totalRecords,
+		"total_pages":   totalPages,
+		"current_page":  page,
+		"page_size":     limit,
+	}
+
+	return c.JSON(fiber.Map{
+		"status":     "success",
+		"message":    "All pending invitations",
+		"data":       dataList,
+		"pagination": pagination,
+	})
+}
+
+// Invite a new user by email
+func InviteUser(c *fiber.Ctx) error {
+	db := database.DB.WithContext(c.UserContext())
+	actor := middlewares.GetAuthUser(c)
+
+	var req models.InvitationRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Invalid JSON format",
+			"data":    nil,
+		})
+	}
+
+	if err := utils.ValidateStruct(req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Données invalides",
+			"errors":  err,
+		})
+	}
+
+	if !actor.CanAssignRole(req.Role) || !actor.CanGrantPermissions(req.Permission) {
+		return c.Status(403).JSON(
+			fiber.Map{
+				"status":  "error",
+				"message": "Vous ne pouvez pas attribuer ce rôle ou ces permissions",
+				"data":    nil,
+			},
+		)
+	}
+
+	var pos models.Pos
+	db.Where("uuid = ?", req.PosUUID).First(&pos)
+	if pos.UUID == "" {
+		return c.Status(404).JSON(fiber.Map{
+			"status":  "error",
+			"message": "No pos found",
+			"data":    nil,
+		})
+	}
+
+	// L'email doit être libre, tous tenants confondus
+	var count int64
+	database.DB.Model(&models.User{}).Where("email = ?", req.Email).Count(&count)
+	if count > 0 {
+		return c.Status(409).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Un utilisateur avec cet email existe déjà",
+			"data":    nil,
+		})
+	}
+
+	database.DB.Model(&models.Invitation{}).
+		Where("email = ? AND accepted_at IS NULL AND revoked_at IS NULL AND expires_at > ?", req.Email, time.Now()).
+		Count(&count)
+	if count > 0 {
+		return c.Status(409).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Une invitation est déjà en attente pour cet email, renvoyez-la",
+			"data":    nil,
+		})
+	}
+
+	invitation := &models.Invitation{
+		UUID:           utils.GenerateUUID(),
+		Email:          req.Email,
+		Fullname:       req.Fullname,
+		Role:           req.Role,
+		Permission:     req.Permission,
+		InvitedBy:      actor.UUID,
+		EntrepriseUUID: pos.EntrepriseUUID,
+		PosUUID:        pos.UUID,
+	}
+
+	if err := sendInvitation(db, invitation, actor, true); err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Erreur lors de l'envoi de l'invitation",
+			"data":    nil,
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"status":  "success",
+		"message": "Invitation envoyée",
+		"data":    invitation,
+	})
+}
+
+// Resend a pending invitation with a new token
+func ResendInvitation(c *fiber.Ctx) error {
+	uuid := c.Params("uuid")
+	db := database.DB.WithContext(c.UserContext())
+
+	var invitation models.Invitation
+	db.Where("uuid = ?", uuid).First(&invitation)
+	if invitation.UUID == "" {
+		return c.Status(404).JSON(fiber.Map{
+			"status":  "error",
+			"message": "No invitation found",
+			"data":    nil,
+		})
+	}
+
+	if invitation.AcceptedAt != nil || invitation.RevokedAt != nil {
+		return c.Status(409).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Cette invitation a déjà été acceptée ou révoquée",
+			"data":    nil,
+		})
+	}
+
+	if err := sendInvitation(db, &invitation, middlewares.GetAuthUser(c), false); err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Erreur lors de l'envoi de l'invitation",
+			"data":    nil,
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"status":  "success",
+		"message": "Invitation renvoyée",
+		"data":    invitation,
+	})
+}
+
+// Revoke a pending invitation
+func RevokeInvitation(c *fiber.Ctx) error {
+	uuid := c.Params("uuid")
+	db := database.DB.WithContext(c.UserContext())
+
+	var invitation models.Invitation
+	db.Where("uuid = ?", uuid).First(&invitation)
+	if invitation.UUID == "" {
+		return c.Status(404).JSON(fiber.Map{
+			"status":  "error",
+			"message": "No invitation found",
+			"data":    nil,
+		})
+	}
+
+	if invitation.AcceptedAt != nil {
+		return c.Status(409).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Cette invitation a déjà été acceptée",
+			"data":    nil,
+		})
+	}
+
+	if invitation.RevokedAt == nil {
+		now := time.Now()
+		db.Model(&invitation).Update("revoked_at", &now)
+	}
+
+	return c.JSON(fiber.Map{
+		"status":  "success",
+		"message": "Invitation revoked success",
+		"data":    invitation,
+	})
+}
+
+// sendInvitation génère un nouveau token, enregistre l'invitation puis envoie
+// l'email. L'enregistrement est annulé si l'email ne part pas.
+func sendInvitation(db *gorm.DB, invitation *models.Invitation, actor *models.User, create bool) error {
+	token, err := utils.GenerateSecureToken(32)
+	if err != nil {
+		return err
+	}
+
+	var entreprise models.Entreprise
+	database.DB.Where("uuid = ?", invitation.EntrepriseUUID).First(&entreprise)
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		invitation.TokenHash = utils.HashToken(token)
+		invitation.ExpiresAt = time.Now().Add(models.InvitationTTL)
+
+		if create {
+			if err := tx.Create(invitation).Error; err != nil {
+				return err
+			}
+		} else if err := tx.Model(invitation).Updates(map[string]interface{}{
+			"token_hash": invitation.TokenHash,
+			"expires_at": invitation.ExpiresAt,
+		}).Error; err != nil {
+			return err
+		}
+
+		emailService := utils.NewEmailService()
+		if err := emailService.SendInvitationEmail(invitation.Email, token, invitation.Fullname, entreprise.Name, actor.Fullname); err != nil {
+			log.Printf("Erreur envoi invitation: %v", err)
+			return err
+		}
+		return nil
+	})
+}
@@ -2,16 +2,18 @@ package users
 
 import (
 	"strconv"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/kgermando/ipos-stock-api/database"
+	"github.com/kgermando/ipos-stock-api/middlewares"
 	"github.com/kgermando/ipos-stock-api/models"
 	"github.com/kgermando/ipos-stock-api/utils"
 )
 
 // Paginate
 func GetPaginatedUsers(c *fiber.Ctx) error {
-	db := database.DB
+	db := database.DB.WithContext(c.UserContext())
 
 	entrepriseUUID := c.Params("entreprise_uuid")
 
@@ -77,7 +79,7 @@ func GetPaginatedUsers(c *fiber.Ctx) error {
 }
 
 func GetPaginatedUsersSupport(c *fiber.Ctx) error {
-	db := database.DB 
+	db := database.DB.WithContext(c.UserContext())
 
 	// Parse query parameters for pagination
 	page, err := strconv.Atoi(c.Query("page", "1"))
@@ -139,7 +141,7 @@ func GetPaginatedUsersSupport(c *fiber.Ctx) error {
 }
 
 func GetPaginatedUserByPosUUID(c *fiber.Ctx) error {
-	db := database.DB
+	db := database.DB.WithContext(c.UserContext())
 
 	entrepriseUUID := c.Params("entreprise_uuid")
 	posUUID := c.Params("pos_uuid")
@@ -208,7 +210,7 @@ func GetPaginatedUserByPosUUID(c *fiber.Ctx) error {
 }
 
 func GetPaginatedNoSerach(c *fiber.Ctx) error {
-	db := database.DB
+	db := database.DB.WithContext(c.UserContext())
 
 	// Parse query parameters for pagination
 	page, err := strconv.Atoi(c.Query("page", "1"))
@@ -265,7 +267,7 @@ func GetPaginatedNoSerach(c *fiber.Ctx) error {
 
 // query all data
 func GetAllUsers(c *fiber.Ctx) error {
-	db := database.DB
+	db := database.DB.WithContext(c.UserContext())
 	var users []models.User
 	db.Find(&users)
 	return c.JSON(fiber.Map{
@@ -276,7 +278,7 @@ func GetAllUsers(c *fiber.Ctx) error {
 }
 
 func GetAllUsersById(c *fiber.Ctx) error {
-	db := database.DB
+	db := database.DB.WithContext(c.UserContext())
 	uuid := c.Params("entreprise_uuid")
 
 	var users []models.User
@@ -291,7 +293,7 @@ func GetAllUsersById(c *fiber.Ctx) error {
 // Get one data
 func GetUser(c *fiber.Ctx) error {
 	uuid := c.Params("uuid")
-	db := database.DB
+	db := database.DB.WithContext(c.UserContext())
 	var user models.User
 	db.Where("uuid = ?", uuid).First(&user)
 	if user.Fullname == "" {
@@ -337,6 +339,17 @@ func CreateUser(c *fiber.Ctx) error {
 		})
 	}
 
+	actor := middlewares.GetAuthUser(c)
+	if !actor.CanAssignRole(p.Role) || !actor.CanGrantPermissions(p.Permission) {
+		return c.Status(403).JSON(
+			fiber.Map{
+				"status":  "error",
+				"message": "Vous ne pouvez pas attribuer ce rôle ou ces permissions",
+				"data":    nil,
+			},
+		)
+	}
+
 	user := &models.User{
 		Fullname:       p.Fullname,
 		Email:          p.Email,
@@ -360,9 +373,9 @@ func CreateUser(c *fiber.Ctx) error {
 
 	user.Sync = true
 
-	database.DB.Create(user)
+	database.DB.WithContext(c.UserContext()).Create(user)
 
-	// if err := database.DB.Create(user).Error; err != nil {
+	// if err := database.DB.WithContext(c.UserContext()).Create(user).Error; err != nil {
 	// 	c.Status(500)
 	// 	sm := strings.Split(err.Error(), ":")
 	// 	m := strings.TrimSpace(sm[1])
@@ -384,7 +397,7 @@ func CreateUser(c *fiber.Ctx) error {
 // Update data
 func UpdateUser(c *fiber.Ctx) error {
 	uuid := c.Params("uuid")
-	db := database.DB
+	db := database.DB.WithContext(c.UserContext())
 
 	type UpdateDataInput struct {
 		Fullname        string `gorm:"not null" json:"fullname"`
@@ -414,6 +427,46 @@ func UpdateUser(c *fiber.Ctx) error {
 	user := new(models.User)
 
 	db.Where("uuid = ?", uuid).First(&user)
+	if user.UUID == "" {
+		return c.Status(404).JSON(
+			fiber.Map{
+				"status":  "error",
+				"message": "No User name found",
+				"data":    nil,
+			},
+		)
+	}
+
+	actor := middlewares.GetAuthUser(c)
+	if !actor.CanManage(user) {
+		return c.Status(403).JSON(
+			fiber.Map{
+				"status":  "error",
+				"message": "Vous ne pouvez pas modifier ce compte",
+				"data":    nil,
+			},
+		)
+	}
+	if !actor.HasEntrepriseScope() && updateData.PosUUID != actor.PosUUID {
+		return c.Status(403).JSON(
+			fiber.Map{
+				"status":  "error",
+				"message": "Vous ne pouvez rattacher un compte qu'à votre point de vente",
+				"data":    nil,
+			},
+		)
+	}
+	if (user.Role != updateData.Role && !actor.CanAssignRole(updateData.Role)) ||
+		!actor.CanGrantPermissions(updateData.Permission) {
+		return c.Status(403).JSON(
+			fiber.Map{
+				"status":  "error",
+				"message": "Vous ne pouvez pas attribuer ce rôle ou ces permissions",
+				"data":    nil,
+			},
+		)
+	}
+
 	user.Fullname = updateData.Fullname
 	user.Email = updateData.Email
 	user.Telephone = updateData.Telephone
@@ -438,7 +491,7 @@ func UpdateUser(c *fiber.Ctx) error {
 func DeleteUser(c *fiber.Ctx) error {
 	uuid := c.Params("uuid")
 
-	db := database.DB
+	db := database.DB.WithContext(c.UserContext())
 
 	var User models.User
 	db.Where("uuid = ?", uuid).First(&User)
@@ -452,6 +505,16 @@ func DeleteUser(c *fiber.Ctx) error {
 		)
 	}
 
+	if !middlewares.GetAuthUser(c).CanManage(&User) {
+		return c.Status(403).JSON(
+			fiber.Map{
+				"status":  "error",
+				"message": "Vous ne pouvez pas modifier ce compte",
+				"data":    nil,
+			},
+		)
+	}
+
 	db.Delete(&User)
 
 	return c.JSON(
@@ -462,3 +525,128 @@ func DeleteUser(c *fiber.Ctx) error {
 		},
 	)
 }
+
+// Revoke all sessions of a user (log out all devices)
+func RevokeUserSessions(c *fiber.Ctx) error {
+	uuid := c.Params("uuid")
+	db := database.DB.WithContext(c.UserContext())
+
+	var user models.User
+	db.Where("uuid = ?", uuid).First(&user)
+	if user.UUID == "" {
+		return c.Status(404).JSON(
+			fiber.Map{
+				"status":  "error",
+				"message": "No User name found",
+				"data":    nil,
+			},
+		)
+	}
+
+	if !middlewares.GetAuthUser(c).CanManage(&user) {
+		return c.Status(403).JSON(
+			fiber.Map{
+				"status":  "error",
+				"message": "Vous ne pouvez pas modifier ce compte",
+				"data":    nil,
+			},
+		)
+	}
+
+	db.Model(&models.Session{}).
+		Where("user_uuid = ? AND revoked_at IS NULL", user.UUID).
+		Update("revoked_at", time.Now())
+
+	return c.JSON(
+		fiber.Map{
+			"status":  "success",
+			"message": "User sessions revoked success",
+			"data":    nil,
+		},
+	)
+}
+
+// Unlock a user account locked after too many failed logins
+func UnlockUser(c *fiber.Ctx) error {
+	uuid := c.Params("uuid")
+	db := database.DB.WithContext(c.UserContext())
+
+	var user models.User
+	db.Where("uuid = ?", uuid).First(&user)
+	if user.UUID == "" {
+		return c.Status(404).JSON(
+			fiber.Map{
+				"status":  "error",
+				"message": "No User name found",
+				"data":    nil,
+			},
+		)
+	}
+
+	if !middlewares.GetAuthUser(c).CanManage(&user) {
+		return c.Status(403).JSON(
+			fiber.Map{
+				"status":  "error",
+				"message": "Vous ne pouvez pas modifier ce compte",
+				"data":    nil,
+			},
+		)
+	}
+
+	database.DB.Where("key IN ?", user.LoginThrottleKeys()).Delete(&models.LoginThrottle{})
+
+	database.DB.Create(&models.SecurityEvent{
+		UUID:           utils.GenerateUUID(),
+		Type:           "unlock",
+		IP:             c.IP(),
+		UserUUID:       user.UUID,
+		ActorUUID:      middlewares.GetAuthUser(c).UUID,
+		EntrepriseUUID: user.EntrepriseUUID,
+	})
+
+	return c.JSON(
+		fiber.Map{
+			"status":  "success",
+			"message": "User unlocked success",
+			"data":    nil,
+		},
+	)
+}
+
+// Reset the PIN of a cashier who forgot it
+func ResetUserPin(c *fiber.Ctx) error {
+	uuid := c.Params("uuid")
+	db := database.DB.WithContext(c.UserContext())
+
+	var user models.User
+	db.Where("uuid = ?", uuid).First(&user)
+	if user.UUID == "" {
+		return c.Status(404).JSON(
+			fiber.Map{
+				"status":  "error",
+				"message": "No User name found",
+				"data":    nil,
+			},
+		)
+	}
+
+	if !middlewares.GetAuthUser(c).CanManage(&user) {
+		return c.Status(403).JSON(
+			fiber.Map{
+				"status":  "error",
+				"message": "Vous ne pouvez pas modifier ce compte",
+				"data":    nil,
+			},
+		)
+	}
+
+	db.Model(&user).Update("pin_hash", "")
+
+	return c.JSON(
+		fiber.Map{
+			"status":  "success",
+			"message": "User PIN reset success",
+			"data":    nil,
+		},
+	)
+}
@@ -6,6 +6,7 @@ import (
 	"strconv"
 
 	"github.com/kgermando/ipos-stock-api/database"
+	"github.com/kgermando/ipos-stock-api/middlewares"
 	"github.com/kgermando/ipos-stock-api/models"
 
 	"github.com/gofiber/fiber/v2"
@@ -13,11 +14,11 @@ import (
 
 // Synchronisation Send data to Local
 func GetDataSynchronisation(c *fiber.Ctx) error {
-	db := database.DB
+	db := database.DB.WithContext(c.UserContext())
 	entrepriseUUID := c.Params("entreprise_uuid")
 	posUUID := c.Params("pos_uuid")
 
-	sync_created := c.Query("sync_created", "2023-01-01")
+	sync_created, syncDone := middlewares.SyncCursor(c, "zones")
 	var data []models.Zone
 
 	if posUUID == "-" {
@@ -34,6 +35,8 @@ func GetDataSynchronisation(c *fiber.Ctx) error {
 			Preload("Pos").
 			Find(&data)
 	}
+	syncDone()
+
 	return c.JSON(fiber.Map{
 		"status":  "success",
 		"message": "All Zones",
@@ -43,7 +46,7 @@ func GetDataSynchronisation(c *fiber.Ctx) error {
 
 // Paginate
 func GetPaginatedZone(c *fiber.Ctx) error {
-	db := database.DB
+	db := database.DB.WithContext(c.UserContext())
 	entrepriseUUID := c.Params("entreprise_uuid")
 	posUUID := c.Params("pos_uuid")
 
@@ -108,7 +111,7 @@ func GetPaginatedZone(c *fiber.Ctx) error {
 // Get All data
 func GetAllZones(c *fiber.Ctx) error {
 	entrepriseUUID := c.Params("entreprise_uuid")
-	db := database.DB
+	db := database.DB.WithContext(c.UserContext())
 
 	var data []models.Zone
 	db.Where("entreprise_uuid = ?", entrepriseUUID).Find(&data)
@@ -122,7 +125,7 @@ func GetAllZones(c *fiber.Ctx) error {
 // Get one data
 func GetZone(c *fiber.Ctx) error {
 	uuid := c.Params("uuid")
-	db := database.DB
+	db := database.DB.WithContext(c.UserContext())
 
 	var zone models.Zone
 	db.Where("uuid = ?", uuid).First(&zone)
@@ -166,7 +169,7 @@ func CreateZone(c *fiber.Ctx) error {
 	}
 
 	p.Sync = true
-	database.DB.Create(p)
+	database.DB.WithContext(c.UserContext()).Create(p)
 
 	return c.JSON(
 		fiber.Map{
@@ -180,7 +183,7 @@ func CreateZone(c *fiber.Ctx) error {
 // Update data
 func UpdateZone(c *fiber.Ctx) error {
 	uuid := c.Params("uuid")
-	db := database.DB
+	db := database.DB.WithContext(c.UserContext())
 
 	type UpdateData struct {
 		Name           string `json:"name"`
@@ -203,12 +206,25 @@ func UpdateZone(c *fiber.Ctx) error {
 
 	zone := new(models.Zone)
 
-	db.Where("uuid = ?", uuid).First(&zone)
+	if err := db.Where("uuid = ?", uuid).First(&zone).Error; err != nil {
+		return c.Status(404).JSON(
+			fiber.Map{
+				"status":  "error",
+				"message": "No zone found",
+				"data":    nil,
+			},
+		)
+	}
+	server := *zone
 	zone.Name = updateData.Name
 	zone.Description = updateData.Description
 	zone.Signature = updateData.Signature
 	zone.EntrepriseUUID = updateData.EntrepriseUUID
 
+	if conflict := middlewares.CheckConflict(c, db, &server, zone); conflict != nil {
+		return middlewares.ConflictResponse(c, conflict)
+	}
+
 	db.Save(&zone)
 
 	return c.JSON(
@@ -224,7 +240,7 @@ func UpdateZone(c *fiber.Ctx) error {
 func DeleteZone(c *fiber.Ctx) error {
 	uuid := c.Params("uuid")
 
-	db := database.DB
+	db := database.DB.WithContext(c.UserContext())
 
 	var zone models.Zone
 	db.Where("uuid = ?", uuid).First(&zone)
@@ -250,7 +266,7 @@ func DeleteZone(c *fiber.Ctx) error {
 }
 
 func UploadCsvDataZone(c *fiber.Ctx) error {
-	db := database.DB
+	db := database.DB.WithContext(c.UserContext())
 
 	type UploadCSV struct {
 		Data           []models.Zone `json:"data"`
@@ -0,0 +1,289 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/kgermando/ipos-stock-api/models"
+	"github.com/kgermando/ipos-stock-api/utils"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrAuditImmutable est retournée lors d'une tentative de modification du journal d'audit
+var ErrAuditImmutable = errors.New("le journal d'audit ne peut pas être modifié")
+
+// Actor identifie l'auteur des modifications enregistrées dans le journal d'audit
+type Actor struct {
+	UserUUID string
+	Type     string // user ou api_key
+	IP       string
+}
+
+type actorKey struct{}
+
+// WithActor attache l'auteur de la requête au contexte utilisé par GORM
+func WithActor(ctx context.Context, a Actor) context.Context {
+	return context.WithValue(ctx, actorKey{}, a)
+}
+
+// ActorFromContext retourne l'auteur attaché au contexte, s'il existe
+func ActorFromContext(ctx context.Context) Actor {
+	if ctx == nil {
+		return Actor{}
+	}
+	a, _ := ctx.Value(actorKey{}).(Actor)
+	return a
+}
+
+// auditMaxRows limite le nombre de lignes tracées pour une mise à jour groupée
+const auditMaxRows = 500
+
+// auditSkipped liste les modèles techniques qui ne sont pas tracés
+var auditSkipped = map[string]bool{
+	"AuditLog":         true,
+	"Session":          true,
+	"DeviceSyncCursor": true,
+	"IdempotencyKey":   true,
+	"LoginThrottle":    true,
+	"SecurityEvent":    true,
+	"RecoveryCode":     true,
+	"PasswordReset":    true,
+}
+
+// auditIgnored liste les colonnes dont la modification seule ne justifie pas
+// une entrée d'audit ; auditRedacted celles dont la valeur est masquée
+var (
+	auditIgnored = map[string]bool{
+		"updated_at": true, "last_seen_at": true, "last_sync_at": true,
+		"last_used_at": true, "last_used_ip": true, "two_factor_last_step": true,
+	}
+	auditRedacted = map[string]bool{
+		"password": true, "pin_hash": true, "two_factor_secret": true,
+		"token_hash": true, "key_hash": true, "refresh_token_hash": true,
+	}
+)
+
+const auditBeforeKey = "audit:before"
+
+func registerAuditCallbacks(db *gorm.DB) {
+	db.Callback().Create().After("gorm:create").Before("gorm:commit_or_rollback_transaction").Register("audit:create", auditCreate)
+
+	db.Callback().Update().After("gorm:setup_reflect_value").Before("gorm:update").Register("audit:before_update", auditCapture)
+	db.Callback().Update().After("gorm:update").Before("gorm:commit_or_rollback_transaction").Register("audit:update", auditUpdate)
+
+	db.Callback().Delete().Before("gorm:delete").Register("audit:before_delete", auditCapture)
+	db.Callback().Delete().After("gorm:delete").Before("gorm:commit_or_rollback_transaction").Register("audit:delete", auditDelete)
+}
+
+func audited(db *gorm.DB) bool {
+	return db.Error == nil && db.Statement.Schema != nil &&
+		db.Statement.Schema.PrioritizedPrimaryField != nil &&
+		!auditSkipped[db.Statement.Schema.Name]
+}
+
+// auditCapture lit l'état des lignes visées avant une mise à jour ou une suppression.
+// Le journal lui-même est protégé : toute modification est refusée.
+func auditCapture(db *gorm.DB) {
+	if db.Statement.Schema != nil && db.Statement.Schema.Name == "AuditLog" {
+		db.AddError(ErrAuditImmutable)
+		return
+	}
+	if !audited(db) {
+		return
+	}
+
+	pk := db.Statement.Schema.PrioritizedPrimaryField
+	q := db.Session(&gorm.Session{NewDB: true}).Table(db.Statement.Table)
+
+	if id, ok := auditPrimaryKey(db); ok {
+		q = q.Where(clause.Eq{Column: clause.Column{Name: pk.DBName}, Value: id})
+	} else if where, ok := db.Statement.Clauses["WHERE"]; ok {
+		q = q.Clauses(where.Expression)
+	} else {
+		return
+	}
+
+	var rows []map[string]interface{}
+	if err := q.Limit(auditMaxRows).Find(&rows).Error; err != nil || len(rows) == 0 {
+		return
+	}
+	db.InstanceSet(auditBeforeKey, rows)
+}
+
+func auditCreate(db *gorm.DB) {
+	if !audited(db) || db.RowsAffected == 0 {
+		return
+	}
+
+	var ids []interface{}
+	switch rv := db.Statement.ReflectValue; rv.Kind() {
+	case reflect.Struct:
+		if id, isZero := db.Statement.Schema.PrioritizedPrimaryField.ValueOf(db.Statement.Context, rv); !isZero {
+			ids = append(ids, id)
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < rv.Len() && i < auditMaxRows; i++ {
+			elem := reflect.Indirect(rv.Index(i))
+			if id, isZero := db.Statement.Schema.PrioritizedPrimaryField.ValueOf(db.Statement.Context, elem); !isZero {
+				ids = append(ids, id)
+			}
+		}
+	}
+
+	for _, row := range auditReload(db, ids) {
+		writeAudit(db, models.AuditCreate, nil, row)
+	}
+}
+
+func auditUpdate(db *gorm.DB) {
+	before, ok := auditBeforeRows(db)
+	if !ok || db.RowsAffected == 0 {
+		return
+	}
+
+	after := map[string]map[string]interface{}{}
+	for _, row := range auditReload(db, auditIDs(db, before)) {
+		after[auditRowID(db, row)] = row
+	}
+
+	for _, old := range before {
+		row, ok := after[auditRowID(db, old)]
+		if !ok {
+			continue
+		}
+
+		changedBefore, changedAfter := map[string]interface{}{}, map[string]interface{}{}
+		for column, value := range row {
+			if auditIgnored[column] || reflect.DeepEqual(old[column], value) {
+				continue
+			}
+			changedBefore[column], changedAfter[column] = old[column], value
+		}
+		if len(changedAfter) == 0 {
+			continue
+		}
+
+		// Conserve l'identification du tenant pour les colonnes inchangées
+		for _, column := range []string{"uuid", "entreprise_uuid", "pos_uuid"} {
+			if _, ok := changedAfter[column]; !ok {
+				if v, ok := row[column]; ok {
+					changedAfter[column] = v
+				}
+			}
+		}
+		writeAudit(db, models.AuditUpdate, changedBefore, changedAfter)
+	}
+}
+
+func auditDelete(db *gorm.DB) {
+	before, ok := auditBeforeRows(db)
+	if !ok || db.RowsAffected == 0 {
+		return
+	}
+	for _, row := range before {
+		writeAudit(db, models.AuditDelete, row, nil)
+	}
+}
+
+func auditBeforeRows(db *gorm.DB) ([]map[string]interface{}, bool) {
+	if !audited(db) {
+		return nil, false
+	}
+	v, ok := db.InstanceGet(auditBeforeKey)
+	if !ok {
+		return nil, false
+	}
+	rows, ok := v.([]map[string]interface{})
+	return rows, ok && len(rows) > 0
+}
+
+// auditPrimaryKey retourne la clé primaire du modèle passé à la requête, si elle est renseignée
+func auditPrimaryKey(db *gorm.DB) (interface{}, bool) {
+	rv := db.Statement.ReflectValue
+	if rv.Kind() != reflect.Struct {
+		return nil, false
+	}
+	id, isZero := db.Statement.Schema.PrioritizedPrimaryField.ValueOf(db.Statement.Context, rv)
+	return id, !isZero
+}
+
+func auditIDs(db *gorm.DB, rows []map[string]interface{}) []interface{} {
+	pk := db.Statement.Schema.PrioritizedPrimaryField.DBName
+	ids := make([]interface{}, 0, len(rows))
+	for _, row := range rows {
+		ids = append(ids, row[pk])
+	}
+	return ids
+}
+
+func auditRowID(db *gorm.DB, row map[string]interface{}) string {
+	return fmt.Sprint(row[db.Statement.Schema.PrioritizedPrimaryField.DBName])
+}
+
+// auditReload relit les lignes par clé primaire, dans la même transaction
+func auditReload(db *gorm.DB, ids []interface{}) []map[string]interface{} {
+	if len(ids) == 0 {
+		return nil
+	}
+	var rows []map[string]interface{}
+	db.Session(&gorm.Session{NewDB: true}).
+		Table(db.Statement.Table).
+		Where(clause.IN{Column: clause.Column{Name: db.Statement.Schema.PrioritizedPrimaryField.DBName}, Values: ids}).
+		Find(&rows)
+	return rows
+}
+
+func writeAudit(db *gorm.DB, action string, before, after map[string]interface{}) {
+	row := after
+	if row == nil {
+		row = before
+	}
+
+	actor := ActorFromContext(db.Statement.Context)
+	entry := &models.AuditLog{
+		UUID:       utils.GenerateUUID(),
+		Action:     action,
+		Entity:     db.Statement.Table,
+		EntityUUID: auditRowID(db, row),
+		Before:     auditJSON(before),
+		After:      auditJSON(after),
+		UserUUID:   actor.UserUUID,
+		ActorType:  actor.Type,
+		IP:         actor.IP,
+	}
+
+	if db.Statement.Schema.Name == "Entreprise" {
+		entry.EntrepriseUUID = entry.EntityUUID
+	} else if v, ok := row["entreprise_uuid"].(string); ok {
+		entry.EntrepriseUUID = v
+	}
+	if v, ok := row["pos_uuid"].(string); ok {
+		entry.PosUUID = v
+	}
+
+	if err := db.Session(&gorm.Session{NewDB: true}).Create(entry).Error; err != nil {
+		db.AddError(fmt.Errorf("audit: %w", err))
+	}
+}
+
+func auditJSON(row map[string]interface{}) json.RawMessage {
+	if row == nil {
+		return nil
+	}
+	clean := make(map[string]interface{}, len(row))
+	for column, value := range row {
+		if auditRedacted[column] {
+			value = "***"
+		}
+		clean[column] = value
+	}
+	data, err := json.Marshal(clean)
+	if err != nil {
+		return nil
+	}
+	return data
+}
@@ -0,0 +1,117 @@
+package database
+
+import (
+	"log"
+	"strings"
+
+	"github.com/kgermando/ipos-stock-api/models"
+	"github.com/kgermando/ipos-stock-api/utils"
+
+	"gorm.io/gorm"
+)
+
+// backfillCommandeLinePrices fige les prix des lignes de commande enregistrées
+// avant l'ajout des colonnes. Les prix d'origine n'étant pas connus, les prix
+// actuels sont repris sans remise, comme les rapports les calculaient jusque-là.
+// Seules les lignes sans prix figé sont modifiées : la migration peut être
+// rejouée à chaque démarrage.
+func backfillCommandeLinePrices(db *gorm.DB) {
+	db.Exec(`UPDATE commande_lines cl
+		SET unit_price = p.prix_vente, unit_cost = p.prix_achat, tva_rate = p.tva
+		FROM products p
+		WHERE cl.product_uuid = p.uuid AND cl.unit_price = 0 AND cl.total_ht = 0`)
+
+	db.Exec(`UPDATE commande_lines cl
+		SET unit_price = pl.prix, tva_rate = pl.tva
+		FROM plats pl
+		WHERE cl.plat_uuid = pl.uuid AND cl.unit_price = 0 AND cl.total_ht = 0`)
+
+	db.Exec(`UPDATE commande_lines
+		SET total_ht = ROUND(CAST(unit_price * quantity AS numeric), 2),
+			tva_amount = ROUND(CAST(unit_price * quantity * tva_rate / 100 AS numeric), 2),
+			total_ttc = ROUND(CAST(unit_price * quantity AS numeric), 2)
+				+ ROUND(CAST(unit_price * quantity * tva_rate / 100 AS numeric), 2)
+		WHERE total_ht = 0 AND unit_price <> 0`)
+}
+
+// normalizeCommandeStatuses ramène les statuts libres des commandes
+// enregistrées avant le cycle de vie aux statuts reconnus ; les valeurs
+// inconnues sont considérées comme des commandes ouvertes. La migration peut
+// être rejouée à chaque démarrage.
+func normalizeCommandeStatuses(db *gorm.DB) {
+	db.Exec(`UPDATE commandes SET status = 'paid'
+		WHERE LOWER(status) IN ('fermée', 'fermee', 'closed', 'completed')`)
+
+	db.Exec(`UPDATE commandes SET status = 'cancelled'
+		WHERE LOWER(status) IN ('annulée', 'annulee', 'canceled')`)
+
+	db.Exec(`UPDATE commandes SET status = 'open'
+		WHERE status IS NULL OR status NOT IN
+			('draft', 'open', 'sent_to_kitchen', 'served', 'paid', 'cancelled', 'refunded')`)
+}
+
+// normalizeUserRoles ramène les rôles libres des comptes créés avant le
+// contrôle d'accès aux rôles reconnus, sans quoi ils n'auraient aucune
+// permission. Par prudence, aucun compte ne devient super admin : les anciens
+// administrateurs deviennent gérants de leur entreprise (voir
+// bootstrapSuperAdmin). La migration peut être rejouée à chaque démarrage.
+func normalizeUserRoles(db *gorm.DB) {
+	known := `('super_admin', 'entreprise_manager', 'pos_manager', 'cashier', 'stock_keeper', 'livreur', 'api_key')`
+
+	legacy := []struct {
+		role   string
+		labels string
+	}{
+		{"entreprise_manager", `('admin', 'administrateur', 'administrator', 'superadmin', 'super admin',
+			'super-admin', 'manager', 'gérant', 'gerant', 'directeur', 'director', 'owner',
+			'propriétaire', 'proprietaire', 'patron', 'dg')`},
+		{"pos_manager", `('gérant pos', 'gerant pos', 'manager pos', 'pos manager', 'pos_manager',
+			'responsable', 'responsable pos', 'superviseur', 'supervisor', 'chef de point de vente')`},
+		{"cashier", `('caissier', 'caissière', 'caissiere', 'caisse', 'cashier', 'vendeur',
+			'vendeuse', 'seller', 'serveur', 'serveuse', 'waiter', 'agent')`},
+		{"stock_keeper", `('magasinier', 'stock', 'stocks', 'stockiste', 'stock keeper',
+			'stock_keeper', 'gestionnaire de stock', 'gestionnaire stock')`},
+		{"livreur", `('livreur', 'livreuse', 'coursier', 'delivery', 'driver')`},
+	}
+	for _, l := range legacy {
+		db.Exec(`UPDATE users SET role = ? WHERE role NOT IN `+known+
+			` AND LOWER(TRIM(role)) IN `+l.labels, l.role)
+	}
+
+	// Un rôle non reconnu ne donne aucune permission ; il est signalé et laissé
+	// tel quel pour qu'un administrateur le corrige
+	var unknown []string
+	db.Raw(`SELECT DISTINCT COALESCE(role, '') FROM users WHERE deleted_at IS NULL AND (role IS NULL OR role NOT IN ` + known + `)`).
+		Scan(&unknown)
+	if len(unknown) > 0 {
+		log.Printf("rôles utilisateur non reconnus, comptes sans permission : %q", unknown)
+	}
+}
+
+// bootstrapSuperAdmin attribue le rôle super_admin au compte dont l'adresse
+// est donnée par SUPER_ADMIN_EMAIL ; aucun autre chemin ne crée ce rôle.
+// Le compte doit déjà exister. Sans effet si la variable est vide.
+func bootstrapSuperAdmin(db *gorm.DB) {
+	email := strings.TrimSpace(utils.Env("SUPER_ADMIN_EMAIL"))
+	if email == "" {
+		return
+	}
+	res := db.Exec(`UPDATE users SET role = ? WHERE LOWER(email) = LOWER(?) AND deleted_at IS NULL`,
+		models.RoleSuperAdmin, email)
+	if res.Error != nil {
+		log.Printf("super_admin %s : %v", email, res.Error)
+		return
+	}
+	if res.RowsAffected == 0 {
+		log.Printf("super_admin %s : aucun compte avec cette adresse", email)
+	}
+}
+
+// resetDeviceSyncCursors abandonne les curseurs de synchronisation tenus sans
+// POS, avant la migration qui ajoute le POS à leur clé : chaque terminal se
+// resynchronise entièrement une fois. Sans effet une fois la table migrée.
+func resetDeviceSyncCursors(db *gorm.DB) {
+	if db.Migrator().HasTable(&models.DeviceSyncCursor{}) && !db.Migrator().HasColumn(&models.DeviceSyncCursor{}, "PosUUID") {
+		db.Migrator().DropTable(&models.DeviceSyncCursor{})
+	}
+}
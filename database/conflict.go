@@ -0,0 +1,196 @@
+package database
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/kgermando/ipos-stock-api/models"
+	"github.com/kgermando/ipos-stock-api/utils"
+	"gorm.io/gorm"
+)
+
+// Precondition décrit la version sur laquelle le client a basé sa modification
+type Precondition struct {
+	UpdatedAt  *time.Time             // nil : pas de précondition, la dernière écriture l'emporte
+	Base       map[string]interface{} // Valeurs lues par le client, nécessaires pour fusionner les champs modifiés des deux côtés
+	Source     string                 // api ou sync
+	DeviceUUID string
+}
+
+// Conflict décrit une écriture faite sur une version périmée d'un enregistrement
+type Conflict struct {
+	Log    models.ConflictLog
+	Server interface{} // État actuel de l'enregistrement sur le serveur
+}
+
+func (c *Conflict) Error() string {
+	return fmt.Sprintf("conflit de version sur %s %s (%s)", c.Log.Entity, c.Log.EntityUUID, c.Log.Fields)
+}
+
+// Rejected indique que la modification du client a été refusée
+func (c *Conflict) Rejected() bool {
+	return c.Log.Resolution == models.ConflictRejected
+}
+
+// conflictIgnored liste les champs d'identité et techniques exclus de la comparaison
+var conflictIgnored = map[string]bool{
+	"uuid": true, "CreatedAt": true, "UpdatedAt": true, "DeletedAt": true,
+	"entreprise_uuid": true, "pos_uuid": true, "signature": true, "sync": true,
+}
+
+// ResolveConflict compare la version attendue par le client à celle du serveur
+// et applique la règle de résolution de la table. Il retourne nil s'il n'y a
+// pas de conflit. Sinon le conflit doit être journalisé avec RecordConflict ;
+// en cas de fusion, client reçoit les valeurs retenues.
+func ResolveConflict(db *gorm.DB, server, client interface{}, pre Precondition) *Conflict {
+	if pre.UpdatedAt == nil {
+		return nil
+	}
+	serverAt, _ := reflect.ValueOf(server).Elem().FieldByName("UpdatedAt").Interface().(time.Time)
+	if serverAt.Truncate(time.Microsecond).Equal(pre.UpdatedAt.Truncate(time.Microsecond)) {
+		return nil
+	}
+
+	serverData, clientData := conflictData(server), conflictData(client)
+
+	// Champs que le client veut modifier alors que le serveur a une autre valeur
+	var changed []string
+	for field, value := range clientData {
+		if current, ok := serverData[field]; ok && !conflictIgnored[field] && !reflect.DeepEqual(current, value) {
+			changed = append(changed, field)
+		}
+	}
+	if len(changed) == 0 {
+		return nil
+	}
+	sort.Strings(changed)
+
+	stmt := &gorm.Statement{DB: db}
+	stmt.Parse(server)
+	entrepriseUUID, _ := serverData["entreprise_uuid"].(string)
+	posUUID, _ := serverData["pos_uuid"].(string)
+	entityUUID, _ := serverData["uuid"].(string)
+	rule := conflictRule(db, stmt.Table, entrepriseUUID)
+
+	conflict := &Conflict{
+		Server: server,
+		Log: models.ConflictLog{
+			UUID:            utils.GenerateUUID(),
+			Entity:          stmt.Table,
+			EntityUUID:      entityUUID,
+			Source:          pre.Source,
+			Policy:          rule.Policy,
+			Fields:          strings.Join(changed, ","),
+			BaseUpdatedAt:   *pre.UpdatedAt,
+			ServerUpdatedAt: serverAt,
+			ClientData:      conflictJSON(clientData),
+			ServerData:      conflictJSON(serverData),
+			UserUUID:        ActorFromContext(db.Statement.Context).UserUUID,
+			DeviceUUID:      pre.DeviceUUID,
+			EntrepriseUUID:  entrepriseUUID,
+			PosUUID:         posUUID,
+		},
+	}
+
+	switch rule.Policy {
+	case models.ConflictClientWins:
+		conflict.Log.Resolution = models.ConflictOverwritten
+
+	case models.ConflictMerge:
+		merged, ok := mergeFields(rule, pre.Base, serverData, clientData, changed)
+		if !ok {
+			conflict.Log.Resolution = models.ConflictRejected
+			break
+		}
+		raw, _ := json.Marshal(merged)
+		if err := json.Unmarshal(raw, client); err != nil {
+			conflict.Log.Resolution = models.ConflictRejected
+			break
+		}
+		conflict.Log.Resolution = models.ConflictMerged
+		conflict.Log.ResultData = conflictJSON(conflictData(client))
+
+	default:
+		conflict.Log.Resolution = models.ConflictRejected
+	}
+
+	return conflict
+}
+
+// RecordConflict enregistre le conflit dans le journal consulté par les gérants
+func RecordConflict(db *gorm.DB, conflict *Conflict) error {
+	return db.Create(&conflict.Log).Error
+}
+
+// conflictRule retourne la règle de l'entreprise pour la table, à défaut la
+// règle par défaut, sinon server_wins
+func conflictRule(db *gorm.DB, entity, entrepriseUUID string) models.ConflictRule {
+	var rule models.ConflictRule
+	if entrepriseUUID != "" {
+		db.Where("entreprise_uuid = ? AND entity = ?", entrepriseUUID, entity).Limit(1).Find(&rule)
+	}
+	if rule.UUID != "" {
+		return rule
+	}
+	if rule, ok := models.DefaultConflictRules[entity]; ok {
+		return rule
+	}
+	return models.ConflictRule{Entity: entity, Policy: models.ConflictServerWins}
+}
+
+// mergeFields fusionne champ par champ à partir des valeurs de base du client :
+// un champ modifié d'un seul côté garde cette modification, un compteur
+// modifié des deux côtés reçoit la somme des deux variations. Sans valeur de
+// base, un champ modifié ne peut pas être fusionné.
+func mergeFields(rule models.ConflictRule, base, server, client map[string]interface{}, changed []string) (map[string]interface{}, bool) {
+	merged := map[string]interface{}{}
+	for _, field := range changed {
+		b, known := base[field]
+		if !known {
+			return nil, false
+		}
+		s, c := server[field], client[field]
+
+		switch {
+		case reflect.DeepEqual(c, b):
+			merged[field] = s // Seul le serveur a modifié ce champ
+		case reflect.DeepEqual(s, b):
+			merged[field] = c // Seul le client a modifié ce champ
+		default:
+			sn, ok1 := s.(float64)
+			cn, ok2 := c.(float64)
+			bn, ok3 := b.(float64)
+			if !rule.IsMergeField(field) || !ok1 || !ok2 || !ok3 {
+				return nil, false
+			}
+			merged[field] = sn + (cn - bn)
+		}
+	}
+	return merged, true
+}
+
+// conflictData retourne les champs simples du modèle, indexés par leur nom JSON
+func conflictData(model interface{}) map[string]interface{} {
+	raw, err := json.Marshal(model)
+	if err != nil {
+		return nil
+	}
+	var data map[string]interface{}
+	json.Unmarshal(raw, &data)
+	for field, value := range data {
+		switch value.(type) {
+		case map[string]interface{}, []interface{}:
+			delete(data, field) // Associations
+		}
+	}
+	return data
+}
+
+func conflictJSON(data map[string]interface{}) json.RawMessage {
+	raw, _ := json.Marshal(data)
+	return raw
+}
@@ -30,27 +30,62 @@ func Connect() {
 	DB = connection
 	fmt.Println("Database Connected 🎉!")
 
+	registerTenantCallbacks(connection)
+	registerAuditCallbacks(connection)
+
+	resetDeviceSyncCursors(connection)
+
 	connection.AutoMigrate(
 		&models.Abonnement{},
+		&models.ApiKey{},
+		&models.AuditLog{},
 		&models.Caisse{},
 		&models.CaisseItem{},
 		&models.Client{},
 		&models.Commande{},
 		&models.CommandeLine{},
+		&models.CommandeStatusHistory{},
+		&models.ConflictLog{},
+		&models.ConflictRule{},
+		&models.Device{},
+		&models.DeviceSyncCursor{},
 		&models.Entreprise{},
+		&models.FiscalJob{},
 		&models.Fournisseur{},
+		&models.IdempotencyKey{},
+		&models.Invitation{},
 		&models.Livraison{},
 		&models.Livreur{},
+		&models.LoginThrottle{},
+		&models.NumberSequence{},
+		&models.NumberingPattern{},
 		&models.PasswordReset{},
+		&models.Payment{},
 		&models.Plat{},
 		&models.Pos{},
 		&models.Product{},
+		&models.Promotion{},
+		&models.ReceiptTemplate{},
+		&models.RecoveryCode{},
 		&models.Reservation{},
 		&models.Restitution{},
+		&models.SaleReturn{},
+		&models.SaleReturnLine{},
+		&models.SecurityEvent{},
+		&models.Session{},
 		&models.Stock{},
 		&models.StockEndommage{},
 		&models.User{},
 		&models.TableBox{},
 		&models.Zone{},
 	)
+
+	// Le journal d'audit est en ajout seul, y compris pour les requêtes SQL brutes
+	connection.Exec("CREATE OR REPLACE RULE audit_logs_no_update AS ON UPDATE TO audit_logs DO INSTEAD NOTHING")
+	connection.Exec("CREATE OR REPLACE RULE audit_logs_no_delete AS ON DELETE TO audit_logs DO INSTEAD NOTHING")
+
+	backfillCommandeLinePrices(connection)
+	normalizeCommandeStatuses(connection)
+	normalizeUserRoles(connection)
+	bootstrapSuperAdmin(connection)
 }
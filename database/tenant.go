@@ -0,0 +1,139 @@
+package database
+
+import (
+	"context"
+	"reflect"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Tenant représente le périmètre de données de l'utilisateur connecté.
+// PosUUID vide signifie que l'utilisateur voit tous les POS de son entreprise.
+type Tenant struct {
+	EntrepriseUUID string
+	PosUUID        string
+}
+
+type tenantKey struct{}
+
+// WithTenant attache le périmètre au contexte utilisé par les requêtes GORM
+func WithTenant(ctx context.Context, t Tenant) context.Context {
+	return context.WithValue(ctx, tenantKey{}, t)
+}
+
+// TenantFromContext retourne le périmètre attaché au contexte, s'il existe
+func TenantFromContext(ctx context.Context) (Tenant, bool) {
+	if ctx == nil {
+		return Tenant{}, false
+	}
+	t, ok := ctx.Value(tenantKey{}).(Tenant)
+	return t, ok && t.EntrepriseUUID != ""
+}
+
+// registerTenantCallbacks ajoute automatiquement les filtres entreprise/POS
+// à toute requête GORM exécutée avec un contexte portant un Tenant
+func registerTenantCallbacks(db *gorm.DB) {
+	db.Callback().Query().Before("gorm:query").Register("tenant:query", tenantWhere)
+	db.Callback().Row().Before("gorm:row").Register("tenant:row", tenantWhere)
+	db.Callback().Update().Before("gorm:update").Register("tenant:update", tenantUpdate)
+	db.Callback().Delete().Before("gorm:delete").Register("tenant:delete", tenantDelete)
+	db.Callback().Create().Before("gorm:create").Register("tenant:create", tenantCreate)
+}
+
+func tenantWhere(db *gorm.DB) {
+	t, ok := TenantFromContext(db.Statement.Context)
+	if !ok || db.Statement.Schema == nil {
+		return
+	}
+
+	var exprs []clause.Expression
+	switch db.Statement.Schema.Name {
+	case "Entreprise":
+		exprs = append(exprs, tenantEq("uuid", t.EntrepriseUUID))
+	case "Pos":
+		exprs = append(exprs, tenantEq("entreprise_uuid", t.EntrepriseUUID))
+		if t.PosUUID != "" {
+			exprs = append(exprs, tenantEq("uuid", t.PosUUID))
+		}
+	default:
+		if db.Statement.Schema.LookUpField("EntrepriseUUID") != nil {
+			exprs = append(exprs, tenantEq("entreprise_uuid", t.EntrepriseUUID))
+		}
+		if t.PosUUID != "" && db.Statement.Schema.LookUpField("PosUUID") != nil {
+			exprs = append(exprs, tenantEq("pos_uuid", t.PosUUID))
+		}
+	}
+
+	if len(exprs) > 0 {
+		db.Statement.AddClause(clause.Where{Exprs: exprs})
+	}
+}
+
+func tenantUpdate(db *gorm.DB) {
+	t, ok := TenantFromContext(db.Statement.Context)
+	if !ok || db.Statement.Schema == nil || !hasConditions(db) {
+		return
+	}
+
+	// Empêche de déplacer un enregistrement vers une autre entreprise, ou
+	// vers un autre POS pour un utilisateur rattaché à un POS
+	if db.Statement.Schema.LookUpField("EntrepriseUUID") != nil {
+		db.Statement.SetColumn("EntrepriseUUID", t.EntrepriseUUID, true)
+	}
+	if t.PosUUID != "" && db.Statement.Schema.LookUpField("PosUUID") != nil {
+		db.Statement.SetColumn("PosUUID", t.PosUUID, true)
+	}
+	tenantWhere(db)
+}
+
+func tenantDelete(db *gorm.DB) {
+	if !hasConditions(db) {
+		return
+	}
+	tenantWhere(db)
+}
+
+func tenantCreate(db *gorm.DB) {
+	t, ok := TenantFromContext(db.Statement.Context)
+	if !ok || db.Statement.Schema == nil {
+		return
+	}
+
+	if db.Statement.Schema.LookUpField("EntrepriseUUID") != nil {
+		db.Statement.SetColumn("EntrepriseUUID", t.EntrepriseUUID, true)
+	}
+	if t.PosUUID != "" && db.Statement.Schema.LookUpField("PosUUID") != nil {
+		db.Statement.SetColumn("PosUUID", t.PosUUID, true)
+	}
+}
+
+// hasConditions évite de transformer une mise à jour globale (refusée par GORM)
+// en mise à jour de toutes les lignes du tenant
+func hasConditions(db *gorm.DB) bool {
+	if _, ok := db.Statement.Clauses["WHERE"]; ok {
+		return true
+	}
+
+	field := db.Statement.Schema.PrioritizedPrimaryField
+	if field == nil {
+		return false
+	}
+
+	rv := db.Statement.ReflectValue
+	switch rv.Kind() {
+	case reflect.Struct:
+		_, isZero := field.ValueOf(db.Statement.Context, rv)
+		return !isZero
+	case reflect.Slice, reflect.Array:
+		return rv.Len() > 0
+	}
+	return false
+}
+
+func tenantEq(column, value string) clause.Expression {
+	return clause.Eq{
+		Column: clause.Column{Table: clause.CurrentTable, Name: column},
+		Value:  value,
+	}
+}
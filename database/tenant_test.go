@@ -0,0 +1,128 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/kgermando/ipos-stock-api/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"gorm.io/gorm/utils/tests"
+)
+
+// dryRun ouvre une connexion sans base : les requêtes sont construites par
+// les callbacks du tenant mais jamais exécutées
+func dryRun(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(tests.DummyDialector{}, &gorm.Config{DryRun: true, Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	registerTenantCallbacks(db)
+	return db
+}
+
+var (
+	posTenant        = Tenant{EntrepriseUUID: "entreprise", PosUUID: "pos"}
+	entrepriseTenant = Tenant{EntrepriseUUID: "entreprise"}
+)
+
+func TestTenantWhere(t *testing.T) {
+	tests := []struct {
+		name    string
+		tenant  *Tenant
+		model   interface{}
+		want    []string // Filtres attendus
+		exclude []string // Filtres qui ne doivent pas apparaître
+	}{
+		{
+			name: "utilisateur rattaché à un POS", tenant: &posTenant, model: &[]models.Product{},
+			want: []string{"`products`.`entreprise_uuid` = ?", "`products`.`pos_uuid` = ?"},
+		},
+		{
+			name: "utilisateur de l'entreprise", tenant: &entrepriseTenant, model: &[]models.Product{},
+			want:    []string{"`products`.`entreprise_uuid` = ?"},
+			exclude: []string{"pos_uuid"},
+		},
+		{
+			name: "sans tenant", model: &[]models.Product{},
+			exclude: []string{"entreprise_uuid", "pos_uuid"},
+		},
+		{
+			name: "entreprise : la sienne seulement", tenant: &posTenant, model: &[]models.Entreprise{},
+			want: []string{"`entreprises`.`uuid` = ?"},
+		},
+		{
+			name: "POS : le sien seulement", tenant: &posTenant, model: &[]models.Pos{},
+			want: []string{"`pos`.`entreprise_uuid` = ?", "`pos`.`uuid` = ?"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.tenant != nil {
+				ctx = WithTenant(ctx, *tt.tenant)
+			}
+			sql := dryRun(t).WithContext(ctx).Find(tt.model).Statement.SQL.String()
+			for _, w := range tt.want {
+				if !strings.Contains(sql, w) {
+					t.Errorf("%s : filtre %s absent", sql, w)
+				}
+			}
+			for _, e := range tt.exclude {
+				if strings.Contains(sql, e) {
+					t.Errorf("%s : filtre %s inattendu", sql, e)
+				}
+			}
+		})
+	}
+}
+
+func TestTenantUpdatePinsScope(t *testing.T) {
+	ctx := WithTenant(context.Background(), posTenant)
+
+	// Le terminal tente de déplacer le produit vers une autre entreprise et un autre POS
+	stmt := dryRun(t).WithContext(ctx).Model(&models.Product{UUID: "produit"}).
+		Updates(models.Product{Name: "Savon", EntrepriseUUID: "autre", PosUUID: "autre-pos"}).Statement
+	sql := stmt.SQL.String()
+
+	if !strings.Contains(sql, "`products`.`entreprise_uuid` = ?") || !strings.Contains(sql, "`products`.`pos_uuid` = ?") {
+		t.Errorf("%s : la mise à jour n'est pas limitée au tenant", sql)
+	}
+	if slices.Contains(stmt.Vars, interface{}("autre")) || slices.Contains(stmt.Vars, interface{}("autre-pos")) {
+		t.Errorf("%s %v : l'enregistrement quitte le tenant", sql, stmt.Vars)
+	}
+}
+
+func TestTenantUpdateWithoutConditions(t *testing.T) {
+	// Une mise à jour globale reste refusée par GORM au lieu de viser tout le tenant
+	ctx := WithTenant(context.Background(), posTenant)
+	err := dryRun(t).WithContext(ctx).Model(&models.Product{}).
+		Updates(map[string]interface{}{"name": "Savon"}).Error
+	if !errors.Is(err, gorm.ErrMissingWhereClause) {
+		t.Errorf("erreur = %v, attendu %v", err, gorm.ErrMissingWhereClause)
+	}
+}
+
+func TestTenantCreatePinsScope(t *testing.T) {
+	tests := []struct {
+		name   string
+		tenant Tenant
+		pos    string
+	}{
+		{"utilisateur rattaché à un POS", posTenant, "pos"},
+		{"utilisateur de l'entreprise : POS choisi", entrepriseTenant, "autre-pos"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			product := models.Product{UUID: "produit", EntrepriseUUID: "autre", PosUUID: "autre-pos"}
+			dryRun(t).WithContext(WithTenant(context.Background(), tt.tenant)).Create(&product)
+			if product.EntrepriseUUID != "entreprise" || product.PosUUID != tt.pos {
+				t.Errorf("créé dans %s/%s, attendu entreprise/%s", product.EntrepriseUUID, product.PosUUID, tt.pos)
+			}
+		})
+	}
+}
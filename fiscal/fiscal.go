@@ -0,0 +1,188 @@
+package fiscal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kgermando/ipos-stock-api/models"
+	"github.com/kgermando/ipos-stock-api/pricing"
+	"github.com/kgermando/ipos-stock-api/utils"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrUnavailable : le dispositif ne répond pas ; la certification est
+	// remise en file d'attente
+	ErrUnavailable = errors.New("dispositif fiscal indisponible")
+	// ErrRejected : le dispositif refuse la facture ; elle n'est pas retentée
+	// automatiquement
+	ErrRejected = errors.New("facture refusée par le dispositif fiscal")
+	// ErrUnknownDevice : FISCAL_DEVICE ne correspond à aucun dispositif enregistré
+	ErrUnknownDevice = errors.New("dispositif fiscal inconnu")
+)
+
+// Type de facture normalisée
+const InvoiceSale = "FV" // Facture de vente
+
+// Item est une ligne de la facture transmise au dispositif
+type Item struct {
+	Name      string  `json:"name"`
+	Quantity  uint64  `json:"quantity"`
+	UnitPrice float64 `json:"unit_price"` // Prix unitaire HT
+	TvaRate   float64 `json:"tva_rate"`
+	TotalHt   float64 `json:"total_ht"`
+	TvaAmount float64 `json:"tva_amount"`
+	TotalTtc  float64 `json:"total_ttc"`
+}
+
+// Invoice est la facture soumise à certification
+type Invoice struct {
+	Type       string    `json:"type"`
+	Number     string    `json:"number"`
+	Date       time.Time `json:"date"`
+	NImpot     string    `json:"nimpot"` // Numéro d'impôt de l'entreprise émettrice
+	Entreprise string    `json:"entreprise"`
+	Pos        string    `json:"pos"`
+	Cashier    string    `json:"cashier"`
+	Client     string    `json:"client"`
+	Currency   string    `json:"currency"`
+	Items      []Item    `json:"items"`
+	TotalHt    float64   `json:"total_ht"`
+	TotalTva   float64   `json:"total_tva"`
+	TotalTtc   float64   `json:"total_ttc"`
+}
+
+// Certificate est la réponse du dispositif pour une facture certifiée
+type Certificate struct {
+	Device      string
+	Counters    string
+	Signature   string
+	QRCode      string
+	CertifiedAt time.Time
+}
+
+// Certifier est un dispositif électronique fiscal. Certify doit retourner une
+// erreur enveloppant ErrUnavailable quand le dispositif est injoignable, et
+// ErrRejected quand il refuse la facture.
+type Certifier interface {
+	Name() string
+	Certify(ctx context.Context, invoice Invoice) (Certificate, error)
+}
+
+// Factory crée un dispositif à partir de la configuration de l'environnement
+type Factory func() (Certifier, error)
+
+var (
+	mu        sync.RWMutex
+	factories = map[string]Factory{}
+	current   Certifier
+)
+
+// Register rend un dispositif sélectionnable par FISCAL_DEVICE
+func Register(name string, factory Factory) {
+	mu.Lock()
+	defer mu.Unlock()
+	factories[name] = factory
+}
+
+// Use installe le dispositif utilisé pour certifier ; nil désactive la certification
+func Use(c Certifier) {
+	mu.Lock()
+	defer mu.Unlock()
+	current = c
+}
+
+// Current retourne le dispositif installé, nil si la certification est désactivée
+func Current() Certifier {
+	mu.RLock()
+	defer mu.RUnlock()
+	return current
+}
+
+// Enabled indique si les commandes réglées sont soumises à certification
+func Enabled() bool {
+	return Current() != nil
+}
+
+// Start installe le dispositif désigné par FISCAL_DEVICE et lance le
+// traitement de la file d'attente. Sans FISCAL_DEVICE, rien n'est certifié.
+func Start(ctx context.Context, db *gorm.DB) error {
+	name := strings.TrimSpace(utils.Env("FISCAL_DEVICE"))
+	if name == "" {
+		return nil
+	}
+
+	mu.RLock()
+	factory, ok := factories[name]
+	mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("%w : %s", ErrUnknownDevice, name)
+	}
+	certifier, err := factory()
+	if err != nil {
+		return err
+	}
+	Use(certifier)
+	log.Printf("Certification fiscale : dispositif %s", certifier.Name())
+
+	go Run(ctx, db)
+	return nil
+}
+
+// invoice construit la facture d'une commande à partir des données du serveur
+func invoice(tx *gorm.DB, commande models.Commande) (Invoice, error) {
+	var pos models.Pos
+	if err := tx.Where("uuid = ?", commande.PosUUID).First(&pos).Error; err != nil {
+		return Invoice{}, err
+	}
+	var entreprise models.Entreprise
+	if err := tx.Where("uuid = ?", pos.EntrepriseUUID).First(&entreprise).Error; err != nil {
+		return Invoice{}, err
+	}
+
+	inv := Invoice{
+		Type:       InvoiceSale,
+		Number:     commande.Ncommande,
+		Date:       commande.CreatedAt,
+		NImpot:     strings.TrimSpace(entreprise.NImpot),
+		Entreprise: entreprise.Name,
+		Pos:        pos.Name,
+		Currency:   pricing.LoadPolicy(tx, pos.EntrepriseUUID).Currency,
+		TotalHt:    commande.TotalHt,
+		TotalTva:   commande.TotalTva,
+		TotalTtc:   commande.TotalTtc,
+	}
+	if commande.CashierUUID != "" {
+		var cashier models.User
+		tx.Select("uuid, fullname").Where("uuid = ?", commande.CashierUUID).Limit(1).Find(&cashier)
+		inv.Cashier = cashier.Fullname
+	}
+	if commande.ClientUUID != "" {
+		var client models.Client
+		tx.Select("uuid, fullname").Where("uuid = ?", commande.ClientUUID).Limit(1).Find(&client)
+		inv.Client = client.Fullname
+	}
+
+	for _, cl := range commande.CommandeLines {
+		name := cl.Product.Name
+		if cl.ItemType == "plat" {
+			name = cl.Plat.Name
+		}
+		inv.Items = append(inv.Items, Item{
+			Name:      name,
+			Quantity:  cl.Quantity,
+			UnitPrice: cl.UnitPrice,
+			TvaRate:   cl.TvaRate,
+			TotalHt:   cl.TotalHt,
+			TvaAmount: cl.TvaAmount,
+			TotalTtc:  cl.TotalTtc,
+		})
+	}
+	return inv, nil
+}
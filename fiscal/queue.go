@@ -0,0 +1,232 @@
+package fiscal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/kgermando/ipos-stock-api/models"
+	"github.com/kgermando/ipos-stock-api/utils"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// ErrDisabled : aucun dispositif fiscal n'est configuré
+	ErrDisabled = errors.New("certification fiscale désactivée")
+	// ErrNotQueued : la commande n'est pas en attente, ou est en cours de certification
+	ErrNotQueued = errors.New("commande absente de la file de certification")
+	// ErrCertified : la facture est certifiée et ne peut plus être annulée
+	ErrCertified = errors.New("facture déjà certifiée")
+	// errIncomplete : les lignes d'une commande synchronisée hors ligne ne sont
+	// pas encore arrivées ; la certification est reportée
+	errIncomplete = errors.New("commande sans lignes, en attente de synchronisation")
+)
+
+const (
+	deviceTimeout = 10 * time.Second // Délai de réponse du dispositif
+	pollInterval  = 10 * time.Second // Fréquence de passage sur la file d'attente
+	batchSize     = 50
+	retryBase     = 30 * time.Second // Premier report quand le dispositif est indisponible
+	retryMax      = 30 * time.Minute // Report maximal
+)
+
+// Enqueue met une commande réglée en file d'attente de certification, dans la
+// transaction qui la règle. Une commande déjà en file est retentée sans délai.
+func Enqueue(tx *gorm.DB, commande *models.Commande) error {
+	if !Enabled() || commande.Fiscal.Status == models.FiscalCertified {
+		return nil
+	}
+
+	now := time.Now()
+	job := models.FiscalJob{
+		UUID:           utils.GenerateUUID(),
+		CommandeUUID:   commande.UUID,
+		NextAttemptAt:  now,
+		EntrepriseUUID: commande.EntrepriseUUID,
+		PosUUID:        commande.PosUUID,
+	}
+	if err := tx.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "commande_uuid"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{"next_attempt_at": now}),
+	}).Create(&job).Error; err != nil {
+		return err
+	}
+
+	commande.Fiscal.Status = models.FiscalPending
+	commande.Fiscal.Error = ""
+	return tx.Model(&models.Commande{}).Where("uuid = ?", commande.UUID).
+		Updates(map[string]interface{}{"fiscal_status": models.FiscalPending, "fiscal_error": ""}).Error
+}
+
+// Withdraw retire de la file d'attente une commande réglée qui est annulée.
+// Une certification en cours est attendue ; une facture déjà certifiée ne
+// peut plus être retirée et l'erreur ErrCertified est retournée.
+func Withdraw(tx *gorm.DB, commande *models.Commande) error {
+	if err := tx.Where("commande_uuid = ?", commande.UUID).Delete(&models.FiscalJob{}).Error; err != nil {
+		return err
+	}
+
+	var status string
+	if err := tx.Model(&models.Commande{}).Where("uuid = ?", commande.UUID).
+		Select("fiscal_status").Scan(&status).Error; err != nil {
+		return err
+	}
+	if status == models.FiscalCertified {
+		return ErrCertified
+	}
+	if status == "" {
+		return nil
+	}
+
+	commande.Fiscal.Status = ""
+	commande.Fiscal.Error = ""
+	return tx.Model(&models.Commande{}).Where("uuid = ?", commande.UUID).
+		Updates(map[string]interface{}{"fiscal_status": "", "fiscal_error": ""}).Error
+}
+
+// Certify soumet au dispositif une commande en file d'attente et enregistre
+// le résultat sur la commande. La tâche reste verrouillée pendant l'appel pour
+// qu'une facture ne soit jamais certifiée deux fois. Si le dispositif est
+// indisponible, la tentative est reportée et l'erreur enveloppe ErrUnavailable.
+func Certify(db *gorm.DB, commandeUUID string) error {
+	certifier := Current()
+	if certifier == nil {
+		return ErrDisabled
+	}
+
+	var result error
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var job models.FiscalJob
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("commande_uuid = ?", commandeUUID).
+			Limit(1).Find(&job).Error; err != nil {
+			return err
+		}
+		if job.UUID == "" {
+			result = ErrNotQueued
+			return nil
+		}
+
+		var commande models.Commande
+		if err := tx.Where("uuid = ?", commandeUUID).
+			Preload("CommandeLines").
+			Preload("CommandeLines.Product").
+			Preload("CommandeLines.Plat").
+			First(&commande).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				result = err
+				return tx.Delete(&job).Error
+			}
+			return err
+		}
+		inv, err := invoice(tx, commande)
+		if err != nil {
+			return err
+		}
+
+		var cert Certificate
+		if len(inv.Items) == 0 {
+			err = errIncomplete
+		} else {
+			ctx, cancel := context.WithTimeout(tx.Statement.Context, deviceTimeout)
+			cert, err = certifier.Certify(ctx, inv)
+			cancel()
+		}
+
+		update := map[string]interface{}{}
+		switch {
+		case err == nil:
+			certifiedAt := cert.CertifiedAt
+			update = map[string]interface{}{
+				"fiscal_status":       models.FiscalCertified,
+				"fiscal_device":       cert.Device,
+				"fiscal_counters":     cert.Counters,
+				"fiscal_signature":    cert.Signature,
+				"fiscal_qr_code":      cert.QRCode,
+				"fiscal_certified_at": &certifiedAt,
+				"fiscal_error":        "",
+			}
+			if err := tx.Delete(&job).Error; err != nil {
+				return err
+			}
+		case errors.Is(err, ErrRejected):
+			update = map[string]interface{}{"fiscal_status": models.FiscalRejected, "fiscal_error": err.Error()}
+			if err := tx.Delete(&job).Error; err != nil {
+				return err
+			}
+		default:
+			if !errors.Is(err, ErrUnavailable) && !errors.Is(err, errIncomplete) {
+				err = fmt.Errorf("%w : %v", ErrUnavailable, err)
+			}
+			job.Attempts++
+			job.NextAttemptAt = time.Now().Add(backoff(job.Attempts))
+			job.LastError = err.Error()
+			if err := tx.Save(&job).Error; err != nil {
+				return err
+			}
+			update["fiscal_error"] = err.Error()
+		}
+		result = err
+
+		return tx.Model(&models.Commande{}).Where("uuid = ?", commandeUUID).Updates(update).Error
+	})
+	if err != nil {
+		return err
+	}
+	return result
+}
+
+// Run certifie les commandes dont la tentative est due, jusqu'à l'arrêt du contexte
+func Run(ctx context.Context, db *gorm.DB) {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			ProcessDue(db.WithContext(ctx))
+		}
+	}
+}
+
+// ProcessDue traite un lot de la file d'attente, dans l'ordre des ventes, et
+// retourne le nombre de commandes certifiées. Le lot s'arrête dès que le
+// dispositif est indisponible.
+func ProcessDue(db *gorm.DB) int {
+	var jobs []models.FiscalJob
+	db.Where("next_attempt_at <= ?", time.Now()).
+		Order("created_at").
+		Limit(batchSize).
+		Find(&jobs)
+
+	certified := 0
+	for _, job := range jobs {
+		err := Certify(db, job.CommandeUUID)
+		switch {
+		case err == nil:
+			certified++
+		case errors.Is(err, ErrUnavailable):
+			return certified
+		case errors.Is(err, ErrNotQueued), errors.Is(err, errIncomplete):
+		default:
+			log.Printf("Certification fiscale de la commande %s : %v", job.CommandeUUID, err)
+		}
+	}
+	return certified
+}
+
+// backoff double le report à chaque échec, jusqu'à retryMax
+func backoff(attempts int) time.Duration {
+	if attempts < 1 {
+		return retryBase
+	}
+	if attempts > 10 {
+		return retryMax
+	}
+	return min(retryBase<<(attempts-1), retryMax)
+}
@@ -0,0 +1,110 @@
+package fiscal
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base32"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kgermando/ipos-stock-api/utils"
+)
+
+func init() {
+	Register("simulator", func() (Certifier, error) {
+		s := NewSimulator(utils.Env("FISCAL_SIMULATOR_KEY"))
+		if rate := utils.Env("FISCAL_SIMULATOR_FAILURE_RATE"); rate != "" {
+			f, err := strconv.ParseFloat(rate, 64)
+			if err != nil || f < 0 || f > 1 {
+				return nil, fmt.Errorf("FISCAL_SIMULATOR_FAILURE_RATE invalide : %q", rate)
+			}
+			s.FailureRate = f
+		}
+		return s, nil
+	})
+}
+
+// Simulator est un dispositif fiscal local, pour les tests et les
+// démonstrations : il signe les factures avec une clé HMAC et tient ses
+// compteurs en mémoire. Ses certifications n'ont aucune valeur légale.
+type Simulator struct {
+	Device      string  // Identifiant simulé du dispositif
+	FailureRate float64 // Part des appels qui échouent comme un dispositif injoignable, de 0 à 1
+	Offline     bool    // Simule un dispositif débranché
+
+	key      []byte
+	mu       sync.Mutex
+	counters map[string]uint64 // Factures certifiées par numéro d'impôt
+	total    uint64
+}
+
+// NewSimulator crée un simulateur signant avec key
+func NewSimulator(key string) *Simulator {
+	if key == "" {
+		key = "ipos-simulator"
+	}
+	return &Simulator{
+		Device:   "SIM-000001",
+		key:      []byte(key),
+		counters: map[string]uint64{},
+	}
+}
+
+// Name retourne le nom du dispositif
+func (s *Simulator) Name() string {
+	return "simulator"
+}
+
+// Certify certifie la facture comme le ferait le dispositif de la DGI
+func (s *Simulator) Certify(ctx context.Context, inv Invoice) (Certificate, error) {
+	if err := ctx.Err(); err != nil {
+		return Certificate{}, fmt.Errorf("%w : %v", ErrUnavailable, err)
+	}
+	if s.Offline || (s.FailureRate > 0 && rand.Float64() < s.FailureRate) {
+		return Certificate{}, fmt.Errorf("%w : simulateur hors ligne", ErrUnavailable)
+	}
+	if inv.NImpot == "" {
+		return Certificate{}, fmt.Errorf("%w : numéro d'impôt de l'entreprise manquant", ErrRejected)
+	}
+	if inv.Number == "" || len(inv.Items) == 0 {
+		return Certificate{}, fmt.Errorf("%w : facture sans numéro ou sans article", ErrRejected)
+	}
+
+	s.mu.Lock()
+	s.counters[inv.NImpot]++
+	s.total++
+	counters := fmt.Sprintf("%d/%d %s", s.counters[inv.NImpot], s.total, inv.Type)
+	s.mu.Unlock()
+
+	now := time.Now()
+	mac := hmac.New(sha256.New, s.key)
+	fmt.Fprintf(mac, "%s|%s|%s|%s|%.2f|%s", s.Device, inv.NImpot, inv.Number,
+		inv.Date.UTC().Format(time.RFC3339), inv.TotalTtc, counters)
+	signature := group(base32.StdEncoding.EncodeToString(mac.Sum(nil)[:15]), 4)
+
+	return Certificate{
+		Device:    s.Device,
+		Counters:  counters,
+		Signature: signature,
+		QRCode: strings.Join([]string{
+			"DEF", s.Device, inv.NImpot, inv.Number, now.Format("20060102150405"),
+			strconv.FormatFloat(inv.TotalTtc, 'f', 2, 64), counters, signature,
+		}, "|"),
+		CertifiedAt: now,
+	}, nil
+}
+
+// group sépare le code en blocs de n caractères : ABCD-EFGH-...
+func group(code string, n int) string {
+	var blocks []string
+	for len(code) > n {
+		blocks = append(blocks, code[:n])
+		code = code[n:]
+	}
+	return strings.Join(append(blocks, code), "-")
+}
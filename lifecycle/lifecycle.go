@@ -0,0 +1,237 @@
+package lifecycle
+
+import (
+	"errors"
+	"slices"
+	"strings"
+
+	"github.com/kgermando/ipos-stock-api/fiscal"
+	"github.com/kgermando/ipos-stock-api/models"
+	"github.com/kgermando/ipos-stock-api/payments"
+	"github.com/kgermando/ipos-stock-api/utils"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrUnknownStatus : statut hors du cycle de vie
+	ErrUnknownStatus = errors.New("statut de commande inconnu")
+	// ErrInvalidTransition : le cycle de vie n'autorise pas ce changement
+	ErrInvalidTransition = errors.New("changement de statut non autorisé")
+	// ErrPayOnly : une commande n'est réglée que par l'encaissement de ses règlements
+	ErrPayOnly = errors.New("une commande n'est réglée que par l'encaissement de ses règlements")
+	// ErrRefundOnly : le remboursement passe par un retour client
+	ErrRefundOnly = errors.New("une commande n'est remboursée que par un retour client")
+	// ErrVoidForbidden : seul un gérant peut annuler une commande réglée
+	ErrVoidForbidden = errors.New("seul un gérant peut annuler une commande réglée")
+	// ErrVoidCertified : une facture certifiée est rendue par un retour client
+	ErrVoidCertified = errors.New("une facture certifiée ne peut plus être annulée, passez par un retour client")
+	// ErrVoidReturned : une commande en partie rendue n'est plus annulée
+	ErrVoidReturned = errors.New("une commande ayant fait l'objet d'un retour ne peut plus être annulée")
+	// ErrNotDeletable : seule une commande brouillon ou ouverte peut être supprimée
+	ErrNotDeletable = errors.New("seule une commande brouillon ou ouverte peut être supprimée, les autres sont annulées")
+)
+
+// legacyStatuses fait correspondre les valeurs libres utilisées avant le
+// cycle de vie aux statuts reconnus
+var legacyStatuses = map[string]string{
+	"":          models.CommandeOpen,
+	"ouverte":   models.CommandeOpen,
+	"pending":   models.CommandeOpen,
+	"fermée":    models.CommandePaid,
+	"fermee":    models.CommandePaid,
+	"closed":    models.CommandePaid,
+	"completed": models.CommandePaid,
+	"annulée":   models.CommandeCancelled,
+	"annulee":   models.CommandeCancelled,
+	"canceled":  models.CommandeCancelled,
+}
+
+// Canonical retourne le statut reconnu correspondant à status, en acceptant
+// les anciennes valeurs des terminaux
+func Canonical(status string) (string, error) {
+	s := strings.ToLower(strings.TrimSpace(status))
+	if slices.Contains(models.CommandeStatuses, s) {
+		return s, nil
+	}
+	if canonical, ok := legacyStatuses[s]; ok {
+		return canonical, nil
+	}
+	return "", ErrUnknownStatus
+}
+
+// Initial retourne le statut d'une commande à sa création : ouverte par
+// défaut ; une commande ne peut pas être créée annulée ou remboursée, ni
+// réglée hors de l'encaissement
+func Initial(status string) (string, error) {
+	s, err := Canonical(status)
+	if err != nil {
+		return "", err
+	}
+	if s == models.CommandePaid {
+		return "", ErrPayOnly
+	}
+	if s == models.CommandeCancelled || s == models.CommandeRefunded {
+		return "", ErrInvalidTransition
+	}
+	return s, nil
+}
+
+// Payable indique si une commande dans ce statut peut recevoir un règlement
+func Payable(status string) bool {
+	return slices.Contains(models.CommandeTransitions[status], models.CommandePaid)
+}
+
+// Deletable indique si une commande dans ce statut peut être supprimée : une
+// commande envoyée en cuisine, servie ou réglée reste tracée et s'annule
+func Deletable(status string) bool {
+	s, err := Canonical(status)
+	return err == nil && (s == models.CommandeDraft || s == models.CommandeOpen)
+}
+
+// Check vérifie que l'utilisateur peut faire passer la commande de from à to.
+// Le règlement est réservé à l'encaissement (voir Pay), le remboursement aux
+// retours clients (voir Refund) et l'annulation d'une commande réglée aux
+// gérants (voir Void).
+func Check(from, to string, user *models.User) error {
+	if !slices.Contains(models.CommandeStatuses, to) {
+		return ErrUnknownStatus
+	}
+	if !slices.Contains(models.CommandeTransitions[from], to) {
+		return ErrInvalidTransition
+	}
+	if to == models.CommandePaid {
+		return ErrPayOnly
+	}
+	if to == models.CommandeRefunded {
+		return ErrRefundOnly
+	}
+	if from == models.CommandePaid && to == models.CommandeCancelled &&
+		(user == nil || !user.Can(models.PermissionCommandeVoid)) {
+		return ErrVoidForbidden
+	}
+	return nil
+}
+
+// Transition fait passer la commande au statut to après contrôle, et trace
+// le changement. Demander le statut actuel ne change rien.
+func Transition(tx *gorm.DB, commande *models.Commande, to string, user *models.User, motif string) error {
+	to, err := Canonical(to)
+	if err != nil {
+		return err
+	}
+	from, err := Canonical(commande.Status)
+	if err != nil {
+		return ErrInvalidTransition
+	}
+	if to == from {
+		return nil
+	}
+	if err := Check(from, to, user); err != nil {
+		return err
+	}
+	if from == models.CommandePaid {
+		if err := Void(tx, commande, user); err != nil {
+			return err
+		}
+	}
+	return move(tx, commande, to, actor(user), motif)
+}
+
+// Pay passe au statut réglé une commande dont les règlements couvrent le
+// total ; seul l'encaissement l'appelle, une fois les règlements enregistrés
+func Pay(tx *gorm.DB, commande *models.Commande, user *models.User) error {
+	if !Payable(commande.Status) {
+		return ErrInvalidTransition
+	}
+	return move(tx, commande, models.CommandePaid, actor(user), "")
+}
+
+// Refund passe une commande réglée entièrement rendue au statut remboursé
+func Refund(tx *gorm.DB, commande *models.Commande, user *models.User, motif string) error {
+	if commande.Status != models.CommandePaid {
+		return ErrInvalidTransition
+	}
+	return move(tx, commande, models.CommandeRefunded, actor(user), motif)
+}
+
+// Void défait la vente d'une commande réglée que l'on annule, sans changer
+// son statut : les règlements sont contre-passés (voir payments.Reverse), les
+// produits remis en stock et la commande retirée de la file de certification.
+// Une commande certifiée ou déjà en partie rendue passe par un retour client.
+func Void(tx *gorm.DB, commande *models.Commande, user *models.User) error {
+	var returns int64
+	if err := tx.Model(&models.SaleReturn{}).Where("commande_uuid = ?", commande.UUID).Count(&returns).Error; err != nil {
+		return err
+	}
+	if returns > 0 {
+		return ErrVoidReturned
+	}
+	if err := fiscal.Withdraw(tx, commande); err != nil {
+		if errors.Is(err, fiscal.ErrCertified) {
+			return ErrVoidCertified
+		}
+		return err
+	}
+
+	if _, err := payments.Reverse(tx, commande, actor(user)); err != nil {
+		return err
+	}
+
+	var lines []models.CommandeLine
+	if err := tx.Where("commande_uuid = ? AND item_type = ?", commande.UUID, "product").
+		Order("product_uuid").Find(&lines).Error; err != nil {
+		return err
+	}
+	for _, line := range lines {
+		if err := tx.Model(&models.Product{}).Where("uuid = ?", line.ProductUUID).
+			Updates(map[string]interface{}{"stock": gorm.Expr("stock + ?", line.Quantity), "sync": true}).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Record trace le passage de la commande de from à son statut actuel, déjà
+// enregistré ; from est vide pour une commande qui vient d'être créée
+func Record(tx *gorm.DB, commande *models.Commande, from string, user *models.User) error {
+	return history(tx, commande, from, actor(user), "")
+}
+
+func move(tx *gorm.DB, commande *models.Commande, to, actorUUID, motif string) error {
+	from := commande.Status
+	if err := tx.Model(commande).Updates(map[string]interface{}{"status": to, "sync": true}).Error; err != nil {
+		return err
+	}
+	commande.Status = to
+	return history(tx, commande, from, actorUUID, motif)
+}
+
+// history trace le changement de statut ; une commande qui vient d'être
+// réglée est mise en file d'attente de certification fiscale
+func history(tx *gorm.DB, commande *models.Commande, from, actorUUID, motif string) error {
+	if err := tx.Create(&models.CommandeStatusHistory{
+		UUID:           utils.GenerateUUID(),
+		CommandeUUID:   commande.UUID,
+		FromStatus:     from,
+		ToStatus:       commande.Status,
+		Motif:          strings.TrimSpace(motif),
+		ActorUUID:      actorUUID,
+		EntrepriseUUID: commande.EntrepriseUUID,
+		PosUUID:        commande.PosUUID,
+		Sync:           true,
+	}).Error; err != nil {
+		return err
+	}
+	if commande.Status == models.CommandePaid {
+		return fiscal.Enqueue(tx, commande)
+	}
+	return nil
+}
+
+func actor(user *models.User) string {
+	if user == nil {
+		return ""
+	}
+	return user.UUID
+}
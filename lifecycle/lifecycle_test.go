@@ -0,0 +1,169 @@
+package lifecycle
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/kgermando/ipos-stock-api/models"
+)
+
+func TestCheck(t *testing.T) {
+	// Transitions accordées à un gérant ; les autres couples de statuts
+	// doivent être refusés avec ErrInvalidTransition
+	allowed := map[string]map[string]error{
+		models.CommandeDraft: {
+			models.CommandeOpen:      nil,
+			models.CommandeCancelled: nil,
+		},
+		models.CommandeOpen: {
+			models.CommandeSentToKitchen: nil,
+			models.CommandeServed:        nil,
+			models.CommandePaid:          ErrPayOnly,
+			models.CommandeCancelled:     nil,
+		},
+		models.CommandeSentToKitchen: {
+			models.CommandeServed:    nil,
+			models.CommandePaid:      ErrPayOnly,
+			models.CommandeCancelled: nil,
+		},
+		models.CommandeServed: {
+			models.CommandePaid:      ErrPayOnly,
+			models.CommandeCancelled: nil,
+		},
+		models.CommandePaid: {
+			models.CommandeRefunded:  ErrRefundOnly,
+			models.CommandeCancelled: nil,
+		},
+		models.CommandeCancelled: {},
+		models.CommandeRefunded:  {},
+	}
+
+	manager := &models.User{Role: models.RolePosManager}
+
+	for _, from := range models.CommandeStatuses {
+		for _, to := range models.CommandeStatuses {
+			want, ok := allowed[from][to]
+			if !ok {
+				want = ErrInvalidTransition
+			}
+			t.Run(from+" vers "+to, func(t *testing.T) {
+				if err := Check(from, to, manager); !errors.Is(err, want) {
+					t.Errorf("Check(%q, %q) = %v, attendu %v", from, to, err, want)
+				}
+			})
+		}
+	}
+
+	if err := Check(models.CommandeOpen, "inconnu", manager); !errors.Is(err, ErrUnknownStatus) {
+		t.Errorf("statut inconnu : %v, attendu %v", err, ErrUnknownStatus)
+	}
+}
+
+func TestCheckVoid(t *testing.T) {
+	// Seul un gérant annule une commande réglée ; les autres annulations
+	// restent ouvertes à tous
+	tests := []struct {
+		name string
+		from string
+		user *models.User
+		err  error
+	}{
+		{"gérant d'entreprise", models.CommandePaid, &models.User{Role: models.RoleEntrepriseManager}, nil},
+		{"gérant de point de vente", models.CommandePaid, &models.User{Role: models.RolePosManager}, nil},
+		{"caissier", models.CommandePaid, &models.User{Role: models.RoleCashier}, ErrVoidForbidden},
+		{"magasinier", models.CommandePaid, &models.User{Role: models.RoleStockKeeper}, ErrVoidForbidden},
+		{"sans utilisateur", models.CommandePaid, nil, ErrVoidForbidden},
+		{"caissier, commande servie", models.CommandeServed, &models.User{Role: models.RoleCashier}, nil},
+		{"sans utilisateur, commande ouverte", models.CommandeOpen, nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := Check(tt.from, models.CommandeCancelled, tt.user); !errors.Is(err, tt.err) {
+				t.Errorf("Check(%q, %q) = %v, attendu %v", tt.from, models.CommandeCancelled, err, tt.err)
+			}
+		})
+	}
+}
+
+func TestCanonical(t *testing.T) {
+	tests := []struct {
+		status string
+		want   string
+		err    error
+	}{
+		{"", models.CommandeOpen, nil},
+		{"open", models.CommandeOpen, nil},
+		{" Served ", models.CommandeServed, nil},
+		{"ouverte", models.CommandeOpen, nil},
+		{"Fermée", models.CommandePaid, nil},
+		{"completed", models.CommandePaid, nil},
+		{"annulée", models.CommandeCancelled, nil},
+		{"canceled", models.CommandeCancelled, nil},
+		{"livrée", "", ErrUnknownStatus},
+	}
+	for _, tt := range tests {
+		got, err := Canonical(tt.status)
+		if got != tt.want || !errors.Is(err, tt.err) {
+			t.Errorf("Canonical(%q) = %q, %v ; attendu %q, %v", tt.status, got, err, tt.want, tt.err)
+		}
+	}
+}
+
+func TestInitial(t *testing.T) {
+	tests := []struct {
+		status string
+		want   string
+		err    error
+	}{
+		{"", models.CommandeOpen, nil},
+		{models.CommandeDraft, models.CommandeDraft, nil},
+		{models.CommandeSentToKitchen, models.CommandeSentToKitchen, nil},
+		{models.CommandePaid, "", ErrPayOnly},
+		{"closed", "", ErrPayOnly},
+		{models.CommandeCancelled, "", ErrInvalidTransition},
+		{models.CommandeRefunded, "", ErrInvalidTransition},
+		{"inconnu", "", ErrUnknownStatus},
+	}
+	for _, tt := range tests {
+		got, err := Initial(tt.status)
+		if got != tt.want || !errors.Is(err, tt.err) {
+			t.Errorf("Initial(%q) = %q, %v ; attendu %q, %v", tt.status, got, err, tt.want, tt.err)
+		}
+	}
+}
+
+func TestPayable(t *testing.T) {
+	tests := map[string]bool{
+		models.CommandeDraft:         false,
+		models.CommandeOpen:          true,
+		models.CommandeSentToKitchen: true,
+		models.CommandeServed:        true,
+		models.CommandePaid:          false,
+		models.CommandeCancelled:     false,
+		models.CommandeRefunded:      false,
+	}
+	for status, want := range tests {
+		if got := Payable(status); got != want {
+			t.Errorf("Payable(%q) = %v, attendu %v", status, got, want)
+		}
+	}
+}
+
+func TestDeletable(t *testing.T) {
+	tests := map[string]bool{
+		models.CommandeDraft:         true,
+		models.CommandeOpen:          true,
+		"pending":                    true,
+		models.CommandeSentToKitchen: false,
+		models.CommandeServed:        false,
+		models.CommandePaid:          false,
+		models.CommandeCancelled:     false,
+		models.CommandeRefunded:      false,
+		"inconnu":                    false,
+	}
+	for status, want := range tests {
+		if got := Deletable(status); got != want {
+			t.Errorf("Deletable(%q) = %v, attendu %v", status, got, want)
+		}
+	}
+}
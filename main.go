@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"log"
 	"os"
 	"strings"
@@ -9,6 +10,7 @@ import (
 	"github.com/gofiber/fiber/v2/middleware/cors"
 	"github.com/gofiber/fiber/v2/middleware/logger"
 	"github.com/kgermando/ipos-stock-api/database"
+	"github.com/kgermando/ipos-stock-api/fiscal"
 	"github.com/kgermando/ipos-stock-api/routes"
 )
 
@@ -27,7 +29,14 @@ func main() {
 
 	database.Connect()
 
-	app := fiber.New()
+	// Certification des factures normalisées par le dispositif fiscal
+	if err := fiscal.Start(context.Background(), database.DB); err != nil {
+		log.Fatal(err)
+	}
+
+	app := fiber.New(fiber.Config{
+		BodyLimit: 16 * 1024 * 1024, // Lots /sync/push d'un terminal resté hors ligne
+	})
 
 	// Initialize default config
 	app.Use(logger.New())
@@ -35,7 +44,8 @@ func main() {
 	// Middleware
 	app.Use(cors.New(cors.Config{
 		AllowOrigins:     "https://ipos-stock.onrender.com, https://www.ipos-stock.app, https://ipos-stock.app, http://localhost:4200, http://192.168.125.185:4200",
-		AllowHeaders:     "Origin, Content-Type, Accept, Authorization, Expires, Cache-Control, Pragma",
+		AllowHeaders:     "Origin, Content-Type, Accept, Authorization, Expires, Cache-Control, Pragma, If-Match, Idempotency-Key",
+		ExposeHeaders:    "X-Sync-Cursor",
 		AllowCredentials: true,
 		AllowMethods: strings.Join([]string{
 			fiber.MethodGet,
@@ -0,0 +1,73 @@
+package middlewares
+
+import (
+	"strings"
+	"time"
+
+	"github.com/kgermando/ipos-stock-api/database"
+	"github.com/kgermando/ipos-stock-api/models"
+	"github.com/kgermando/ipos-stock-api/utils"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// apiKeyFromRequest retourne la clé d'API passée dans l'en-tête X-API-Key ou
+// comme token Bearer, ou une chaîne vide s'il s'agit d'un JWT
+func apiKeyFromRequest(c *fiber.Ctx) string {
+	if key := strings.TrimSpace(c.Get("X-API-Key")); key != "" {
+		return key
+	}
+	token := strings.TrimSpace(strings.TrimPrefix(c.Get(fiber.HeaderAuthorization), "Bearer "))
+	if strings.HasPrefix(token, models.ApiKeyPrefix) {
+		return token
+	}
+	return ""
+}
+
+// authenticateApiKey charge la clé d'API et l'utilisateur technique associé
+func authenticateApiKey(c *fiber.Ctx, rawKey string) error {
+	key := &models.ApiKey{}
+	if err := database.DB.Where("key_hash = ?", utils.HashToken(rawKey)).First(key).Error; err != nil || !key.IsActive() {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"status":  "error",
+			"message": "clé d'API invalide, expirée ou révoquée",
+		})
+	}
+
+	// Les routes de compte (profil, mot de passe, sessions) sont réservées aux humains
+	if strings.HasPrefix(c.Path(), "/api/auth/") {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"status":  "error",
+			"message": "cette route n'est pas accessible avec une clé d'API",
+		})
+	}
+
+	entreprise := models.Entreprise{}
+	if err := database.DB.Where("uuid = ?", key.EntrepriseUUID).First(&entreprise).Error; err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"status":  "error",
+			"message": "clé d'API invalide, expirée ou révoquée",
+		})
+	}
+
+	if key.LastUsedAt == nil || time.Since(*key.LastUsedAt) > time.Minute || key.LastUsedIP != c.IP() {
+		database.DB.Model(key).UpdateColumns(map[string]interface{}{
+			"last_used_at": time.Now(),
+			"last_used_ip": c.IP(),
+		})
+	}
+
+	c.Locals("user", key.Principal(entreprise))
+	c.Locals("api_key", key)
+
+	return c.Next()
+}
+
+// GetApiKey retourne la clé d'API de la requête, nil pour un utilisateur connecté
+func GetApiKey(c *fiber.Ctx) *models.ApiKey {
+	key, ok := c.Locals("api_key").(*models.ApiKey)
+	if !ok {
+		return nil
+	}
+	return key
+}
@@ -1,26 +1,113 @@
 package middlewares
 
 import (
-	"fmt"
+	"strings"
+	"time"
 
+	"github.com/kgermando/ipos-stock-api/database"
+	"github.com/kgermando/ipos-stock-api/models"
 	"github.com/kgermando/ipos-stock-api/utils"
 
-	"github.com/gofiber/fiber"
+	"github.com/gofiber/fiber/v2"
 )
 
+// IsAuthenticated vérifie le token Bearer (ou la clé d'API) et charge
+// l'utilisateur connecté
 func IsAuthenticated(c *fiber.Ctx) error {
+	if apiKey := apiKeyFromRequest(c); apiKey != "" {
+		return authenticateApiKey(c, apiKey)
+	}
+
+	authHeader := c.Get(fiber.HeaderAuthorization)
+	token := strings.TrimSpace(strings.TrimPrefix(authHeader, "Bearer "))
+
+	if authHeader == "" || token == "" || token == authHeader {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"status":  "error",
+			"message": "unauthenticated",
+		})
+	}
+
+	userUUID, sessionUUID, err := utils.VerifyAccessToken(token)
+	if err != nil || userUUID == "" {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"status":  "error",
+			"message": "unauthenticated",
+		})
+	}
 
-	token := c.Query("token")
+	// Le token doit appartenir à une session encore active (non révoquée par un logout)
+	session := &models.Session{}
+	if err := database.DB.Where("uuid = ? AND user_uuid = ?", sessionUUID, userUUID).First(session).Error; err != nil || !session.IsActive() {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"status":  "error",
+			"message": "session expirée ou révoquée",
+		})
+	}
 
-	fmt.Println("Token:", token)
- 
+	user := &models.User{}
+	result := database.DB.Where("uuid = ?", userUUID).
+		Preload("Entreprise").
+		Preload("Pos").
+		First(user)
 
-	if _, err := utils.VerifyJwt(token); err != nil {
-		c.Status(fiber.StatusUnauthorized)
-		return c.JSON(fiber.Map{
+	if result.Error != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"status":  "error",
 			"message": "unauthenticated",
 		})
 	}
- c.Next()
- return nil
+
+	if !user.Status {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"status":  "error",
+			"message": "vous n'êtes pas autorisé de se connecter 😰",
+		})
+	}
+
+	// Rôle soumis à la double authentification : seules les routes /auth restent
+	// accessibles tant que l'utilisateur ne l'a pas activée
+	if user.TwoFactorRequired() && !user.TwoFactorEnabled && !strings.HasPrefix(c.Path(), "/api/auth/") {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"status":  "error",
+			"message": "la double authentification doit être activée pour votre rôle",
+		})
+	}
+
+	c.Locals("user", user)
+	c.Locals("session", session)
+
+	if session.DeviceUUID != "" {
+		device := &models.Device{}
+		if err := database.DB.Where("uuid = ?", session.DeviceUUID).First(device).Error; err != nil || device.RevokedAt != nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"status":  "error",
+				"message": "cet appareil a été révoqué",
+			})
+		}
+		if time.Since(device.LastSeenAt) > time.Minute {
+			database.DB.Model(device).UpdateColumn("last_seen_at", time.Now())
+		}
+		c.Locals("device", device)
+	}
+
+	return c.Next()
+}
+
+// GetAuthUser retourne l'utilisateur chargé par IsAuthenticated
+func GetAuthUser(c *fiber.Ctx) *models.User {
+	user, ok := c.Locals("user").(*models.User)
+	if !ok {
+		return nil
+	}
+	return user
+}
+
+// GetSession retourne la session associée au token d'accès de la requête
+func GetSession(c *fiber.Ctx) *models.Session {
+	session, ok := c.Locals("session").(*models.Session)
+	if !ok {
+		return nil
+	}
+	return session
 }
@@ -0,0 +1,68 @@
+package middlewares
+
+import (
+	"time"
+
+	"github.com/kgermando/ipos-stock-api/database"
+	"github.com/kgermando/ipos-stock-api/models"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm/clause"
+)
+
+// GetDevice retourne le terminal associé à la session de la requête, s'il existe
+func GetDevice(c *fiber.Ctx) *models.Device {
+	device, ok := c.Locals("device").(*models.Device)
+	if !ok {
+		return nil
+	}
+	return device
+}
+
+// SyncCursor retourne la date à partir de laquelle envoyer les données de
+// l'entité au terminal, et une fonction à appeler une fois les données servies.
+// Le curseur est tenu par terminal, par POS et par entité. Il n'avance que
+// lorsque le terminal confirme avoir enregistré la réponse, en renvoyant au
+// prochain appel (paramètre sync_ack) la valeur reçue dans l'en-tête
+// X-Sync-Cursor : une réponse perdue est servie à nouveau. Sans terminal
+// enregistré, le paramètre sync_created du client est utilisé comme
+// auparavant ; ?full=true force une resynchronisation.
+func SyncCursor(c *fiber.Ctx, entity string) (string, func()) {
+	device := GetDevice(c)
+	if device == nil {
+		return c.Query("sync_created", "2023-01-01"), func() {}
+	}
+
+	posUUID := c.Params("pos_uuid", device.PosUUID)
+	start := time.Now().Truncate(time.Microsecond) // Précision des dates Postgres
+	since := "2023-01-01"
+
+	cursor := models.DeviceSyncCursor{}
+	database.DB.Where("device_uuid = ? AND pos_uuid = ? AND entity = ?", device.UUID, posUUID, entity).
+		Limit(1).Find(&cursor)
+
+	// Le terminal confirme la réponse précédente : le curseur avance jusqu'à elle
+	if ack, err := time.Parse(time.RFC3339Nano, c.Query("sync_ack")); err == nil &&
+		cursor.Pending != nil && cursor.Pending.Equal(ack) {
+		cursor.Cursor, cursor.Pending = *cursor.Pending, nil
+		database.DB.Model(&cursor).Updates(map[string]interface{}{"cursor": cursor.Cursor, "pending": nil})
+		database.DB.Model(device).Update("last_sync_at", &cursor.Cursor)
+	}
+
+	if c.Query("full") != "true" && !cursor.Cursor.IsZero() {
+		since = cursor.Cursor.Format(time.RFC3339Nano)
+	}
+
+	return since, func() {
+		database.DB.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "device_uuid"}, {Name: "pos_uuid"}, {Name: "entity"}},
+			DoUpdates: clause.AssignmentColumns([]string{"pending", "updated_at"}),
+		}).Create(&models.DeviceSyncCursor{
+			DeviceUUID: device.UUID,
+			PosUUID:    posUUID,
+			Entity:     entity,
+			Pending:    &start,
+		})
+		c.Set("X-Sync-Cursor", start.Format(time.RFC3339Nano))
+	}
+}
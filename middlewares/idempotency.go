@@ -0,0 +1,122 @@
+package middlewares
+
+import (
+	"bytes"
+	"sync"
+	"time"
+
+	"github.com/kgermando/ipos-stock-api/database"
+	"github.com/kgermando/ipos-stock-api/models"
+	"github.com/kgermando/ipos-stock-api/utils"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm/clause"
+)
+
+// maxIdempotencyKeyLength limite la taille de l'en-tête Idempotency-Key
+const maxIdempotencyKeyLength = 255
+
+var (
+	idempotencyTTLOnce  sync.Once
+	idempotencyTTLValue time.Duration
+)
+
+// idempotencyTTL lit une fois la durée IDEMPOTENCY_TTL (ex : 24h, 90m)
+func idempotencyTTL() time.Duration {
+	idempotencyTTLOnce.Do(func() {
+		idempotencyTTLValue = models.IdempotencyDefaultTTL
+		if d, err := time.ParseDuration(utils.Env("IDEMPOTENCY_TTL")); err == nil && d > 0 {
+			idempotencyTTLValue = d
+		}
+	})
+	return idempotencyTTLValue
+}
+
+// Idempotency rend rejouables les requêtes POST portant un en-tête
+// Idempotency-Key : la première réponse est conservée par clé et par
+// entreprise, puis renvoyée telle quelle aux tentatives suivantes, sans
+// exécuter à nouveau le handler.
+func Idempotency(c *fiber.Ctx) error {
+	key := c.Get("Idempotency-Key")
+	if c.Method() != fiber.MethodPost || key == "" {
+		return c.Next()
+	}
+	if len(key) > maxIdempotencyKeyLength {
+		return c.Status(400).JSON(fiber.Map{
+			"status":  "error",
+			"message": "En-tête Idempotency-Key trop long",
+		})
+	}
+
+	user := GetAuthUser(c)
+	tenant := user.EntrepriseUUID
+	if t, ok := database.TenantFromContext(c.UserContext()); ok {
+		tenant = t.EntrepriseUUID
+	}
+
+	now := time.Now()
+	hash := utils.HashToken(c.Method() + " " + c.Path() + "\n" + string(c.Body()))
+	record := models.IdempotencyKey{
+		UUID:           utils.GenerateUUID(),
+		Key:            key,
+		EntrepriseUUID: tenant,
+		UserUUID:       user.UUID,
+		Method:         c.Method(),
+		Path:           c.Path(),
+		RequestHash:    hash,
+		ExpiresAt:      now.Add(idempotencyTTL()),
+	}
+
+	// Une clé expirée peut être réutilisée
+	database.DB.Where("key = ? AND entreprise_uuid = ? AND expires_at < ?", key, tenant, now).
+		Delete(&models.IdempotencyKey{})
+
+	// Réserve la clé ; en cas de course, une seule requête obtient la ligne
+	result := database.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&record)
+	if result.Error != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Erreur interne du serveur",
+		})
+	}
+
+	if result.RowsAffected == 0 {
+		var existing models.IdempotencyKey
+		database.DB.Where("key = ? AND entreprise_uuid = ?", key, tenant).First(&existing)
+
+		switch {
+		case existing.RequestHash != hash:
+			return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+				"status":  "error",
+				"message": "Cette clé d'idempotence a déjà été utilisée pour une autre requête",
+			})
+		case !existing.Completed:
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"status":  "error",
+				"message": "La requête d'origine est encore en cours de traitement",
+			})
+		}
+
+		c.Set("Idempotent-Replayed", "true")
+		c.Set(fiber.HeaderContentType, existing.ContentType)
+		return c.Status(existing.StatusCode).Send(existing.Body)
+	}
+
+	err := c.Next()
+
+	// Une erreur serveur libère la clé pour permettre une nouvelle tentative
+	status := c.Response().StatusCode()
+	if err != nil || status >= 500 {
+		database.DB.Where("uuid = ?", record.UUID).Delete(&models.IdempotencyKey{})
+		return err
+	}
+
+	database.DB.Model(&record).Updates(map[string]interface{}{
+		"completed":    true,
+		"status_code":  status,
+		"content_type": string(c.Response().Header.ContentType()),
+		"body":         bytes.Clone(c.Response().Body()),
+	})
+
+	return nil
+}
@@ -0,0 +1,31 @@
+package middlewares
+
+import (
+	"github.com/gofiber/fiber/v2"
+)
+
+// Can n'autorise la route que si l'utilisateur connecté dispose de toutes les
+// permissions demandées
+func Can(permissions ...string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		user := GetAuthUser(c)
+		if user == nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"status":  "error",
+				"message": "unauthenticated",
+			})
+		}
+
+		for _, p := range permissions {
+			if !user.Can(p) {
+				return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+					"status":     "error",
+					"message":    "vous n'avez pas la permission d'effectuer cette action",
+					"permission": p,
+				})
+			}
+		}
+
+		return c.Next()
+	}
+}
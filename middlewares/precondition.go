@@ -0,0 +1,57 @@
+package middlewares
+
+import (
+	"strings"
+	"time"
+
+	"github.com/kgermando/ipos-stock-api/database"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// GetPrecondition lit l'en-tête If-Match : la date de modification (UpdatedAt)
+// de la version de l'enregistrement lue par le client. Sans en-tête, la
+// modification est appliquée comme auparavant.
+func GetPrecondition(c *fiber.Ctx) database.Precondition {
+	pre := database.Precondition{Source: "api"}
+	if device := GetDevice(c); device != nil {
+		pre.DeviceUUID = device.UUID
+	}
+
+	value := strings.TrimSpace(c.Get(fiber.HeaderIfMatch))
+	if value == "" || value == "*" {
+		return pre
+	}
+	value = strings.Trim(strings.TrimPrefix(value, "W/"), `"`)
+
+	// Une version illisible ne correspond à aucune version du serveur
+	at, _ := time.Parse(time.RFC3339Nano, value)
+	pre.UpdatedAt = &at
+	return pre
+}
+
+// CheckConflict vérifie la précondition de la requête avant la mise à jour de
+// client, dont server est l'état lu en base. Le conflit éventuel est journalisé ;
+// il est retourné si la modification doit être refusée.
+func CheckConflict(c *fiber.Ctx, db *gorm.DB, server, client interface{}) *database.Conflict {
+	conflict := database.ResolveConflict(db, server, client, GetPrecondition(c))
+	if conflict == nil {
+		return nil
+	}
+	database.RecordConflict(db, conflict)
+	if !conflict.Rejected() {
+		return nil
+	}
+	return conflict
+}
+
+// ConflictResponse répond 409 avec l'état actuel de l'enregistrement
+func ConflictResponse(c *fiber.Ctx, conflict *database.Conflict) error {
+	return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+		"status":  "error",
+		"message": "L'enregistrement a été modifié entre-temps, rechargez-le avant de réessayer",
+		"data":    conflict.Server,
+		"fields":  strings.Split(conflict.Log.Fields, ","),
+	})
+}
@@ -0,0 +1,102 @@
+package middlewares
+
+import (
+	"github.com/kgermando/ipos-stock-api/database"
+	"github.com/kgermando/ipos-stock-api/models"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// TenantScope attache au contexte de la requête l'entreprise et le POS de
+// l'utilisateur connecté, puis vérifie les paramètres de requête
+// entreprise_uuid et pos_uuid (utilisés par le dashboard).
+// Les handlers obtiennent une connexion filtrée avec database.DB.WithContext(c.UserContext()),
+// qui porte aussi l'auteur des modifications pour le journal d'audit.
+func TenantScope(c *fiber.Ctx) error {
+	user := GetAuthUser(c)
+	if user == nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"status":  "error",
+			"message": "unauthenticated",
+		})
+	}
+
+	actor := database.Actor{UserUUID: user.UUID, Type: "user", IP: c.IP()}
+	if user.IsApiKey() {
+		actor.Type = "api_key"
+	}
+	c.SetUserContext(database.WithActor(c.UserContext(), actor))
+
+	if user.IsSuperAdmin() {
+		return c.Next()
+	}
+
+	tenant := tenantOf(user)
+	if !allowedTenant(tenant, c.Query("entreprise_uuid"), c.Query("pos_uuid")) {
+		return forbiddenTenant(c)
+	}
+
+	// Les statistiques du dashboard ne passent pas par la connexion filtrée :
+	// un utilisateur limité à son POS doit donc toujours le préciser
+	if tenant.PosUUID != "" && c.Query("entreprise_uuid") != "" && c.Query("pos_uuid") != tenant.PosUUID {
+		return forbiddenTenant(c)
+	}
+
+	c.SetUserContext(database.WithTenant(c.UserContext(), tenant))
+
+	return c.Next()
+}
+
+// TenantParams vérifie les paramètres de route :entreprise_uuid et :pos_uuid.
+// Les paramètres de route ne sont pas visibles depuis un middleware monté avec
+// Use, il faut donc l'ajouter sur chaque route qui les déclare.
+func TenantParams(c *fiber.Ctx) error {
+	user := GetAuthUser(c)
+	if user == nil || user.IsSuperAdmin() {
+		return c.Next()
+	}
+
+	if !allowedTenant(tenantOf(user), c.Params("entreprise_uuid"), c.Params("pos_uuid")) {
+		return forbiddenTenant(c)
+	}
+
+	return c.Next()
+}
+
+func tenantOf(user *models.User) database.Tenant {
+	tenant := database.Tenant{EntrepriseUUID: user.EntrepriseUUID}
+	if !user.HasEntrepriseScope() {
+		tenant.PosUUID = user.PosUUID
+	}
+	return tenant
+}
+
+// allowedTenant vérifie que l'entreprise et le POS demandés appartiennent au
+// périmètre de l'utilisateur. "-" désigne tous les POS.
+func allowedTenant(tenant database.Tenant, entrepriseUUID, posUUID string) bool {
+	if entrepriseUUID != "" && entrepriseUUID != tenant.EntrepriseUUID {
+		return false
+	}
+
+	if posUUID == "" || posUUID == "-" {
+		return true
+	}
+
+	if tenant.PosUUID != "" {
+		return posUUID == tenant.PosUUID
+	}
+
+	var count int64
+	database.DB.Model(&models.Pos{}).
+		Where("uuid = ? AND entreprise_uuid = ?", posUUID, tenant.EntrepriseUUID).
+		Count(&count)
+
+	return count > 0
+}
+
+func forbiddenTenant(c *fiber.Ctx) error {
+	return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+		"status":  "error",
+		"message": "accès refusé à cette entreprise ou à ce point de vente",
+	})
+}
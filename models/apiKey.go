@@ -0,0 +1,88 @@
+package models
+
+import (
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ApiKeyPrefix permet de distinguer une clé d'API d'un token JWT
+const ApiKeyPrefix = "ipk_"
+
+// ApiKeyResources liste les ressources accessibles aux intégrations externes
+var ApiKeyResources = []string{"products", "stocks", "commandes", "clients", "plats"}
+
+// ApiKey donne un accès machine (comptabilité, e-commerce) à une entreprise,
+// éventuellement limité à un POS. Seule l'empreinte de la clé est stockée.
+type ApiKey struct {
+	UUID      string `gorm:"type:varchar(255);primary_key" json:"uuid"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	DeletedAt gorm.DeletedAt `gorm:"index"`
+
+	Name           string     `gorm:"not null" json:"name"`
+	Prefix         string     `gorm:"type:varchar(20)" json:"prefix"` // Début de la clé, pour l'identifier dans la liste
+	KeyHash        string     `gorm:"type:varchar(64);not null;uniqueIndex" json:"-"`
+	Scopes         string     `json:"scopes"` // Séparés par des virgules : read:products, write:commandes...
+	ExpiresAt      *time.Time `json:"expires_at"`
+	LastUsedAt     *time.Time `json:"last_used_at"`
+	LastUsedIP     string     `json:"last_used_ip"`
+	RevokedAt      *time.Time `json:"revoked_at"`
+	CreatedBy      string     `gorm:"type:varchar(255)" json:"created_by"`
+	EntrepriseUUID string     `gorm:"type:varchar(255);not null;index" json:"entreprise_uuid"`
+	PosUUID        string     `gorm:"type:varchar(255)" json:"pos_uuid"` // Vide : tous les POS de l'entreprise
+}
+
+type ApiKeyRequest struct {
+	Name      string     `json:"name" validate:"required"`
+	Scopes    []string   `json:"scopes" validate:"required"`
+	PosUUID   string     `json:"pos_uuid"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// IsActive indique si la clé n'est ni révoquée ni expirée
+func (k *ApiKey) IsActive() bool {
+	return k.RevokedAt == nil && (k.ExpiresAt == nil || time.Now().Before(*k.ExpiresAt))
+}
+
+// ScopePermission convertit un scope "action:ressource" en permission
+// "ressource:action". Retourne "" si le scope n'est pas accordable à une clé.
+func ScopePermission(scope string) string {
+	parts := strings.SplitN(strings.TrimSpace(scope), ":", 2)
+	if len(parts) != 2 {
+		return ""
+	}
+	action, resource := parts[0], parts[1]
+	if action != "read" && action != "write" && action != "delete" && !(action == "stock" && resource == "products") {
+		return ""
+	}
+	for _, r := range ApiKeyResources {
+		if r == resource {
+			return resource + ":" + action
+		}
+	}
+	return ""
+}
+
+// Principal construit l'utilisateur technique utilisé par le contrôle d'accès
+// pour les requêtes authentifiées par cette clé
+func (k *ApiKey) Principal(entreprise Entreprise) *User {
+	var perms []string
+	for _, s := range strings.Split(k.Scopes, ",") {
+		if p := ScopePermission(s); p != "" {
+			perms = append(perms, p)
+		}
+	}
+
+	return &User{
+		UUID:           k.UUID,
+		Fullname:       k.Name,
+		Role:           RoleApiKey,
+		Permission:     strings.Join(perms, ","),
+		Status:         true,
+		EntrepriseUUID: k.EntrepriseUUID,
+		Entreprise:     entreprise,
+		PosUUID:        k.PosUUID,
+	}
+}
@@ -0,0 +1,32 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Actions enregistrées dans le journal d'audit
+const (
+	AuditCreate = "create"
+	AuditUpdate = "update"
+	AuditDelete = "delete"
+)
+
+// AuditLog trace une modification d'un enregistrement. Les lignes sont écrites
+// par les callbacks GORM du package database et ne peuvent être ni modifiées
+// ni supprimées.
+type AuditLog struct {
+	UUID      string    `gorm:"type:varchar(255);primary_key" json:"uuid"`
+	CreatedAt time.Time `gorm:"index" json:"created_at"`
+
+	Action         string          `gorm:"type:varchar(20);not null;index" json:"action"`
+	Entity         string          `gorm:"type:varchar(100);not null;index:idx_audit_entity" json:"entity"` // Nom de la table
+	EntityUUID     string          `gorm:"type:varchar(255);index:idx_audit_entity" json:"entity_uuid"`
+	Before         json.RawMessage `gorm:"type:jsonb" json:"before"` // Valeurs avant modification (colonnes modifiées seulement pour un update)
+	After          json.RawMessage `gorm:"type:jsonb" json:"after"`
+	UserUUID       string          `gorm:"type:varchar(255);index" json:"user_uuid"` // Utilisateur ou clé d'API à l'origine de la modification
+	ActorType      string          `gorm:"type:varchar(20)" json:"actor_type"`       // user, api_key ou vide (système)
+	IP             string          `json:"ip"`
+	EntrepriseUUID string          `gorm:"type:varchar(255);index" json:"entreprise_uuid"`
+	PosUUID        string          `gorm:"type:varchar(255);index" json:"pos_uuid"`
+}
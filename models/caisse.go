@@ -16,6 +16,7 @@ type Caisse struct {
 	MontantEntre   float64 `gorm:"default:0" json:"montant_entre"`             // Montant d'entrée
 	MontantSorti   float64 `gorm:"default:0" json:"montant_sorti"`             // Montant de sortie
 	MontantDebut   float64 `gorm:"default:0" json:"montant_debut"`             // Montant de début
+	PaymentMethods string  `json:"payment_methods"`                            // Moyens encaissés, séparés par des virgules ; vide = caisse par défaut
 	Signature      string  `json:"signature"`                                  // Signature de la transaction
 	EntrepriseUUID string  `json:"entreprise_uuid"`                            // ID de l'entreprise
 	PosUUID        string  `gorm:"type:varchar(255);not null" json:"pos_uuid"` // ID du point de vente
@@ -0,0 +1,34 @@
+package models
+
+// CheckoutItem est un article du panier : un produit ou un plat
+type CheckoutItem struct {
+	ProductUUID string `json:"product_uuid"`
+	PlatUUID    string `json:"plat_uuid"`
+	Quantity    uint64 `json:"quantity" validate:"required,min=1"`
+}
+
+// CheckoutRequest décrit une vente complète envoyée en un seul appel. Les
+// prix et totaux sont calculés par le serveur ; les totaux affichés par le
+// terminal peuvent être envoyés pour être contrôlés.
+type CheckoutRequest struct {
+	UUID          string           `json:"uuid"` // Facultatif, généré par le terminal pour rejouer la vente sans doublon
+	PosUUID       string           `json:"pos_uuid" validate:"required"`
+	CaisseUUID    string           `json:"caisse_uuid" validate:"required"` // Caisse qui encaisse le paiement
+	ClientUUID    string           `json:"client_uuid"`
+	TableBoxUUID  string           `json:"table_box_uuid"`
+	LivraisonUUID string           `json:"livraison_uuid"`
+	Signature     string           `json:"signature"`
+	Items         []CheckoutItem   `json:"items" validate:"required,min=1,dive"`
+	CouponCodes   []string         `json:"coupon_codes"`
+	Payments      []PaymentRequest `json:"payments" validate:"dive"` // Vide : tout en espèces dans la caisse choisie
+	TotalHt       float64          `json:"total_ht"`
+	TotalTva      float64          `json:"total_tva"`
+	TotalTtc      float64          `json:"total_ttc"`
+}
+
+// QuoteRequest demande le chiffrage d'un panier sans l'enregistrer
+type QuoteRequest struct {
+	PosUUID     string         `json:"pos_uuid" validate:"required"`
+	Items       []CheckoutItem `json:"items" validate:"required,min=1,dive"`
+	CouponCodes []string       `json:"coupon_codes"`
+}
@@ -21,6 +21,8 @@ type Client struct {
 	Organisation string `json:"organisation"`
 	WebSite      string `json:"website"`
 
+	CreditBalance float64 `gorm:"default:0" json:"credit_balance"` // Avoirs non consommés, tenus par le serveur
+
 	Signature      string `json:"signature"`
 	EntrepriseUUID string `json:"entreprise_uuid"`
 	PosUUID        string `gorm:"type:varchar(255);not null" json:"pos_uuid"`
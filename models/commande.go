@@ -14,16 +14,17 @@ type Commande struct {
 	PosUUID   string         `gorm:"type:varchar(255);not null" json:"pos_uuid"`
 	Pos       Pos            `gorm:"foreignKey:PosUUID;references:UUID"` // Point de vente
 
-	Ncommande string  `gorm:"not null" json:"ncommande"` // Number Random
-	Status    string  `json:"status"`                    // Ouverte et Fermée
-	TotalHt   float64 `gorm:"not null" json:"total_ht"`  // Total amount excluding tax
-	TotalTva  float64 `gorm:"not null" json:"total_tva"` // Total tax amount
-	TotalTtc  float64 `gorm:"not null" json:"total_ttc"` // Total amount including tax
-	// TotalRemise    float64 `gorm:"not null" json:"total_remise"`       // Total discount amount
+	Ncommande   string  `gorm:"not null" json:"ncommande"`     // Numéro de facture attribué par le serveur
+	Status      string  `gorm:"index" json:"status"`           // draft, open, sent_to_kitchen, served, paid, cancelled, refunded
+	TotalHt     float64 `gorm:"not null" json:"total_ht"`      // Total amount excluding tax
+	TotalTva    float64 `gorm:"not null" json:"total_tva"`     // Total tax amount
+	TotalTtc    float64 `gorm:"not null" json:"total_ttc"`     // Total amount including tax
+	TotalRemise float64 `gorm:"default:0" json:"total_remise"` // Total discount amount
 	// TotalGlobal    float64 `gorm:"not null" json:"total_global"`       // Total amount including all lines and taxes with reduction
 	ClientUUID     string `gorm:"type:varchar(255);not null" json:"client_uuid"`
 	Client         Client `gorm:"foreignKey:ClientUUID;references:UUID"` // Client
 	Signature      string `json:"signature"`
+	CashierUUID    string `gorm:"type:varchar(255);index" json:"cashier_uuid"` // Caissier actif lors de la création
 	EntrepriseUUID string `json:"entreprise_uuid"`
 	Sync           bool   `gorm:"default:false" json:"sync"`
 
@@ -34,4 +35,9 @@ type Commande struct {
 	Livraison     Livraison `gorm:"foreignKey:LivraisonUUID;references:UUID"`
 
 	CommandeLines []CommandeLine `gorm:"foreignKey:CommandeUUID;references:UUID"` // Liste des lignes de commande
+	Payments      []Payment      `gorm:"foreignKey:CommandeUUID;references:UUID"` // Règlements de la commande
+
+	StatusHistory []CommandeStatusHistory `gorm:"foreignKey:CommandeUUID;references:UUID"` // Changements de statut
+
+	Fiscal FiscalCertificate `gorm:"embedded;embeddedPrefix:fiscal_" json:"fiscal"` // Certification de la facture normalisée
 }
@@ -26,4 +26,19 @@ type CommandeLine struct {
 	PosUUID        string `gorm:"type:varchar(255);not null" json:"pos_uuid"`
 	Pos            Pos    `gorm:"foreignKey:PosUUID;references:UUID"` // Point de vente
 	Sync           bool   `gorm:"default:false" json:"sync"`
+
+	// Prix figés au moment de la vente : les rapports ne dépendent pas des
+	// modifications ultérieures du produit ou du plat
+	UnitPrice    float64 `gorm:"default:0" json:"unit_price"`    // Prix de vente unitaire HT
+	UnitCost     float64 `gorm:"default:0" json:"unit_cost"`     // Prix d'achat unitaire (produits)
+	Remise       float64 `gorm:"default:0" json:"remise"`        // Remise en pourcentage
+	RemiseAmount float64 `gorm:"default:0" json:"remise_amount"` // Montant de la remise
+	TvaRate      float64 `gorm:"default:0" json:"tva_rate"`      // Taux de TVA en pourcentage
+	TvaAmount    float64 `gorm:"default:0" json:"tva_amount"`    // Montant de la TVA
+	TotalHt      float64 `gorm:"default:0" json:"total_ht"`      // Montant HT remisé
+	TotalTtc     float64 `gorm:"default:0" json:"total_ttc"`
+
+	// Promotion retenue pour la ligne et montant qu'elle retire du HT
+	PromotionUUID   string  `gorm:"type:varchar(255);index" json:"promotion_uuid"`
+	PromotionAmount float64 `gorm:"default:0" json:"promotion_amount"`
 }
@@ -0,0 +1,64 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Statuts du cycle de vie d'une commande
+const (
+	CommandeDraft         = "draft"           // Brouillon, non encore confirmée
+	CommandeOpen          = "open"            // Ouverte, en cours de prise
+	CommandeSentToKitchen = "sent_to_kitchen" // Envoyée en cuisine
+	CommandeServed        = "served"          // Servie, en attente de règlement
+	CommandePaid          = "paid"            // Entièrement réglée
+	CommandeCancelled     = "cancelled"       // Annulée
+	CommandeRefunded      = "refunded"        // Entièrement rendue par le client
+)
+
+// CommandeStatuses liste les statuts reconnus
+var CommandeStatuses = []string{
+	CommandeDraft, CommandeOpen, CommandeSentToKitchen, CommandeServed,
+	CommandePaid, CommandeCancelled, CommandeRefunded,
+}
+
+// CommandeTransitions liste, pour chaque statut, ceux vers lesquels la
+// commande peut passer. Annulée et remboursée sont des états finaux ; une
+// commande réglée est rendue par un retour client, ou annulée par un gérant.
+var CommandeTransitions = map[string][]string{
+	CommandeDraft:         {CommandeOpen, CommandeCancelled},
+	CommandeOpen:          {CommandeSentToKitchen, CommandeServed, CommandePaid, CommandeCancelled},
+	CommandeSentToKitchen: {CommandeServed, CommandePaid, CommandeCancelled},
+	CommandeServed:        {CommandePaid, CommandeCancelled},
+	CommandePaid:          {CommandeRefunded, CommandeCancelled},
+	CommandeCancelled:     {},
+	CommandeRefunded:      {},
+}
+
+// PermissionCommandeVoid autorise l'annulation d'une commande déjà réglée
+const PermissionCommandeVoid = "commandes:void"
+
+// CommandeStatusHistory trace chaque changement de statut d'une commande
+type CommandeStatusHistory struct {
+	UUID      string `gorm:"type:varchar(255);primary_key" json:"uuid"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	DeletedAt gorm.DeletedAt `gorm:"index"`
+
+	CommandeUUID string `gorm:"type:varchar(255);not null;index" json:"commande_uuid"`
+	FromStatus   string `json:"from_status"` // Vide à la création de la commande
+	ToStatus     string `gorm:"not null" json:"to_status"`
+	Motif        string `json:"motif"`
+	ActorUUID    string `gorm:"type:varchar(255);index" json:"actor_uuid"` // Utilisateur à l'origine du changement
+
+	EntrepriseUUID string `json:"entreprise_uuid"`
+	PosUUID        string `gorm:"type:varchar(255);not null" json:"pos_uuid"`
+	Sync           bool   `gorm:"default:false" json:"sync"`
+}
+
+// CommandeStatusRequest demande le passage d'une commande à un nouveau statut
+type CommandeStatusRequest struct {
+	Status string `json:"status" validate:"required"`
+	Motif  string `json:"motif"`
+}
@@ -0,0 +1,83 @@
+package models
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+// Règles de résolution d'un conflit de version
+const (
+	ConflictServerWins = "server_wins" // La modification du client est refusée (409)
+	ConflictClientWins = "client_wins" // La modification du client écrase celle du serveur
+	ConflictMerge      = "merge"       // Fusion champ par champ, les compteurs sont additionnés
+)
+
+// Issue d'un conflit enregistré dans le journal
+const (
+	ConflictRejected    = "rejected"
+	ConflictOverwritten = "overwritten"
+	ConflictMerged      = "merged"
+)
+
+// DefaultConflictRules s'applique aux tables sans règle propre à l'entreprise.
+// Les autres tables sont en server_wins.
+var DefaultConflictRules = map[string]ConflictRule{
+	"products": {Entity: "products", Policy: ConflictMerge, MergeFields: "stock,stock_endommage,restitution"},
+	"caisses":  {Entity: "caisses", Policy: ConflictMerge, MergeFields: "montant_entre,montant_sorti"},
+}
+
+// ConflictRule définit, pour une entreprise, comment résoudre les écritures
+// concurrentes sur une table
+type ConflictRule struct {
+	UUID      string `gorm:"type:varchar(255);primary_key" json:"uuid"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+
+	EntrepriseUUID string `gorm:"type:varchar(255);not null;uniqueIndex:idx_conflict_rule" json:"entreprise_uuid"`
+	Entity         string `gorm:"type:varchar(100);not null;uniqueIndex:idx_conflict_rule" json:"entity"` // Nom de la table
+	Policy         string `gorm:"type:varchar(20);not null" json:"policy"`                                // server_wins, client_wins ou merge
+	MergeFields    string `json:"merge_fields"`                                                           // Compteurs fusionnés par différence, séparés par des virgules
+}
+
+type ConflictRuleRequest struct {
+	Entity      string `json:"entity" validate:"required"`
+	Policy      string `json:"policy" validate:"required,oneof=server_wins client_wins merge"`
+	MergeFields string `json:"merge_fields"`
+}
+
+// IsMergeField indique si le champ est un compteur fusionné par différence
+func (r ConflictRule) IsMergeField(field string) bool {
+	for _, f := range strings.Split(r.MergeFields, ",") {
+		if strings.TrimSpace(f) == field {
+			return true
+		}
+	}
+	return false
+}
+
+// ConflictLog trace une écriture faite sur une version périmée d'un
+// enregistrement, pour revue par les gérants
+type ConflictLog struct {
+	UUID      string    `gorm:"type:varchar(255);primary_key" json:"uuid"`
+	CreatedAt time.Time `gorm:"index" json:"created_at"`
+
+	Entity          string          `gorm:"type:varchar(100);not null;index:idx_conflict_entity" json:"entity"`
+	EntityUUID      string          `gorm:"type:varchar(255);index:idx_conflict_entity" json:"entity_uuid"`
+	Source          string          `gorm:"type:varchar(20)" json:"source"` // api ou sync
+	Policy          string          `gorm:"type:varchar(20)" json:"policy"`
+	Resolution      string          `gorm:"type:varchar(20);index" json:"resolution"` // rejected, overwritten ou merged
+	Fields          string          `json:"fields"`                                   // Champs modifiés des deux côtés
+	BaseUpdatedAt   time.Time       `json:"base_updated_at"`                          // Version sur laquelle le client s'est basé
+	ServerUpdatedAt time.Time       `json:"server_updated_at"`                        // Version trouvée sur le serveur
+	ClientData      json.RawMessage `gorm:"type:jsonb" json:"client_data"`
+	ServerData      json.RawMessage `gorm:"type:jsonb" json:"server_data"`
+	ResultData      json.RawMessage `gorm:"type:jsonb" json:"result_data"` // Valeurs retenues après fusion
+
+	UserUUID       string     `gorm:"type:varchar(255)" json:"user_uuid"`
+	DeviceUUID     string     `gorm:"type:varchar(255)" json:"device_uuid"`
+	EntrepriseUUID string     `gorm:"type:varchar(255);index" json:"entreprise_uuid"`
+	PosUUID        string     `gorm:"type:varchar(255);index" json:"pos_uuid"`
+	ReviewedAt     *time.Time `json:"reviewed_at"`
+	ReviewedBy     string     `gorm:"type:varchar(255)" json:"reviewed_by"`
+}
@@ -145,6 +145,16 @@ type RepartitionTransactionsData struct {
 	Colors      []string  `json:"colors"`
 }
 
+// PaymentMethodsData représente la répartition des ventes par moyen de paiement
+type PaymentMethodsData struct {
+	Methods     []string  `json:"methods"`
+	Labels      []string  `json:"labels"`
+	Values      []float64 `json:"values"`
+	Counts      []int64   `json:"counts"`
+	Percentages []float64 `json:"percentages"`
+	Colors      []string  `json:"colors"`
+}
+
 // TopTransaction représente une transaction importante (entrée ou sortie)
 type TopTransaction struct {
 	Libelle   string    `json:"libelle"`
@@ -0,0 +1,48 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Device représente une tablette ou un terminal rattaché à un point de vente
+type Device struct {
+	UUID      string `gorm:"type:varchar(255);primary_key" json:"uuid"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	DeletedAt gorm.DeletedAt `gorm:"index"`
+
+	DeviceID       string     `gorm:"type:varchar(255);not null;index" json:"device_id"` // Identifiant généré par l'application
+	Name           string     `json:"name"`
+	Platform       string     `json:"platform"` // android, ios, windows, web
+	AppVersion     string     `json:"app_version"`
+	LastSeenAt     time.Time  `json:"last_seen_at"`
+	LastSyncAt     *time.Time `json:"last_sync_at"` // Dernière synchronisation confirmée par l'appareil
+	RevokedAt      *time.Time `json:"revoked_at"`
+	UserUUID       string     `gorm:"type:varchar(255)" json:"user_uuid"` // Dernier utilisateur connecté
+	EntrepriseUUID string     `gorm:"type:varchar(255);not null" json:"entreprise_uuid"`
+	PosUUID        string     `gorm:"type:varchar(255);not null" json:"pos_uuid"`
+	Pos            Pos        `gorm:"foreignKey:PosUUID;references:UUID"`
+
+	SyncCursors []DeviceSyncCursor `gorm:"foreignKey:DeviceUUID;references:UUID" json:"sync_cursors"`
+}
+
+// DeviceSyncCursor garde, par POS et par type d'entité, la date de la
+// dernière synchronisation confirmée par le terminal
+type DeviceSyncCursor struct {
+	DeviceUUID string     `gorm:"type:varchar(255);primaryKey" json:"device_uuid"`
+	PosUUID    string     `gorm:"type:varchar(255);primaryKey" json:"pos_uuid"` // "-" pour toute l'entreprise
+	Entity     string     `gorm:"type:varchar(100);primaryKey" json:"entity"`
+	Cursor     time.Time  `json:"cursor"`
+	Pending    *time.Time `json:"pending"` // Réponse servie, en attente de confirmation
+	UpdatedAt  time.Time
+}
+
+// DeviceInfo est envoyé par l'application lors du login
+type DeviceInfo struct {
+	DeviceID   string `json:"device_id"`
+	Name       string `json:"name"`
+	Platform   string `json:"platform"`
+	AppVersion string `json:"app_version"`
+}
@@ -28,6 +28,8 @@ type Entreprise struct {
 
 	TypeAbonnement string `json:"type_abonnement"` // Pack starter, business, pro, entreprise
 
+	TwoFactorRoles string `json:"two_factor_roles"` // Rôles pour lesquels la double authentification est obligatoire, séparés par des virgules
+
 	Users      []User       `gorm:"foreignKey:EntrepriseUUID;references:UUID"` // Liste des utilisateurs de l'entreprise
 	Pos        []Pos        `gorm:"foreignKey:EntrepriseUUID;references:UUID"` // Liste des utilisateurs de l'entreprise
 	Abonnement []Abonnement `gorm:"foreignKey:EntrepriseUUID;references:UUID"` // Liste des utilisateurs de l'entreprise
@@ -51,6 +53,8 @@ type EntrepriseInfos struct {
 
 	TypeAbonnement string `json:"type_abonnement"` // Pack starter, business, pro, entreprise
 
+	TwoFactorRoles string `json:"two_factor_roles"`
+
 	TotalUser       int `json:"total_user"`
 	TotalPos        int `json:"total_pos"`
 	TotalAbonnement int `json:"total_abonnement"`
@@ -0,0 +1,40 @@
+package models
+
+import (
+	"time"
+)
+
+// Statuts de certification fiscale d'une commande
+const (
+	FiscalPending   = "pending"   // En file d'attente du dispositif fiscal
+	FiscalCertified = "certified" // Facture normalisée certifiée
+	FiscalRejected  = "rejected"  // Refusée par le dispositif ; à corriger puis relancer
+)
+
+// FiscalCertificate est la certification d'une facture normalisée par le
+// dispositif électronique fiscal de la DGI. Elle n'est tenue que par le serveur.
+type FiscalCertificate struct {
+	Status      string     `gorm:"index" json:"status"` // Vide si la commande n'est pas soumise à certification
+	Device      string     `json:"device"`              // Identifiant du dispositif fiscal
+	Counters    string     `json:"counters"`            // Compteurs du dispositif, ex. 42/57 FV
+	Signature   string     `json:"signature"`           // Code de certification
+	QRCode      string     `json:"qr_code"`             // Contenu du QR code imprimé sur la facture
+	CertifiedAt *time.Time `json:"certified_at"`
+	Error       string     `json:"error"` // Dernière erreur du dispositif
+}
+
+// FiscalJob est une commande réglée en attente de certification. Tant que le
+// dispositif est indisponible, la tentative est reportée à NextAttemptAt.
+type FiscalJob struct {
+	UUID      string `gorm:"type:varchar(255);primary_key" json:"uuid"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+
+	CommandeUUID  string    `gorm:"type:varchar(255);not null;uniqueIndex" json:"commande_uuid"`
+	Attempts      int       `gorm:"default:0" json:"attempts"`
+	NextAttemptAt time.Time `gorm:"index" json:"next_attempt_at"`
+	LastError     string    `json:"last_error"`
+
+	EntrepriseUUID string `json:"entreprise_uuid"`
+	PosUUID        string `gorm:"type:varchar(255)" json:"pos_uuid"`
+}
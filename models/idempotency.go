@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+// IdempotencyDefaultTTL est la durée de conservation d'une réponse lorsque
+// la variable IDEMPOTENCY_TTL n'est pas définie
+const IdempotencyDefaultTTL = 24 * time.Hour
+
+// IdempotencyKey conserve la première réponse obtenue pour un en-tête
+// Idempotency-Key, afin de la rejouer lorsque le client renvoie la même requête
+type IdempotencyKey struct {
+	UUID      string `gorm:"type:varchar(255);primary_key" json:"uuid"`
+	CreatedAt time.Time
+
+	Key            string    `gorm:"type:varchar(255);not null;uniqueIndex:idx_idempotency_key" json:"key"`
+	EntrepriseUUID string    `gorm:"type:varchar(255);not null;uniqueIndex:idx_idempotency_key" json:"entreprise_uuid"`
+	UserUUID       string    `gorm:"type:varchar(255)" json:"user_uuid"`
+	Method         string    `gorm:"type:varchar(10)" json:"method"`
+	Path           string    `json:"path"`
+	RequestHash    string    `gorm:"type:varchar(64)" json:"request_hash"` // SHA-256 de la méthode, du chemin et du corps
+	Completed      bool      `gorm:"default:false" json:"completed"`       // false tant que la première requête est en cours
+	StatusCode     int       `json:"status_code"`
+	ContentType    string    `json:"content_type"`
+	Body           []byte    `json:"-"`
+	ExpiresAt      time.Time `gorm:"index" json:"expires_at"`
+}
@@ -0,0 +1,54 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// InvitationTTL est la durée de validité d'une invitation
+const InvitationTTL = 72 * time.Hour
+
+// Invitation permet à un gérant d'inviter un employé par email ; l'invité
+// choisit lui-même son mot de passe. Seule l'empreinte du token est stockée.
+type Invitation struct {
+	UUID      string `gorm:"type:varchar(255);primary_key" json:"uuid"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	DeletedAt gorm.DeletedAt `gorm:"index"`
+
+	Email          string     `gorm:"not null;index" json:"email"`
+	Fullname       string     `json:"fullname"`
+	Role           string     `gorm:"not null" json:"role"`
+	Permission     string     `json:"permission"`
+	TokenHash      string     `gorm:"type:varchar(64);uniqueIndex" json:"-"`
+	ExpiresAt      time.Time  `json:"expires_at"`
+	AcceptedAt     *time.Time `json:"accepted_at"`
+	RevokedAt      *time.Time `json:"revoked_at"`
+	InvitedBy      string     `gorm:"type:varchar(255)" json:"invited_by"`
+	UserUUID       string     `gorm:"type:varchar(255)" json:"user_uuid"` // Compte créé à l'acceptation
+	EntrepriseUUID string     `gorm:"type:varchar(255);not null" json:"entreprise_uuid"`
+	Entreprise     Entreprise `gorm:"foreignKey:EntrepriseUUID;references:UUID" json:"-"`
+	PosUUID        string     `gorm:"type:varchar(255);not null" json:"pos_uuid"`
+	Pos            Pos        `gorm:"foreignKey:PosUUID;references:UUID" json:"pos"`
+}
+
+type InvitationRequest struct {
+	Email      string `json:"email" validate:"required,email"`
+	Fullname   string `json:"fullname"`
+	Role       string `json:"role" validate:"required"`
+	Permission string `json:"permission"`
+	PosUUID    string `json:"pos_uuid" validate:"required"`
+}
+
+type AcceptInvitationRequest struct {
+	Fullname        string `json:"fullname" validate:"required"`
+	Telephone       string `json:"telephone" validate:"required"`
+	Password        string `json:"password" validate:"required,min=8"`
+	PasswordConfirm string `json:"password_confirm" validate:"required"`
+}
+
+// IsPending indique si l'invitation peut encore être acceptée
+func (i *Invitation) IsPending() bool {
+	return i.AcceptedAt == nil && i.RevokedAt == nil && time.Now().Before(i.ExpiresAt)
+}
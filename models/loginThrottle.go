@@ -0,0 +1,40 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// LoginThrottle compte les tentatives échouées pour une clé
+// (identifiant de connexion, adresse IP, email de réinitialisation...)
+type LoginThrottle struct {
+	Key           string     `gorm:"type:varchar(255);primary_key" json:"key"`
+	Failures      int        `gorm:"default:0" json:"failures"`
+	LastFailureAt time.Time  `json:"last_failure_at"`
+	LockedUntil   *time.Time `json:"locked_until"`
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
+
+// SecurityEvent enregistre les verrouillages et déverrouillages de comptes
+type SecurityEvent struct {
+	UUID      string `gorm:"type:varchar(255);primary_key" json:"uuid"`
+	CreatedAt time.Time
+
+	Type           string `gorm:"not null;index" json:"type"` // lockout, unlock
+	Key            string `json:"key"`
+	IP             string `json:"ip"`
+	UserUUID       string `gorm:"type:varchar(255);index" json:"user_uuid"` // Compte concerné
+	ActorUUID      string `gorm:"type:varchar(255)" json:"actor_uuid"`      // Gérant ayant effectué l'action
+	EntrepriseUUID string `gorm:"type:varchar(255)" json:"entreprise_uuid"`
+}
+
+// LoginThrottleKey retourne la clé de comptage d'un identifiant de connexion
+func LoginThrottleKey(identifier string) string {
+	return "login:id:" + strings.ToLower(strings.TrimSpace(identifier))
+}
+
+// LoginThrottleKeys retourne les clés de verrouillage du compte (email et téléphone)
+func (u *User) LoginThrottleKeys() []string {
+	return []string{LoginThrottleKey(u.Email), LoginThrottleKey(u.Telephone)}
+}
@@ -0,0 +1,59 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Types de documents numérotés par le serveur
+const (
+	DocumentCommande      = "commande"       // Factures de vente
+	DocumentSaleReturn    = "sale_return"    // Avoirs
+	DocumentPurchaseOrder = "purchase_order" // Bons de commande fournisseur
+)
+
+// DocumentTypes liste les types de documents numérotés
+var DocumentTypes = []string{DocumentCommande, DocumentSaleReturn, DocumentPurchaseOrder}
+
+// DefaultNumberPatterns donne le modèle de numérotation de chaque type de
+// document lorsqu'aucun n'est configuré
+var DefaultNumberPatterns = map[string]string{
+	DocumentCommande:      "{POS}-{YYYY}-{000001}",
+	DocumentSaleReturn:    "AV-{POS}-{YYYY}-{000001}",
+	DocumentPurchaseOrder: "BC-{POS}-{YYYY}-{000001}",
+}
+
+// NumberSequence est le dernier numéro attribué pour un type de document,
+// un point de vente et un exercice. Une ligne n'est jamais supprimée ni
+// décrémentée : un numéro attribué n'est jamais réutilisé.
+type NumberSequence struct {
+	UUID      string `gorm:"type:varchar(255);primary_key" json:"uuid"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+
+	EntrepriseUUID string `gorm:"type:varchar(255);not null;uniqueIndex:idx_number_sequence" json:"entreprise_uuid"`
+	PosUUID        string `gorm:"type:varchar(255);not null;uniqueIndex:idx_number_sequence" json:"pos_uuid"`
+	DocumentType   string `gorm:"type:varchar(50);not null;uniqueIndex:idx_number_sequence" json:"document_type"`
+	Year           int    `gorm:"not null;uniqueIndex:idx_number_sequence" json:"year"` // Exercice
+	LastValue      uint64 `gorm:"not null;default:0" json:"last_value"`
+}
+
+// NumberingPattern configure le modèle de numérotation d'un type de document
+// pour l'entreprise, ou pour un seul point de vente. Jetons reconnus : {POS}
+// (code du point de vente), {YYYY}, {YY}, {MM} et le compteur, dont le nombre
+// de chiffres est celui du jeton ({000001} : 6 chiffres).
+type NumberingPattern struct {
+	UUID      string `gorm:"type:varchar(255);primary_key" json:"uuid"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	DeletedAt gorm.DeletedAt `gorm:"index"`
+
+	DocumentType string `gorm:"type:varchar(50);not null" json:"document_type"`
+	Pattern      string `gorm:"not null" json:"pattern"`
+	PosScope     string `gorm:"type:varchar(255)" json:"pos_scope"` // POS concerné ; vide = tous les POS de l'entreprise
+
+	EntrepriseUUID string `json:"entreprise_uuid"`
+	Signature      string `json:"signature"`
+	Sync           bool   `gorm:"default:false" json:"sync"`
+}
@@ -0,0 +1,81 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Moyens de paiement
+const (
+	PaymentCash        = "cash"
+	PaymentMpesa       = "mpesa"
+	PaymentAirtelMoney = "airtel_money"
+	PaymentOrangeMoney = "orange_money"
+	PaymentCard        = "card"
+	PaymentStoreCredit = "store_credit" // Consommation de l'avoir du client
+)
+
+// PaymentMethods liste les moyens de paiement acceptés
+var PaymentMethods = []string{PaymentCash, PaymentMpesa, PaymentAirtelMoney, PaymentOrangeMoney, PaymentCard, PaymentStoreCredit}
+
+// PaymentMethodLabels donne le libellé affiché de chaque moyen de paiement
+var PaymentMethodLabels = map[string]string{
+	PaymentCash:        "Espèces",
+	PaymentMpesa:       "M-Pesa",
+	PaymentAirtelMoney: "Airtel Money",
+	PaymentOrangeMoney: "Orange Money",
+	PaymentCard:        "Carte bancaire",
+	PaymentStoreCredit: "Avoir client",
+}
+
+// IsMobileMoney indique si le moyen de paiement est un portefeuille mobile
+func IsMobileMoney(method string) bool {
+	return method == PaymentMpesa || method == PaymentAirtelMoney || method == PaymentOrangeMoney
+}
+
+// Payment est un règlement (tender) d'une commande. Une commande peut être
+// réglée en plusieurs fois et par plusieurs moyens ; chaque règlement est
+// porté en entrée dans une caisse, sauf l'avoir qui est débité du compte client.
+type Payment struct {
+	UUID      string `gorm:"type:varchar(255);primary_key" json:"uuid"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	DeletedAt gorm.DeletedAt `gorm:"index"`
+
+	CommandeUUID   string `gorm:"type:varchar(255);not null;index" json:"commande_uuid"`
+	CaisseUUID     string `gorm:"type:varchar(255);not null" json:"caisse_uuid"`
+	CaisseItemUUID string `gorm:"type:varchar(255)" json:"caisse_item_uuid"` // Entrée de caisse correspondante
+
+	Method       string  `gorm:"not null;index" json:"method"`    // cash, mpesa, airtel_money, orange_money, card, store_credit
+	Amount       float64 `gorm:"not null" json:"amount"`          // Part de la commande réglée, dans la devise de l'entreprise
+	Currency     string  `gorm:"type:varchar(3)" json:"currency"` // Devise remise par le client
+	ExchangeRate float64 `gorm:"default:1" json:"exchange_rate"`  // Valeur d'une unité de Currency dans la devise de l'entreprise
+	Tendered     float64 `gorm:"default:0" json:"tendered"`       // Montant remis par le client, en Currency
+	Change       float64 `gorm:"default:0" json:"change"`         // Monnaie rendue, dans la devise de l'entreprise
+	Reference    string  `json:"reference"`                       // Référence externe : transaction mobile money, autorisation carte
+
+	CashierUUID    string `gorm:"type:varchar(255);index" json:"cashier_uuid"`
+	EntrepriseUUID string `json:"entreprise_uuid"`
+	PosUUID        string `gorm:"type:varchar(255);not null" json:"pos_uuid"`
+	Signature      string `json:"signature"`
+	Sync           bool   `gorm:"default:false" json:"sync"`
+}
+
+// PaymentRequest est un règlement envoyé par le terminal
+type PaymentRequest struct {
+	Method       string  `json:"method" validate:"required"`
+	Amount       float64 `json:"amount" validate:"required,gt=0"`
+	Currency     string  `json:"currency"`
+	ExchangeRate float64 `json:"exchange_rate"`
+	Tendered     float64 `json:"tendered"`
+	Reference    string  `json:"reference"`
+	CaisseUUID   string  `json:"caisse_uuid"` // Facultatif : sinon la caisse du POS qui encaisse ce moyen
+}
+
+// PayCommandeRequest règle tout ou partie d'une commande existante
+type PayCommandeRequest struct {
+	CaisseUUID string           `json:"caisse_uuid"` // Caisse par défaut des règlements
+	Signature  string           `json:"signature"`
+	Payments   []PaymentRequest `json:"payments" validate:"required,min=1,dive"`
+}
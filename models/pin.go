@@ -0,0 +1,41 @@
+package models
+
+import (
+	"golang.org/x/crypto/bcrypt"
+)
+
+type PinRequest struct {
+	Pin      string `json:"pin" validate:"required"`
+	Password string `json:"password" validate:"required"`
+}
+
+type PinSwitchRequest struct {
+	Pin      string `json:"pin" validate:"required"`
+	UserUUID string `json:"user_uuid" validate:"required"` // Caissier choisi sur l'écran de verrouillage
+}
+
+// PinRoles liste les rôles qui peuvent prendre la main sur un terminal par code PIN
+var PinRoles = []string{RoleCashier}
+
+// HasPinRole indique si le rôle peut se connecter par code PIN
+func HasPinRole(role string) bool {
+	for _, r := range PinRoles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// SetPin enregistre l'empreinte du code PIN
+func (u *User) SetPin(pin string) {
+	hp, _ := bcrypt.GenerateFromPassword([]byte(pin), bcrypt.DefaultCost)
+	u.PinHash = string(hp)
+}
+
+func (u *User) ComparePin(pin string) bool {
+	if u.PinHash == "" {
+		return false
+	}
+	return bcrypt.CompareHashAndPassword([]byte(u.PinHash), []byte(pin)) == nil
+}
@@ -0,0 +1,32 @@
+package models
+
+import "testing"
+
+func TestHasPinRole(t *testing.T) {
+	for role := range RolePermissions {
+		want := role == RoleCashier
+		if got := HasPinRole(role); got != want {
+			t.Errorf("HasPinRole(%q) = %v, attendu %v", role, got, want)
+		}
+	}
+}
+
+func TestComparePin(t *testing.T) {
+	var u User
+	if u.ComparePin("") || u.ComparePin("1234") {
+		t.Error("un compte sans code PIN ne doit accepter aucun code")
+	}
+
+	u.SetPin("1234")
+	if u.PinHash == "" || u.PinHash == "1234" {
+		t.Fatalf("empreinte du code PIN = %q", u.PinHash)
+	}
+	if !u.ComparePin("1234") {
+		t.Error("le bon code PIN est refusé")
+	}
+	for _, pin := range []string{"", "4321", "12345", "1234 "} {
+		if u.ComparePin(pin) {
+			t.Errorf("code PIN %q accepté", pin)
+		}
+	}
+}
@@ -14,6 +14,7 @@ type Pos struct {
 	EntrepriseUUID string         `gorm:"type:varchar(255);not null" json:"entreprise_uuid"`
 	Entreprise     Entreprise     `gorm:"foreignKey:EntrepriseUUID;references:UUID"`
 	Name           string         `gorm:"not null" json:"name"`
+	Code           string         `json:"code"` // Code court repris dans la numérotation des documents
 	Adresse        string         `json:"adresse"`
 	Email          string         `json:"email"`
 	Telephone      string         `json:"telephone"`
@@ -0,0 +1,59 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Types de promotion
+const (
+	PromotionPercentage = "percentage"  // Pourcentage de remise
+	PromotionFixed      = "fixed"       // Montant retiré par unité
+	PromotionBuyXGetY   = "buy_x_get_y" // Y unités offertes pour X achetées
+	PromotionBundle     = "bundle"      // Lot de N unités à prix fixe
+)
+
+// Promotion est une remise temporaire appliquée automatiquement, ou sur
+// présentation d'un code coupon, aux lignes de commande qu'elle cible.
+// Une ligne reçoit au plus une promotion : la plus avantageuse pour le client.
+type Promotion struct {
+	UUID      string `gorm:"type:varchar(255);primary_key" json:"uuid"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	DeletedAt gorm.DeletedAt `gorm:"index"`
+
+	Name        string  `gorm:"not null" json:"name"`
+	Description string  `json:"description"`
+	Type        string  `gorm:"not null" json:"type"`   // percentage, fixed, buy_x_get_y ou bundle
+	Value       float64 `gorm:"default:0" json:"value"` // Pourcentage, montant par unité ou prix du lot
+	BuyQuantity uint64  `gorm:"default:0" json:"buy_quantity"`
+	GetQuantity uint64  `gorm:"default:0" json:"get_quantity"`
+
+	// Articles ciblés ; les critères vides ne filtrent pas
+	ItemType  string `json:"item_type"` // "product", "plat" ou vide pour les deux
+	ItemUUID  string `gorm:"type:varchar(255)" json:"item_uuid"`
+	Categorie string `json:"categorie"` // Catégorie de plats
+
+	// Période de validité et plage horaire (happy hour), à l'heure du serveur
+	StartsAt  *time.Time `json:"starts_at"`
+	EndsAt    *time.Time `json:"ends_at"`
+	StartTime string     `gorm:"type:varchar(5)" json:"start_time"` // HH:MM
+	EndTime   string     `gorm:"type:varchar(5)" json:"end_time"`   // HH:MM, peut être avant StartTime (nuit)
+	Weekdays  string     `json:"weekdays"`                          // Jours séparés par des virgules, 0 = dimanche
+
+	CouponCode string `gorm:"type:varchar(50);index" json:"coupon_code"` // Vide : promotion automatique
+	UsageLimit int64  `gorm:"default:0" json:"usage_limit"`              // Nombre de commandes maximum, 0 = illimité
+	UsageCount int64  `gorm:"default:0" json:"usage_count"`
+	Stackable  bool   `gorm:"default:false" json:"stackable"` // Se cumule avec la remise propre de l'article
+	Priority   int    `gorm:"default:0" json:"priority"`      // Départage deux promotions de même montant
+	Active     bool   `gorm:"default:true" json:"active"`
+
+	// PosScope limite la promotion à un point de vente ; vide, elle vaut pour
+	// toute l'entreprise. Le champ ne s'appelle pas PosUUID pour rester visible
+	// des utilisateurs rattachés à un POS.
+	PosScope       string `gorm:"type:varchar(255)" json:"pos_scope"`
+	EntrepriseUUID string `json:"entreprise_uuid"`
+	Signature      string `json:"signature"`
+	Sync           bool   `gorm:"default:false" json:"sync"`
+}
@@ -0,0 +1,57 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Formats d'impression d'une commande
+const (
+	ReceiptPDF    = "pdf"    // Facture A4
+	ReceiptESCPOS = "escpos" // Ticket pour imprimante thermique
+)
+
+// ReceiptTemplate personnalise les factures et tickets d'un point de vente
+type ReceiptTemplate struct {
+	UUID      string `gorm:"type:varchar(255);primary_key" json:"uuid"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	DeletedAt gorm.DeletedAt `gorm:"index"`
+
+	PosUUID string `gorm:"type:varchar(255);not null;uniqueIndex" json:"pos_uuid"`
+	Pos     Pos    `gorm:"foreignKey:PosUUID;references:UUID"` // Point de vente
+
+	InvoiceTitle string `json:"invoice_title"` // Titre de la facture A4
+	ReceiptTitle string `json:"receipt_title"` // Titre du ticket
+	Header       string `json:"header"`        // Lignes libres sous les coordonnées, une par ligne
+	Footer       string `json:"footer"`        // Pied de page, une ligne par ligne
+	PaperWidth   int    `json:"paper_width"`   // Largeur du papier thermique : 58 ou 80 mm
+
+	ShowClient       bool `json:"show_client"`
+	ShowCashier      bool `json:"show_cashier"`
+	ShowTvaBreakdown bool `json:"show_tva_breakdown"`
+	ShowPayments     bool `json:"show_payments"`
+	CutPaper         bool `json:"cut_paper"`   // Coupe le papier en fin de ticket
+	OpenDrawer       bool `json:"open_drawer"` // Ouvre le tiroir-caisse à l'impression
+
+	EntrepriseUUID string `json:"entreprise_uuid"`
+	Signature      string `json:"signature"`
+	Sync           bool   `gorm:"default:false" json:"sync"`
+}
+
+// DefaultReceiptTemplate est le modèle d'un point de vente qui n'en a pas configuré
+func DefaultReceiptTemplate(posUUID string) ReceiptTemplate {
+	return ReceiptTemplate{
+		PosUUID:          posUUID,
+		InvoiceTitle:     "FACTURE",
+		ReceiptTitle:     "TICKET DE CAISSE",
+		Footer:           "Merci pour votre visite !",
+		PaperWidth:       80,
+		ShowClient:       true,
+		ShowCashier:      true,
+		ShowTvaBreakdown: true,
+		ShowPayments:     true,
+		CutPaper:         true,
+	}
+}
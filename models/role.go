@@ -0,0 +1,143 @@
+package models
+
+import (
+	"sort"
+	"strings"
+)
+
+// Rôles reconnus par le contrôle d'accès
+const (
+	RoleSuperAdmin        = "super_admin"        // Support ICTECH, toutes les entreprises
+	RoleEntrepriseManager = "entreprise_manager" // Gérant de l'entreprise, tous les POS
+	RolePosManager        = "pos_manager"        // Gérant d'un point de vente
+	RoleCashier           = "cashier"            // Caissier
+	RoleStockKeeper       = "stock_keeper"       // Magasinier
+	RoleLivreur           = "livreur"            // Livreur
+
+	// RoleApiKey est porté par l'utilisateur technique d'une clé d'API ; il
+	// n'accorde aucune permission par lui-même et ne peut pas être attribué
+	RoleApiKey = "api_key"
+)
+
+// PermissionAll donne accès à toutes les actions
+const PermissionAll = "*"
+
+// crud retourne les permissions read, write et delete d'une ressource
+func crud(resources ...string) []string {
+	var perms []string
+	for _, r := range resources {
+		perms = append(perms, r+":read", r+":write", r+":delete")
+	}
+	return perms
+}
+
+// RolePermissions liste les permissions accordées par défaut à chaque rôle.
+// Les permissions sont de la forme "ressource:action".
+var RolePermissions = map[string][]string{
+	RoleSuperAdmin: {PermissionAll},
+	RoleEntrepriseManager: append(crud(
+		"users", "pos", "caisses", "products", "plats", "tablebox", "reservations",
+		"stocks", "clients", "fournisseurs", "zones", "livreurs", "livraisons", "commandes", "promotions", "returns",
+	), "dashboard:read", "entreprise:read", "entreprise:write", "abonnements:read", "products:stock", "commandes:void",
+		"numbering:read", "numbering:write", "receipts:read", "receipts:write", "fiscal:read", "fiscal:write",
+		"devices:read", "devices:write", "apikeys:read", "apikeys:write", "audit:read",
+		"conflicts:read", "conflicts:review", "conflicts:write", "sync:push"),
+	RolePosManager: append(crud(
+		"caisses", "products", "plats", "tablebox", "reservations",
+		"stocks", "clients", "fournisseurs", "zones", "livreurs", "livraisons", "commandes", "promotions", "returns",
+	), "dashboard:read", "entreprise:read", "pos:read", "users:read", "users:write", "products:stock", "commandes:void",
+		"numbering:read", "receipts:read", "receipts:write", "fiscal:read", "fiscal:write",
+		"devices:read", "devices:write", "apikeys:read", "apikeys:write", "audit:read",
+		"conflicts:read", "conflicts:review", "sync:push"),
+	RoleCashier: {
+		"entreprise:read", "pos:read", "products:read", "products:stock", "plats:read",
+		"tablebox:read", "tablebox:write", "reservations:read", "reservations:write",
+		"clients:read", "clients:write", "commandes:read", "commandes:write",
+		"caisses:read", "caisses:write", "zones:read", "livreurs:read",
+		"livraisons:read", "livraisons:write", "promotions:read", "returns:read",
+		"receipts:read", "sync:push",
+	},
+	RoleStockKeeper: append(crud("stocks"),
+		"entreprise:read", "pos:read", "dashboard:read", "products:read", "products:write", "products:stock",
+		"plats:read", "fournisseurs:read", "fournisseurs:write", "sync:push",
+	),
+	RoleLivreur: {
+		"entreprise:read", "pos:read", "livraisons:read", "livraisons:write",
+		"zones:read", "clients:read", "commandes:read",
+	},
+}
+
+// IsValidRole indique si le rôle fait partie des rôles connus
+func IsValidRole(role string) bool {
+	_, ok := RolePermissions[role]
+	return ok
+}
+
+// Permissions retourne les permissions effectives de l'utilisateur : celles de
+// son rôle complétées par la liste séparée par des virgules du champ Permission
+func (u *User) Permissions() []string {
+	set := map[string]bool{}
+	for _, p := range RolePermissions[u.Role] {
+		set[p] = true
+	}
+	for _, p := range strings.Split(u.Permission, ",") {
+		if p = strings.TrimSpace(p); strings.Contains(p, ":") {
+			set[p] = true
+		}
+	}
+
+	perms := make([]string, 0, len(set))
+	for p := range set {
+		perms = append(perms, p)
+	}
+	sort.Strings(perms)
+	return perms
+}
+
+// Can indique si l'utilisateur dispose de la permission demandée
+func (u *User) Can(permission string) bool {
+	for _, p := range u.Permissions() {
+		if p == PermissionAll || p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// CanAssignRole indique si l'utilisateur peut attribuer ce rôle à un autre
+// utilisateur, afin d'empêcher toute élévation de privilèges
+func (u *User) CanAssignRole(role string) bool {
+	if !IsValidRole(role) {
+		return false
+	}
+	switch u.Role {
+	case RoleSuperAdmin:
+		return true
+	case RoleEntrepriseManager:
+		return role != RoleSuperAdmin
+	case RolePosManager:
+		return role == RoleCashier || role == RoleStockKeeper || role == RoleLivreur
+	}
+	return false
+}
+
+// CanManage indique si l'utilisateur peut modifier le compte cible : il doit
+// pouvoir attribuer le rôle actuel de la cible, et un utilisateur rattaché à
+// un POS ne gère que les comptes de son POS
+func (u *User) CanManage(target *User) bool {
+	if !u.CanAssignRole(target.Role) {
+		return false
+	}
+	return u.HasEntrepriseScope() || target.PosUUID == u.PosUUID
+}
+
+// CanGrantPermissions vérifie que l'utilisateur possède lui-même chacune des
+// permissions supplémentaires (séparées par des virgules) qu'il veut accorder
+func (u *User) CanGrantPermissions(list string) bool {
+	for _, p := range strings.Split(list, ",") {
+		if p = strings.TrimSpace(p); strings.Contains(p, ":") && !u.Can(p) {
+			return false
+		}
+	}
+	return true
+}
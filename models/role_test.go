@@ -0,0 +1,100 @@
+package models
+
+import "testing"
+
+func TestCan(t *testing.T) {
+	tests := []struct {
+		name       string
+		user       User
+		permission string
+		want       bool
+	}{
+		{"super admin : toutes les permissions", User{Role: RoleSuperAdmin}, "entreprise:delete", true},
+		{"gérant : permission de son rôle", User{Role: RoleEntrepriseManager}, "users:write", true},
+		{"caissier : permission de son rôle", User{Role: RoleCashier}, "commandes:write", true},
+		{"caissier : permission d'un autre rôle", User{Role: RoleCashier}, "users:write", false},
+		{"caissier : permission supplémentaire", User{Role: RoleCashier, Permission: " returns:write , stocks:read"}, "returns:write", true},
+		{"libellé sans action ignoré", User{Role: RoleCashier, Permission: "*"}, "users:write", false},
+		{"rôle inconnu : aucune permission", User{Role: "admin"}, "commandes:read", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.user.Can(tt.permission); got != tt.want {
+				t.Errorf("Can(%q) = %v, attendu %v", tt.permission, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCanAssignRole(t *testing.T) {
+	// Rôles que chacun peut attribuer ; tous les autres sont refusés
+	allowed := map[string][]string{
+		RoleSuperAdmin:        {RoleSuperAdmin, RoleEntrepriseManager, RolePosManager, RoleCashier, RoleStockKeeper, RoleLivreur, RoleApiKey},
+		RoleEntrepriseManager: {RoleEntrepriseManager, RolePosManager, RoleCashier, RoleStockKeeper, RoleLivreur, RoleApiKey},
+		RolePosManager:        {RoleCashier, RoleStockKeeper, RoleLivreur},
+	}
+
+	for actor := range RolePermissions {
+		for role := range RolePermissions {
+			want := false
+			for _, r := range allowed[actor] {
+				want = want || r == role
+			}
+			u := User{Role: actor}
+			if got := u.CanAssignRole(role); got != want {
+				t.Errorf("%s attribue %s : %v, attendu %v", actor, role, got, want)
+			}
+		}
+		u := User{Role: actor}
+		if u.CanAssignRole("admin") {
+			t.Errorf("%s attribue un rôle inconnu", actor)
+		}
+	}
+}
+
+func TestCanManage(t *testing.T) {
+	entrepriseManager := &User{Role: RoleEntrepriseManager, PosUUID: "pos-a"}
+	posManager := &User{Role: RolePosManager, PosUUID: "pos-a"}
+
+	tests := []struct {
+		name   string
+		actor  *User
+		target *User
+		want   bool
+	}{
+		{"gérant de POS : caissier de son POS", posManager, &User{Role: RoleCashier, PosUUID: "pos-a"}, true},
+		{"gérant de POS : caissier d'un autre POS", posManager, &User{Role: RoleCashier, PosUUID: "pos-b"}, false},
+		{"gérant de POS : autre gérant de son POS", posManager, &User{Role: RolePosManager, PosUUID: "pos-a"}, false},
+		{"gérant de POS : gérant d'entreprise", posManager, &User{Role: RoleEntrepriseManager, PosUUID: "pos-a"}, false},
+		{"gérant d'entreprise : caissier d'un autre POS", entrepriseManager, &User{Role: RoleCashier, PosUUID: "pos-b"}, true},
+		{"gérant d'entreprise : super admin", entrepriseManager, &User{Role: RoleSuperAdmin}, false},
+		{"caissier : autre caissier", &User{Role: RoleCashier, PosUUID: "pos-a"}, &User{Role: RoleCashier, PosUUID: "pos-a"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.actor.CanManage(tt.target); got != tt.want {
+				t.Errorf("CanManage = %v, attendu %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCanGrantPermissions(t *testing.T) {
+	posManager := &User{Role: RolePosManager}
+
+	tests := []struct {
+		list string
+		want bool
+	}{
+		{"", true},
+		{"returns:write, stocks:read", true},
+		{"users:delete", false}, // Le gérant de POS ne l'a pas lui-même
+		{"commandes:read,numbering:write", false},
+		{"*", true}, // Ignoré : ce n'est pas une permission
+	}
+	for _, tt := range tests {
+		if got := posManager.CanGrantPermissions(tt.list); got != tt.want {
+			t.Errorf("CanGrantPermissions(%q) = %v, attendu %v", tt.list, got, tt.want)
+		}
+	}
+}
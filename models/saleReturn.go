@@ -0,0 +1,99 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Remboursement d'un retour client
+const (
+	RefundCaisse      = "caisse"       // Sortie de caisse
+	RefundStoreCredit = "store_credit" // Avoir crédité sur le compte du client
+)
+
+// Devenir de l'article rendu
+const (
+	ReturnRestock = "restock" // Remis en stock, revendable
+	ReturnDamaged = "damaged" // Porté en stock endommagé
+	ReturnNone    = "none"    // Sans mouvement de stock (plats)
+)
+
+// SaleReturn est un retour client (avoir) sur une commande réglée. Il
+// reprend tout ou partie des lignes de la commande d'origine et rembourse le
+// client en caisse ou en avoir.
+type SaleReturn struct {
+	UUID      string `gorm:"type:varchar(255);primary_key" json:"uuid"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	DeletedAt gorm.DeletedAt `gorm:"index"`
+
+	Nreturn      string   `gorm:"not null" json:"nreturn"` // Numéro de l'avoir attribué par le serveur
+	CommandeUUID string   `gorm:"type:varchar(255);not null;index" json:"commande_uuid"`
+	Commande     Commande `gorm:"foreignKey:CommandeUUID;references:UUID"` // Commande d'origine
+	ClientUUID   string   `gorm:"type:varchar(255)" json:"client_uuid"`
+	Motif        string   `json:"motif"` // Raison du retour
+
+	RefundMethod   string `gorm:"not null" json:"refund_method"` // caisse ou store_credit
+	CaisseUUID     string `gorm:"type:varchar(255)" json:"caisse_uuid"`
+	CaisseItemUUID string `gorm:"type:varchar(255)" json:"caisse_item_uuid"` // Sortie de caisse du remboursement
+
+	TotalHt  float64 `gorm:"not null" json:"total_ht"`
+	TotalTva float64 `gorm:"not null" json:"total_tva"`
+	TotalTtc float64 `gorm:"not null" json:"total_ttc"` // Montant remboursé
+
+	CashierUUID    string `gorm:"type:varchar(255);index" json:"cashier_uuid"`
+	EntrepriseUUID string `json:"entreprise_uuid"`
+	PosUUID        string `gorm:"type:varchar(255);not null" json:"pos_uuid"`
+	Pos            Pos    `gorm:"foreignKey:PosUUID;references:UUID"` // Point de vente
+	Signature      string `json:"signature"`
+	Sync           bool   `gorm:"default:false" json:"sync"`
+
+	Lines []SaleReturnLine `gorm:"foreignKey:SaleReturnUUID;references:UUID" json:"lines"` // Articles rendus
+}
+
+// SaleReturnLine est un article rendu. Les montants sont la part, au prorata
+// de la quantité, des montants figés sur la ligne de commande d'origine.
+type SaleReturnLine struct {
+	UUID      string `gorm:"type:varchar(255);primary_key" json:"uuid"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	DeletedAt gorm.DeletedAt `gorm:"index"`
+
+	SaleReturnUUID   string `gorm:"type:varchar(255);not null;index" json:"sale_return_uuid"`
+	CommandeLineUUID string `gorm:"type:varchar(255);not null;index" json:"commande_line_uuid"`
+	ProductUUID      string `gorm:"type:varchar(255)" json:"product_uuid"`
+	PlatUUID         string `gorm:"type:varchar(255)" json:"plat_uuid"`
+	ItemType         string `gorm:"not null" json:"item_type"` // "product" ou "plat"
+
+	Quantity           uint64 `gorm:"not null" json:"quantity"`
+	Disposition        string `gorm:"not null" json:"disposition"`                   // restock, damaged ou none
+	StockEndommageUUID string `gorm:"type:varchar(255)" json:"stock_endommage_uuid"` // Stock endommagé créé pour l'article
+
+	UnitCost  float64 `gorm:"default:0" json:"unit_cost"` // Prix d'achat unitaire de la ligne d'origine
+	TotalHt   float64 `gorm:"default:0" json:"total_ht"`
+	TvaAmount float64 `gorm:"default:0" json:"tva_amount"`
+	TotalTtc  float64 `gorm:"default:0" json:"total_ttc"`
+
+	EntrepriseUUID string `json:"entreprise_uuid"`
+	PosUUID        string `gorm:"type:varchar(255);not null" json:"pos_uuid"`
+	Sync           bool   `gorm:"default:false" json:"sync"`
+}
+
+// SaleReturnRequest est le retour envoyé par le terminal
+type SaleReturnRequest struct {
+	UUID         string                  `json:"uuid"`
+	CommandeUUID string                  `json:"commande_uuid" validate:"required"`
+	Motif        string                  `json:"motif"`
+	RefundMethod string                  `json:"refund_method" validate:"required,oneof=caisse store_credit"`
+	CaisseUUID   string                  `json:"caisse_uuid"` // Obligatoire pour un remboursement en caisse
+	Signature    string                  `json:"signature"`
+	Lines        []SaleReturnLineRequest `json:"lines" validate:"required,min=1,dive"`
+}
+
+// SaleReturnLineRequest est un article rendu
+type SaleReturnLineRequest struct {
+	CommandeLineUUID string `json:"commande_line_uuid" validate:"required"`
+	Quantity         uint64 `json:"quantity" validate:"required,gt=0"`
+	Damaged          bool   `json:"damaged"` // Article abîmé : porté en stock endommagé plutôt que remis en vente
+}
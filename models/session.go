@@ -0,0 +1,31 @@
+package models
+
+import (
+	"time"
+)
+
+// Session représente un refresh token émis lors d'une connexion.
+// Le token lui-même n'est jamais stocké, seulement son empreinte SHA-256.
+type Session struct {
+	UUID      string `gorm:"type:varchar(255);primary_key" json:"uuid"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+
+	UserUUID         string     `gorm:"type:varchar(255);not null;index" json:"user_uuid"`
+	RefreshTokenHash string     `gorm:"type:varchar(64);uniqueIndex" json:"-"`
+	ExpiresAt        time.Time  `json:"expires_at"`
+	RevokedAt        *time.Time `json:"revoked_at"`
+	ReplacedBy       string     `gorm:"type:varchar(255)" json:"-"` // Session créée lors de la rotation du token
+	DeviceUUID       string     `gorm:"type:varchar(255);index" json:"device_uuid"`
+	UserAgent        string     `json:"user_agent"`
+	IP               string     `json:"ip"`
+}
+
+// IsActive indique si la session peut encore être utilisée
+func (s *Session) IsActive() bool {
+	return s.RevokedAt == nil && time.Now().Before(s.ExpiresAt)
+}
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" validate:"required"`
+}
@@ -0,0 +1,24 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSessionIsActive(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name    string
+		session Session
+		want    bool
+	}{
+		{"en cours", Session{ExpiresAt: now.Add(time.Hour)}, true},
+		{"expirée", Session{ExpiresAt: now.Add(-time.Second)}, false},
+		{"révoquée", Session{ExpiresAt: now.Add(time.Hour), RevokedAt: &now}, false},
+	}
+	for _, tt := range tests {
+		if got := tt.session.IsActive(); got != tt.want {
+			t.Errorf("%s : IsActive = %v, attendu %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// RecoveryCode est un code de secours à usage unique, stocké sous forme d'empreinte
+type RecoveryCode struct {
+	UUID      string `gorm:"type:varchar(255);primary_key" json:"uuid"`
+	CreatedAt time.Time
+
+	UserUUID string     `gorm:"type:varchar(255);not null;index" json:"user_uuid"`
+	CodeHash string     `gorm:"type:varchar(64);not null" json:"-"`
+	UsedAt   *time.Time `json:"used_at"`
+}
+
+type TwoFactorCodeRequest struct {
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recovery_code"`
+	Password     string `json:"password"`
+}
+
+type TwoFactorLoginRequest struct {
+	ChallengeToken string      `json:"challenge_token" validate:"required"`
+	Code           string      `json:"code"`
+	RecoveryCode   string      `json:"recovery_code"`
+	Device         *DeviceInfo `json:"device"`
+}
+
+// RequiresTwoFactor indique si l'entreprise impose la double authentification au rôle
+func (e *Entreprise) RequiresTwoFactor(role string) bool {
+	for _, r := range strings.Split(e.TwoFactorRoles, ",") {
+		if strings.TrimSpace(r) == role && role != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// TwoFactorRequired suppose que l'entreprise de l'utilisateur a été préchargée
+func (u *User) TwoFactorRequired() bool {
+	return u.Entreprise.RequiresTwoFactor(u.Role)
+}
@@ -27,6 +27,12 @@ type User struct {
 	Pos             Pos        `gorm:"foreignKey:PosUUID;references:UUID" json:"pos"`
 	Signature       string     `json:"signature"`
 	Sync            bool       `gorm:"default:false" json:"sync"`
+
+	TwoFactorEnabled  bool   `gorm:"default:false" json:"two_factor_enabled"`
+	TwoFactorSecret   string `json:"-"`
+	TwoFactorLastStep int64  `json:"-"` // Dernière période TOTP acceptée, empêche le rejeu d'un code
+
+	PinHash string `json:"-"` // Code PIN (4 à 6 chiffres) pour changer de caissier sur un terminal partagé
 }
 
 type UserResponse struct {
@@ -45,13 +51,33 @@ type UserResponse struct {
 	CreatedAt      time.Time
 	UpdatedAt      time.Time
 	Sync           bool `json:"sync"`
+
+	TwoFactorEnabled  bool `json:"two_factor_enabled"`
+	TwoFactorRequired bool `json:"two_factor_required"`
+	HasPin            bool `json:"has_pin"`
 }
 
 type Login struct {
 	// Email    string `json:"email" validate:"required,email"`
 	// Phone    string `json:"phone" validate:"required"`
-	Identifier string `json:"identifier" validate:"required"`
-	Password   string `json:"password" validate:"required"`
+	Identifier string      `json:"identifier" validate:"required"`
+	Password   string      `json:"password" validate:"required"`
+	Device     *DeviceInfo `json:"device"` // Terminal POS à enregistrer, optionnel
+}
+
+// IsSuperAdmin indique si l'utilisateur peut accéder à toutes les entreprises
+func (u *User) IsSuperAdmin() bool {
+	return u.Role == RoleSuperAdmin
+}
+
+// HasEntrepriseScope indique si l'utilisateur voit tous les POS de son entreprise
+func (u *User) HasEntrepriseScope() bool {
+	return u.IsSuperAdmin() || u.Role == RoleEntrepriseManager || (u.Role == RoleApiKey && u.PosUUID == "")
+}
+
+// IsApiKey indique que la requête est authentifiée par une clé d'API
+func (u *User) IsApiKey() bool {
+	return u.Role == RoleApiKey
 }
 
 func (u *User) SetPassword(p string) {
@@ -0,0 +1,131 @@
+package numbering
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/kgermando/ipos-stock-api/models"
+	"github.com/kgermando/ipos-stock-api/utils"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrUnknownDocument : type de document non numéroté
+	ErrUnknownDocument = errors.New("type de document inconnu")
+	// ErrInvalidPattern : le modèle ne garantit pas des numéros uniques
+	ErrInvalidPattern = errors.New("le modèle doit contenir {POS}, l'exercice ({YYYY} ou {YY}) et un compteur tel que {000001}")
+)
+
+// counterToken est le compteur d'un modèle : des zéros terminés par un 1
+var counterToken = regexp.MustCompile(`\{0*1\}`)
+
+// Validate vérifie qu'un modèle produit des numéros uniques par point de
+// vente et par exercice
+func Validate(pattern string) error {
+	if len(counterToken.FindAllString(pattern, -1)) != 1 ||
+		!strings.Contains(pattern, "{POS}") ||
+		!(strings.Contains(pattern, "{YYYY}") || strings.Contains(pattern, "{YY}")) {
+		return ErrInvalidPattern
+	}
+	return nil
+}
+
+// Pattern retourne le modèle applicable au point de vente : le sien, sinon
+// celui de l'entreprise, sinon le modèle par défaut du type de document
+func Pattern(tx *gorm.DB, docType string, pos models.Pos) string {
+	var patterns []models.NumberingPattern
+	tx.Where("entreprise_uuid = ? AND document_type = ?", pos.EntrepriseUUID, docType).
+		Where("pos_scope = ? OR pos_scope = '' OR pos_scope IS NULL", pos.UUID).
+		Find(&patterns)
+
+	pattern := models.DefaultNumberPatterns[docType]
+	for _, p := range patterns {
+		if p.PosScope == pos.UUID {
+			return p.Pattern
+		}
+		pattern = p.Pattern
+	}
+	return pattern
+}
+
+// Next attribue le numéro suivant du type de document pour le point de vente
+// et l'exercice en cours. Le compteur est incrémenté dans la transaction tx,
+// qui verrouille la séquence jusqu'à sa fin : deux ventes simultanées
+// obtiennent des numéros consécutifs et, si la transaction est annulée, le
+// numéro l'est aussi, sans laisser de trou.
+func Next(tx *gorm.DB, docType string, pos models.Pos, at time.Time) (string, error) {
+	if !slices.Contains(models.DocumentTypes, docType) {
+		return "", ErrUnknownDocument
+	}
+
+	var value uint64
+	err := tx.Raw(`INSERT INTO number_sequences
+			(uuid, created_at, updated_at, entreprise_uuid, pos_uuid, document_type, year, last_value)
+		VALUES (?, ?, ?, ?, ?, ?, ?, 1)
+		ON CONFLICT (entreprise_uuid, pos_uuid, document_type, year)
+		DO UPDATE SET last_value = number_sequences.last_value + 1, updated_at = EXCLUDED.updated_at
+		RETURNING last_value`,
+		utils.GenerateUUID(), at, at, pos.EntrepriseUUID, pos.UUID, docType, at.Year()).
+		Scan(&value).Error
+	if err != nil {
+		return "", err
+	}
+
+	return Format(Pattern(tx, docType, pos), pos, at, value), nil
+}
+
+// Peek retourne le numéro que recevra le prochain document, sans l'attribuer
+func Peek(tx *gorm.DB, docType string, pos models.Pos, at time.Time) (string, error) {
+	if !slices.Contains(models.DocumentTypes, docType) {
+		return "", ErrUnknownDocument
+	}
+
+	var seq models.NumberSequence
+	tx.Where("entreprise_uuid = ? AND pos_uuid = ? AND document_type = ? AND year = ?",
+		pos.EntrepriseUUID, pos.UUID, docType, at.Year()).
+		Limit(1).Find(&seq)
+
+	return Format(Pattern(tx, docType, pos), pos, at, seq.LastValue+1), nil
+}
+
+// Format remplace les jetons du modèle
+func Format(pattern string, pos models.Pos, at time.Time, value uint64) string {
+	number := strings.NewReplacer(
+		"{POS}", Code(pos),
+		"{YYYY}", at.Format("2006"),
+		"{YY}", at.Format("06"),
+		"{MM}", at.Format("01"),
+	).Replace(pattern)
+
+	return counterToken.ReplaceAllStringFunc(number, func(token string) string {
+		return fmt.Sprintf("%0*d", len(token)-2, value)
+	})
+}
+
+// Code retourne le code du point de vente utilisé dans les numéros : celui
+// configuré, sinon les initiales de son nom
+func Code(pos models.Pos) string {
+	if code := strings.TrimSpace(pos.Code); code != "" {
+		return strings.ToUpper(code)
+	}
+
+	var initials []rune
+	for _, word := range strings.Fields(pos.Name) {
+		for _, r := range word {
+			if unicode.IsLetter(r) || unicode.IsDigit(r) {
+				initials = append(initials, unicode.ToUpper(r))
+				break
+			}
+		}
+	}
+	if len(initials) == 0 {
+		return "POS"
+	}
+	return string(initials)
+}
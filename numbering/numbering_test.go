@@ -0,0 +1,75 @@
+package numbering
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/kgermando/ipos-stock-api/models"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		pattern string
+		err     error
+	}{
+		{"{POS}-{YYYY}-{000001}", nil},
+		{"FAC/{POS}/{YY}{MM}/{0001}", nil},
+		{"{POS}-{000001}", ErrInvalidPattern},           // Sans exercice
+		{"{YYYY}-{000001}", ErrInvalidPattern},          // Sans point de vente
+		{"{POS}-{YYYY}", ErrInvalidPattern},             // Sans compteur
+		{"{POS}-{YYYY}-{001}-{001}", ErrInvalidPattern}, // Deux compteurs
+		{"{POS}-{YYYY}-{000002}", ErrInvalidPattern},    // Compteur mal formé
+	}
+	for _, tt := range tests {
+		if err := Validate(tt.pattern); !errors.Is(err, tt.err) {
+			t.Errorf("Validate(%q) = %v, attendu %v", tt.pattern, err, tt.err)
+		}
+	}
+
+	for docType, pattern := range models.DefaultNumberPatterns {
+		if err := Validate(pattern); err != nil {
+			t.Errorf("modèle par défaut de %s invalide : %v", docType, err)
+		}
+	}
+}
+
+func TestFormat(t *testing.T) {
+	pos := models.Pos{Code: "gm", Name: "Grand Marché"}
+	at := time.Date(2026, time.March, 5, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		pattern string
+		value   uint64
+		want    string
+	}{
+		{"{POS}-{YYYY}-{000001}", 42, "GM-2026-000042"},
+		{"AV-{POS}-{YYYY}-{000001}", 1, "AV-GM-2026-000001"},
+		{"FAC/{POS}/{YY}{MM}/{0001}", 42, "FAC/GM/2603/0042"},
+		{"{POS}-{YY}-{1}", 7, "GM-26-7"},
+		{"{POS}-{YYYY}-{0001}", 1234567, "GM-2026-1234567"}, // Le compteur déborde sans être tronqué
+	}
+	for _, tt := range tests {
+		if got := Format(tt.pattern, pos, at, tt.value); got != tt.want {
+			t.Errorf("Format(%q, %d) = %q, attendu %q", tt.pattern, tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestCode(t *testing.T) {
+	tests := []struct {
+		pos  models.Pos
+		want string
+	}{
+		{models.Pos{Code: " kin1 ", Name: "Kinshasa Gombe"}, "KIN1"},
+		{models.Pos{Name: "Grand Marché Central"}, "GMC"},
+		{models.Pos{Name: "la gombe"}, "LG"},
+		{models.Pos{Name: "(Dépôt) 2e étage"}, "D2É"},
+		{models.Pos{Name: "  "}, "POS"},
+	}
+	for _, tt := range tests {
+		if got := Code(tt.pos); got != tt.want {
+			t.Errorf("Code(%+v) = %q, attendu %q", tt.pos, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,271 @@
+package payments
+
+import (
+	"errors"
+	"slices"
+	"strings"
+
+	"github.com/kgermando/ipos-stock-api/models"
+	"github.com/kgermando/ipos-stock-api/pricing"
+	"github.com/kgermando/ipos-stock-api/utils"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// ErrUnknownMethod : moyen de paiement non pris en charge
+	ErrUnknownMethod = errors.New("moyen de paiement inconnu")
+	// ErrReferenceRequired : un paiement mobile money doit porter la référence de la transaction
+	ErrReferenceRequired = errors.New("la référence de la transaction mobile money est obligatoire")
+	// ErrExchangeRate : un paiement dans une autre devise doit indiquer le taux de change
+	ErrExchangeRate = errors.New("le taux de change est obligatoire pour un paiement dans une autre devise")
+	// ErrInsufficientTender : le montant remis en espèces ne couvre pas le règlement
+	ErrInsufficientTender = errors.New("le montant remis est inférieur au montant à régler")
+	// ErrOverpaid : les règlements dépassent le reste à payer
+	ErrOverpaid = errors.New("les règlements dépassent le reste à payer")
+	// ErrNoCaisse : aucune caisse du point de vente n'encaisse ce moyen de paiement
+	ErrNoCaisse = errors.New("aucune caisse ne peut encaisser ce moyen de paiement")
+	// ErrInsufficientCredit : l'avoir du client ne couvre pas le règlement
+	ErrInsufficientCredit = errors.New("l'avoir du client est insuffisant")
+)
+
+// Tenders sont les règlements à enregistrer pour une commande
+type Tenders struct {
+	Commande      *models.Commande
+	Requests      []models.PaymentRequest
+	DefaultCaisse string // Caisse utilisée lorsqu'aucune caisse n'est dédiée au moyen de paiement
+	CashierUUID   string
+	Signature     string
+}
+
+// Paid retourne le montant déjà réglé sur la commande
+func Paid(tx *gorm.DB, commandeUUID string) float64 {
+	var paid float64
+	tx.Model(&models.Payment{}).
+		Where("commande_uuid = ?", commandeUUID).
+		Select("COALESCE(SUM(amount), 0)").
+		Scan(&paid)
+	return paid
+}
+
+// Sum additionne les montants des règlements demandés
+func Sum(reqs []models.PaymentRequest, policy pricing.Policy) float64 {
+	var total float64
+	for _, r := range reqs {
+		total += r.Amount
+	}
+	return policy.Round(total)
+}
+
+// Post vérifie et enregistre les règlements dans la limite du reste à payer,
+// puis porte chacun en entrée dans la caisse qui encaisse son moyen de
+// paiement. Un règlement par avoir est débité du compte du client.
+func Post(tx *gorm.DB, t Tenders, due float64, policy pricing.Policy) ([]models.Payment, []models.CaisseItem, error) {
+	if Sum(t.Requests, policy) > policy.Round(due) {
+		return nil, nil, ErrOverpaid
+	}
+
+	commande := t.Commande
+	var payments []models.Payment
+	var items []models.CaisseItem
+
+	for _, r := range t.Requests {
+		payment, err := newPayment(r, policy)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		payment.UUID = utils.GenerateUUID()
+		payment.CommandeUUID = commande.UUID
+		payment.CashierUUID = t.CashierUUID
+		payment.EntrepriseUUID = commande.EntrepriseUUID
+		payment.PosUUID = commande.PosUUID
+		payment.Signature = t.Signature
+		payment.Sync = true
+
+		if payment.Method == models.PaymentStoreCredit {
+			if err := debitCredit(tx, commande.ClientUUID, payment.Amount); err != nil {
+				return nil, nil, err
+			}
+			if err := tx.Create(&payment).Error; err != nil {
+				return nil, nil, err
+			}
+			payments = append(payments, payment)
+			continue
+		}
+
+		caisseUUID, err := resolveCaisse(tx, commande.PosUUID, payment.Method, r.CaisseUUID, t.DefaultCaisse)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		label := models.PaymentMethodLabels[payment.Method]
+		libelle := "Vente commande N° " + commande.Ncommande + " (" + label + ")"
+		if payment.Reference != "" {
+			libelle += " réf. " + payment.Reference
+		}
+		item := models.CaisseItem{
+			UUID:            utils.GenerateUUID(),
+			CaisseUUID:      caisseUUID,
+			TypeTransaction: "Entree",
+			Montant:         payment.Amount,
+			Libelle:         libelle,
+			Reference:       commande.Ncommande,
+			Signature:       t.Signature,
+			CashierUUID:     t.CashierUUID,
+			EntrepriseUUID:  commande.EntrepriseUUID,
+			PosUUID:         commande.PosUUID,
+			Sync:            true,
+		}
+		if err := tx.Omit(clause.Associations).Create(&item).Error; err != nil {
+			return nil, nil, err
+		}
+
+		payment.CaisseUUID = caisseUUID
+		payment.CaisseItemUUID = item.UUID
+		if err := tx.Create(&payment).Error; err != nil {
+			return nil, nil, err
+		}
+
+		payments = append(payments, payment)
+		items = append(items, item)
+	}
+	return payments, items, nil
+}
+
+// Reverse annule les règlements d'une commande : chaque encaissement est
+// porté en sortie dans la caisse qui l'a reçu, et l'avoir consommé est rendu
+// au client
+func Reverse(tx *gorm.DB, commande *models.Commande, cashierUUID string) ([]models.CaisseItem, error) {
+	var payments []models.Payment
+	if err := tx.Where("commande_uuid = ?", commande.UUID).Order("created_at").Find(&payments).Error; err != nil {
+		return nil, err
+	}
+
+	var items []models.CaisseItem
+	for _, payment := range payments {
+		if payment.Method == models.PaymentStoreCredit {
+			if err := tx.Model(&models.Client{}).Where("uuid = ?", commande.ClientUUID).
+				Updates(map[string]interface{}{"credit_balance": gorm.Expr("credit_balance + ?", payment.Amount), "sync": true}).Error; err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if payment.CaisseUUID == "" {
+			continue
+		}
+
+		item := models.CaisseItem{
+			UUID:            utils.GenerateUUID(),
+			CaisseUUID:      payment.CaisseUUID,
+			TypeTransaction: "Sortie",
+			Montant:         payment.Amount,
+			Libelle:         "Annulation commande N° " + commande.Ncommande + " (" + models.PaymentMethodLabels[payment.Method] + ")",
+			Reference:       commande.Ncommande,
+			CashierUUID:     cashierUUID,
+			EntrepriseUUID:  commande.EntrepriseUUID,
+			PosUUID:         commande.PosUUID,
+			Sync:            true,
+		}
+		if err := tx.Omit(clause.Associations).Create(&item).Error; err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// debitCredit consomme l'avoir du client ; le solde ne peut pas devenir négatif
+func debitCredit(tx *gorm.DB, clientUUID string, amount float64) error {
+	if clientUUID == "" {
+		return ErrInsufficientCredit
+	}
+	res := tx.Model(&models.Client{}).
+		Where("uuid = ? AND credit_balance >= ?", clientUUID, amount).
+		Updates(map[string]interface{}{"credit_balance": gorm.Expr("credit_balance - ?", amount), "sync": true})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrInsufficientCredit
+	}
+	return nil
+}
+
+// newPayment contrôle un règlement et calcule la monnaie rendue. Seules les
+// espèces peuvent dépasser le montant réglé ; les autres moyens sont débités
+// du montant exact.
+func newPayment(r models.PaymentRequest, policy pricing.Policy) (models.Payment, error) {
+	p := models.Payment{
+		Method:       strings.ToLower(strings.TrimSpace(r.Method)),
+		Amount:       policy.Round(r.Amount),
+		Currency:     strings.ToUpper(strings.TrimSpace(r.Currency)),
+		ExchangeRate: r.ExchangeRate,
+		Tendered:     r.Tendered,
+		Reference:    strings.TrimSpace(r.Reference),
+	}
+
+	if !slices.Contains(models.PaymentMethods, p.Method) {
+		return p, ErrUnknownMethod
+	}
+	if models.IsMobileMoney(p.Method) && p.Reference == "" {
+		return p, ErrReferenceRequired
+	}
+
+	if p.Currency == "" || p.Currency == policy.Currency {
+		p.Currency, p.ExchangeRate = policy.Currency, 1
+	} else if p.ExchangeRate <= 0 {
+		return p, ErrExchangeRate
+	}
+
+	if p.Method != models.PaymentCash || p.Tendered == 0 {
+		p.Tendered = p.Amount / p.ExchangeRate
+		return p, nil
+	}
+
+	received := policy.Round(p.Tendered * p.ExchangeRate)
+	if received < p.Amount {
+		return p, ErrInsufficientTender
+	}
+	p.Change = policy.Round(received - p.Amount)
+	return p, nil
+}
+
+// resolveCaisse choisit la caisse du règlement : celle demandée, sinon celle
+// du point de vente dédiée au moyen de paiement, sinon la caisse par défaut,
+// sinon la première caisse du point de vente sans moyen dédié
+func resolveCaisse(tx *gorm.DB, posUUID, method, requested, fallback string) (string, error) {
+	if requested != "" {
+		if !posCaisse(tx, posUUID, requested) {
+			return "", ErrNoCaisse
+		}
+		return requested, nil
+	}
+
+	var caisses []models.Caisse
+	tx.Where("pos_uuid = ?", posUUID).Order("created_at").Find(&caisses)
+	for _, caisse := range caisses {
+		for _, m := range strings.Split(caisse.PaymentMethods, ",") {
+			if strings.EqualFold(strings.TrimSpace(m), method) {
+				return caisse.UUID, nil
+			}
+		}
+	}
+	if fallback != "" && posCaisse(tx, posUUID, fallback) {
+		return fallback, nil
+	}
+	for _, caisse := range caisses {
+		if strings.TrimSpace(caisse.PaymentMethods) == "" {
+			return caisse.UUID, nil
+		}
+	}
+	return "", ErrNoCaisse
+}
+
+// posCaisse indique si la caisse appartient au POS de la commande
+func posCaisse(tx *gorm.DB, posUUID, caisseUUID string) bool {
+	var count int64
+	tx.Model(&models.Caisse{}).Where("uuid = ? AND pos_uuid = ?", caisseUUID, posUUID).Count(&count)
+	return count > 0
+}
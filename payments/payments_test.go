@@ -0,0 +1,105 @@
+package payments
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/kgermando/ipos-stock-api/models"
+	"github.com/kgermando/ipos-stock-api/pricing"
+)
+
+func TestSum(t *testing.T) {
+	tests := []struct {
+		currency string
+		amounts  []float64
+		want     float64
+	}{
+		{"CDF", nil, 0},
+		{"CDF", []float64{10000, 2500.4}, 12500},
+		{"USD", []float64{0.1, 0.2}, 0.3},
+		{"USD", []float64{9.994, 0.001}, 9.99},
+	}
+	for _, tt := range tests {
+		reqs := make([]models.PaymentRequest, 0, len(tt.amounts))
+		for _, a := range tt.amounts {
+			reqs = append(reqs, models.PaymentRequest{Method: models.PaymentCash, Amount: a})
+		}
+		if got := Sum(reqs, pricing.PolicyFor(tt.currency)); got != tt.want {
+			t.Errorf("Sum(%s %v) = %v, attendu %v", tt.currency, tt.amounts, got, tt.want)
+		}
+	}
+}
+
+func TestNewPayment(t *testing.T) {
+	cdf := pricing.PolicyFor("CDF")
+
+	tests := []struct {
+		name     string
+		req      models.PaymentRequest
+		err      error
+		currency string
+		tendered float64
+		change   float64
+	}{
+		{
+			name:     "espèces au montant exact",
+			req:      models.PaymentRequest{Method: "cash", Amount: 12500},
+			currency: "CDF", tendered: 12500,
+		},
+		{
+			name:     "espèces avec monnaie rendue",
+			req:      models.PaymentRequest{Method: " CASH ", Amount: 12500, Tendered: 20000},
+			currency: "CDF", tendered: 20000, change: 7500,
+		},
+		{
+			name: "espèces insuffisantes",
+			req:  models.PaymentRequest{Method: "cash", Amount: 12500, Tendered: 10000},
+			err:  ErrInsufficientTender,
+		},
+		{
+			name:     "dollars convertis au taux du jour",
+			req:      models.PaymentRequest{Method: "cash", Amount: 28000, Currency: "usd", ExchangeRate: 2800, Tendered: 20},
+			currency: "USD", tendered: 20, change: 28000,
+		},
+		{
+			name: "dollars sans taux de change",
+			req:  models.PaymentRequest{Method: "cash", Amount: 28000, Currency: "USD"},
+			err:  ErrExchangeRate,
+		},
+		{
+			name:     "carte débitée du montant exact",
+			req:      models.PaymentRequest{Method: "card", Amount: 5000, Tendered: 9000},
+			currency: "CDF", tendered: 5000,
+		},
+		{
+			name:     "mobile money avec référence",
+			req:      models.PaymentRequest{Method: models.PaymentMpesa, Amount: 5000, Reference: " MP123 "},
+			currency: "CDF", tendered: 5000,
+		},
+		{
+			name: "mobile money sans référence",
+			req:  models.PaymentRequest{Method: models.PaymentMpesa, Amount: 5000},
+			err:  ErrReferenceRequired,
+		},
+		{
+			name: "moyen inconnu",
+			req:  models.PaymentRequest{Method: "cheque", Amount: 5000},
+			err:  ErrUnknownMethod,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := newPayment(tt.req, cdf)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("erreur = %v, attendu %v", err, tt.err)
+			}
+			if tt.err != nil {
+				return
+			}
+			if p.Currency != tt.currency || p.Tendered != tt.tendered || p.Change != tt.change {
+				t.Errorf("règlement = %s remis %v rendu %v ; attendu %s remis %v rendu %v",
+					p.Currency, p.Tendered, p.Change, tt.currency, tt.tendered, tt.change)
+			}
+		})
+	}
+}
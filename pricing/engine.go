@@ -0,0 +1,176 @@
+package pricing
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/kgermando/ipos-stock-api/models"
+
+	"gorm.io/gorm"
+)
+
+// Totals sont les montants d'une commande, somme de ceux de ses lignes
+type Totals struct {
+	TotalHt     float64 `json:"total_ht"`
+	TotalRemise float64 `json:"total_remise"`
+	TotalTva    float64 `json:"total_tva"`
+	TotalTtc    float64 `json:"total_ttc"`
+}
+
+// MismatchError signale des totaux envoyés par le client qui ne
+// correspondent pas au calcul du serveur
+type MismatchError struct {
+	Fields   []string `json:"fields"`
+	Expected Totals   `json:"expected"`
+	Received Totals   `json:"received"`
+}
+
+func (e *MismatchError) Error() string {
+	return fmt.Sprintf("totaux incohérents (%s) : attendu TTC %g, reçu %g",
+		strings.Join(e.Fields, ", "), e.Expected.TotalTtc, e.Received.TotalTtc)
+}
+
+// ProductLine fige sur la ligne le prix, le coût, la remise et la TVA
+// actuels du produit. La remise ne s'applique qu'à partir de la quantité minimale.
+func ProductLine(line *models.CommandeLine, p models.Product, policy Policy) {
+	line.ItemType = "product"
+	line.ProductUUID = p.UUID
+	line.PlatUUID = ""
+	line.UnitPrice = p.PrixVente
+	line.UnitCost = p.PrixAchat
+	line.TvaRate = p.Tva
+	line.Remise = 0
+	if float64(line.Quantity) >= p.RemiseMinQuantity {
+		line.Remise = p.Remise
+	}
+	line.PromotionUUID, line.PromotionAmount = "", 0
+	Compute(line, policy)
+}
+
+// PlatLine fige sur la ligne le prix, la remise et la TVA actuels du plat
+func PlatLine(line *models.CommandeLine, p models.Plat, policy Policy) {
+	line.ItemType = "plat"
+	line.PlatUUID = p.UUID
+	line.ProductUUID = ""
+	line.UnitPrice = p.Prix
+	line.UnitCost = 0
+	line.TvaRate = p.Tva
+	line.Remise = p.Remise
+	line.PromotionUUID, line.PromotionAmount = "", 0
+	Compute(line, policy)
+}
+
+// Snapshot charge le produit ou le plat de la ligne, fige ses prix actuels
+// et lui applique la meilleure des promotions données
+func Snapshot(tx *gorm.DB, line *models.CommandeLine, policy Policy, promos Promotions) error {
+	if line.ProductUUID != "" {
+		var product models.Product
+		if err := tx.Where("uuid = ?", line.ProductUUID).First(&product).Error; err != nil {
+			return err
+		}
+		ProductLine(line, product, policy)
+		promos.Apply(line, "", policy)
+		return nil
+	}
+
+	var plat models.Plat
+	if err := tx.Where("uuid = ?", line.PlatUUID).First(&plat).Error; err != nil {
+		return err
+	}
+	PlatLine(line, plat, policy)
+	promos.Apply(line, plat.Categorie, policy)
+	return nil
+}
+
+// Compute recalcule les montants de la ligne à partir des prix figés : la
+// remise en pourcentage s'applique au prix brut, le montant de la promotion
+// est ensuite retiré, puis la TVA s'applique au montant remisé
+func Compute(line *models.CommandeLine, policy Policy) {
+	gross := line.UnitPrice * float64(line.Quantity)
+	line.RemiseAmount = policy.Round(gross * line.Remise / 100)
+	line.PromotionAmount = math.Min(line.PromotionAmount, policy.Round(gross-line.RemiseAmount))
+	line.TotalHt = policy.Round(gross - line.RemiseAmount - line.PromotionAmount)
+	line.TvaAmount = policy.Round(line.TotalHt * line.TvaRate / 100)
+	line.TotalTtc = policy.Round(line.TotalHt + line.TvaAmount)
+}
+
+// Sum additionne les montants des lignes
+func Sum(lines []models.CommandeLine, policy Policy) Totals {
+	var t Totals
+	for _, line := range lines {
+		t.TotalHt += line.TotalHt
+		t.TotalRemise += line.RemiseAmount + line.PromotionAmount
+		t.TotalTva += line.TvaAmount
+		t.TotalTtc += line.TotalTtc
+	}
+	t.TotalHt = policy.Round(t.TotalHt)
+	t.TotalRemise = policy.Round(t.TotalRemise)
+	t.TotalTva = policy.Round(t.TotalTva)
+	t.TotalTtc = policy.Round(t.TotalTtc)
+	return t
+}
+
+// Verify compare les totaux envoyés par le client à ceux du serveur. Un
+// client qui n'envoie aucun total (tous à zéro) laisse le serveur les calculer.
+func Verify(received, expected Totals, policy Policy) error {
+	if received.TotalHt == 0 && received.TotalTva == 0 && received.TotalTtc == 0 {
+		return nil
+	}
+
+	var fields []string
+	if !policy.Matches(received.TotalHt, expected.TotalHt) {
+		fields = append(fields, "total_ht")
+	}
+	if !policy.Matches(received.TotalTva, expected.TotalTva) {
+		fields = append(fields, "total_tva")
+	}
+	if !policy.Matches(received.TotalTtc, expected.TotalTtc) {
+		fields = append(fields, "total_ttc")
+	}
+	if len(fields) == 0 {
+		return nil
+	}
+	return &MismatchError{Fields: fields, Expected: expected, Received: received}
+}
+
+// CommandeTotals retourne les totaux envoyés avec la commande
+func CommandeTotals(commande *models.Commande) Totals {
+	return Totals{
+		TotalHt:     commande.TotalHt,
+		TotalRemise: commande.TotalRemise,
+		TotalTva:    commande.TotalTva,
+		TotalTtc:    commande.TotalTtc,
+	}
+}
+
+// Apply reporte les totaux calculés sur la commande
+func Apply(commande *models.Commande, t Totals) {
+	commande.TotalHt = t.TotalHt
+	commande.TotalRemise = t.TotalRemise
+	commande.TotalTva = t.TotalTva
+	commande.TotalTtc = t.TotalTtc
+}
+
+// Recompute recalcule les totaux d'une commande à partir de ses lignes
+// enregistrées et les enregistre
+func Recompute(tx *gorm.DB, commandeUUID string) (Totals, error) {
+	var commande models.Commande
+	if err := tx.Where("uuid = ?", commandeUUID).First(&commande).Error; err != nil {
+		return Totals{}, err
+	}
+
+	var lines []models.CommandeLine
+	if err := tx.Where("commande_uuid = ?", commandeUUID).Find(&lines).Error; err != nil {
+		return Totals{}, err
+	}
+
+	t := Sum(lines, LoadPolicy(tx, commande.EntrepriseUUID))
+	err := tx.Model(&commande).Updates(map[string]interface{}{
+		"total_ht":     t.TotalHt,
+		"total_remise": t.TotalRemise,
+		"total_tva":    t.TotalTva,
+		"total_ttc":    t.TotalTtc,
+	}).Error
+	return t, err
+}
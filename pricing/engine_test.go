@@ -0,0 +1,107 @@
+package pricing
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/kgermando/ipos-stock-api/models"
+)
+
+func TestCompute(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy Policy
+		line   models.CommandeLine
+		want   Totals
+	}{
+		{
+			name:   "CDF avec remise",
+			policy: PolicyFor("CDF"),
+			line:   models.CommandeLine{UnitPrice: 1500, Quantity: 3, Remise: 10, TvaRate: 16},
+			want:   Totals{TotalHt: 4050, TotalRemise: 450, TotalTva: 648, TotalTtc: 4698},
+		},
+		{
+			name:   "CDF TVA arrondie au franc",
+			policy: PolicyFor("CDF"),
+			line:   models.CommandeLine{UnitPrice: 333, Quantity: 1, TvaRate: 16},
+			want:   Totals{TotalHt: 333, TotalTva: 53, TotalTtc: 386},
+		},
+		{
+			name:   "USD TVA arrondie au centime",
+			policy: PolicyFor("USD"),
+			line:   models.CommandeLine{UnitPrice: 2.99, Quantity: 3, TvaRate: 16},
+			want:   Totals{TotalHt: 8.97, TotalTva: 1.44, TotalTtc: 10.41},
+		},
+		{
+			name:   "promotion plafonnée au montant remisé",
+			policy: PolicyFor("CDF"),
+			line:   models.CommandeLine{UnitPrice: 1000, Quantity: 2, Remise: 50, PromotionAmount: 5000},
+			want:   Totals{TotalHt: 0, TotalRemise: 2000, TotalTva: 0, TotalTtc: 0},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			line := tt.line
+			Compute(&line, tt.policy)
+			if got := Sum([]models.CommandeLine{line}, tt.policy); got != tt.want {
+				t.Errorf("Compute = %+v, attendu %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSum(t *testing.T) {
+	policy := PolicyFor("USD")
+	lines := []models.CommandeLine{
+		{UnitPrice: 0.1, Quantity: 1},
+		{UnitPrice: 0.2, Quantity: 1},
+	}
+	for i := range lines {
+		Compute(&lines[i], policy)
+	}
+	if got := Sum(lines, policy); got.TotalTtc != 0.3 || got.TotalHt != 0.3 {
+		t.Errorf("Sum = %+v, attendu 0.3 HT et TTC", got)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	cdf := PolicyFor("CDF")
+	usd := PolicyFor("USD")
+	expectedCDF := Totals{TotalHt: 4050, TotalTva: 648, TotalTtc: 4698}
+	expectedUSD := Totals{TotalHt: 8.97, TotalTva: 1.44, TotalTtc: 10.41}
+
+	tests := []struct {
+		name     string
+		policy   Policy
+		received Totals
+		expected Totals
+		fields   []string // nil : accepté
+	}{
+		{"totaux absents", cdf, Totals{}, expectedCDF, nil},
+		{"totaux identiques", cdf, expectedCDF, expectedCDF, nil},
+		{"écart inférieur au franc", cdf, Totals{TotalHt: 4050.4, TotalTva: 648.3, TotalTtc: 4698.2}, expectedCDF, nil},
+		{"TTC d'un franc de trop", cdf, Totals{TotalHt: 4050, TotalTva: 648, TotalTtc: 4699}, expectedCDF, []string{"total_ttc"}},
+		{"HT et TVA faux", cdf, Totals{TotalHt: 4500, TotalTva: 720, TotalTtc: 4698}, expectedCDF, []string{"total_ht", "total_tva"}},
+		{"écart inférieur au centime", usd, Totals{TotalHt: 8.97, TotalTva: 1.444, TotalTtc: 10.412}, expectedUSD, nil},
+		{"TVA d'un centime de trop", usd, Totals{TotalHt: 8.97, TotalTva: 1.45, TotalTtc: 10.41}, expectedUSD, []string{"total_tva"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Verify(tt.received, tt.expected, tt.policy)
+			if tt.fields == nil {
+				if err != nil {
+					t.Fatalf("Verify = %v, attendu nil", err)
+				}
+				return
+			}
+			var mismatch *MismatchError
+			if !errors.As(err, &mismatch) {
+				t.Fatalf("Verify = %v, attendu *MismatchError", err)
+			}
+			if !slices.Equal(mismatch.Fields, tt.fields) {
+				t.Errorf("champs = %v, attendu %v", mismatch.Fields, tt.fields)
+			}
+		})
+	}
+}
@@ -0,0 +1,96 @@
+package pricing
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/kgermando/ipos-stock-api/models"
+
+	"gorm.io/gorm"
+)
+
+// ErrPriceMismatch : le prix envoyé par le terminal n'est pas celui pratiqué
+// par le serveur au moment de la vente
+var ErrPriceMismatch = errors.New("prix différent de celui du serveur au moment de la vente")
+
+// valueAt retourne la valeur d'une colonne d'un produit ou d'un plat à
+// l'instant at, d'après le journal d'audit : c'est l'ancienne valeur de la
+// première modification de la colonne après at, sinon la valeur actuelle
+func valueAt(tx *gorm.DB, table, uuid, column string, at time.Time, current float64) float64 {
+	var before []string
+	tx.Model(&models.AuditLog{}).
+		Where("entity = ? AND entity_uuid = ? AND action = ? AND created_at > ?", table, uuid, models.AuditUpdate, at).
+		Where("before->>? IS NOT NULL", column).
+		Select("before->>?", column).
+		Order("created_at").
+		Limit(1).
+		Scan(&before)
+	if len(before) == 0 {
+		return current
+	}
+	v, err := strconv.ParseFloat(before[0], 64)
+	if err != nil {
+		return current
+	}
+	return v
+}
+
+// Offline fige les prix d'une ligne vendue hors ligne à l'instant at. Le prix
+// envoyé par le terminal doit être celui du serveur à cet instant ; la TVA, la
+// remise et la promotion sont recalculées à partir des données du serveur, le
+// terminal ne les choisit pas.
+func Offline(tx *gorm.DB, line *models.CommandeLine, posUUID string, at time.Time, policy Policy) error {
+	sent, promotionUUID := line.UnitPrice, line.PromotionUUID
+	categorie := ""
+
+	if line.ProductUUID != "" {
+		var p models.Product
+		if err := tx.Where("uuid = ?", line.ProductUUID).First(&p).Error; err != nil {
+			return err
+		}
+		p.PrixVente = valueAt(tx, "products", p.UUID, "prix_vente", at, p.PrixVente)
+		p.Tva = valueAt(tx, "products", p.UUID, "tva", at, p.Tva)
+		p.Remise = valueAt(tx, "products", p.UUID, "remise", at, p.Remise)
+		p.RemiseMinQuantity = valueAt(tx, "products", p.UUID, "remise_min_quantity", at, p.RemiseMinQuantity)
+		ProductLine(line, p, policy)
+	} else {
+		var p models.Plat
+		if err := tx.Where("uuid = ?", line.PlatUUID).First(&p).Error; err != nil {
+			return err
+		}
+		p.Prix = valueAt(tx, "plats", p.UUID, "prix", at, p.Prix)
+		p.Tva = valueAt(tx, "plats", p.UUID, "tva", at, p.Tva)
+		p.Remise = valueAt(tx, "plats", p.UUID, "remise", at, p.Remise)
+		PlatLine(line, p, policy)
+		categorie = p.Categorie
+	}
+	if sent != 0 && !policy.Matches(sent, line.UnitPrice) {
+		return ErrPriceMismatch
+	}
+
+	// Promotions automatiques valables à l'instant de la vente, et celle
+	// retenue par le terminal si elle l'était aussi (coupon présenté hors ligne)
+	promos, err := LoadPromotions(tx, posUUID, nil, at)
+	if err != nil {
+		return err
+	}
+	if promotionUUID != "" && !promos.has(promotionUUID) {
+		var p models.Promotion
+		if tx.Where("uuid = ? AND (pos_scope = '' OR pos_scope IS NULL OR pos_scope = ?)", promotionUUID, posUUID).
+			Limit(1).Find(&p); p.UUID != "" && validAt(p, at) {
+			promos = append(promos, p)
+		}
+	}
+	promos.Apply(line, categorie, policy)
+	return nil
+}
+
+func (ps Promotions) has(uuid string) bool {
+	for _, p := range ps {
+		if p.UUID == uuid {
+			return true
+		}
+	}
+	return false
+}
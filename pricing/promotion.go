@@ -0,0 +1,223 @@
+package pricing
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kgermando/ipos-stock-api/models"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrCouponInvalid : le code coupon n'existe pas ou n'est pas valable maintenant
+	ErrCouponInvalid = errors.New("code coupon invalide ou expiré")
+	// ErrCouponExhausted : la promotion a atteint son nombre d'utilisations
+	ErrCouponExhausted = errors.New("promotion épuisée")
+)
+
+// Promotions sont les promotions valables pour une vente : les promotions
+// automatiques du point de vente et celles des coupons présentés
+type Promotions []models.Promotion
+
+// LoadPromotions charge les promotions valables à l'instant donné pour le
+// point de vente. Chaque code coupon doit correspondre à une promotion valable.
+func LoadPromotions(tx *gorm.DB, posUUID string, coupons []string, now time.Time) (Promotions, error) {
+	codes := make([]string, 0, len(coupons))
+	for _, code := range coupons {
+		if code = strings.ToUpper(strings.TrimSpace(code)); code != "" {
+			codes = append(codes, code)
+		}
+	}
+
+	query := tx.Where("active = ? AND (pos_scope = '' OR pos_scope IS NULL OR pos_scope = ?)", true, posUUID)
+	if len(codes) > 0 {
+		query = query.Where("(coupon_code = '' OR coupon_code IS NULL OR UPPER(coupon_code) IN ?)", codes)
+	} else {
+		query = query.Where("coupon_code = '' OR coupon_code IS NULL")
+	}
+
+	var all []models.Promotion
+	if err := query.Order("priority DESC").Find(&all).Error; err != nil {
+		return nil, err
+	}
+
+	var ps Promotions
+	found := map[string]bool{}
+	for _, p := range all {
+		if validAt(p, now) {
+			ps = append(ps, p)
+			found[strings.ToUpper(p.CouponCode)] = true
+		}
+	}
+	for _, code := range codes {
+		if !found[code] {
+			return nil, ErrCouponInvalid
+		}
+	}
+	return ps, nil
+}
+
+// Apply retient pour la ligne la promotion la plus avantageuse parmi celles
+// qui la ciblent. Une promotion cumulable s'ajoute à la remise propre de
+// l'article ; sinon elle la remplace, seulement si elle est plus forte.
+// categorie est la catégorie du plat de la ligne.
+func (ps Promotions) Apply(line *models.CommandeLine, categorie string, policy Policy) {
+	line.PromotionUUID = ""
+	line.PromotionAmount = 0
+	Compute(line, policy)
+
+	remise := line.Remise
+	best, bestUUID, bestStackable := line.RemiseAmount, "", false
+	for _, p := range ps {
+		if !targets(p, line, categorie) {
+			continue
+		}
+		// Une promotion non cumulable remplace la remise : elle porte sur le prix plein
+		total := discount(p, line, 0, policy)
+		if p.Stackable {
+			total = discount(p, line, remise, policy) + line.RemiseAmount
+		}
+		if total > best {
+			best, bestUUID, bestStackable = total, p.UUID, p.Stackable
+		}
+	}
+	if bestUUID == "" {
+		return
+	}
+
+	if !bestStackable {
+		line.Remise = 0
+	}
+	line.PromotionUUID = bestUUID
+	for _, p := range ps {
+		if p.UUID == bestUUID {
+			line.PromotionAmount = discount(p, line, line.Remise, policy)
+		}
+	}
+	Compute(line, policy)
+}
+
+// Used retourne les promotions retenues par au moins une des lignes
+func (ps Promotions) Used(lines []models.CommandeLine) []models.Promotion {
+	used := []models.Promotion{}
+	for _, p := range ps {
+		for _, line := range lines {
+			if line.PromotionUUID == p.UUID {
+				used = append(used, p)
+				break
+			}
+		}
+	}
+	return used
+}
+
+// Refresh recalcule le montant de la promotion déjà retenue sur la ligne,
+// par exemple après un changement de quantité, puis les montants de la ligne
+func Refresh(tx *gorm.DB, line *models.CommandeLine, policy Policy) {
+	if line.PromotionUUID != "" {
+		var p models.Promotion
+		if err := tx.Unscoped().Where("uuid = ?", line.PromotionUUID).First(&p).Error; err == nil {
+			line.PromotionAmount = discount(p, line, line.Remise, policy)
+		}
+	}
+	Compute(line, policy)
+}
+
+// Redeem compte une utilisation de chaque promotion retenue par les lignes
+// d'une commande, dans la limite fixée par la promotion
+func Redeem(tx *gorm.DB, lines []models.CommandeLine) error {
+	used := map[string]bool{}
+	for _, line := range lines {
+		if line.PromotionUUID == "" || used[line.PromotionUUID] {
+			continue
+		}
+		used[line.PromotionUUID] = true
+
+		result := tx.Model(&models.Promotion{}).
+			Where("uuid = ? AND (usage_limit = 0 OR usage_count < usage_limit)", line.PromotionUUID).
+			UpdateColumn("usage_count", gorm.Expr("usage_count + 1"))
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrCouponExhausted
+		}
+	}
+	return nil
+}
+
+// validAt vérifie la période, les jours, la plage horaire et le nombre
+// d'utilisations de la promotion
+func validAt(p models.Promotion, now time.Time) bool {
+	if !p.Active || (p.StartsAt != nil && now.Before(*p.StartsAt)) || (p.EndsAt != nil && now.After(*p.EndsAt)) {
+		return false
+	}
+	if p.UsageLimit > 0 && p.UsageCount >= p.UsageLimit {
+		return false
+	}
+
+	if p.Weekdays != "" {
+		day := strconv.Itoa(int(now.Weekday()))
+		match := false
+		for _, d := range strings.Split(p.Weekdays, ",") {
+			if strings.TrimSpace(d) == day {
+				match = true
+			}
+		}
+		if !match {
+			return false
+		}
+	}
+
+	if p.StartTime != "" && p.EndTime != "" {
+		clock := now.Format("15:04")
+		if p.StartTime <= p.EndTime {
+			return clock >= p.StartTime && clock < p.EndTime
+		}
+		// Plage qui passe minuit, ex : 22:00 - 02:00
+		return clock >= p.StartTime || clock < p.EndTime
+	}
+	return true
+}
+
+// targets indique si la promotion s'applique à l'article de la ligne
+func targets(p models.Promotion, line *models.CommandeLine, categorie string) bool {
+	if p.ItemType != "" && p.ItemType != line.ItemType {
+		return false
+	}
+	if p.ItemUUID != "" && p.ItemUUID != line.ProductUUID && p.ItemUUID != line.PlatUUID {
+		return false
+	}
+	if p.Categorie != "" && (line.ItemType != "plat" || !strings.EqualFold(p.Categorie, categorie)) {
+		return false
+	}
+	return true
+}
+
+// discount calcule le montant retiré par la promotion, sur le prix unitaire
+// diminué de la remise de l'article lorsque la promotion s'y cumule
+func discount(p models.Promotion, line *models.CommandeLine, remise float64, policy Policy) float64 {
+	unit := line.UnitPrice * (1 - remise/100)
+	qty := line.Quantity
+	var amount float64
+
+	switch p.Type {
+	case models.PromotionPercentage:
+		amount = unit * float64(qty) * p.Value / 100
+	case models.PromotionFixed:
+		amount = math.Min(p.Value, unit) * float64(qty)
+	case models.PromotionBuyXGetY:
+		if group := p.BuyQuantity + p.GetQuantity; p.GetQuantity > 0 && group > 0 {
+			amount = float64(qty/group*p.GetQuantity) * unit
+		}
+	case models.PromotionBundle:
+		if p.BuyQuantity > 0 {
+			amount = float64(qty/p.BuyQuantity) * math.Max(0, float64(p.BuyQuantity)*unit-p.Value)
+		}
+	}
+	return policy.Round(math.Max(0, amount))
+}
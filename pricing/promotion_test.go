@@ -0,0 +1,194 @@
+package pricing
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kgermando/ipos-stock-api/models"
+)
+
+func productLine(unitPrice float64, quantity uint64, remise float64) models.CommandeLine {
+	return models.CommandeLine{
+		ItemType:    "product",
+		ProductUUID: "produit",
+		UnitPrice:   unitPrice,
+		Quantity:    quantity,
+		Remise:      remise,
+	}
+}
+
+func TestPromotionsApply(t *testing.T) {
+	percent := func(uuid string, value float64, stackable bool) models.Promotion {
+		return models.Promotion{UUID: uuid, Type: models.PromotionPercentage, Value: value, Stackable: stackable, Active: true}
+	}
+
+	tests := []struct {
+		name      string
+		line      models.CommandeLine
+		categorie string
+		promos    Promotions
+		promotion string // Promotion retenue, vide si aucune
+		remise    float64
+		amount    float64 // Montant de la promotion
+		totalHt   float64
+	}{
+		{
+			name: "sans promotion, la remise de l'article reste",
+			line: productLine(1000, 4, 10), remise: 10, totalHt: 3600,
+		},
+		{
+			name:   "non cumulable plus forte que la remise : la remplace",
+			line:   productLine(1000, 4, 10),
+			promos: Promotions{percent("p20", 20, false)},
+			// 20 % du prix plein, la remise de 10 % est abandonnée
+			promotion: "p20", remise: 0, amount: 800, totalHt: 3200,
+		},
+		{
+			name:   "non cumulable plus faible que la remise : ignorée",
+			line:   productLine(1000, 4, 10),
+			promos: Promotions{percent("p5", 5, false)},
+			remise: 10, totalHt: 3600,
+		},
+		{
+			name:   "cumulable : s'ajoute à la remise, sur le prix remisé",
+			line:   productLine(1000, 4, 10),
+			promos: Promotions{percent("p5", 5, true)},
+			// 5 % de 900 x 4
+			promotion: "p5", remise: 10, amount: 180, totalHt: 3420,
+		},
+		{
+			name:      "la plus avantageuse l'emporte : non cumulable 800 contre cumulable 400 + 360",
+			line:      productLine(1000, 4, 10),
+			promos:    Promotions{percent("cumul", 10, true), percent("p20", 20, false)},
+			promotion: "p20", remise: 0, amount: 800, totalHt: 3200,
+		},
+		{
+			name:      "la plus avantageuse l'emporte : cumulable 400 + 540 contre non cumulable 800",
+			line:      productLine(1000, 4, 10),
+			promos:    Promotions{percent("p20", 20, false), percent("cumul", 15, true)},
+			promotion: "cumul", remise: 10, amount: 540, totalHt: 3060,
+		},
+		{
+			name:      "montant fixe par unité",
+			line:      productLine(1000, 4, 0),
+			promos:    Promotions{{UUID: "fixe", Type: models.PromotionFixed, Value: 150, Active: true}},
+			promotion: "fixe", amount: 600, totalHt: 3400,
+		},
+		{
+			name:    "autre article : ignorée",
+			line:    productLine(1000, 4, 0),
+			promos:  Promotions{{UUID: "autre", Type: models.PromotionPercentage, Value: 50, ItemUUID: "autre-produit", Active: true}},
+			totalHt: 4000,
+		},
+		{
+			name:      "catégorie de plats, sans tenir compte de la casse",
+			line:      models.CommandeLine{ItemType: "plat", PlatUUID: "plat", UnitPrice: 2000, Quantity: 2},
+			categorie: "boissons",
+			promos:    Promotions{{UUID: "boissons", Type: models.PromotionPercentage, Value: 25, Categorie: "Boissons", Active: true}},
+			promotion: "boissons", amount: 1000, totalHt: 3000,
+		},
+		{
+			name:    "catégorie de plats : ne vise pas les produits",
+			line:    productLine(2000, 2, 0),
+			promos:  Promotions{{UUID: "boissons", Type: models.PromotionPercentage, Value: 25, Categorie: "boissons", Active: true}},
+			totalHt: 4000,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			line := tt.line
+			tt.promos.Apply(&line, tt.categorie, PolicyFor("CDF"))
+			if line.PromotionUUID != tt.promotion {
+				t.Errorf("promotion = %q, attendu %q", line.PromotionUUID, tt.promotion)
+			}
+			if line.Remise != tt.remise {
+				t.Errorf("remise = %v, attendu %v", line.Remise, tt.remise)
+			}
+			if line.PromotionAmount != tt.amount {
+				t.Errorf("montant de la promotion = %v, attendu %v", line.PromotionAmount, tt.amount)
+			}
+			if line.TotalHt != tt.totalHt {
+				t.Errorf("total HT = %v, attendu %v", line.TotalHt, tt.totalHt)
+			}
+		})
+	}
+}
+
+func TestPromotionsApplyQuantity(t *testing.T) {
+	buy2get1 := models.Promotion{UUID: "2+1", Type: models.PromotionBuyXGetY, BuyQuantity: 2, GetQuantity: 1, Active: true}
+	bundle := models.Promotion{UUID: "lot", Type: models.PromotionBundle, BuyQuantity: 3, Value: 2500, Active: true}
+	dearBundle := models.Promotion{UUID: "lot-cher", Type: models.PromotionBundle, BuyQuantity: 3, Value: 3500, Active: true}
+
+	tests := []struct {
+		name      string
+		promo     models.Promotion
+		remise    float64
+		quantity  uint64
+		promotion string
+		amount    float64
+	}{
+		{"2 achetés 1 offert : groupe incomplet", buy2get1, 0, 2, "", 0},
+		{"2 achetés 1 offert : un groupe", buy2get1, 0, 3, "2+1", 1000},
+		{"2 achetés 1 offert : deux groupes et un reste", buy2get1, 0, 7, "2+1", 2000},
+		{"lot de 3 à 2500 : lot incomplet", bundle, 0, 2, "", 0},
+		{"lot de 3 à 2500 : deux lots et un reste", bundle, 0, 7, "lot", 1000},
+		{"lot plus cher que le prix normal : ignoré", dearBundle, 0, 3, "", 0},
+		// Non cumulables : la remise de 10 % est remplacée, l'unité offerte est au prix plein
+		{"2 achetés 1 offert : remplace une remise plus faible", buy2get1, 10, 3, "2+1", 1000},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			line := productLine(1000, tt.quantity, tt.remise)
+			Promotions{tt.promo}.Apply(&line, "", PolicyFor("CDF"))
+			if line.PromotionUUID != tt.promotion {
+				t.Errorf("promotion = %q, attendu %q", line.PromotionUUID, tt.promotion)
+			}
+			if line.PromotionAmount != tt.amount {
+				t.Errorf("montant = %v, attendu %v", line.PromotionAmount, tt.amount)
+			}
+		})
+	}
+}
+
+func TestValidAt(t *testing.T) {
+	// Octobre 2026 : le 16 est un vendredi, le 18 un dimanche
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2026, time.October, day, hour, minute, 0, 0, time.Local)
+	}
+	nightly := models.Promotion{Active: true, StartTime: "22:00", EndTime: "02:00"}
+	evening := models.Promotion{Active: true, StartTime: "17:00", EndTime: "19:00"}
+	fridayNight := models.Promotion{Active: true, StartTime: "22:00", EndTime: "02:00", Weekdays: "5"}
+	future := at(20, 0, 0)
+	past := at(10, 0, 0)
+
+	tests := []struct {
+		name  string
+		promo models.Promotion
+		now   time.Time
+		want  bool
+	}{
+		{"nuit : début de la plage", nightly, at(16, 22, 0), true},
+		{"nuit : avant minuit", nightly, at(16, 23, 30), true},
+		{"nuit : après minuit", nightly, at(17, 1, 59), true},
+		{"nuit : fin de la plage exclue", nightly, at(17, 2, 0), false},
+		{"nuit : avant la plage", nightly, at(16, 21, 59), false},
+		{"nuit : en journée", nightly, at(16, 12, 0), false},
+		{"soirée : dans la plage", evening, at(16, 18, 0), true},
+		{"soirée : fin exclue", evening, at(16, 19, 0), false},
+		{"soirée : après minuit", evening, at(17, 1, 0), false},
+		{"jour autorisé", fridayNight, at(16, 23, 0), true},
+		{"jour non autorisé", fridayNight, at(18, 23, 0), false},
+		{"inactive", models.Promotion{Active: false}, at(16, 12, 0), false},
+		{"pas encore commencée", models.Promotion{Active: true, StartsAt: &future}, at(16, 12, 0), false},
+		{"terminée", models.Promotion{Active: true, EndsAt: &past}, at(16, 12, 0), false},
+		{"épuisée", models.Promotion{Active: true, UsageLimit: 10, UsageCount: 10}, at(16, 12, 0), false},
+		{"sans restriction", models.Promotion{Active: true}, at(16, 12, 0), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := validAt(tt.promo, tt.now); got != tt.want {
+				t.Errorf("validAt(%s) = %v, attendu %v", tt.now.Format("Mon 15:04"), got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,112 @@
+package pricing
+
+import (
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/kgermando/ipos-stock-api/models"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrInvalidItem : un article doit référencer un produit ou un plat
+	ErrInvalidItem = errors.New("chaque article doit référencer un produit ou un plat")
+	// ErrItemNotFound : un produit ou un plat n'existe pas sur le point de vente
+	ErrItemNotFound = errors.New("produit ou plat introuvable pour ce point de vente")
+	// ErrUnavailable : un plat n'est pas disponible à la vente
+	ErrUnavailable = errors.New("plat indisponible")
+)
+
+// Quote est le détail chiffré d'un panier, sans effet sur le stock
+type Quote struct {
+	Policy
+	Totals
+	Lines      []models.CommandeLine `json:"lines"`
+	Promotions []models.Promotion    `json:"promotions"` // Promotions retenues par au moins une ligne
+}
+
+// Items regroupe les quantités du panier par produit et par plat
+func Items(items []models.CheckoutItem) (products, plats map[string]uint64, err error) {
+	products, plats = map[string]uint64{}, map[string]uint64{}
+	for _, item := range items {
+		switch {
+		case item.ProductUUID != "" && item.PlatUUID == "":
+			products[item.ProductUUID] += item.Quantity
+		case item.PlatUUID != "" && item.ProductUUID == "":
+			plats[item.PlatUUID] += item.Quantity
+		default:
+			return nil, nil, ErrInvalidItem
+		}
+	}
+	return products, plats, nil
+}
+
+// NewQuote chiffre le panier avec les prix et promotions actuels du point
+// de vente, en tenant compte des coupons présentés
+func NewQuote(tx *gorm.DB, posUUID string, items []models.CheckoutItem, coupons []string) (*Quote, error) {
+	productQty, platQty, err := Items(items)
+	if err != nil {
+		return nil, err
+	}
+
+	var pos models.Pos
+	if err := tx.Where("uuid = ?", posUUID).First(&pos).Error; err != nil {
+		return nil, ErrItemNotFound
+	}
+	quote := &Quote{Policy: LoadPolicy(tx, pos.EntrepriseUUID)}
+
+	promos, err := LoadPromotions(tx, posUUID, coupons, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	productUUIDs := SortedKeys(productQty)
+	var products []models.Product
+	if len(productUUIDs) > 0 {
+		tx.Where("uuid IN ? AND pos_uuid = ?", productUUIDs, posUUID).Order("uuid").Find(&products)
+	}
+	if len(products) != len(productUUIDs) {
+		return nil, ErrItemNotFound
+	}
+
+	platUUIDs := SortedKeys(platQty)
+	var plats []models.Plat
+	if len(platUUIDs) > 0 {
+		tx.Where("uuid IN ? AND pos_uuid = ?", platUUIDs, posUUID).Order("uuid").Find(&plats)
+	}
+	if len(plats) != len(platUUIDs) {
+		return nil, ErrItemNotFound
+	}
+
+	for _, product := range products {
+		line := models.CommandeLine{Quantity: productQty[product.UUID]}
+		ProductLine(&line, product, quote.Policy)
+		promos.Apply(&line, "", quote.Policy)
+		quote.Lines = append(quote.Lines, line)
+	}
+	for _, plat := range plats {
+		if !plat.IsAvailable {
+			return nil, ErrUnavailable
+		}
+		line := models.CommandeLine{Quantity: platQty[plat.UUID]}
+		PlatLine(&line, plat, quote.Policy)
+		promos.Apply(&line, plat.Categorie, quote.Policy)
+		quote.Lines = append(quote.Lines, line)
+	}
+
+	quote.Totals = Sum(quote.Lines, quote.Policy)
+	quote.Promotions = promos.Used(quote.Lines)
+	return quote, nil
+}
+
+// SortedKeys retourne les identifiants dans un ordre stable
+func SortedKeys(m map[string]uint64) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,71 @@
+package pricing
+
+import (
+	"math"
+	"strings"
+
+	"github.com/kgermando/ipos-stock-api/database"
+	"github.com/kgermando/ipos-stock-api/models"
+
+	"gorm.io/gorm"
+)
+
+// DefaultCurrency est la devise d'une entreprise qui n'en a pas renseigné
+const DefaultCurrency = "CDF"
+
+// currencyDecimals donne le nombre de décimales de chaque devise. Le franc
+// congolais n'a plus de subdivision en circulation : ses montants sont arrondis
+// au franc. Les devises absentes sont arrondies au centime.
+var currencyDecimals = map[string]int{
+	"CDF": 0,
+	"USD": 2,
+	"EUR": 2,
+}
+
+// Policy est la règle d'arrondi d'une devise. Chaque montant d'une ligne
+// (remise, HT, TVA, TTC) est arrondi au plus proche, à mi-chemin en
+// s'éloignant de zéro ; les totaux de la commande sont la somme des montants
+// arrondis des lignes.
+type Policy struct {
+	Currency string `json:"currency"`
+	Decimals int    `json:"decimals"`
+}
+
+// PolicyFor retourne la règle d'arrondi de la devise
+func PolicyFor(currency string) Policy {
+	currency = strings.ToUpper(strings.TrimSpace(currency))
+	if currency == "" {
+		currency = DefaultCurrency
+	}
+	decimals, ok := currencyDecimals[currency]
+	if !ok {
+		decimals = 2
+	}
+	return Policy{Currency: currency, Decimals: decimals}
+}
+
+// LoadPolicy retourne la règle d'arrondi de la devise de l'entreprise. Sans
+// entreprise indiquée, celle du périmètre de la requête est utilisée.
+func LoadPolicy(tx *gorm.DB, entrepriseUUID string) Policy {
+	if entrepriseUUID == "" {
+		if t, ok := database.TenantFromContext(tx.Statement.Context); ok {
+			entrepriseUUID = t.EntrepriseUUID
+		}
+	}
+	var entreprise models.Entreprise
+	if entrepriseUUID != "" {
+		tx.Select("currency").Where("uuid = ?", entrepriseUUID).Limit(1).Find(&entreprise)
+	}
+	return PolicyFor(entreprise.Currency)
+}
+
+// Round arrondit un montant à la plus petite unité de la devise
+func (p Policy) Round(v float64) float64 {
+	unit := math.Pow10(p.Decimals)
+	return math.Round(v*unit) / unit
+}
+
+// Matches indique si deux montants sont égaux une fois arrondis dans la devise
+func (p Policy) Matches(a, b float64) bool {
+	return p.Round(a) == p.Round(b)
+}
@@ -0,0 +1,69 @@
+package pricing
+
+import "testing"
+
+func TestPolicyFor(t *testing.T) {
+	tests := []struct {
+		currency string
+		want     Policy
+	}{
+		{"", Policy{Currency: "CDF", Decimals: 0}},
+		{"CDF", Policy{Currency: "CDF", Decimals: 0}},
+		{" usd ", Policy{Currency: "USD", Decimals: 2}},
+		{"eur", Policy{Currency: "EUR", Decimals: 2}},
+		{"XAF", Policy{Currency: "XAF", Decimals: 2}}, // Devise inconnue : au centime
+	}
+	for _, tt := range tests {
+		if got := PolicyFor(tt.currency); got != tt.want {
+			t.Errorf("PolicyFor(%q) = %+v, attendu %+v", tt.currency, got, tt.want)
+		}
+	}
+}
+
+func TestPolicyRound(t *testing.T) {
+	cdf, usd := PolicyFor("CDF"), PolicyFor("USD")
+	tests := []struct {
+		name   string
+		policy Policy
+		value  float64
+		want   float64
+	}{
+		{"CDF entier", cdf, 1500, 1500},
+		{"CDF arrondi inférieur", cdf, 1234.49, 1234},
+		{"CDF mi-chemin", cdf, 1234.5, 1235},
+		{"CDF négatif mi-chemin", cdf, -2.5, -3},
+		{"USD centimes", usd, 19.99, 19.99},
+		{"USD arrondi inférieur", usd, 19.994, 19.99},
+		{"USD mi-chemin", usd, 10.125, 10.13},
+		{"USD arrondi supérieur", usd, 1.4352 + 8.97, 10.41},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.policy.Round(tt.value); got != tt.want {
+				t.Errorf("Round(%v) = %v, attendu %v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPolicyMatches(t *testing.T) {
+	cdf, usd := PolicyFor("CDF"), PolicyFor("USD")
+	tests := []struct {
+		name   string
+		policy Policy
+		a, b   float64
+		want   bool
+	}{
+		{"CDF même franc", cdf, 1000.4, 999.6, true},
+		{"CDF un franc d'écart", cdf, 1000, 1001, false},
+		{"USD même centime", usd, 10.001, 10.004, true},
+		{"USD un centime d'écart", usd, 10.01, 10.02, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.policy.Matches(tt.a, tt.b); got != tt.want {
+				t.Errorf("Matches(%v, %v) = %v, attendu %v", tt.a, tt.b, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,231 @@
+package receipts
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/kgermando/ipos-stock-api/models"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+)
+
+// Commandes ESC/POS
+var (
+	escInit        = []byte{0x1b, 0x40}             // ESC @ : réinitialise l'imprimante
+	escCodePage    = []byte{0x1b, 0x74, 16}         // ESC t 16 : page de code WPC1252
+	escAlignLeft   = []byte{0x1b, 0x61, 0}          // ESC a 0
+	escAlignCenter = []byte{0x1b, 0x61, 1}          // ESC a 1
+	escBoldOn      = []byte{0x1b, 0x45, 1}          // ESC E 1
+	escBoldOff     = []byte{0x1b, 0x45, 0}          // ESC E 0
+	escFeed        = []byte{0x1b, 0x64, 4}          // ESC d 4 : avance de 4 lignes
+	escCut         = []byte{0x1d, 0x56, 66, 0}      // GS V B 0 : coupe partielle après avance
+	escDrawer      = []byte{0x1b, 0x70, 0, 25, 250} // ESC p : impulsion du tiroir-caisse
+)
+
+// Columns retourne le nombre de caractères par ligne en police A pour la
+// largeur de papier du modèle
+func Columns(paperWidth int) int {
+	if paperWidth == 58 {
+		return 32
+	}
+	return 48
+}
+
+// ticket construit le flux ESC/POS ligne par ligne
+type ticket struct {
+	buf     bytes.Buffer
+	width   int
+	encoder *encoding.Encoder
+}
+
+// ESCPOS retourne le ticket de la commande pour une imprimante thermique de
+// la largeur du modèle
+func ESCPOS(r *Receipt) []byte {
+	t := &ticket{
+		width:   Columns(r.Template.PaperWidth),
+		encoder: encoding.ReplaceUnsupported(charmap.Windows1252.NewEncoder()),
+	}
+	currency := r.Policy.Currency
+
+	t.raw(escInit, escCodePage)
+	if r.Template.OpenDrawer {
+		t.raw(escDrawer)
+	}
+
+	// En-tête
+	t.raw(escAlignCenter, escBoldOn)
+	t.line(r.Entreprise.Name)
+	t.raw(escBoldOff)
+	for _, line := range []string{r.Pos.Name, r.Pos.Adresse, r.Pos.Telephone} {
+		t.wrapped(line)
+	}
+	for _, line := range r.Legal() {
+		t.wrapped(line)
+	}
+	for _, line := range Lines(r.Template.Header) {
+		t.wrapped(line)
+	}
+	t.feed()
+	t.raw(escBoldOn)
+	t.line(r.Template.ReceiptTitle)
+	t.raw(escBoldOff)
+	t.line("N° " + r.Commande.Ncommande)
+	t.raw(escAlignLeft)
+	t.line(r.Commande.CreatedAt.Format("02/01/2006 15:04"))
+	if r.Template.ShowCashier && r.Cashier != "" {
+		t.wrapped("Caissier : " + r.Cashier)
+	}
+	if r.Template.ShowClient && r.Client != nil {
+		t.wrapped("Client : " + r.Client.Fullname)
+	}
+	if r.Commande.Status == models.CommandeCancelled || r.Commande.Status == models.CommandeRefunded {
+		t.line("*** " + strings.ToUpper(r.Commande.Status) + " ***")
+	}
+	t.rule()
+
+	// Articles : désignation, puis quantité x prix et montant
+	for _, l := range r.Lines {
+		t.wrapped(l.Name)
+		t.columns("  "+strconv.FormatUint(l.Quantity, 10)+" x "+r.Amount(l.UnitPrice), r.Amount(l.TotalHt))
+		if l.Remise > 0 {
+			t.columns("  Remise", "-"+r.Amount(l.Remise))
+		}
+	}
+	t.rule()
+
+	// Totaux
+	t.columns("Total HT", r.Amount(r.Commande.TotalHt))
+	if r.Commande.TotalRemise > 0 {
+		t.columns("dont remises", r.Amount(r.Commande.TotalRemise))
+	}
+	t.columns("TVA", r.Amount(r.Commande.TotalTva))
+	t.raw(escBoldOn)
+	t.columns("TOTAL TTC "+currency, r.Amount(r.Commande.TotalTtc))
+	t.raw(escBoldOff)
+
+	if r.Template.ShowTvaBreakdown && len(r.Tva) > 0 {
+		t.rule()
+		for _, tva := range r.Tva {
+			t.columns("TVA "+Percent(tva.Rate)+" sur "+r.Amount(tva.Base), r.Amount(tva.Amount))
+		}
+	}
+
+	if r.Template.ShowPayments && len(r.Payments) > 0 {
+		t.rule()
+		for _, p := range r.Payments {
+			t.columns(models.PaymentMethodLabels[p.Method], r.Amount(p.Amount))
+			if p.Reference != "" {
+				t.wrapped("  Réf. " + p.Reference)
+			}
+		}
+		if _, change := r.Paid(); change > 0 {
+			t.columns("Monnaie rendue", r.Amount(change))
+		}
+	}
+
+	// Mentions de la facture normalisée
+	if fiscal := r.FiscalLines(); len(fiscal) > 0 {
+		t.rule()
+		t.raw(escAlignCenter)
+		if r.Commande.Fiscal.QRCode != "" {
+			t.qrCode(r.Commande.Fiscal.QRCode)
+		}
+		for _, line := range fiscal {
+			t.wrapped(line)
+		}
+		t.raw(escAlignLeft)
+	}
+
+	// Pied de page
+	if footer := Lines(r.Template.Footer); len(footer) > 0 {
+		t.feed()
+		t.raw(escAlignCenter)
+		for _, line := range footer {
+			t.wrapped(line)
+		}
+		t.raw(escAlignLeft)
+	}
+
+	t.raw(escFeed)
+	if r.Template.CutPaper {
+		t.raw(escCut)
+	}
+	return t.buf.Bytes()
+}
+
+// raw écrit des commandes de l'imprimante
+func (t *ticket) raw(cmds ...[]byte) {
+	for _, cmd := range cmds {
+		t.buf.Write(cmd)
+	}
+}
+
+// line écrit une ligne de texte, tronquée à la largeur du papier
+func (t *ticket) line(text string) {
+	if utf8.RuneCountInString(text) > t.width {
+		text = string([]rune(text)[:t.width])
+	}
+	encoded, err := t.encoder.String(text)
+	if err != nil {
+		encoded = text
+	}
+	t.buf.WriteString(encoded)
+	t.buf.WriteByte('\n')
+}
+
+// wrapped écrit un texte sur autant de lignes que nécessaire, coupé entre les mots
+func (t *ticket) wrapped(text string) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return
+	}
+	current := ""
+	for _, word := range strings.Fields(text) {
+		switch {
+		case current == "":
+			current = word
+		case utf8.RuneCountInString(current)+1+utf8.RuneCountInString(word) <= t.width:
+			current += " " + word
+		default:
+			t.line(current)
+			current = word
+		}
+	}
+	t.line(current)
+}
+
+// columns écrit un libellé à gauche et un montant aligné à droite
+func (t *ticket) columns(left, right string) {
+	space := t.width - utf8.RuneCountInString(left) - utf8.RuneCountInString(right)
+	if space < 1 {
+		t.wrapped(left)
+		left, space = "", t.width-utf8.RuneCountInString(right)
+	}
+	t.line(left + strings.Repeat(" ", max(space, 0)) + right)
+}
+
+// qrCode imprime un QR code (GS ( k, modèle 2, correction d'erreur M)
+func (t *ticket) qrCode(data string) {
+	n := len(data) + 3
+	t.raw(
+		[]byte{0x1d, 0x28, 0x6b, 4, 0, 0x31, 0x41, 0x32, 0},               // Modèle 2
+		[]byte{0x1d, 0x28, 0x6b, 3, 0, 0x31, 0x43, 5},                     // Taille des modules
+		[]byte{0x1d, 0x28, 0x6b, 3, 0, 0x31, 0x45, 0x31},                  // Correction d'erreur M
+		[]byte{0x1d, 0x28, 0x6b, byte(n), byte(n >> 8), 0x31, 0x50, 0x30}, // Données
+		[]byte(data),
+		[]byte{0x1d, 0x28, 0x6b, 3, 0, 0x31, 0x51, 0x30}, // Impression
+	)
+	t.feed()
+}
+
+// rule écrit une ligne de séparation
+func (t *ticket) rule() {
+	t.line(strings.Repeat("-", t.width))
+}
+
+func (t *ticket) feed() {
+	t.buf.WriteByte('\n')
+}
@@ -0,0 +1,236 @@
+package receipts
+
+import (
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/kgermando/ipos-stock-api/models"
+
+	"github.com/boombuler/barcode/qr"
+	"github.com/go-pdf/fpdf"
+	"github.com/go-pdf/fpdf/contrib/barcode"
+)
+
+// Colonnes du tableau des articles de la facture A4 : largeurs en mm
+var invoiceColumns = []struct {
+	title string
+	width float64
+	align string
+}{
+	{"Désignation", 70, "L"},
+	{"Qté", 15, "R"},
+	{"P.U. HT", 25, "R"},
+	{"Remise", 22, "R"},
+	{"TVA", 15, "R"},
+	{"Total HT", 33, "R"},
+}
+
+// PDF écrit la facture A4 de la commande
+func PDF(r *Receipt, w io.Writer) error {
+	pdf := fpdf.New("P", "mm", "A4", "")
+	pdf.SetTitle(r.Template.InvoiceTitle+" "+r.Commande.Ncommande, true)
+	pdf.SetAutoPageBreak(true, 20)
+	tr := pdf.UnicodeTranslatorFromDescriptor("") // Polices standard en cp1252
+	currency := r.Policy.Currency
+
+	pdf.SetFooterFunc(func() {
+		pdf.SetY(-15)
+		pdf.SetFont("Helvetica", "I", 8)
+		pdf.SetTextColor(110, 110, 110)
+		for _, line := range Lines(r.Template.Footer) {
+			pdf.CellFormat(0, 4, tr(line), "", 1, "C", false, 0, "")
+		}
+		pdf.SetTextColor(0, 0, 0)
+	})
+	pdf.AddPage()
+
+	// Émetteur : entreprise, identifiants légaux, point de vente
+	pdf.SetFont("Helvetica", "B", 15)
+	pdf.CellFormat(110, 7, tr(r.Entreprise.Name), "", 1, "L", false, 0, "")
+	pdf.SetFont("Helvetica", "", 9)
+	issuer := []string{r.Entreprise.Adresse, joinNonEmpty(" - ", r.Entreprise.Telephone, r.Entreprise.Email)}
+	issuer = append(issuer, r.Legal()...)
+	issuer = append(issuer, joinNonEmpty(" - ", r.Pos.Name, r.Pos.Adresse, r.Pos.Telephone))
+	issuer = append(issuer, Lines(r.Template.Header)...)
+	for _, line := range issuer {
+		if line != "" {
+			pdf.CellFormat(110, 4.5, tr(line), "", 1, "L", false, 0, "")
+		}
+	}
+	bottom := pdf.GetY()
+
+	// Références de la facture, à droite
+	pdf.SetXY(125, 12)
+	pdf.SetFont("Helvetica", "B", 16)
+	pdf.CellFormat(75, 8, tr(r.Template.InvoiceTitle), "", 2, "R", false, 0, "")
+	pdf.SetFont("Helvetica", "", 9)
+	refs := []string{
+		"N° " + r.Commande.Ncommande,
+		"Date : " + r.Commande.CreatedAt.Format("02/01/2006 15:04"),
+	}
+	if r.Template.ShowCashier && r.Cashier != "" {
+		refs = append(refs, "Caissier : "+r.Cashier)
+	}
+	if r.Commande.Status == models.CommandeCancelled || r.Commande.Status == models.CommandeRefunded {
+		refs = append(refs, "Statut : "+strings.ToUpper(r.Commande.Status))
+	}
+	for _, line := range refs {
+		pdf.CellFormat(75, 4.5, tr(line), "", 2, "R", false, 0, "")
+	}
+
+	// Client
+	if r.Template.ShowClient && r.Client != nil {
+		pdf.SetXY(125, pdf.GetY()+4)
+		pdf.SetFont("Helvetica", "B", 9)
+		pdf.CellFormat(75, 5, tr("Client"), "LTR", 2, "L", false, 0, "")
+		pdf.SetFont("Helvetica", "", 9)
+		client := []string{r.Client.Fullname, r.Client.Organisation, r.Client.Telephone, r.Client.Adress}
+		var lines []string
+		for _, line := range client {
+			if line != "" {
+				lines = append(lines, line)
+			}
+		}
+		for i, line := range lines {
+			border := "LR"
+			if i == len(lines)-1 {
+				border = "LRB"
+			}
+			pdf.CellFormat(75, 4.5, tr(line), border, 2, "L", false, 0, "")
+		}
+	}
+	if y := pdf.GetY(); y > bottom {
+		bottom = y
+	}
+	pdf.SetXY(10, bottom+8)
+
+	// Articles
+	pdf.SetFont("Helvetica", "B", 9)
+	pdf.SetFillColor(235, 235, 235)
+	for _, col := range invoiceColumns {
+		pdf.CellFormat(col.width, 7, tr(col.title), "1", 0, col.align, true, 0, "")
+	}
+	pdf.Ln(-1)
+	pdf.SetFont("Helvetica", "", 9)
+	for _, l := range r.Lines {
+		remise := ""
+		if l.Remise > 0 {
+			remise = r.Amount(l.Remise)
+		}
+		cells := []string{
+			l.Name,
+			strconv.FormatUint(l.Quantity, 10),
+			r.Amount(l.UnitPrice),
+			remise,
+			Percent(l.TvaRate),
+			r.Amount(l.TotalHt),
+		}
+		for i, col := range invoiceColumns {
+			text := tr(cells[i])
+			if i == 0 {
+				text = fit(pdf, text, col.width-2)
+			}
+			pdf.CellFormat(col.width, 6, text, "1", 0, col.align, false, 0, "")
+		}
+		pdf.Ln(-1)
+	}
+
+	// Totaux, à droite
+	pdf.Ln(4)
+	top := pdf.GetY()
+	totals := [][2]string{
+		{"Total HT", r.Amount(r.Commande.TotalHt)},
+		{"dont remises", r.Amount(r.Commande.TotalRemise)},
+		{"TVA", r.Amount(r.Commande.TotalTva)},
+	}
+	for _, t := range totals {
+		pdf.SetX(130)
+		pdf.CellFormat(37, 6, tr(t[0]), "1", 0, "L", false, 0, "")
+		pdf.CellFormat(33, 6, tr(t[1]), "1", 1, "R", false, 0, "")
+	}
+	pdf.SetX(130)
+	pdf.SetFont("Helvetica", "B", 10)
+	pdf.CellFormat(37, 7, tr("Total TTC ("+currency+")"), "1", 0, "L", true, 0, "")
+	pdf.CellFormat(33, 7, tr(r.Amount(r.Commande.TotalTtc)), "1", 1, "R", true, 0, "")
+	after := pdf.GetY()
+
+	// Récapitulatif de la TVA par taux, à gauche des totaux
+	if r.Template.ShowTvaBreakdown && len(r.Tva) > 0 {
+		pdf.SetXY(10, top)
+		pdf.SetFont("Helvetica", "B", 9)
+		for _, title := range []string{"Taux TVA", "Base HT", "Montant TVA"} {
+			pdf.CellFormat(33, 6, tr(title), "1", 0, "C", true, 0, "")
+		}
+		pdf.Ln(-1)
+		pdf.SetFont("Helvetica", "", 9)
+		for _, t := range r.Tva {
+			pdf.CellFormat(33, 6, tr(Percent(t.Rate)), "1", 0, "R", false, 0, "")
+			pdf.CellFormat(33, 6, tr(r.Amount(t.Base)), "1", 0, "R", false, 0, "")
+			pdf.CellFormat(33, 6, tr(r.Amount(t.Amount)), "1", 1, "R", false, 0, "")
+		}
+		if y := pdf.GetY(); y > after {
+			after = y
+		}
+	}
+	pdf.SetXY(10, after+6)
+
+	// Règlements
+	if r.Template.ShowPayments && len(r.Payments) > 0 {
+		pdf.SetFont("Helvetica", "B", 9)
+		pdf.CellFormat(0, 6, tr("Règlements"), "", 1, "L", false, 0, "")
+		pdf.SetFont("Helvetica", "", 9)
+		for _, p := range r.Payments {
+			label := joinNonEmpty(" - ", models.PaymentMethodLabels[p.Method], p.Reference)
+			pdf.CellFormat(60, 5, tr(label), "", 0, "L", false, 0, "")
+			pdf.CellFormat(40, 5, tr(r.Amount(p.Amount)+" "+currency), "", 1, "R", false, 0, "")
+		}
+		if _, change := r.Paid(); change > 0 {
+			pdf.CellFormat(60, 5, tr("Monnaie rendue"), "", 0, "L", false, 0, "")
+			pdf.CellFormat(40, 5, tr(r.Amount(change)+" "+currency), "", 1, "R", false, 0, "")
+		}
+	}
+
+	// Mentions de la facture normalisée, avec le QR code du dispositif fiscal
+	if fiscal := r.FiscalLines(); len(fiscal) > 0 {
+		pdf.Ln(4)
+		x, y := pdf.GetX(), pdf.GetY()
+		if r.Commande.Fiscal.QRCode != "" {
+			key := barcode.RegisterQR(pdf, r.Commande.Fiscal.QRCode, qr.M, qr.Unicode)
+			barcode.Barcode(pdf, key, x, y, 30, 30, false)
+			x += 34
+		}
+		pdf.SetXY(x, y)
+		for i, line := range fiscal {
+			style := ""
+			if i == 0 {
+				style = "B"
+			}
+			pdf.SetFont("Helvetica", style, 9)
+			pdf.CellFormat(0, 5, tr(line), "", 2, "L", false, 0, "")
+		}
+	}
+
+	return pdf.Output(w)
+}
+
+// fit tronque le texte pour qu'il tienne dans la largeur de la cellule
+func fit(pdf *fpdf.Fpdf, text string, width float64) string {
+	if pdf.GetStringWidth(text) <= width {
+		return text
+	}
+	for len(text) > 0 && pdf.GetStringWidth(text+"...") > width {
+		text = text[:len(text)-1]
+	}
+	return text + "..."
+}
+
+func joinNonEmpty(sep string, parts ...string) string {
+	var kept []string
+	for _, p := range parts {
+		if p = strings.TrimSpace(p); p != "" {
+			kept = append(kept, p)
+		}
+	}
+	return strings.Join(kept, sep)
+}
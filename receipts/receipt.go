@@ -0,0 +1,213 @@
+package receipts
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/kgermando/ipos-stock-api/models"
+	"github.com/kgermando/ipos-stock-api/pricing"
+
+	"gorm.io/gorm"
+)
+
+// Line est un article imprimé, avec les prix figés sur la ligne de commande
+type Line struct {
+	Name      string
+	Quantity  uint64
+	UnitPrice float64 // Prix unitaire HT
+	Remise    float64 // Remise et promotion de la ligne
+	TvaRate   float64
+	TotalHt   float64
+	TotalTtc  float64
+}
+
+// TvaRate est le récapitulatif d'un taux de TVA : base HT et montant
+type TvaRate struct {
+	Rate   float64
+	Base   float64
+	Amount float64
+}
+
+// Receipt regroupe tout ce qui est imprimé pour une commande
+type Receipt struct {
+	Entreprise models.Entreprise
+	Pos        models.Pos
+	Commande   models.Commande
+	Client     *models.Client // nil si la commande n'a pas de client connu
+	Cashier    string
+	Lines      []Line
+	Tva        []TvaRate
+	Payments   []models.Payment
+	Template   models.ReceiptTemplate
+	Policy     pricing.Policy
+}
+
+// Load lit la commande et tout ce qu'il faut pour l'imprimer
+func Load(tx *gorm.DB, commandeUUID string) (*Receipt, error) {
+	r := &Receipt{}
+	if err := tx.Where("uuid = ?", commandeUUID).
+		Preload("CommandeLines", func(db *gorm.DB) *gorm.DB { return db.Order("created_at") }).
+		Preload("CommandeLines.Product").
+		Preload("CommandeLines.Plat").
+		Preload("Payments", func(db *gorm.DB) *gorm.DB { return db.Order("created_at") }).
+		First(&r.Commande).Error; err != nil {
+		return nil, err
+	}
+	if err := tx.Where("uuid = ?", r.Commande.PosUUID).First(&r.Pos).Error; err != nil {
+		return nil, err
+	}
+	tx.Where("uuid = ?", r.Pos.EntrepriseUUID).First(&r.Entreprise)
+
+	if r.Commande.ClientUUID != "" {
+		var client models.Client
+		if tx.Where("uuid = ?", r.Commande.ClientUUID).Limit(1).Find(&client); client.UUID != "" {
+			r.Client = &client
+		}
+	}
+	if r.Commande.CashierUUID != "" {
+		var cashier models.User
+		tx.Select("uuid, fullname").Where("uuid = ?", r.Commande.CashierUUID).Limit(1).Find(&cashier)
+		r.Cashier = cashier.Fullname
+	}
+
+	r.Template = Template(tx, r.Pos.UUID)
+	r.Policy = pricing.LoadPolicy(tx, r.Pos.EntrepriseUUID)
+	r.Payments = r.Commande.Payments
+
+	for _, cl := range r.Commande.CommandeLines {
+		name := cl.Product.Name
+		if cl.ItemType == "plat" {
+			name = cl.Plat.Name
+		}
+		r.Lines = append(r.Lines, Line{
+			Name:      name,
+			Quantity:  cl.Quantity,
+			UnitPrice: cl.UnitPrice,
+			Remise:    cl.RemiseAmount + cl.PromotionAmount,
+			TvaRate:   cl.TvaRate,
+			TotalHt:   cl.TotalHt,
+			TotalTtc:  cl.TotalTtc,
+		})
+	}
+	r.Tva = Breakdown(r.Commande.CommandeLines, r.Policy)
+	return r, nil
+}
+
+// Template retourne le modèle d'impression du point de vente, ou celui par défaut
+func Template(tx *gorm.DB, posUUID string) models.ReceiptTemplate {
+	var tpl models.ReceiptTemplate
+	if tx.Where("pos_uuid = ?", posUUID).Limit(1).Find(&tpl); tpl.UUID == "" {
+		tpl = models.DefaultReceiptTemplate(posUUID)
+	}
+	if tpl.PaperWidth != 58 {
+		tpl.PaperWidth = 80
+	}
+	return tpl
+}
+
+// Breakdown récapitule la TVA par taux, du plus petit au plus grand
+func Breakdown(lines []models.CommandeLine, policy pricing.Policy) []TvaRate {
+	byRate := map[float64]*TvaRate{}
+	for _, l := range lines {
+		t, ok := byRate[l.TvaRate]
+		if !ok {
+			t = &TvaRate{Rate: l.TvaRate}
+			byRate[l.TvaRate] = t
+		}
+		t.Base += l.TotalHt
+		t.Amount += l.TvaAmount
+	}
+
+	rates := make([]TvaRate, 0, len(byRate))
+	for _, t := range byRate {
+		rates = append(rates, TvaRate{Rate: t.Rate, Base: policy.Round(t.Base), Amount: policy.Round(t.Amount)})
+	}
+	sort.Slice(rates, func(i, j int) bool { return rates[i].Rate < rates[j].Rate })
+	return rates
+}
+
+// Amount formate un montant à la française dans la devise de l'entreprise :
+// espaces entre les milliers et virgule décimale (12 500,50)
+func (r *Receipt) Amount(v float64) string {
+	s := strconv.FormatFloat(r.Policy.Round(v), 'f', r.Policy.Decimals, 64)
+	sign := ""
+	if strings.HasPrefix(s, "-") {
+		sign, s = "-", s[1:]
+	}
+	whole, decimals, _ := strings.Cut(s, ".")
+
+	var b strings.Builder
+	for i, d := range whole {
+		if i > 0 && (len(whole)-i)%3 == 0 {
+			b.WriteByte(' ')
+		}
+		b.WriteRune(d)
+	}
+	if decimals != "" {
+		b.WriteString("," + decimals)
+	}
+	return sign + b.String()
+}
+
+// Percent formate un taux sans décimales inutiles (16 %, 5,5 %)
+func Percent(v float64) string {
+	return strings.Replace(strconv.FormatFloat(v, 'f', -1, 64), ".", ",", 1) + " %"
+}
+
+// Lines découpe un texte libre du modèle en lignes non vides
+func Lines(text string) []string {
+	var lines []string
+	for _, l := range strings.Split(text, "\n") {
+		if l = strings.TrimSpace(l); l != "" {
+			lines = append(lines, l)
+		}
+	}
+	return lines
+}
+
+// Legal retourne les identifiants légaux de l'entreprise à imprimer
+func (r *Receipt) Legal() []string {
+	var legal []string
+	if r.Entreprise.Rccm != "" {
+		legal = append(legal, "RCCM : "+r.Entreprise.Rccm)
+	}
+	if r.Entreprise.IdNat != "" {
+		legal = append(legal, "Id. Nat. : "+r.Entreprise.IdNat)
+	}
+	if r.Entreprise.NImpot != "" {
+		legal = append(legal, "N° Impôt : "+r.Entreprise.NImpot)
+	}
+	return legal
+}
+
+// Paid retourne le total réglé et la monnaie rendue
+func (r *Receipt) Paid() (paid, change float64) {
+	for _, p := range r.Payments {
+		paid += p.Amount
+		change += p.Change
+	}
+	return r.Policy.Round(paid), r.Policy.Round(change)
+}
+
+// FiscalLines retourne les mentions de la facture normalisée ; aucune si la
+// commande n'est pas soumise à la certification fiscale
+func (r *Receipt) FiscalLines() []string {
+	f := r.Commande.Fiscal
+	switch f.Status {
+	case models.FiscalCertified:
+		lines := []string{
+			"FACTURE NORMALISÉE",
+			"Dispositif : " + f.Device,
+			"Compteurs : " + f.Counters,
+			"Code : " + f.Signature,
+		}
+		if f.CertifiedAt != nil {
+			lines = append(lines, "Certifiée le "+f.CertifiedAt.Format("02/01/2006 15:04:05"))
+		}
+		return lines
+	case models.FiscalPending, models.FiscalRejected:
+		return []string{"Facture en attente de certification fiscale"}
+	}
+	return nil
+}
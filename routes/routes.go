@@ -3,24 +3,36 @@ package routes
 import (
 	"github.com/gofiber/fiber/v2"
 	"github.com/kgermando/ipos-stock-api/controllers/abonnements"
+	"github.com/kgermando/ipos-stock-api/controllers/apikeys"
+	"github.com/kgermando/ipos-stock-api/controllers/audit"
 	"github.com/kgermando/ipos-stock-api/controllers/auth"
 	"github.com/kgermando/ipos-stock-api/controllers/caisses"
 	"github.com/kgermando/ipos-stock-api/controllers/clients"
 	"github.com/kgermando/ipos-stock-api/controllers/commandes"
+	"github.com/kgermando/ipos-stock-api/controllers/conflicts"
 	"github.com/kgermando/ipos-stock-api/controllers/dashboard"
+	"github.com/kgermando/ipos-stock-api/controllers/devices"
 	"github.com/kgermando/ipos-stock-api/controllers/entreprises"
+	"github.com/kgermando/ipos-stock-api/controllers/fiscal"
 	"github.com/kgermando/ipos-stock-api/controllers/fournisseurs"
 	"github.com/kgermando/ipos-stock-api/controllers/livraisons"
 	"github.com/kgermando/ipos-stock-api/controllers/livreurs"
+	"github.com/kgermando/ipos-stock-api/controllers/numbering"
+	"github.com/kgermando/ipos-stock-api/controllers/payments"
 	"github.com/kgermando/ipos-stock-api/controllers/plats"
 	"github.com/kgermando/ipos-stock-api/controllers/pos"
+	"github.com/kgermando/ipos-stock-api/controllers/pricing"
 	"github.com/kgermando/ipos-stock-api/controllers/products"
+	"github.com/kgermando/ipos-stock-api/controllers/promotions"
+	"github.com/kgermando/ipos-stock-api/controllers/receipts"
 	"github.com/kgermando/ipos-stock-api/controllers/reservations"
 	"github.com/kgermando/ipos-stock-api/controllers/stocks"
+	"github.com/kgermando/ipos-stock-api/controllers/synchronisation"
 	tablebox "github.com/kgermando/ipos-stock-api/controllers/tableBox"
 	"github.com/kgermando/ipos-stock-api/controllers/users"
 
 	"github.com/kgermando/ipos-stock-api/controllers/zones"
+	"github.com/kgermando/ipos-stock-api/middlewares"
 
 	"github.com/gofiber/fiber/v2/middleware/logger"
 )
@@ -40,320 +52,444 @@ func Setup(app *fiber.App) {
 	a.Post("/forgot-password", auth.Forgot)
 	a.Get("/verify-reset-token/:token", auth.VerifyResetToken)
 	a.Post("/reset/:token", auth.ResetPassword)
+	a.Post("/refresh", auth.Refresh)
+	a.Post("/login/2fa", auth.LoginTwoFactor)
+	a.Get("/invitation/:token", auth.VerifyInvitation)
+	a.Post("/invitation/:token", auth.AcceptInvitation)
 
 	// Enterprise management in auth context
 	a.Post("/entreprise/create", entreprises.CreateEntreprise)
-	a.Put("/entreprise/update/:uuid", entreprises.UpdateEntreprise)
 
 	// Protected authentication routes
-	// app.Use(middlewares.IsAuthenticated)
+	// Toutes les routes déclarées après ce point exigent un token Bearer valide
+	api.Use(middlewares.IsAuthenticated, middlewares.TenantScope, middlewares.Idempotency)
 	a.Get("/user", auth.AuthUser)
+	a.Get("/permissions", auth.GetPermissions)
 	a.Put("/profil/info", auth.UpdateInfo)
 	a.Put("/change-password", auth.ChangePassword)
 	a.Post("/logout", auth.Logout)
+	a.Post("/logout-all", auth.LogoutAll)
+	a.Post("/2fa/setup", auth.SetupTwoFactor)
+	a.Post("/2fa/confirm", auth.ConfirmTwoFactor)
+	a.Post("/2fa/disable", auth.DisableTwoFactor)
+	a.Post("/2fa/recovery-codes", auth.RegenerateRecoveryCodes)
+	a.Put("/pin", auth.SetPin)
+	a.Delete("/pin", auth.RemovePin)
+	a.Post("/pin/switch", auth.SwitchCashier)
 
 	// ============================================================
 	// DASHBOARD ROUTES
 	// ============================================================
 	dash := api.Group("/dashboard")
 	main := dash.Group("/main")
-	main.Get("/stats", dashboard.GetDashboardStats)
-	main.Get("/sales-chart", dashboard.GetSalesChartData)
-	main.Get("/plat-chart", dashboard.GetPlatChartData)
-	main.Get("/product-chart", dashboard.GetProductChartData)
-	main.Get("/stock-alerts", dashboard.GetStockAlerts)
-	main.Get("/expiration-alerts", dashboard.GetExpirationAlerts)
-	main.Get("/stock-rotation", dashboard.GetStockRotationData)
-	main.Get("/plat-statistics", dashboard.GetPlatStatistics)
-	main.Get("/livraison-statistics", dashboard.GetLivraisonStatistics)
-	main.Get("/livraison-zones", dashboard.GetLivraisonZonesData)
-	main.Get("/livreur-performance", dashboard.GetLivreurPerformance)
-	main.Get("/caisse-statistics", dashboard.GetCaisseStatistics)
-	main.Get("/flux-tresorerie", dashboard.GetFluxTresorerieData)
-	main.Get("/repartition-transactions", dashboard.GetRepartitionTransactionsData)
-	main.Get("/top-transactions", dashboard.GetTopTransactions)
-	main.Get("/historique-tresorerie", dashboard.GetHistoriqueTresorerie)
-	main.Get("/top-caisses", dashboard.GetTopCaisses)
+	main.Get("/stats", middlewares.Can("dashboard:read"), dashboard.GetDashboardStats)
+	main.Get("/sales-chart", middlewares.Can("dashboard:read"), dashboard.GetSalesChartData)
+	main.Get("/plat-chart", middlewares.Can("dashboard:read"), dashboard.GetPlatChartData)
+	main.Get("/product-chart", middlewares.Can("dashboard:read"), dashboard.GetProductChartData)
+	main.Get("/stock-alerts", middlewares.Can("dashboard:read"), dashboard.GetStockAlerts)
+	main.Get("/expiration-alerts", middlewares.Can("dashboard:read"), dashboard.GetExpirationAlerts)
+	main.Get("/stock-rotation", middlewares.Can("dashboard:read"), dashboard.GetStockRotationData)
+	main.Get("/plat-statistics", middlewares.Can("dashboard:read"), dashboard.GetPlatStatistics)
+	main.Get("/livraison-statistics", middlewares.Can("dashboard:read"), dashboard.GetLivraisonStatistics)
+	main.Get("/livraison-zones", middlewares.Can("dashboard:read"), dashboard.GetLivraisonZonesData)
+	main.Get("/livreur-performance", middlewares.Can("dashboard:read"), dashboard.GetLivreurPerformance)
+	main.Get("/caisse-statistics", middlewares.Can("dashboard:read"), dashboard.GetCaisseStatistics)
+	main.Get("/flux-tresorerie", middlewares.Can("dashboard:read"), dashboard.GetFluxTresorerieData)
+	main.Get("/repartition-transactions", middlewares.Can("dashboard:read"), dashboard.GetRepartitionTransactionsData)
+	main.Get("/top-transactions", middlewares.Can("dashboard:read"), dashboard.GetTopTransactions)
+	main.Get("/payment-methods", middlewares.Can("dashboard:read"), dashboard.GetPaymentMethodsData)
+	main.Get("/historique-tresorerie", middlewares.Can("dashboard:read"), dashboard.GetHistoriqueTresorerie)
+	main.Get("/top-caisses", middlewares.Can("dashboard:read"), dashboard.GetTopCaisses)
 
 	// ============================================================
 	// ENTREPRISE ROUTES
 	// ============================================================
 	e := api.Group("/entreprises")
-	e.Get("/all/paginate", entreprises.GetPaginatedEntreprise)
-	e.Get("/all", entreprises.GetAllEntreprises)
-	e.Post("/create", entreprises.CreateEntreprise)
-	e.Get("/get/:uuid", entreprises.GetEntreprise)
-	e.Put("/update/:uuid", entreprises.UpdateEntreprise)
-	e.Delete("/delete/:uuid", entreprises.DeleteEntreprise)
+	e.Get("/all/paginate", middlewares.Can("entreprises:manage"), entreprises.GetPaginatedEntreprise)
+	e.Get("/all", middlewares.Can("entreprises:manage"), entreprises.GetAllEntreprises)
+	e.Post("/create", middlewares.Can("entreprises:manage"), entreprises.CreateEntreprise)
+	e.Get("/get/:uuid", middlewares.Can("entreprise:read"), entreprises.GetEntreprise)
+	e.Put("/update/:uuid", middlewares.Can("entreprise:write"), entreprises.UpdateEntreprise)
+	e.Delete("/delete/:uuid", middlewares.Can("entreprises:manage"), entreprises.DeleteEntreprise)
 
 	// ============================================================
 	// ABONNEMENTS ROUTES
 	// ============================================================
 	ab := api.Group("/abonnements")
-	ab.Get("/all/paginate/:entreprise_uuid", abonnements.GetPaginatedAbonnementsEntreprise)
-	ab.Get("/all/paginate", abonnements.GetPaginatedAbonnements)
-	ab.Get("/all", abonnements.GetAllAbonnements)
-	ab.Get("/current", abonnements.GetAbonnementActuel)
-	ab.Get("/expiring", abonnements.GetAbonnementsExpirant)
-	ab.Get("/statistics", abonnements.GetStatistiquesAbonnements)
-	ab.Get("/verify/:uuid", abonnements.VerifierValiditeAbonnement)
-	ab.Get("/get/:uuid", abonnements.GetAbonnement)
-	ab.Post("/create", abonnements.CreateAbonnement)
-	ab.Put("/update-statut/:uuid", abonnements.UpdateStatutAbonnement)
-	ab.Put("/update/:uuid", abonnements.UpdateAbonnement)
-	ab.Delete("/delete/:uuid", abonnements.DeleteAbonnement)
+	ab.Get("/all/paginate/:entreprise_uuid", middlewares.Can("abonnements:read"), middlewares.TenantParams, abonnements.GetPaginatedAbonnementsEntreprise)
+	ab.Get("/all/paginate", middlewares.Can("abonnements:read"), abonnements.GetPaginatedAbonnements)
+	ab.Get("/all", middlewares.Can("abonnements:read"), abonnements.GetAllAbonnements)
+	ab.Get("/current", middlewares.Can("abonnements:read"), abonnements.GetAbonnementActuel)
+	ab.Get("/expiring", middlewares.Can("abonnements:read"), abonnements.GetAbonnementsExpirant)
+	ab.Get("/statistics", middlewares.Can("abonnements:read"), abonnements.GetStatistiquesAbonnements)
+	ab.Get("/verify/:uuid", middlewares.Can("abonnements:read"), abonnements.VerifierValiditeAbonnement)
+	ab.Get("/get/:uuid", middlewares.Can("abonnements:read"), abonnements.GetAbonnement)
+	ab.Post("/create", middlewares.Can("abonnements:manage"), abonnements.CreateAbonnement)
+	ab.Put("/update-statut/:uuid", middlewares.Can("abonnements:manage"), abonnements.UpdateStatutAbonnement)
+	ab.Put("/update/:uuid", middlewares.Can("abonnements:manage"), abonnements.UpdateAbonnement)
+	ab.Delete("/delete/:uuid", middlewares.Can("abonnements:manage"), abonnements.DeleteAbonnement)
 
 	// ============================================================
 	// USERS ROUTES
 	// ============================================================
 	u := api.Group("/users")
-	u.Get("/all/paginate/nosearch", users.GetPaginatedNoSerach)
-	u.Get("/all/paginate", users.GetPaginatedUsersSupport)
-	u.Get("/:entreprise_uuid/:pos_uuid/all/paginate", users.GetPaginatedUserByPosUUID)
-	u.Get("/:entreprise_uuid/all/paginate", users.GetPaginatedUsers)
-	u.Get("/all/:entreprise_uuid", users.GetAllUsersById)
-	u.Get("/all", users.GetAllUsers)
-	u.Post("/create", users.CreateUser)
-	u.Get("/get/:uuid", users.GetUser)
-	u.Put("/update/:uuid", users.UpdateUser)
-	u.Delete("/delete/:uuid", users.DeleteUser)
+	u.Get("/all/paginate/nosearch", middlewares.Can("users:read"), users.GetPaginatedNoSerach)
+	u.Get("/all/paginate", middlewares.Can("users:read"), users.GetPaginatedUsersSupport)
+	u.Get("/:entreprise_uuid/:pos_uuid/all/paginate", middlewares.Can("users:read"), middlewares.TenantParams, users.GetPaginatedUserByPosUUID)
+	u.Get("/:entreprise_uuid/all/paginate", middlewares.Can("users:read"), middlewares.TenantParams, users.GetPaginatedUsers)
+	u.Get("/all/:entreprise_uuid", middlewares.Can("users:read"), middlewares.TenantParams, users.GetAllUsersById)
+	u.Get("/all", middlewares.Can("users:read"), users.GetAllUsers)
+	u.Post("/create", middlewares.Can("users:write"), users.CreateUser)
+	u.Get("/get/:uuid", middlewares.Can("users:read"), users.GetUser)
+	u.Put("/update/:uuid", middlewares.Can("users:write"), users.UpdateUser)
+	u.Delete("/delete/:uuid", middlewares.Can("users:delete"), users.DeleteUser)
+	u.Post("/sessions/revoke/:uuid", middlewares.Can("users:write"), users.RevokeUserSessions)
+	u.Put("/unlock/:uuid", middlewares.Can("users:write"), users.UnlockUser)
+	u.Put("/pin/reset/:uuid", middlewares.Can("users:write"), users.ResetUserPin)
+	u.Get("/invitations/:entreprise_uuid/pending", middlewares.Can("users:read"), middlewares.TenantParams, users.GetPendingInvitations)
+	u.Post("/invitations/create", middlewares.Can("users:write"), users.InviteUser)
+	u.Put("/invitations/resend/:uuid", middlewares.Can("users:write"), users.ResendInvitation)
+	u.Put("/invitations/revoke/:uuid", middlewares.Can("users:write"), users.RevokeInvitation)
 
 	// ============================================================
 	// POS ROUTES
 	// ============================================================
 	p := api.Group("/pos")
-	p.Get("/all/paginate", pos.GetPaginatedPos)
-	p.Get("/:entreprise_uuid/all/paginate", pos.GetPaginatedPosByUUID)
-	p.Get("/:entreprise_uuid/all", pos.GetAllPosByUUId)
-	p.Post("/create", pos.CreatePos)
-	p.Get("/get/:uuid", pos.GetPos)
-	p.Put("/update/:uuid", pos.UpdatePos)
-	p.Delete("/delete/:uuid", pos.DeletePos)
+	p.Get("/all/paginate", middlewares.Can("pos:read"), pos.GetPaginatedPos)
+	p.Get("/:entreprise_uuid/all/paginate", middlewares.Can("pos:read"), middlewares.TenantParams, pos.GetPaginatedPosByUUID)
+	p.Get("/:entreprise_uuid/all", middlewares.Can("pos:read"), middlewares.TenantParams, pos.GetAllPosByUUId)
+	p.Post("/create", middlewares.Can("pos:write"), pos.CreatePos)
+	p.Get("/get/:uuid", middlewares.Can("pos:read"), pos.GetPos)
+	p.Put("/update/:uuid", middlewares.Can("pos:write"), pos.UpdatePos)
+	p.Delete("/delete/:uuid", middlewares.Can("pos:delete"), pos.DeletePos)
+
+	// ============================================================
+	// DEVICES ROUTES
+	// ============================================================
+	dv := api.Group("/devices")
+	dv.Get("/:entreprise_uuid/:pos_uuid/all", middlewares.Can("devices:read"), middlewares.TenantParams, devices.GetAllDevicesByPos)
+	dv.Get("/get/:uuid", middlewares.Can("devices:read"), devices.GetDevice)
+	dv.Put("/revoke/:uuid", middlewares.Can("devices:write"), devices.RevokeDevice)
+	dv.Put("/restore/:uuid", middlewares.Can("devices:write"), devices.RestoreDevice)
+
+	// ============================================================
+	// API KEYS ROUTES
+	// ============================================================
+	ak := api.Group("/api-keys")
+	ak.Get("/:entreprise_uuid/all", middlewares.Can("apikeys:read"), middlewares.TenantParams, apikeys.GetAllApiKeys)
+	ak.Post("/create", middlewares.Can("apikeys:write"), apikeys.CreateApiKey)
+	ak.Put("/revoke/:uuid", middlewares.Can("apikeys:write"), apikeys.RevokeApiKey)
+
+	// ============================================================
+	// SYNCHRONISATION ROUTES
+	// ============================================================
+	sy := api.Group("/sync")
+	sy.Get("/:entreprise_uuid/:pos_uuid/changes", middlewares.TenantParams, synchronisation.GetChanges)
+	sy.Post("/push", middlewares.Can("sync:push"), synchronisation.Push)
+
+	// ============================================================
+	// AUDIT LOGS ROUTES (lecture seule)
+	// ============================================================
+	al := api.Group("/audit-logs")
+	al.Get("/:entreprise_uuid/all/paginate", middlewares.Can("audit:read"), middlewares.TenantParams, audit.GetPaginatedAuditLogs)
+	al.Get("/get/:uuid", middlewares.Can("audit:read"), audit.GetAuditLog)
+
+	// ============================================================
+	// CONFLICTS ROUTES (écritures concurrentes)
+	// ============================================================
+	cf := api.Group("/conflicts")
+	cf.Get("/:entreprise_uuid/all/paginate", middlewares.Can("conflicts:read"), middlewares.TenantParams, conflicts.GetPaginatedConflicts)
+	cf.Get("/:entreprise_uuid/rules", middlewares.Can("conflicts:read"), middlewares.TenantParams, conflicts.GetConflictRules)
+	cf.Put("/:entreprise_uuid/rules", middlewares.Can("conflicts:write"), middlewares.TenantParams, conflicts.SetConflictRule)
+	cf.Get("/get/:uuid", middlewares.Can("conflicts:read"), conflicts.GetConflict)
+	cf.Put("/review/:uuid", middlewares.Can("conflicts:review"), conflicts.ReviewConflict)
 
 	// ============================================================
 	// CAISSES ROUTES
 	// ============================================================
 	cais := api.Group("/caisses")
-	cais.Get("/:entreprise_uuid/:pos_uuid/all/synchronisation", caisses.GetDataSynchronisation)
-	cais.Get("/:entreprise_uuid/:pos_uuid/all", caisses.GetAllCaisseByPos)
-	cais.Get("/:entreprise_uuid/all/total", caisses.GetTotalAllCaisses)
-	cais.Get("/:entreprise_uuid/all", caisses.GetAllCaisses)
-	cais.Post("/create", caisses.CreateCaisse)
-	cais.Get("/get/:uuid", caisses.GetCaisse)
-	cais.Put("/update/:uuid", caisses.UpdateCaisse)
-	cais.Delete("/delete/:uuid", caisses.DeleteCaisse)
+	cais.Get("/:entreprise_uuid/:pos_uuid/all/synchronisation", middlewares.Can("caisses:read"), middlewares.TenantParams, caisses.GetDataSynchronisation)
+	cais.Get("/:entreprise_uuid/:pos_uuid/all", middlewares.Can("caisses:read"), middlewares.TenantParams, caisses.GetAllCaisseByPos)
+	cais.Get("/:entreprise_uuid/all/total", middlewares.Can("caisses:read"), middlewares.TenantParams, caisses.GetTotalAllCaisses)
+	cais.Get("/:entreprise_uuid/all", middlewares.Can("caisses:read"), middlewares.TenantParams, caisses.GetAllCaisses)
+	cais.Post("/create", middlewares.Can("caisses:write"), caisses.CreateCaisse)
+	cais.Get("/get/:uuid", middlewares.Can("caisses:read"), caisses.GetCaisse)
+	cais.Put("/update/:uuid", middlewares.Can("caisses:write"), caisses.UpdateCaisse)
+	cais.Delete("/delete/:uuid", middlewares.Can("caisses:delete"), caisses.DeleteCaisse)
 
 	// ============================================================
 	// CAISSE ITEMS ROUTES
 	// ============================================================
 	caisseItem := api.Group("/caisse-items")
-	caisseItem.Get("/:entreprise_uuid/:pos_uuid/all/synchronisation", caisses.GetDataSynchronisationCaisseItem)
-	caisseItem.Get("/:entreprise_uuid/:caisse_uuid/all/paginate", caisses.GetPaginatedCaisseItems)
-	caisseItem.Get("/:entreprise_uuid/:caisse_uuid/all", caisses.GetAllCaisseItems)
-	caisseItem.Post("/create", caisses.CreateCaisseItem)
-	caisseItem.Get("/get/:uuid", caisses.GetCaisseItem)
-	caisseItem.Put("/update/:uuid", caisses.UpdateCaisseItem)
-	caisseItem.Delete("/delete/:uuid", caisses.DeleteCaisseItem)
+	caisseItem.Get("/:entreprise_uuid/:pos_uuid/all/synchronisation", middlewares.Can("caisses:read"), middlewares.TenantParams, caisses.GetDataSynchronisationCaisseItem)
+	caisseItem.Get("/:entreprise_uuid/:caisse_uuid/all/paginate", middlewares.Can("caisses:read"), middlewares.TenantParams, caisses.GetPaginatedCaisseItems)
+	caisseItem.Get("/:entreprise_uuid/:caisse_uuid/all", middlewares.Can("caisses:read"), middlewares.TenantParams, caisses.GetAllCaisseItems)
+	caisseItem.Post("/create", middlewares.Can("caisses:write"), caisses.CreateCaisseItem)
+	caisseItem.Get("/get/:uuid", middlewares.Can("caisses:read"), caisses.GetCaisseItem)
+	caisseItem.Put("/update/:uuid", middlewares.Can("caisses:write"), caisses.UpdateCaisseItem)
+	caisseItem.Delete("/delete/:uuid", middlewares.Can("caisses:delete"), caisses.DeleteCaisseItem)
 
 	// ============================================================
 	// PRODUCTS ROUTES
 	// ============================================================
 	pr := api.Group("/products")
-	pr.Get("/:entreprise_uuid/:pos_uuid/all/synchronisation", products.GetDataSynchronisation)
-	pr.Post("/:entreprise_uuid/:pos_uuid/upload-excel", products.UploadProductsFromExcel)
-	pr.Get("/:entreprise_uuid/:pos_uuid/all/search", products.GetAllProductBySearch)
-	pr.Get("/:entreprise_uuid/:pos_uuid/all/paginate", products.GetPaginatedProductByPosUUID)
-	pr.Get("/:entreprise_uuid/:pos_uuid/all", products.GetAllProducts)
-	pr.Get("/:entreprise_uuid/all/paginate", products.GetPaginatedProductEntreprise)
-	pr.Get("/excel-format-info", products.GetExcelFormatInfo)
-	pr.Get("/excel-template", products.GenerateProductExcelTemplate)
-	pr.Post("/create", products.CreateProduct)
-	pr.Get("/get/:uuid", products.GetProduct)
-	pr.Put("/update/stock-endommage/:uuid", products.UpdateProductStockEndommage)
-	pr.Put("/update/restitution/:uuid", products.UpdateProductRestitution)
-	pr.Put("/update/stock/:uuid", products.UpdateProductStockDispo)
-	pr.Put("/update/:uuid", products.UpdateProduct)
-	pr.Delete("/delete/:uuid", products.DeleteProduct)
+	pr.Get("/:entreprise_uuid/:pos_uuid/all/synchronisation", middlewares.Can("products:read"), middlewares.TenantParams, products.GetDataSynchronisation)
+	pr.Post("/:entreprise_uuid/:pos_uuid/upload-excel", middlewares.Can("products:write"), middlewares.TenantParams, products.UploadProductsFromExcel)
+	pr.Get("/:entreprise_uuid/:pos_uuid/all/search", middlewares.Can("products:read"), middlewares.TenantParams, products.GetAllProductBySearch)
+	pr.Get("/:entreprise_uuid/:pos_uuid/all/paginate", middlewares.Can("products:read"), middlewares.TenantParams, products.GetPaginatedProductByPosUUID)
+	pr.Get("/:entreprise_uuid/:pos_uuid/all", middlewares.Can("products:read"), middlewares.TenantParams, products.GetAllProducts)
+	pr.Get("/:entreprise_uuid/all/paginate", middlewares.Can("products:read"), middlewares.TenantParams, products.GetPaginatedProductEntreprise)
+	pr.Get("/excel-format-info", middlewares.Can("products:read"), products.GetExcelFormatInfo)
+	pr.Get("/excel-template", middlewares.Can("products:read"), products.GenerateProductExcelTemplate)
+	pr.Post("/create", middlewares.Can("products:write"), products.CreateProduct)
+	pr.Get("/get/:uuid", middlewares.Can("products:read"), products.GetProduct)
+	pr.Put("/update/stock-endommage/:uuid", middlewares.Can("products:stock"), products.UpdateProductStockEndommage)
+	pr.Put("/update/restitution/:uuid", middlewares.Can("products:stock"), products.UpdateProductRestitution)
+	pr.Put("/update/stock/:uuid", middlewares.Can("products:stock"), products.UpdateProductStockDispo)
+	pr.Put("/update/:uuid", middlewares.Can("products:write"), products.UpdateProduct)
+	pr.Delete("/delete/:uuid", middlewares.Can("products:delete"), products.DeleteProduct)
 
 	// ============================================================
 	// PLATS ROUTES
 	// ============================================================
 	pl := api.Group("/plats")
-	pl.Get("/:entreprise_uuid/:pos_uuid/all/synchronisation", plats.GetDataSynchronisation)
-	pl.Get("/:entreprise_uuid/:pos_uuid/all/available", plats.GetAvailablePlats)
-	pl.Get("/:entreprise_uuid/:pos_uuid/all/search", plats.GetAllPlatBySearch)
-	pl.Get("/:entreprise_uuid/:pos_uuid/all/paginate", plats.GetPaginatedPlatByPosUUID)
-	pl.Get("/:entreprise_uuid/:pos_uuid/all", plats.GetAllPlats)
-	pl.Get("/:entreprise_uuid/all/paginate", plats.GetPaginatedPlatEntreprise)
-	pl.Post("/create", plats.CreatePlat)
-	pl.Get("/get/:uuid", plats.GetPlat)
-	pl.Put("/update/availability/:uuid", plats.UpdatePlatAvailability)
-	pl.Put("/update/:uuid", plats.UpdatePlat)
-	pl.Delete("/delete/:uuid", plats.DeletePlat)
+	pl.Get("/:entreprise_uuid/:pos_uuid/all/synchronisation", middlewares.Can("plats:read"), middlewares.TenantParams, plats.GetDataSynchronisation)
+	pl.Get("/:entreprise_uuid/:pos_uuid/all/available", middlewares.Can("plats:read"), middlewares.TenantParams, plats.GetAvailablePlats)
+	pl.Get("/:entreprise_uuid/:pos_uuid/all/search", middlewares.Can("plats:read"), middlewares.TenantParams, plats.GetAllPlatBySearch)
+	pl.Get("/:entreprise_uuid/:pos_uuid/all/paginate", middlewares.Can("plats:read"), middlewares.TenantParams, plats.GetPaginatedPlatByPosUUID)
+	pl.Get("/:entreprise_uuid/:pos_uuid/all", middlewares.Can("plats:read"), middlewares.TenantParams, plats.GetAllPlats)
+	pl.Get("/:entreprise_uuid/all/paginate", middlewares.Can("plats:read"), middlewares.TenantParams, plats.GetPaginatedPlatEntreprise)
+	pl.Post("/create", middlewares.Can("plats:write"), plats.CreatePlat)
+	pl.Get("/get/:uuid", middlewares.Can("plats:read"), plats.GetPlat)
+	pl.Put("/update/availability/:uuid", middlewares.Can("plats:write"), plats.UpdatePlatAvailability)
+	pl.Put("/update/:uuid", middlewares.Can("plats:write"), plats.UpdatePlat)
+	pl.Delete("/delete/:uuid", middlewares.Can("plats:delete"), plats.DeletePlat)
+
+	// ============================================================
+	// PROMOTIONS ROUTES
+	// ============================================================
+	pm := api.Group("/promotions")
+	pm.Get("/:entreprise_uuid/all/paginate", middlewares.Can("promotions:read"), middlewares.TenantParams, promotions.GetPaginatedPromotion)
+	pm.Post("/create", middlewares.Can("promotions:write"), promotions.CreatePromotion)
+	pm.Get("/get/:uuid", middlewares.Can("promotions:read"), promotions.GetPromotion)
+	pm.Put("/update/:uuid", middlewares.Can("promotions:write"), promotions.UpdatePromotion)
+	pm.Delete("/delete/:uuid", middlewares.Can("promotions:delete"), promotions.DeletePromotion)
 
 	// ============================================================
 	// TABLEBOX ROUTES
 	// ============================================================
 	tb := api.Group("/tablebox")
-	tb.Get("/:entreprise_uuid/:pos_uuid/all/synchronisation", tablebox.GetDataSynchronisation)
-	tb.Get("/:entreprise_uuid/:pos_uuid/all/search", tablebox.GetAllTableBoxBySearch)
-	tb.Get("/:entreprise_uuid/:pos_uuid/all/paginate", tablebox.GetPaginatedTableBoxByPosUUID)
-	tb.Get("/:entreprise_uuid/:pos_uuid/all", tablebox.GetAllTableBoxs)
-	tb.Get("/:entreprise_uuid/:pos_uuid/category/:category", tablebox.GetTableBoxsByCategory)
-	tb.Get("/:entreprise_uuid/:pos_uuid/statut/:statut", tablebox.GetTableBoxsByStatut)
-	tb.Get("/:entreprise_uuid/all/paginate", tablebox.GetPaginatedTableBoxEntreprise)
-	tb.Post("/create", tablebox.CreateTableBox)
-	tb.Get("/get/:uuid", tablebox.GetTableBox)
-	tb.Put("/update/:uuid", tablebox.UpdateTableBox)
-	tb.Delete("/delete/:uuid", tablebox.DeleteTableBox)
+	tb.Get("/:entreprise_uuid/:pos_uuid/all/synchronisation", middlewares.Can("tablebox:read"), middlewares.TenantParams, tablebox.GetDataSynchronisation)
+	tb.Get("/:entreprise_uuid/:pos_uuid/all/search", middlewares.Can("tablebox:read"), middlewares.TenantParams, tablebox.GetAllTableBoxBySearch)
+	tb.Get("/:entreprise_uuid/:pos_uuid/all/paginate", middlewares.Can("tablebox:read"), middlewares.TenantParams, tablebox.GetPaginatedTableBoxByPosUUID)
+	tb.Get("/:entreprise_uuid/:pos_uuid/all", middlewares.Can("tablebox:read"), middlewares.TenantParams, tablebox.GetAllTableBoxs)
+	tb.Get("/:entreprise_uuid/:pos_uuid/category/:category", middlewares.Can("tablebox:read"), middlewares.TenantParams, tablebox.GetTableBoxsByCategory)
+	tb.Get("/:entreprise_uuid/:pos_uuid/statut/:statut", middlewares.Can("tablebox:read"), middlewares.TenantParams, tablebox.GetTableBoxsByStatut)
+	tb.Get("/:entreprise_uuid/all/paginate", middlewares.Can("tablebox:read"), middlewares.TenantParams, tablebox.GetPaginatedTableBoxEntreprise)
+	tb.Post("/create", middlewares.Can("tablebox:write"), tablebox.CreateTableBox)
+	tb.Get("/get/:uuid", middlewares.Can("tablebox:read"), tablebox.GetTableBox)
+	tb.Put("/update/:uuid", middlewares.Can("tablebox:write"), tablebox.UpdateTableBox)
+	tb.Delete("/delete/:uuid", middlewares.Can("tablebox:delete"), tablebox.DeleteTableBox)
 
 	// ============================================================
 	// RESERVATIONS ROUTES
 	// ============================================================
 	r := api.Group("/reservations")
-	r.Get("/:entreprise_uuid/:pos_uuid/all/synchronisation", reservations.GetDataSynchronisation)
-	r.Get("/:entreprise_uuid/:pos_uuid/all/search", reservations.GetAllReservationBySearch)
-	r.Get("/:entreprise_uuid/:pos_uuid/all/paginate", reservations.GetPaginatedReservationByPosUUID)
-	r.Get("/:entreprise_uuid/:pos_uuid/all", reservations.GetAllReservations)
-	r.Get("/:entreprise_uuid/:pos_uuid/status/:status", reservations.GetReservationsByStatus)
-	r.Get("/:entreprise_uuid/:pos_uuid/date/:date", reservations.GetReservationsByDate)
-	r.Get("/:entreprise_uuid/:pos_uuid/table/:table", reservations.GetReservationsByTable)
-	r.Get("/:entreprise_uuid/all/paginate", reservations.GetPaginatedReservationEntreprise)
-	r.Post("/create", reservations.CreateReservation)
-	r.Get("/get/:uuid", reservations.GetReservation)
-	r.Put("/update/:uuid", reservations.UpdateReservation)
-	r.Delete("/delete/:uuid", reservations.DeleteReservation)
+	r.Get("/:entreprise_uuid/:pos_uuid/all/synchronisation", middlewares.Can("reservations:read"), middlewares.TenantParams, reservations.GetDataSynchronisation)
+	r.Get("/:entreprise_uuid/:pos_uuid/all/search", middlewares.Can("reservations:read"), middlewares.TenantParams, reservations.GetAllReservationBySearch)
+	r.Get("/:entreprise_uuid/:pos_uuid/all/paginate", middlewares.Can("reservations:read"), middlewares.TenantParams, reservations.GetPaginatedReservationByPosUUID)
+	r.Get("/:entreprise_uuid/:pos_uuid/all", middlewares.Can("reservations:read"), middlewares.TenantParams, reservations.GetAllReservations)
+	r.Get("/:entreprise_uuid/:pos_uuid/status/:status", middlewares.Can("reservations:read"), middlewares.TenantParams, reservations.GetReservationsByStatus)
+	r.Get("/:entreprise_uuid/:pos_uuid/date/:date", middlewares.Can("reservations:read"), middlewares.TenantParams, reservations.GetReservationsByDate)
+	r.Get("/:entreprise_uuid/:pos_uuid/table/:table", middlewares.Can("reservations:read"), middlewares.TenantParams, reservations.GetReservationsByTable)
+	r.Get("/:entreprise_uuid/all/paginate", middlewares.Can("reservations:read"), middlewares.TenantParams, reservations.GetPaginatedReservationEntreprise)
+	r.Post("/create", middlewares.Can("reservations:write"), reservations.CreateReservation)
+	r.Get("/get/:uuid", middlewares.Can("reservations:read"), reservations.GetReservation)
+	r.Put("/update/:uuid", middlewares.Can("reservations:write"), reservations.UpdateReservation)
+	r.Delete("/delete/:uuid", middlewares.Can("reservations:delete"), reservations.DeleteReservation)
 
 	// ============================================================
 	// STOCKS ROUTES
 	// ============================================================
 	s := api.Group("/stocks")
-	s.Get("/:entreprise_uuid/:pos_uuid/all/synchronisation", stocks.GetDataSynchronisationStock)
-	s.Get("/all/paginate/:product_uuid", stocks.GetPaginatedStock)
-	s.Get("/all/total/:product_uuid", stocks.GetTotalStock)
-	s.Get("/all/get/:product_uuid", stocks.GetStockMargeBeneficiaire)
-	s.Get("/all/:product_uuid", stocks.GetAllStocks)
-	s.Post("/create", stocks.CreateStock)
-	s.Get("/get/:uuid", stocks.GetStock)
-	s.Put("/update/:uuid", stocks.UpdateStock)
-	s.Delete("/delete/:uuid", stocks.DeleteStock)
+	s.Get("/:entreprise_uuid/:pos_uuid/all/synchronisation", middlewares.Can("stocks:read"), middlewares.TenantParams, stocks.GetDataSynchronisationStock)
+	s.Get("/all/paginate/:product_uuid", middlewares.Can("stocks:read"), stocks.GetPaginatedStock)
+	s.Get("/all/total/:product_uuid", middlewares.Can("stocks:read"), stocks.GetTotalStock)
+	s.Get("/all/get/:product_uuid", middlewares.Can("stocks:read"), stocks.GetStockMargeBeneficiaire)
+	s.Get("/all/:product_uuid", middlewares.Can("stocks:read"), stocks.GetAllStocks)
+	s.Post("/create", middlewares.Can("stocks:write"), stocks.CreateStock)
+	s.Get("/get/:uuid", middlewares.Can("stocks:read"), stocks.GetStock)
+	s.Put("/update/:uuid", middlewares.Can("stocks:write"), stocks.UpdateStock)
+	s.Delete("/delete/:uuid", middlewares.Can("stocks:delete"), stocks.DeleteStock)
 
 	// ============================================================
 	// STOCK ENDOMMAGES ROUTES
 	// ============================================================
 	se := api.Group("/stock-endommages")
-	se.Get("/:entreprise_uuid/:pos_uuid/all/synchronisation", stocks.GetDataSynchronisationStockEndommage)
-	se.Get("/all/paginate/:product_uuid", stocks.GetPaginatedStockEndommage)
-	se.Get("/all/total/:product_uuid", stocks.GetTotalStockEndommage)
-	se.Get("/all/:product_uuid", stocks.GetAllStockEndommages)
-	se.Post("/create", stocks.CreateStockEndommage)
-	se.Get("/get/:uuid", stocks.GetStockEndommage)
-	se.Put("/update/:uuid", stocks.UpdateStockEndommage)
-	se.Delete("/delete/:uuid", stocks.DeleteStockEndommage)
+	se.Get("/:entreprise_uuid/:pos_uuid/all/synchronisation", middlewares.Can("stocks:read"), middlewares.TenantParams, stocks.GetDataSynchronisationStockEndommage)
+	se.Get("/all/paginate/:product_uuid", middlewares.Can("stocks:read"), stocks.GetPaginatedStockEndommage)
+	se.Get("/all/total/:product_uuid", middlewares.Can("stocks:read"), stocks.GetTotalStockEndommage)
+	se.Get("/all/:product_uuid", middlewares.Can("stocks:read"), stocks.GetAllStockEndommages)
+	se.Post("/create", middlewares.Can("stocks:write"), stocks.CreateStockEndommage)
+	se.Get("/get/:uuid", middlewares.Can("stocks:read"), stocks.GetStockEndommage)
+	se.Put("/update/:uuid", middlewares.Can("stocks:write"), stocks.UpdateStockEndommage)
+	se.Delete("/delete/:uuid", middlewares.Can("stocks:delete"), stocks.DeleteStockEndommage)
 
 	// ============================================================
 	// RESTITUTIONS ROUTES
 	// ============================================================
 	re := api.Group("/restitutions")
-	re.Get("/:entreprise_uuid/:pos_uuid/all/synchronisation", stocks.GetDataSynchronisationRestitution)
-	re.Get("/all/paginate/:product_uuid", stocks.GetPaginatedRestitution)
-	re.Get("/all/total/:product_uuid", stocks.GetTotalRestitution)
-	re.Get("/all/:product_uuid", stocks.GetAllRestitutions)
-	re.Post("/create", stocks.CreateRestitution)
-	re.Get("/get/:uuid", stocks.GetRestitution)
-	re.Put("/update/:uuid", stocks.UpdateRestitution)
-	re.Delete("/delete/:uuid", stocks.DeleteRestitution)
+	re.Get("/:entreprise_uuid/:pos_uuid/all/synchronisation", middlewares.Can("stocks:read"), middlewares.TenantParams, stocks.GetDataSynchronisationRestitution)
+	re.Get("/all/paginate/:product_uuid", middlewares.Can("stocks:read"), stocks.GetPaginatedRestitution)
+	re.Get("/all/total/:product_uuid", middlewares.Can("stocks:read"), stocks.GetTotalRestitution)
+	re.Get("/all/:product_uuid", middlewares.Can("stocks:read"), stocks.GetAllRestitutions)
+	re.Post("/create", middlewares.Can("stocks:write"), stocks.CreateRestitution)
+	re.Get("/get/:uuid", middlewares.Can("stocks:read"), stocks.GetRestitution)
+	re.Put("/update/:uuid", middlewares.Can("stocks:write"), stocks.UpdateRestitution)
+	re.Delete("/delete/:uuid", middlewares.Can("stocks:delete"), stocks.DeleteRestitution)
 
 	// ============================================================
 	// CLIENTS ROUTES
 	// ============================================================
 	cl := api.Group("/clients")
-	cl.Get("/:entreprise_uuid/:pos_uuid/all/synchronisation", clients.GetDataSynchronisation)
-	cl.Get("/:entreprise_uuid/:pos_uuid/all/paginate", clients.GetPaginatedClient)
-	cl.Get("/:entreprise_uuid/all", clients.GetAllClients)
-	cl.Post("/uploads", clients.UploadCsvDataClient)
-	cl.Post("/create", clients.CreateClient)
-	cl.Get("/get/:uuid", clients.GetClient)
-	cl.Put("/update/:uuid", clients.UpdateClient)
-	cl.Delete("/delete/:uuid", clients.DeleteClient)
+	cl.Get("/:entreprise_uuid/:pos_uuid/all/synchronisation", middlewares.Can("clients:read"), middlewares.TenantParams, clients.GetDataSynchronisation)
+	cl.Get("/:entreprise_uuid/:pos_uuid/all/paginate", middlewares.Can("clients:read"), middlewares.TenantParams, clients.GetPaginatedClient)
+	cl.Get("/:entreprise_uuid/all", middlewares.Can("clients:read"), middlewares.TenantParams, clients.GetAllClients)
+	cl.Post("/uploads", middlewares.Can("clients:write"), clients.UploadCsvDataClient)
+	cl.Post("/create", middlewares.Can("clients:write"), clients.CreateClient)
+	cl.Get("/get/:uuid", middlewares.Can("clients:read"), clients.GetClient)
+	cl.Put("/update/:uuid", middlewares.Can("clients:write"), clients.UpdateClient)
+	cl.Delete("/delete/:uuid", middlewares.Can("clients:delete"), clients.DeleteClient)
 
 	// ============================================================
 	// FOURNISSEURS ROUTES
 	// ============================================================
 	fs := api.Group("/fournisseurs")
-	fs.Get("/:entreprise_uuid/:pos_uuid/all/synchronisation", fournisseurs.GetDataSynchronisation)
-	fs.Get("/:entreprise_uuid/:pos_uuid/all/paginate", fournisseurs.GetPaginatedFournisseur)
-	fs.Get("/:entreprise_uuid/all", fournisseurs.GetAllFournisseurs)
-	fs.Post("/create", fournisseurs.CreateFournisseur)
-	fs.Get("/get/:uuid", fournisseurs.GetFournisseur)
-	fs.Put("/update/:uuid", fournisseurs.UpdateFournisseur)
-	fs.Delete("/delete/:uuid", fournisseurs.DeleteFournisseur)
+	fs.Get("/:entreprise_uuid/:pos_uuid/all/synchronisation", middlewares.Can("fournisseurs:read"), middlewares.TenantParams, fournisseurs.GetDataSynchronisation)
+	fs.Get("/:entreprise_uuid/:pos_uuid/all/paginate", middlewares.Can("fournisseurs:read"), middlewares.TenantParams, fournisseurs.GetPaginatedFournisseur)
+	fs.Get("/:entreprise_uuid/all", middlewares.Can("fournisseurs:read"), middlewares.TenantParams, fournisseurs.GetAllFournisseurs)
+	fs.Post("/create", middlewares.Can("fournisseurs:write"), fournisseurs.CreateFournisseur)
+	fs.Get("/get/:uuid", middlewares.Can("fournisseurs:read"), fournisseurs.GetFournisseur)
+	fs.Put("/update/:uuid", middlewares.Can("fournisseurs:write"), fournisseurs.UpdateFournisseur)
+	fs.Delete("/delete/:uuid", middlewares.Can("fournisseurs:delete"), fournisseurs.DeleteFournisseur)
 
 	// ============================================================
 	// ZONES ROUTES
 	// ============================================================
 	z := api.Group("/zones")
-	z.Get("/:entreprise_uuid/:pos_uuid/all/synchronisation", zones.GetDataSynchronisation)
-	z.Get("/:entreprise_uuid/:pos_uuid/all/paginate", zones.GetPaginatedZone)
-	z.Get("/:entreprise_uuid/all", zones.GetAllZones)
-	z.Post("/uploads", zones.UploadCsvDataZone)
-	z.Post("/create", zones.CreateZone)
-	z.Get("/get/:uuid", zones.GetZone)
-	z.Put("/update/:uuid", zones.UpdateZone)
-	z.Delete("/delete/:uuid", zones.DeleteZone)
+	z.Get("/:entreprise_uuid/:pos_uuid/all/synchronisation", middlewares.Can("zones:read"), middlewares.TenantParams, zones.GetDataSynchronisation)
+	z.Get("/:entreprise_uuid/:pos_uuid/all/paginate", middlewares.Can("zones:read"), middlewares.TenantParams, zones.GetPaginatedZone)
+	z.Get("/:entreprise_uuid/all", middlewares.Can("zones:read"), middlewares.TenantParams, zones.GetAllZones)
+	z.Post("/uploads", middlewares.Can("zones:write"), zones.UploadCsvDataZone)
+	z.Post("/create", middlewares.Can("zones:write"), zones.CreateZone)
+	z.Get("/get/:uuid", middlewares.Can("zones:read"), zones.GetZone)
+	z.Put("/update/:uuid", middlewares.Can("zones:write"), zones.UpdateZone)
+	z.Delete("/delete/:uuid", middlewares.Can("zones:delete"), zones.DeleteZone)
 
 	// ============================================================
 	// LIVREURS ROUTES
 	// ============================================================
 	lv := api.Group("/livreurs")
-	lv.Get("/:entreprise_uuid/:pos_uuid/all/synchronisation", livreurs.GetDataSynchronisation)
-	lv.Get("/:entreprise_uuid/:pos_uuid/all/paginate", livreurs.GetPaginatedLivreur)
-	lv.Get("/:entreprise_uuid/:pos_uuid/type/:type", livreurs.GetLivreursByType)
-	lv.Get("/:entreprise_uuid/all", livreurs.GetAllLivreurs)
-	lv.Post("/uploads", livreurs.UploadCsvDataLivreur)
-	lv.Post("/create", livreurs.CreateLivreur)
-	lv.Get("/get/:uuid", livreurs.GetLivreur)
-	lv.Put("/update/:uuid", livreurs.UpdateLivreur)
-	lv.Delete("/delete/:uuid", livreurs.DeleteLivreur)
+	lv.Get("/:entreprise_uuid/:pos_uuid/all/synchronisation", middlewares.Can("livreurs:read"), middlewares.TenantParams, livreurs.GetDataSynchronisation)
+	lv.Get("/:entreprise_uuid/:pos_uuid/all/paginate", middlewares.Can("livreurs:read"), middlewares.TenantParams, livreurs.GetPaginatedLivreur)
+	lv.Get("/:entreprise_uuid/:pos_uuid/type/:type", middlewares.Can("livreurs:read"), middlewares.TenantParams, livreurs.GetLivreursByType)
+	lv.Get("/:entreprise_uuid/all", middlewares.Can("livreurs:read"), middlewares.TenantParams, livreurs.GetAllLivreurs)
+	lv.Post("/uploads", middlewares.Can("livreurs:write"), livreurs.UploadCsvDataLivreur)
+	lv.Post("/create", middlewares.Can("livreurs:write"), livreurs.CreateLivreur)
+	lv.Get("/get/:uuid", middlewares.Can("livreurs:read"), livreurs.GetLivreur)
+	lv.Put("/update/:uuid", middlewares.Can("livreurs:write"), livreurs.UpdateLivreur)
+	lv.Delete("/delete/:uuid", middlewares.Can("livreurs:delete"), livreurs.DeleteLivreur)
 
 	// ============================================================
 	// LIVRAISONS ROUTES
 	// ============================================================
 	liv := api.Group("/livraisons")
-	liv.Get("/:entreprise_uuid/:pos_uuid/all/synchronisation", livraisons.GetDataSynchronisation)
-	liv.Get("/:entreprise_uuid/:pos_uuid/all/paginate", livraisons.GetPaginatedLivraison)
-	liv.Get("/:entreprise_uuid/all", livraisons.GetAllLivraisons)
-	liv.Post("/uploads", livraisons.UploadCsvDataLivraison)
-	liv.Post("/create", livraisons.CreateLivraison)
-	liv.Get("/get/:uuid", livraisons.GetLivraison)
-	liv.Put("/update/:uuid", livraisons.UpdateLivraison)
-	liv.Delete("/delete/:uuid", livraisons.DeleteLivraison)
+	liv.Get("/:entreprise_uuid/:pos_uuid/all/synchronisation", middlewares.Can("livraisons:read"), middlewares.TenantParams, livraisons.GetDataSynchronisation)
+	liv.Get("/:entreprise_uuid/:pos_uuid/all/paginate", middlewares.Can("livraisons:read"), middlewares.TenantParams, livraisons.GetPaginatedLivraison)
+	liv.Get("/:entreprise_uuid/all", middlewares.Can("livraisons:read"), middlewares.TenantParams, livraisons.GetAllLivraisons)
+	liv.Post("/uploads", middlewares.Can("livraisons:write"), livraisons.UploadCsvDataLivraison)
+	liv.Post("/create", middlewares.Can("livraisons:write"), livraisons.CreateLivraison)
+	liv.Get("/get/:uuid", middlewares.Can("livraisons:read"), livraisons.GetLivraison)
+	liv.Put("/update/:uuid", middlewares.Can("livraisons:write"), livraisons.UpdateLivraison)
+	liv.Delete("/delete/:uuid", middlewares.Can("livraisons:delete"), livraisons.DeleteLivraison)
+
+	// ============================================================
+	// CHECKOUT ROUTES (vente complète en un seul appel)
+	// ============================================================
+	api.Post("/checkout", middlewares.Can("commandes:write", "caisses:write"), commandes.Checkout)
+
+	// ============================================================
+	// PAYMENTS ROUTES
+	// ============================================================
+	pay := api.Group("/payments")
+	pay.Get("/commande/:commande_uuid", middlewares.Can("commandes:read"), payments.GetCommandePayments)
+	pay.Post("/commande/:commande_uuid", middlewares.Can("commandes:write", "caisses:write"), payments.PayCommande)
+
+	// ============================================================
+	// PRICING ROUTES (calcul des totaux par le serveur)
+	// ============================================================
+	api.Post("/pricing/quote", middlewares.Can("commandes:read"), pricing.Quote)
+
+	// ============================================================
+	// NUMBERING ROUTES (numérotation des factures et avoirs)
+	// ============================================================
+	num := api.Group("/numbering")
+	num.Get("/:entreprise_uuid/patterns", middlewares.Can("numbering:read"), middlewares.TenantParams, numbering.GetNumberingPatterns)
+	num.Post("/patterns/save", middlewares.Can("numbering:write"), numbering.SaveNumberingPattern)
+	num.Delete("/patterns/delete/:uuid", middlewares.Can("numbering:write"), numbering.DeleteNumberingPattern)
+	num.Get("/preview/:pos_uuid/:document_type", middlewares.Can("numbering:read"), middlewares.TenantParams, numbering.PreviewNumber)
+
+	// ============================================================
+	// RECEIPT TEMPLATES ROUTES (modèles des factures et tickets)
+	// ============================================================
+	rtpl := api.Group("/receipt-templates")
+	rtpl.Get("/get/:pos_uuid", middlewares.Can("receipts:read"), middlewares.TenantParams, receipts.GetReceiptTemplate)
+	rtpl.Put("/update/:pos_uuid", middlewares.Can("receipts:write"), middlewares.TenantParams, receipts.SaveReceiptTemplate)
+
+	// ============================================================
+	// FISCAL ROUTES (certification des factures normalisées)
+	// ============================================================
+	fsc := api.Group("/fiscal")
+	fsc.Get("/:entreprise_uuid/queue", middlewares.Can("fiscal:read"), middlewares.TenantParams, fiscal.GetFiscalQueue)
+	fsc.Post("/certify/:uuid", middlewares.Can("fiscal:write"), fiscal.CertifyCommande)
 
 	// ============================================================
 	// COMMANDES ROUTES
 	// ============================================================
 	cmd := api.Group("/commandes")
-	cmd.Get("/:entreprise_uuid/:pos_uuid/all/synchronisation", commandes.GetDataSynchronisation)
-	cmd.Get("/:entreprise_uuid/:pos_uuid/all/paginate", commandes.GetPaginatedCommandePOS)
-	cmd.Get("/:entreprise_uuid/:pos_uuid/all", commandes.GetAllCommandes)
-	cmd.Get("/:entreprise_uuid/all/paginate", commandes.GetPaginatedCommandeEntreprise)
-	cmd.Post("/create", commandes.CreateCommande)
-	cmd.Get("/get/:uuid", commandes.GetCommande)
-	cmd.Put("/update/:uuid", commandes.UpdateCommande)
-	cmd.Delete("/delete/:uuid", commandes.DeleteCommande)
+	cmd.Get("/:entreprise_uuid/:pos_uuid/all/synchronisation", middlewares.Can("commandes:read"), middlewares.TenantParams, commandes.GetDataSynchronisation)
+	cmd.Get("/:entreprise_uuid/:pos_uuid/all/paginate", middlewares.Can("commandes:read"), middlewares.TenantParams, commandes.GetPaginatedCommandePOS)
+	cmd.Get("/:entreprise_uuid/:pos_uuid/all", middlewares.Can("commandes:read"), middlewares.TenantParams, commandes.GetAllCommandes)
+	cmd.Get("/:entreprise_uuid/all/paginate", middlewares.Can("commandes:read"), middlewares.TenantParams, commandes.GetPaginatedCommandeEntreprise)
+	cmd.Post("/create", middlewares.Can("commandes:write"), commandes.CreateCommande)
+	cmd.Get("/get/:uuid", middlewares.Can("commandes:read"), commandes.GetCommande)
+	cmd.Put("/update/:uuid", middlewares.Can("commandes:write"), commandes.UpdateCommande)
+	cmd.Put("/status/:uuid", middlewares.Can("commandes:write"), commandes.UpdateCommandeStatus)
+	cmd.Get("/status-history/:uuid", middlewares.Can("commandes:read"), commandes.GetCommandeStatusHistory)
+	cmd.Get("/:uuid/receipt", middlewares.Can("commandes:read"), commandes.GetCommandeReceipt)
+	cmd.Delete("/delete/:uuid", middlewares.Can("commandes:delete"), commandes.DeleteCommande)
 
 	// ============================================================
 	// COMMANDE LINES ROUTES
 	// ============================================================
 	cmdl := api.Group("/commande-lines")
-	cmdl.Get("/:entreprise_uuid/:pos_uuid/all/synchronisation", commandes.GetDataSynchronisationCommandeLine)
-	cmdl.Get("/all/paginate/:commande_uuid", commandes.GetPaginatedCommandeLineByID)
-	cmdl.Get("/all/total/:product_uuid", commandes.GetTotalCommandeLine)
-	cmdl.Get("/all/:commande_uuid", commandes.GetAllCommandeLineByUUId)
-	cmdl.Get("/all", commandes.GetAllCommandeLines)
-	cmdl.Post("/create", commandes.CreateCommandeLine)
-	cmdl.Get("/get/:uuid", commandes.GetCommandeLine)
-	cmdl.Put("/update/:uuid", commandes.UpdateCommandeLine)
-	cmdl.Delete("/delete/:uuid", commandes.DeleteCommandeLine)
+	cmdl.Get("/:entreprise_uuid/:pos_uuid/all/synchronisation", middlewares.Can("commandes:read"), middlewares.TenantParams, commandes.GetDataSynchronisationCommandeLine)
+	cmdl.Get("/all/paginate/:commande_uuid", middlewares.Can("commandes:read"), commandes.GetPaginatedCommandeLineByID)
+	cmdl.Get("/all/total/:product_uuid", middlewares.Can("commandes:read"), commandes.GetTotalCommandeLine)
+	cmdl.Get("/all/:commande_uuid", middlewares.Can("commandes:read"), commandes.GetAllCommandeLineByUUId)
+	cmdl.Get("/all", middlewares.Can("commandes:read"), commandes.GetAllCommandeLines)
+	cmdl.Post("/create", middlewares.Can("commandes:write"), commandes.CreateCommandeLine)
+	cmdl.Get("/get/:uuid", middlewares.Can("commandes:read"), commandes.GetCommandeLine)
+	cmdl.Put("/update/:uuid", middlewares.Can("commandes:write"), commandes.UpdateCommandeLine)
+	cmdl.Delete("/delete/:uuid", middlewares.Can("commandes:delete"), commandes.DeleteCommandeLine)
+
+	// ============================================================
+	// SALE RETURNS ROUTES (retours clients et avoirs)
+	// ============================================================
+	ret := api.Group("/sale-returns")
+	ret.Get("/:entreprise_uuid/all/paginate", middlewares.Can("returns:read"), middlewares.TenantParams, commandes.GetPaginatedSaleReturn)
+	ret.Get("/commande/:commande_uuid", middlewares.Can("returns:read"), commandes.GetCommandeSaleReturns)
+	ret.Post("/create", middlewares.Can("returns:write"), commandes.CreateSaleReturn)
+	ret.Get("/get/:uuid", middlewares.Can("returns:read"), commandes.GetSaleReturn)
 
 }
@@ -145,22 +145,108 @@ func (es *EmailService) SendPasswordResetEmail(to, token, employeeName string) e
 		return fmt.Errorf("erreur lors de l'exécution du template: %v", err)
 	}
 
+	return es.send(to, subject, body.String())
+}
+
+// send envoie un email HTML via SMTP
+func (es *EmailService) send(to, subject, html string) error {
 	// Construction du message email
 	msg := fmt.Sprintf("To: %s\r\n"+
 		"Subject: %s\r\n"+
 		"MIME-Version: 1.0\r\n"+
 		"Content-Type: text/html; charset=UTF-8\r\n"+
 		"\r\n"+
-		"%s", to, subject, body.String())
+		"%s", to, subject, html)
 
 	// Configuration SMTP
 	auth := smtp.PlainAuth("", es.Username, es.Password, es.Host)
 
 	// Envoi de l'email
-	err = smtp.SendMail(es.Host+":"+es.Port, auth, es.From, []string{to}, []byte(msg))
+	err := smtp.SendMail(es.Host+":"+es.Port, auth, es.From, []string{to}, []byte(msg))
 	if err != nil {
 		return fmt.Errorf("erreur lors de l'envoi de l'email: %v", err)
 	}
 
 	return nil
 }
+
+// SendInvitationEmail envoie l'invitation à rejoindre une entreprise
+func (es *EmailService) SendInvitationEmail(to, token, inviteeName, entrepriseName, inviterName string) error {
+	if es.Host == "" || es.Port == "" || es.Username == "" || es.Password == "" {
+		return fmt.Errorf("configuration email incomplète")
+	}
+
+	subject := "Invitation à rejoindre " + entrepriseName + " - IPOS-STOCK"
+
+	htmlTemplate := `
+<!DOCTYPE html>
+<html>
+<head>
+    <meta charset="UTF-8">
+    <title>Invitation</title>
+    <style>
+        body { font-family: Arial, sans-serif; line-height: 1.6; color: #333; }
+        .container { max-width: 600px; margin: 0 auto; padding: 20px; }
+        .header { background: #007bff; color: white; padding: 20px; text-align: center; }
+        .content { padding: 20px; background: #f9f9f9; }
+        .button { display: inline-block; background: #007bff; color: white; padding: 12px 24px; text-decoration: none; border-radius: 5px; margin: 20px 0; }
+        .token-value { background: white; border: 1px solid #ddd; border-radius: 4px; padding: 15px; font-family: 'Courier New', monospace; word-break: break-all; }
+        .footer { background: #333; color: white; padding: 15px; text-align: center; font-size: 12px; }
+        .warning { background: #fff3cd; border: 1px solid #ffeaa7; padding: 15px; margin: 15px 0; border-radius: 5px; }
+    </style>
+</head>
+<body>
+    <div class="container">
+        <div class="header">
+            <h1>Bienvenue sur IPOS-STOCK</h1>
+        </div>
+        <div class="content">
+            <p>Bonjour {{.InviteeName}},</p>
+            <p>{{.InviterName}} vous invite à rejoindre <strong>{{.EntrepriseName}}</strong> sur IPOS-STOCK.</p>
+            <p>Pour activer votre compte, choisissez votre mot de passe :</p>
+            <p><a class="button" href="{{.InvitationURL}}">Activer mon compte</a></p>
+            <p>Ou utilisez ce code dans l'application :</p>
+            <div class="token-value">{{.Token}}</div>
+
+            <div class="warning">
+                <strong>⚠️ Important :</strong>
+                <ul>
+                    <li>Cette invitation expire dans 72 heures</li>
+                    <li>Si vous ne vous attendiez pas à cette invitation, ignorez cet email</li>
+                </ul>
+            </div>
+        </div>
+        <div class="footer">
+            <p>Cet email a été généré automatiquement, merci de ne pas y répondre.</p>
+            <p>&copy; 2025 ICTECH - Tous droits réservés</p>
+        </div>
+    </div>
+</body>
+</html>`
+
+	tmpl, err := template.New("invitation").Parse(htmlTemplate)
+	if err != nil {
+		return fmt.Errorf("erreur lors du parsing du template: %v", err)
+	}
+
+	data := struct {
+		InviteeName    string
+		InviterName    string
+		EntrepriseName string
+		InvitationURL  string
+		Token          string
+	}{
+		InviteeName:    inviteeName,
+		InviterName:    inviterName,
+		EntrepriseName: entrepriseName,
+		InvitationURL:  Env("INVITATION_URL") + token,
+		Token:          token,
+	}
+
+	var body bytes.Buffer
+	if err := tmpl.Execute(&body, data); err != nil {
+		return fmt.Errorf("erreur lors de l'exécution du template: %v", err)
+	}
+
+	return es.send(to, subject, body.String())
+}
@@ -1,37 +1,101 @@
 package utils
 
 import (
-	"os"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"time"
 
 	"github.com/golang-jwt/jwt/v4"
 )
 
-var SECRET_KEY string = os.Getenv("SECRET_KEY")
+const (
+	AccessTokenTTL    = 15 * time.Minute    // Durée de vie du token d'accès
+	RefreshTokenTTL   = 30 * 24 * time.Hour // Durée de vie du refresh token
+	ChallengeTokenTTL = 5 * time.Minute     // Durée de vie du token de second facteur
+)
+
+// Usage d'un token, porté par le claim "sub"
+const (
+	tokenSubjectAccess    = "access"
+	tokenSubjectChallenge = "2fa"
+)
 
-func GenerateJwt(issuer string) (string, error) {
+// secretKey lit la clé au moment de l'appel, une fois le fichier .env chargé
+func secretKey() []byte {
+	return []byte(Env("SECRET_KEY"))
+}
+
+// GenerateAccessToken émet un token d'accès de courte durée lié à une session
+func GenerateAccessToken(userUUID, sessionUUID string) (string, error) {
 
-	claims := jwt.NewWithClaims(jwt.SigningMethodHS256, &jwt.StandardClaims{
-		Issuer:    issuer,
-		ExpiresAt: time.Now().Add(time.Hour * 72).Unix(), // 3 days
+	claims := jwt.NewWithClaims(jwt.SigningMethodHS256, &jwt.RegisteredClaims{
+		Issuer:    userUUID,
+		Subject:   tokenSubjectAccess,
+		ID:        sessionUUID,
+		IssuedAt:  jwt.NewNumericDate(time.Now()),
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(AccessTokenTTL)),
 	})
 
-	token, err := claims.SignedString([]byte(SECRET_KEY))
+	return claims.SignedString(secretKey())
+}
+
+// VerifyAccessToken retourne l'utilisateur et la session portés par le token
+func VerifyAccessToken(tokenString string) (string, string, error) {
+	claims, err := parseToken(tokenString, tokenSubjectAccess)
+	if err != nil {
+		return "", "", err
+	}
 
-	return token, err
+	return claims.Issuer, claims.ID, nil
 }
 
-func VerifyJwt(cookie string) (string, error) {
+// GenerateChallengeToken émet le token intermédiaire du login en deux étapes
+func GenerateChallengeToken(userUUID string) (string, error) {
 
-	token, err := jwt.ParseWithClaims(cookie, &jwt.StandardClaims{}, func(t *jwt.Token) (interface{}, error) {
-		return []byte(SECRET_KEY), nil
+	claims := jwt.NewWithClaims(jwt.SigningMethodHS256, &jwt.RegisteredClaims{
+		Issuer:    userUUID,
+		Subject:   tokenSubjectChallenge,
+		IssuedAt:  jwt.NewNumericDate(time.Now()),
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(ChallengeTokenTTL)),
 	})
 
-	if err != nil || !token.Valid {
+	return claims.SignedString(secretKey())
+}
+
+// VerifyChallengeToken retourne l'utilisateur ayant validé son mot de passe
+func VerifyChallengeToken(tokenString string) (string, error) {
+	claims, err := parseToken(tokenString, tokenSubjectChallenge)
+	if err != nil {
 		return "", err
 	}
 
-	claims := token.Claims.(*jwt.StandardClaims)
-
 	return claims.Issuer, nil
-}
\ No newline at end of file
+}
+
+func parseToken(tokenString, subject string) (*jwt.RegisteredClaims, error) {
+
+	token, err := jwt.ParseWithClaims(tokenString, &jwt.RegisteredClaims{}, func(t *jwt.Token) (interface{}, error) {
+		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, jwt.ErrSignatureInvalid
+		}
+		return secretKey(), nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	claims := token.Claims.(*jwt.RegisteredClaims)
+	if !token.Valid || claims.Subject != subject {
+		return nil, errors.New("token invalide")
+	}
+
+	return claims, nil
+}
+
+// HashToken calcule l'empreinte SHA-256 d'un token opaque avant stockage
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,90 @@
+package utils
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+func TestAccessToken(t *testing.T) {
+	t.Setenv("SECRET_KEY", "clé de test")
+
+	token, err := GenerateAccessToken("utilisateur", "session")
+	if err != nil {
+		t.Fatal(err)
+	}
+	user, session, err := VerifyAccessToken(token)
+	if err != nil {
+		t.Fatalf("VerifyAccessToken : %v", err)
+	}
+	if user != "utilisateur" || session != "session" {
+		t.Errorf("VerifyAccessToken = %q, %q ; attendu utilisateur, session", user, session)
+	}
+
+	// Le token du second facteur ne donne pas accès à l'API, et inversement
+	challenge, err := GenerateChallengeToken("utilisateur")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := VerifyAccessToken(challenge); err == nil {
+		t.Error("un token de second facteur est accepté comme token d'accès")
+	}
+	if _, err := VerifyChallengeToken(token); err == nil {
+		t.Error("un token d'accès est accepté comme token de second facteur")
+	}
+	if got, err := VerifyChallengeToken(challenge); err != nil || got != "utilisateur" {
+		t.Errorf("VerifyChallengeToken = %q, %v", got, err)
+	}
+}
+
+func TestAccessTokenRejected(t *testing.T) {
+	t.Setenv("SECRET_KEY", "clé de test")
+
+	sign := func(method jwt.SigningMethod, key interface{}, expiresAt time.Time) string {
+		s, err := jwt.NewWithClaims(method, &jwt.RegisteredClaims{
+			Issuer:    "utilisateur",
+			Subject:   tokenSubjectAccess,
+			ID:        "session",
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+		}).SignedString(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s
+	}
+	later := time.Now().Add(time.Minute)
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{"expiré", sign(jwt.SigningMethodHS256, []byte("clé de test"), time.Now().Add(-time.Second))},
+		{"signé avec une autre clé", sign(jwt.SigningMethodHS256, []byte("autre clé"), later)},
+		{"non signé", sign(jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType, later)},
+		{"mal formé", "pas.un.token"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := VerifyAccessToken(tt.token); err == nil {
+				t.Error("token accepté")
+			}
+		})
+	}
+}
+
+func TestHashToken(t *testing.T) {
+	token, err := GenerateSecureToken(32)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(token) != 64 {
+		t.Errorf("refresh token de %d caractères, attendu 64", len(token))
+	}
+	if HashToken(token) != HashToken(token) || len(HashToken(token)) != 64 {
+		t.Error("l'empreinte doit être stable et tenir sur 64 caractères")
+	}
+	if HashToken(token) == token || HashToken(token) == HashToken(token+"x") {
+		t.Error("l'empreinte doit différer du token et d'un autre token")
+	}
+}
@@ -64,6 +64,14 @@ func ValidatePassword(password string) error {
 	return nil
 }
 
+// ValidatePin vérifie qu'un code PIN contient de 4 à 6 chiffres
+func ValidatePin(pin string) error {
+	if !regexp.MustCompile(`^[0-9]{4,6}$`).MatchString(pin) {
+		return errors.New("le code PIN doit contenir de 4 à 6 chiffres")
+	}
+	return nil
+}
+
 // joinStrings joint des chaînes avec un séparateur
 func joinStrings(strings []string, separator string) string {
 	if len(strings) == 0 {
@@ -0,0 +1,21 @@
+package utils
+
+import "testing"
+
+func TestValidatePin(t *testing.T) {
+	tests := map[string]bool{
+		"1234":    true,
+		"000000":  true,
+		"123":     false,
+		"1234567": false,
+		"12a4":    false,
+		" 1234":   false,
+		"":        false,
+		"١٢٣٤":    false, // Chiffres arabes-indiens
+	}
+	for pin, ok := range tests {
+		if err := ValidatePin(pin); (err == nil) != ok {
+			t.Errorf("ValidatePin(%q) = %v", pin, err)
+		}
+	}
+}
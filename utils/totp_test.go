@@ -0,0 +1,74 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+// rfc6238Secret est la clé SHA1 des vecteurs de test de la RFC 6238
+// ("12345678901234567890"), encodée en base32
+const rfc6238Secret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestTOTPCode(t *testing.T) {
+	// Vecteurs de l'annexe B de la RFC 6238 (SHA1), réduits à 6 chiffres
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},          // 94287082
+		{1111111109, "081804"},  // 07081804
+		{1111111111, "050471"},  // 14050471
+		{1234567890, "005924"},  // 89005924
+		{2000000000, "279037"},  // 69279037
+		{20000000000, "353130"}, // 65353130
+	}
+	for _, tt := range tests {
+		got, err := TOTPCode(rfc6238Secret, tt.unix/TOTPPeriod)
+		if err != nil {
+			t.Fatalf("TOTPCode(T=%d) : %v", tt.unix, err)
+		}
+		if got != tt.want {
+			t.Errorf("TOTPCode(T=%d) = %s, attendu %s", tt.unix, got, tt.want)
+		}
+	}
+
+	if _, err := TOTPCode("pas du base32 !", 1); err == nil {
+		t.Error("TOTPCode doit refuser un secret invalide")
+	}
+}
+
+func TestValidateTOTP(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	step := now.Unix() / TOTPPeriod
+	code := func(offset int64) string {
+		c, err := TOTPCode(rfc6238Secret, step+offset)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return c
+	}
+
+	tests := []struct {
+		name string
+		code string
+		ok   bool
+		step int64
+	}{
+		{"période courante", code(0), true, step},
+		{"période précédente tolérée", code(-1), true, step - 1},
+		{"période suivante tolérée", code(1), true, step + 1},
+		{"deux périodes de retard", code(-2), false, 0},
+		{"deux périodes d'avance", code(2), false, 0},
+		{"espaces autour du code", " " + code(0) + " ", true, step},
+		{"code trop court", code(0)[:5], false, 0},
+		{"code faux", "000000", false, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := ValidateTOTP(rfc6238Secret, tt.code, now)
+			if ok != tt.ok || got != tt.step {
+				t.Errorf("ValidateTOTP(%q) = %d, %v ; attendu %d, %v", tt.code, got, ok, tt.step, tt.ok)
+			}
+		})
+	}
+}